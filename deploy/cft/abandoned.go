@@ -0,0 +1,294 @@
+package cft
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// timeNow is stubbed in tests.
+var timeNow = time.Now
+
+// AbandonedResource is a resource that was removed from the config and abandoned by a deployment update.
+// Abandoned resources keep existing, and holding data, until they are cleaned up.
+type AbandonedResource struct {
+	Name string `json:"name"`
+
+	// Type is the type of the resource in the expanded config of the deployment, e.g. storage.v1.bucket.
+	Type string `json:"type"`
+
+	Properties map[string]interface{} `json:"properties,omitempty"`
+
+	// AbandonedAt is the time of the update that abandoned the resource in RFC 3339 format.
+	AbandonedAt string `json:"abandoned_at"`
+
+	// BackedUp must be set by the user, in the abandoned resources of the project's generated fields,
+	// to allow resources that still hold data to be deleted.
+	BackedUp bool `json:"backed_up,omitempty"`
+}
+
+// abandonedResourcesURL returns the URL of the object in the logs bucket of the project its abandoned resources are
+// stored in.
+func abandonedResourcesURL(project *Project) string {
+	return fmt.Sprintf("gs://%s/abandoned_resources.json", project.AuditLogs.LogsGCSBucket.Name)
+}
+
+// loadAbandonedResources returns the abandoned resources of the project and whether they were stored.
+// Once stored, the stored resources are authoritative and the abandoned resources in the generated fields of the
+// project only mark resources as backed up. Until then, or if the project has no logs bucket, the abandoned resources
+// are taken from the generated fields.
+func loadAbandonedResources(project *Project) ([]*AbandonedResource, bool, error) {
+	url := abandonedResourcesURL(project)
+	out, err := cmdCombinedOutput(exec.Command("gsutil", "cat", url))
+	if err != nil {
+		if strings.Contains(string(out), "No URLs matched") || isBucketNotFoundOutput(out) {
+			return project.GeneratedFields.AbandonedResources, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to read %s: %v\n%v", url, err, string(out))
+	}
+
+	var stored []*AbandonedResource
+	if err := json.Unmarshal(out, &stored); err != nil {
+		return nil, false, fmt.Errorf("failed to unmarshal %s: %v", url, err)
+	}
+	backedUp := make(map[string]bool)
+	for _, a := range project.GeneratedFields.AbandonedResources {
+		backedUp[a.Name] = a.BackedUp
+	}
+	for _, a := range stored {
+		a.BackedUp = a.BackedUp || backedUp[a.Name]
+	}
+	return stored, true, nil
+}
+
+// storeAbandonedResources stores the abandoned resources of the project in its logs bucket.
+// If the project has no logs bucket, they are only kept in its generated fields.
+func storeAbandonedResources(project *Project, abandoned []*AbandonedResource) error {
+	if abandoned == nil {
+		abandoned = []*AbandonedResource{}
+	}
+	b, err := json.MarshalIndent(abandoned, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal abandoned resources: %v", err)
+	}
+
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		return fmt.Errorf("failed to write abandoned resources to file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %v", err)
+	}
+
+	cmd := exec.Command("gsutil", "cp", tmp.Name(), abandonedResourcesURL(project))
+	if out, err := cmdCombinedOutput(cmd); err != nil {
+		if isBucketNotFoundOutput(out) {
+			log.Printf("Logs bucket %q of project %q does not exist, keep the abandoned resources in its generated_fields",
+				project.AuditLogs.LogsGCSBucket.Name, project.ID)
+			return nil
+		}
+		return fmt.Errorf("failed to run command %v: %v\n%v", cmd.Args, err, string(out))
+	}
+	return nil
+}
+
+func isBucketNotFoundOutput(out []byte) bool {
+	return strings.Contains(string(out), "BucketNotFoundException")
+}
+
+// updateAbandonedResources returns the abandoned resources after the expanded config of the deployment was updated
+// from previous to current. Resources in previous but not in current are added, and resources added back to current
+// are removed.
+func updateAbandonedResources(abandoned []*AbandonedResource, previous, current *Deployment) []*AbandonedResource {
+	inCurrent := make(map[string]bool)
+	if current != nil {
+		for _, res := range current.Resources {
+			inCurrent[res.Name] = true
+		}
+	}
+
+	var updated []*AbandonedResource
+	seen := make(map[string]bool)
+	for _, a := range abandoned {
+		if !inCurrent[a.Name] {
+			updated = append(updated, a)
+			seen[a.Name] = true
+		}
+	}
+
+	if previous == nil {
+		return updated
+	}
+	now := timeNow().UTC().Format(time.RFC3339)
+	for _, res := range previous.Resources {
+		if inCurrent[res.Name] || seen[res.Name] {
+			continue
+		}
+		log.Printf("Resource %q was removed from the config and abandoned", res.Name)
+		updated = append(updated, &AbandonedResource{
+			Name:        res.Name,
+			Type:        res.Type,
+			Properties:  res.Properties,
+			AbandonedAt: now,
+		})
+	}
+	return updated
+}
+
+// abandonedCleaner checks whether abandoned resources of a kind hold data and deletes them.
+type abandonedCleaner struct {
+	// empty returns whether the resource holds no data.
+	empty func(projectID string, a *AbandonedResource) (bool, error)
+
+	// remove deletes the resource, including any data it holds.
+	remove func(projectID string, a *AbandonedResource) error
+}
+
+// abandonedCleaners maps the types of resources to their cleaners.
+var abandonedCleaners = map[string]abandonedCleaner{
+	"bigquery.v2.dataset":    {empty: bigqueryDatasetEmpty, remove: removeBigqueryDataset},
+	"compute.v1.instance":    {empty: alwaysHoldsData, remove: removeGCEInstance},
+	"logging.v2.metric":      {empty: neverHoldsData, remove: removeMetric},
+	"pubsub.v1.subscription": {empty: alwaysHoldsData, remove: removePubsubSubscription},
+	"pubsub.v1.topic":        {empty: neverHoldsData, remove: removePubsubTopic},
+	"storage.v1.bucket":      {empty: gcsBucketEmpty, remove: removeGCSBucket},
+}
+
+// CleanupAbandonedResources deletes the abandoned resources of the project that hold no data or are backed up.
+// If confirm is false, it only logs the resources it would delete.
+// Otherwise the abandoned resources that are left are stored in place of the cleaned up ones.
+// It returns the abandoned resources that are left.
+func CleanupAbandonedResources(project *Project, confirm bool) ([]*AbandonedResource, error) {
+	abandoned, stored, err := loadAbandonedResources(project)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load abandoned resources: %v", err)
+	}
+
+	var left []*AbandonedResource
+	for _, a := range abandoned {
+		c, ok := abandonedCleaners[a.Type]
+		if !ok {
+			log.Printf("Cleaning up %q is not supported for type %q, delete it manually", a.Name, a.Type)
+			left = append(left, a)
+			continue
+		}
+
+		if !a.BackedUp {
+			empty, err := c.empty(project.ID, a)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check if %q is empty: %v", a.Name, err)
+			}
+			if !empty {
+				log.Printf("Not deleting %q as it holds data and is not backed up. Set backed_up once it is backed up", a.Name)
+				left = append(left, a)
+				continue
+			}
+		}
+
+		if !confirm {
+			log.Printf("Would delete %q", a.Name)
+			left = append(left, a)
+			continue
+		}
+		log.Printf("Deleting %q", a.Name)
+		if err := c.remove(project.ID, a); err != nil {
+			return nil, fmt.Errorf("failed to delete %q: %v", a.Name, err)
+		}
+	}
+
+	if confirm && (stored || len(left) > 0) {
+		if err := storeAbandonedResources(project, left); err != nil {
+			return nil, fmt.Errorf("failed to store abandoned resources: %v", err)
+		}
+	}
+	return left, nil
+}
+
+// alwaysHoldsData is used for resources whose data cannot be checked, such as the disks of instances and the
+// undelivered messages of subscriptions.
+func alwaysHoldsData(string, *AbandonedResource) (bool, error) {
+	return false, nil
+}
+
+func neverHoldsData(string, *AbandonedResource) (bool, error) {
+	return true, nil
+}
+
+func gcsBucketEmpty(projectID string, a *AbandonedResource) (bool, error) {
+	// Include noncurrent versions of objects.
+	out, err := runCleanupCommand(exec.Command("gsutil", "ls", "-a", "gs://"+a.Name))
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(out) == "", nil
+}
+
+func removeGCSBucket(projectID string, a *AbandonedResource) error {
+	empty, err := gcsBucketEmpty(projectID, a)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command("gsutil", "rb", "gs://"+a.Name)
+	if !empty {
+		// Remove all objects, including noncurrent versions, and the bucket.
+		cmd = exec.Command("gsutil", "-m", "rm", "-r", "-a", "gs://"+a.Name)
+	}
+	_, err = runCleanupCommand(cmd)
+	return err
+}
+
+func bigqueryDatasetEmpty(projectID string, a *AbandonedResource) (bool, error) {
+	out, err := runCleanupCommand(exec.Command("bq", "ls", "--format=json", fmt.Sprintf("%s:%s", projectID, a.Name)))
+	if err != nil {
+		return false, err
+	}
+	out = strings.TrimSpace(out)
+	return out == "" || out == "[]", nil
+}
+
+func removeBigqueryDataset(projectID string, a *AbandonedResource) error {
+	_, err := runCleanupCommand(exec.Command("bq", "rm", "-r", "-f", "-d", fmt.Sprintf("%s:%s", projectID, a.Name)))
+	return err
+}
+
+// removePubsubTopic deletes the topic. Its subscriptions hold its messages and are abandoned as separate resources.
+func removePubsubTopic(projectID string, a *AbandonedResource) error {
+	cmd := exec.Command("gcloud", "pubsub", "topics", "delete", fmt.Sprintf("%v", a.Properties["topic"]), "--project", projectID, "--quiet")
+	_, err := runCleanupCommand(cmd)
+	return err
+}
+
+func removePubsubSubscription(projectID string, a *AbandonedResource) error {
+	cmd := exec.Command("gcloud", "pubsub", "subscriptions", "delete", fmt.Sprintf("%v", a.Properties["subscription"]), "--project", projectID, "--quiet")
+	_, err := runCleanupCommand(cmd)
+	return err
+}
+
+func removeGCEInstance(projectID string, a *AbandonedResource) error {
+	cmd := exec.Command("gcloud", "compute", "instances", "delete", a.Name, "--zone", fmt.Sprintf("%v", a.Properties["zone"]), "--project", projectID, "--quiet")
+	_, err := runCleanupCommand(cmd)
+	return err
+}
+
+func removeMetric(projectID string, a *AbandonedResource) error {
+	_, err := runCleanupCommand(exec.Command("gcloud", "logging", "metrics", "delete", a.Name, "--project", projectID, "--quiet"))
+	return err
+}
+
+func runCleanupCommand(cmd *exec.Cmd) (string, error) {
+	out, err := cmdCombinedOutput(cmd)
+	if err != nil {
+		return "", fmt.Errorf("failed to run command %v: %v\n%v", cmd.Args, err, string(out))
+	}
+	return string(out), nil
+}
@@ -0,0 +1,331 @@
+package cft
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDeployTracksAbandonedResources(t *testing.T) {
+	defer func(orig func() time.Time) { timeNow = orig }(timeNow)
+	timeNow = func() time.Time { return time.Date(2019, 5, 1, 12, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name         string
+		noLogsBucket bool
+		wantStored   bool
+	}{
+		{name: "logs_bucket", wantStored: true},
+		{name: "no_logs_bucket", noLogsBucket: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, project := getTestConfigAndProject(t, &ConfigData{`
+resources:
+- gcs_bucket:
+    properties:
+      name: foo-bucket
+      location: us-east1
+- pubsub:
+    properties:
+      topic: foo-topic
+      messageStoragePolicy:
+        allowedPersistenceRegions:
+        - us-central1`})
+			project.GeneratedFields.AbandonedResources = []*AbandonedResource{
+				{Name: "foo-bucket", Type: "storage.v1.bucket", AbandonedAt: "2019-01-01T00:00:00Z"},
+				{Name: "older-bucket", Type: "storage.v1.bucket", AbandonedAt: "2019-01-01T00:00:00Z"},
+			}
+
+			// old-bucket was removed from the config and the subscription of foo-topic from its resource.
+			commander := &fakeCommander{
+				listDeploymentName: "managed-data-protect-toolkit",
+				wantDeploymentCommand: []string{
+					"gcloud", "deployment-manager", "deployments", "update", "managed-data-protect-toolkit",
+					"--delete-policy", "ABANDON", "--project", project.ID},
+				expandedManifest: `
+resources:
+- name: foo-bucket
+  type: storage.v1.bucket
+  properties:
+    name: foo-bucket
+- name: old-bucket
+  type: storage.v1.bucket
+  properties:
+    name: old-bucket
+- name: foo-topic-topic
+  type: pubsub.v1.topic
+  properties:
+    topic: foo-topic
+- name: foo-topic-subscription-1
+  type: pubsub.v1.subscription
+  properties:
+    subscription: foo-subscription
+    topic: $(ref.foo-topic-topic.name)`,
+				deployedExpandedManifest: `
+resources:
+- name: foo-bucket
+  type: storage.v1.bucket
+  properties:
+    name: foo-bucket
+- name: foo-topic-topic
+  type: pubsub.v1.topic
+  properties:
+    topic: foo-topic`,
+				noLogsBucket: tc.noLogsBucket,
+			}
+			cmdRun = commander.Run
+			cmdCombinedOutput = commander.CombinedOutput
+
+			if err := Deploy(project); err != nil {
+				t.Fatalf("Deploy: %v", err)
+			}
+
+			// foo-bucket was added back to the config, so it is no longer abandoned.
+			want := []*AbandonedResource{
+				{Name: "older-bucket", Type: "storage.v1.bucket", AbandonedAt: "2019-01-01T00:00:00Z"},
+				{
+					Name:        "old-bucket",
+					Type:        "storage.v1.bucket",
+					Properties:  map[string]interface{}{"name": "old-bucket"},
+					AbandonedAt: "2019-05-01T12:00:00Z",
+				},
+				{
+					Name:        "foo-topic-subscription-1",
+					Type:        "pubsub.v1.subscription",
+					Properties:  map[string]interface{}{"subscription": "foo-subscription", "topic": "$(ref.foo-topic-topic.name)"},
+					AbandonedAt: "2019-05-01T12:00:00Z",
+				},
+			}
+			if diff := cmp.Diff(project.GeneratedFields.AbandonedResources, want); diff != "" {
+				t.Errorf("abandoned resources differ (-got +want):\n%v", diff)
+			}
+
+			if !tc.wantStored {
+				if len(commander.gotAbandonedResourcesFiles) != 0 {
+					t.Errorf("abandoned resources stored %d times, want never", len(commander.gotAbandonedResourcesFiles))
+				}
+				return
+			}
+			if len(commander.gotAbandonedResourcesFiles) != 1 {
+				t.Fatalf("abandoned resources stored %d times, want once", len(commander.gotAbandonedResourcesFiles))
+			}
+			var stored []*AbandonedResource
+			if err := json.Unmarshal(commander.gotAbandonedResourcesFiles[0], &stored); err != nil {
+				t.Fatalf("json.Unmarshal: %v", err)
+			}
+			if diff := cmp.Diff(stored, want); diff != "" {
+				t.Errorf("stored abandoned resources differ (-got +want):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestCleanupAbandonedResources(t *testing.T) {
+	defer func(orig func(*exec.Cmd) ([]byte, error)) { cmdCombinedOutput = orig }(cmdCombinedOutput)
+
+	abandoned := []*AbandonedResource{
+		{Name: "empty-bucket", Type: "storage.v1.bucket"},
+		{Name: "full-bucket", Type: "storage.v1.bucket"},
+		{Name: "backed-up-bucket", Type: "storage.v1.bucket", BackedUp: true},
+		{Name: "foo_dataset", Type: "bigquery.v2.dataset"},
+		{Name: "foo-instance", Type: "compute.v1.instance", Properties: map[string]interface{}{"zone": "us-east1-a"}},
+		{Name: "foo-topic-topic", Type: "pubsub.v1.topic", Properties: map[string]interface{}{"topic": "foo-topic"}},
+		{Name: "foo-topic-subscription-1", Type: "pubsub.v1.subscription", Properties: map[string]interface{}{"subscription": "foo-subscription"}},
+		{Name: "foo-router", Type: "compute.v1.router"},
+	}
+	outputs := map[string]string{
+		"gsutil ls -a gs://empty-bucket":             "",
+		"gsutil ls -a gs://full-bucket":              "gs://full-bucket/foo.txt#1\n",
+		"gsutil ls -a gs://backed-up-bucket":         "gs://backed-up-bucket/foo.txt#1\n",
+		"bq ls --format=json my-project:foo_dataset": "[]\n",
+	}
+
+	tests := []struct {
+		name         string
+		confirm      bool
+		noLogsBucket bool
+		wantCommands []string
+		wantLeft     []string
+	}{
+		{
+			name: "dry_run",
+			wantCommands: []string{
+				"gsutil cat gs://my-project-logs/abandoned_resources.json",
+				"gsutil ls -a gs://empty-bucket",
+				"gsutil ls -a gs://full-bucket",
+				"bq ls --format=json my-project:foo_dataset",
+			},
+			wantLeft: []string{
+				"empty-bucket", "full-bucket", "backed-up-bucket", "foo_dataset", "foo-instance", "foo-topic-topic",
+				"foo-topic-subscription-1", "foo-router",
+			},
+		},
+		{
+			name:    "confirm",
+			confirm: true,
+			wantCommands: []string{
+				"gsutil cat gs://my-project-logs/abandoned_resources.json",
+				"gsutil ls -a gs://empty-bucket",
+				"gsutil ls -a gs://empty-bucket",
+				"gsutil rb gs://empty-bucket",
+				"gsutil ls -a gs://full-bucket",
+				"gsutil ls -a gs://backed-up-bucket",
+				"gsutil -m rm -r -a gs://backed-up-bucket",
+				"bq ls --format=json my-project:foo_dataset",
+				"bq rm -r -f -d my-project:foo_dataset",
+				"gcloud pubsub topics delete foo-topic --project my-project --quiet",
+				"gsutil cp FILE gs://my-project-logs/abandoned_resources.json",
+			},
+			wantLeft: []string{"full-bucket", "foo-instance", "foo-topic-subscription-1", "foo-router"},
+		},
+		{
+			// The abandoned resources are taken from and left in the generated fields.
+			name:         "confirm_no_logs_bucket",
+			confirm:      true,
+			noLogsBucket: true,
+			wantCommands: []string{
+				"gsutil cat gs://my-project-logs/abandoned_resources.json",
+				"gsutil ls -a gs://empty-bucket",
+				"gsutil ls -a gs://empty-bucket",
+				"gsutil rb gs://empty-bucket",
+				"gsutil ls -a gs://full-bucket",
+				"gsutil ls -a gs://backed-up-bucket",
+				"gsutil -m rm -r -a gs://backed-up-bucket",
+				"bq ls --format=json my-project:foo_dataset",
+				"bq rm -r -f -d my-project:foo_dataset",
+				"gcloud pubsub topics delete foo-topic --project my-project --quiet",
+				"gsutil cp FILE gs://my-project-logs/abandoned_resources.json",
+			},
+			wantLeft: []string{"full-bucket", "foo-instance", "foo-topic-subscription-1", "foo-router"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotCommands []string
+			cmdCombinedOutput = func(cmd *exec.Cmd) ([]byte, error) {
+				if cmd.Args[1] == "cp" {
+					cmd.Args[2] = "FILE"
+				}
+				args := strings.Join(cmd.Args, " ")
+				gotCommands = append(gotCommands, args)
+				if tc.noLogsBucket && (cmd.Args[1] == "cat" || cmd.Args[1] == "cp") {
+					return []byte("BucketNotFoundException: 404 gs://my-project-logs bucket does not exist."), errors.New("exit status 1")
+				}
+				if cmd.Args[1] == "cat" {
+					return []byte("CommandException: No URLs matched: " + cmd.Args[2]), errors.New("exit status 1")
+				}
+				if out, ok := outputs[args]; ok {
+					return []byte(out), nil
+				}
+				if strings.Contains(args, " rb ") || strings.Contains(args, " rm ") || strings.Contains(args, " cp ") || strings.Contains(args, " delete ") {
+					return nil, nil
+				}
+				return nil, errors.New("unexpected command")
+			}
+
+			_, project := getTestConfigAndProject(t, nil)
+			project.GeneratedFields.AbandonedResources = abandoned
+
+			left, err := CleanupAbandonedResources(project, tc.confirm)
+			if err != nil {
+				t.Fatalf("CleanupAbandonedResources: %v", err)
+			}
+
+			var gotLeft []string
+			for _, a := range left {
+				gotLeft = append(gotLeft, a.Name)
+			}
+			if diff := cmp.Diff(gotLeft, tc.wantLeft); diff != "" {
+				t.Errorf("resources left differ (-got +want):\n%v", diff)
+			}
+			if diff := cmp.Diff(gotCommands, tc.wantCommands); diff != "" {
+				t.Errorf("commands differ (-got +want):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestCleanupStoredAbandonedResources(t *testing.T) {
+	defer func(orig func(*exec.Cmd) ([]byte, error)) { cmdCombinedOutput = orig }(cmdCombinedOutput)
+
+	const storedJSON = `[
+  {"name": "empty-bucket", "type": "storage.v1.bucket", "abandoned_at": "2019-01-01T00:00:00Z"},
+  {"name": "full-bucket", "type": "storage.v1.bucket", "abandoned_at": "2019-01-01T00:00:00Z"},
+  {"name": "other-full-bucket", "type": "storage.v1.bucket", "abandoned_at": "2019-01-01T00:00:00Z"}
+]`
+	outputs := map[string]string{
+		"gsutil cat gs://my-project-logs/abandoned_resources.json": storedJSON,
+		"gsutil ls -a gs://empty-bucket":                           "",
+		"gsutil ls -a gs://full-bucket":                            "gs://full-bucket/foo.txt#1\n",
+		"gsutil ls -a gs://other-full-bucket":                      "gs://other-full-bucket/foo.txt#1\n",
+	}
+
+	var gotCommands []string
+	var gotStored []*AbandonedResource
+	cmdCombinedOutput = func(cmd *exec.Cmd) ([]byte, error) {
+		if cmd.Args[1] == "cp" {
+			b, err := ioutil.ReadFile(cmd.Args[2])
+			if err != nil {
+				return nil, err
+			}
+			if err := json.Unmarshal(b, &gotStored); err != nil {
+				return nil, err
+			}
+			cmd.Args[2] = "FILE"
+		}
+		args := strings.Join(cmd.Args, " ")
+		gotCommands = append(gotCommands, args)
+		if out, ok := outputs[args]; ok {
+			return []byte(out), nil
+		}
+		if strings.Contains(args, " rb ") || strings.Contains(args, " rm ") || strings.Contains(args, " cp ") {
+			return nil, nil
+		}
+		return nil, errors.New("unexpected command")
+	}
+
+	// Once stored, the generated fields only mark resources as backed up.
+	// Resources that are only listed in them were already cleaned up and are ignored.
+	_, project := getTestConfigAndProject(t, nil)
+	project.GeneratedFields.AbandonedResources = []*AbandonedResource{
+		{Name: "full-bucket", Type: "storage.v1.bucket", BackedUp: true},
+		{Name: "deleted-bucket", Type: "storage.v1.bucket"},
+	}
+
+	left, err := CleanupAbandonedResources(project, true)
+	if err != nil {
+		t.Fatalf("CleanupAbandonedResources: %v", err)
+	}
+
+	wantLeft := []*AbandonedResource{
+		{Name: "other-full-bucket", Type: "storage.v1.bucket", AbandonedAt: "2019-01-01T00:00:00Z"},
+	}
+	if diff := cmp.Diff(left, wantLeft); diff != "" {
+		t.Errorf("resources left differ (-got +want):\n%v", diff)
+	}
+	if diff := cmp.Diff(gotStored, wantLeft); diff != "" {
+		t.Errorf("stored resources differ (-got +want):\n%v", diff)
+	}
+	wantCommands := []string{
+		"gsutil cat gs://my-project-logs/abandoned_resources.json",
+		"gsutil ls -a gs://empty-bucket",
+		"gsutil ls -a gs://empty-bucket",
+		"gsutil rb gs://empty-bucket",
+		"gsutil ls -a gs://full-bucket",
+		"gsutil -m rm -r -a gs://full-bucket",
+		"gsutil ls -a gs://other-full-bucket",
+		"gsutil cp FILE gs://my-project-logs/abandoned_resources.json",
+	}
+	if diff := cmp.Diff(gotCommands, wantCommands); diff != "" {
+		t.Errorf("commands differ (-got +want):\n%v", diff)
+	}
+}
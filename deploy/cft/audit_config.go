@@ -0,0 +1,138 @@
+package cft
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// AuditConfig configures the audit logs of a service in a project.
+type AuditConfig struct {
+	// Service is the service to log, e.g. storage.googleapis.com, or allServices.
+	Service string `json:"service"`
+
+	// LogTypes are the types of logs to write: ADMIN_READ, DATA_READ or DATA_WRITE.
+	LogTypes []string `json:"log_types"`
+
+	// ExemptedMembers are not logged for any of the log types, e.g. service accounts of high-volume pipelines.
+	ExemptedMembers []string `json:"exempted_members,omitempty"`
+}
+
+var auditLogTypes = map[string]bool{
+	"ADMIN_READ": true,
+	"DATA_READ":  true,
+	"DATA_WRITE": true,
+}
+
+func (c *AuditConfig) init() error {
+	if c.Service == "" {
+		return errors.New("service must be set")
+	}
+	if len(c.LogTypes) == 0 {
+		return errors.New("log_types must be set")
+	}
+	for _, t := range c.LogTypes {
+		if !auditLogTypes[t] {
+			return fmt.Errorf("unknown log type %q, want ADMIN_READ, DATA_READ or DATA_WRITE", t)
+		}
+	}
+	for _, m := range c.ExemptedMembers {
+		if !strings.Contains(m, ":") {
+			return fmt.Errorf("exempted member %q must have a type, e.g. serviceAccount:%s", m, m)
+		}
+	}
+	return nil
+}
+
+// checkAuditConfigs checks that the audit configs declared for a project keep all audit logs of all services, as
+// Forseti requires them in all projects. Only the exempted members and extra configs of specific services may vary.
+func checkAuditConfigs(configs []*AuditConfig) error {
+	for _, c := range configs {
+		if c.Service != "allServices" {
+			continue
+		}
+		types := make(map[string]bool)
+		for _, t := range c.LogTypes {
+			types[t] = true
+		}
+		if len(types) == len(auditLogTypes) {
+			return nil
+		}
+	}
+	return errors.New("a config of allServices with log types ADMIN_READ, DATA_READ and DATA_WRITE must be set")
+}
+
+// iamAuditConfig is an audit config in an IAM policy.
+// See https://cloud.google.com/resource-manager/reference/rest/Shared.Types/Policy#auditconfig.
+type iamAuditConfig struct {
+	Service         string              `json:"service"`
+	AuditLogConfigs []iamAuditLogConfig `json:"auditLogConfigs"`
+}
+
+type iamAuditLogConfig struct {
+	LogType         string   `json:"logType"`
+	ExemptedMembers []string `json:"exemptedMembers,omitempty"`
+}
+
+func (c *AuditConfig) iamAuditConfig() iamAuditConfig {
+	ic := iamAuditConfig{Service: c.Service}
+	for _, t := range c.LogTypes {
+		ic.AuditLogConfigs = append(ic.AuditLogConfigs, iamAuditLogConfig{LogType: t, ExemptedMembers: c.ExemptedMembers})
+	}
+	return ic
+}
+
+// deployAuditConfigs sets the audit configs of the project in its IAM policy.
+// Audit configs set previously are replaced and the bindings of the policy are left unchanged.
+func deployAuditConfigs(project *Project) error {
+	cmd := exec.Command("gcloud", "projects", "get-iam-policy", project.ID, "--format", "json")
+	out, err := cmdOutput(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to get IAM policy: %v", err)
+	}
+
+	// Keep the other fields of the policy, including the etag to detect concurrent changes.
+	policy := make(map[string]interface{})
+	if err := json.Unmarshal(out, &policy); err != nil {
+		return fmt.Errorf("failed to unmarshal IAM policy: %v", err)
+	}
+	var configs []iamAuditConfig
+	for _, c := range project.AuditConfigs {
+		configs = append(configs, c.iamAuditConfig())
+	}
+	policy["auditConfigs"] = configs
+
+	b, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("failed to marshal IAM policy: %v", err)
+	}
+
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		return fmt.Errorf("failed to write IAM policy to file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %v", err)
+	}
+
+	args := []string{"projects", "set-iam-policy", project.ID, tmp.Name()}
+	log.Printf("Running gcloud command with args: %v", args)
+
+	cmd = exec.Command("gcloud", args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stdout
+	if err := cmdRun(cmd); err != nil {
+		return fmt.Errorf("failed to run command: %v", err)
+	}
+	return nil
+}
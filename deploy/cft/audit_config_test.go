@@ -0,0 +1,172 @@
+package cft
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ghodss/yaml"
+)
+
+func TestDeployAuditConfigs(t *testing.T) {
+	_, project := getTestConfigAndProject(t, &ConfigData{`
+audit_configs:
+- service: allServices
+  log_types:
+  - ADMIN_READ
+  - DATA_READ
+  - DATA_WRITE
+  exempted_members:
+  - serviceAccount:pipeline@my-project.iam.gserviceaccount.com
+- service: storage.googleapis.com
+  log_types:
+  - DATA_READ`})
+
+	defer func(orig func(*exec.Cmd) ([]byte, error)) { cmdOutput = orig }(cmdOutput)
+	defer func(orig func(*exec.Cmd) error) { cmdRun = orig }(cmdRun)
+
+	var gotCommands []string
+	cmdOutput = func(cmd *exec.Cmd) ([]byte, error) {
+		gotCommands = append(gotCommands, strings.Join(cmd.Args, " "))
+		return []byte(`{
+  "bindings": [{"role": "roles/owner", "members": ["group:my-project-owners@my-domain.com"]}],
+  "auditConfigs": [{"service": "allServices", "auditLogConfigs": [{"logType": "ADMIN_READ"}]}],
+  "etag": "BwWKmjvelug=",
+  "version": 1
+}`), nil
+	}
+	var gotPolicy interface{}
+	cmdRun = func(cmd *exec.Cmd) error {
+		// The policy file is removed after the command runs.
+		b, err := ioutil.ReadFile(cmd.Args[4])
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(b, &gotPolicy); err != nil {
+			return err
+		}
+		gotCommands = append(gotCommands, strings.Join(cmd.Args[:4], " "))
+		return nil
+	}
+
+	if err := Deploy(project); err != nil {
+		t.Fatalf("Deploy: %v", err)
+	}
+
+	wantCommands := []string{
+		"gcloud projects get-iam-policy my-project --format json",
+		"gcloud projects set-iam-policy my-project",
+	}
+	if diff := cmp.Diff(gotCommands, wantCommands); diff != "" {
+		t.Errorf("commands differ (-got +want):\n%v", diff)
+	}
+
+	wantPolicyYAML := `
+bindings:
+- role: roles/owner
+  members:
+  - group:my-project-owners@my-domain.com
+auditConfigs:
+- service: allServices
+  auditLogConfigs:
+  - logType: ADMIN_READ
+    exemptedMembers:
+    - serviceAccount:pipeline@my-project.iam.gserviceaccount.com
+  - logType: DATA_READ
+    exemptedMembers:
+    - serviceAccount:pipeline@my-project.iam.gserviceaccount.com
+  - logType: DATA_WRITE
+    exemptedMembers:
+    - serviceAccount:pipeline@my-project.iam.gserviceaccount.com
+- service: storage.googleapis.com
+  auditLogConfigs:
+  - logType: DATA_READ
+etag: BwWKmjvelug=
+version: 1
+`
+	var wantPolicy interface{}
+	if err := yaml.Unmarshal([]byte(wantPolicyYAML), &wantPolicy); err != nil {
+		t.Fatalf("yaml.Unmarshal: %v", err)
+	}
+	if diff := cmp.Diff(gotPolicy, wantPolicy); diff != "" {
+		t.Errorf("policy differs (-got +want):\n%v", diff)
+	}
+}
+
+func TestAuditConfigErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config AuditConfig
+		err    string
+	}{
+		{
+			"missing_service",
+			AuditConfig{LogTypes: []string{"DATA_READ"}},
+			"service must be set",
+		},
+		{
+			"missing_log_types",
+			AuditConfig{Service: "allServices"},
+			"log_types must be set",
+		},
+		{
+			"unknown_log_type",
+			AuditConfig{Service: "allServices", LogTypes: []string{"DATA_DELETE"}},
+			"unknown log type",
+		},
+		{
+			"member_without_type",
+			AuditConfig{Service: "allServices", LogTypes: []string{"DATA_READ"}, ExemptedMembers: []string{"pipeline@my-project.iam.gserviceaccount.com"}},
+			"must have a type",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.config.init(); err == nil {
+				t.Fatalf("init error: got nil, want %v", tc.err)
+			} else if !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("init: got error %q, want error with substring %q", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestCheckAuditConfigs(t *testing.T) {
+	allLogs := []string{"ADMIN_READ", "DATA_READ", "DATA_WRITE"}
+	tests := []struct {
+		name    string
+		configs []*AuditConfig
+		wantErr bool
+	}{
+		{
+			"all_services",
+			[]*AuditConfig{
+				{Service: "allServices", LogTypes: allLogs, ExemptedMembers: []string{"serviceAccount:pipeline@my-project.iam.gserviceaccount.com"}},
+				{Service: "storage.googleapis.com", LogTypes: []string{"DATA_READ"}},
+			},
+			false,
+		},
+		{
+			"missing_all_services",
+			[]*AuditConfig{{Service: "storage.googleapis.com", LogTypes: allLogs}},
+			true,
+		},
+		{
+			"missing_log_types",
+			[]*AuditConfig{{Service: "allServices", LogTypes: []string{"ADMIN_READ", "DATA_WRITE"}}},
+			true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := checkAuditConfigs(tc.configs); (err != nil) != tc.wantErr {
+				t.Fatalf("checkAuditConfigs = %v, want error: %t", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package cft
 
 import (
 	"errors"
+	"fmt"
 )
 
 // BigqueryDataset represents a bigquery dataset.
@@ -19,13 +20,33 @@ type BigqueryDatasetProperties struct {
 
 // Access defines a dataset access. Only one non-role field should be set.
 type Access struct {
-	Role         string `json:"role"`
+	Role         string `json:"role,omitempty"`
 	UserByEmail  string `json:"userByEmail,omitempty"`
 	GroupByEmail string `json:"groupByEmail,omitempty"`
 	SpecialGroup string `json:"specialGroup,omitempty"`
 
-	// View is allowed but not monitored. Parse it into a generic map.
-	View map[string]interface{} `json:"view,omitempty"`
+	// View grants an authorized view access to the dataset.
+	View *DatasetView `json:"view,omitempty"`
+}
+
+// DatasetView identifies an authorized view.
+type DatasetView struct {
+	ProjectID string `json:"projectId"`
+	DatasetID string `json:"datasetId"`
+	TableID   string `json:"tableId"`
+}
+
+func init() {
+	RegisterResourceKind(ResourceKind{
+		Key:         "bigquery_dataset",
+		New:         func() ParsedResource { return new(BigqueryDataset) },
+		HoldsData:   true,
+		Location:    func(r ParsedResource) string { return r.(*BigqueryDataset).Location },
+		ForsetiType: "dataset",
+		ForsetiID: func(project *Project, r ParsedResource) (string, error) {
+			return fmt.Sprintf("%s:%s", project.ID, r.Name()), nil
+		},
+	})
 }
 
 // Init initializes a new dataset with the given project.
@@ -39,6 +60,11 @@ func (d *BigqueryDataset) Init(project *Project) error {
 	if d.SetDefaultOwner {
 		return errors.New("setDefaultOwner must not be true")
 	}
+	for _, a := range d.Accesses {
+		if v := a.View; v != nil && (v.ProjectID == "" || v.DatasetID == "" || v.TableID == "") {
+			return fmt.Errorf("view %+v must set projectId, datasetId and tableId", *v)
+		}
+	}
 
 	// Note: duplicate accesses are de-duplicated by deployment manager.
 	roleAndGroups := []struct {
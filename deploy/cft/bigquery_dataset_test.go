@@ -20,6 +20,10 @@ properties:
     role: OWNER
   - specialGroup: allAuthenticatedUsers
     role: READER
+  - view:
+      projectId: my-project
+      datasetId: views
+      tableId: foo_view
 `
 
 	wantdatasetYAML := `
@@ -31,6 +35,10 @@ properties:
     role: OWNER
   - specialGroup: allAuthenticatedUsers
     role: READER
+  - view:
+      projectId: my-project
+      datasetId: views
+      tableId: foo_view
   - groupByEmail: my-project-owners@my-domain.com
     role: OWNER
   - groupByEmail: some-readwrite-group@my-domain.com
@@ -96,6 +104,11 @@ func TestDatasetErrors(t *testing.T) {
 			"properties: {name: foo-dataset, location: US, setDefaultOwner: true}",
 			"setDefaultOwner must not be true",
 		},
+		{
+			"incomplete_view",
+			"properties: {name: foo-dataset, location: US, access: [{view: {projectId: my-project, datasetId: views}}]}",
+			"must set projectId, datasetId and tableId",
+		},
 	}
 
 	for _, tc := range tests {
@@ -4,8 +4,11 @@ package cft
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"path/filepath"
+
+	"github.com/ghodss/yaml"
 )
 
 // Config represents a (partial) representation of a projects YAML file.
@@ -16,11 +19,21 @@ type Config struct {
 		OrganizationID string   `json:"organization_id"`
 		FolderID       string   `json:"folder_id"`
 		AllowedAPIs    []string `json:"allowed_apis"`
+
+		// TemplateDirs are searched for templates before the built-in templates.
+		// Relative directories are resolved against the directory of the config file.
+		TemplateDirs []string `json:"template_dirs"`
 	} `json:"overall"`
-	AuditLogsProject *Project   `json:"audit_logs_project"`
-	Projects         []*Project `json:"projects"`
+	AuditLogsProject *Project          `json:"audit_logs_project"`
+	Projects         []*Project        `json:"projects"`
+	ServicePerimeter *ServicePerimeter `json:"service_perimeter"`
 }
 
+// AuditLogsSinkFilter is the filter of the sinks exporting the audit logs of projects to their audit logs dataset.
+// Only audit logs are exported, as other logs may contain sensitive data.
+// It must match the filter of the sink created by the data_project template.
+const AuditLogsSinkFilter = `logName:"logs/cloudaudit.googleapis.com"`
+
 // Project defines a single project's configuration.
 type Project struct {
 	ID                  string   `json:"project_id"`
@@ -30,21 +43,12 @@ type Project struct {
 	DataReadOnlyGroups  []string `json:"data_readonly_groups"`
 	EnabledAPIs         []string `json:"enabled_apis"`
 
-	// Note: typically only one resource in the struct is set at one time.
-	// Go does not have the concept of "one-of", so despite only one of these resources
-	// typically being non-empty, we still check them all. The one-of check is done by the schema.
-	Resources []*struct {
-		// The following structs are embedded so the json parser skips directly to their fields.
-		BigqueryDatasetPair
-		FirewallPair
-		GCEInstancePair
-		GCSBucketPair
-		GKEClusterPair
-		PubsubPair
-
-		// TODO: make this behave more like standard deployment manager resources
-		GKEWorkload json.RawMessage `json:"gke_workload"`
-	} `json:"resources"`
+	Resources []*ProjectResource `json:"resources"`
+
+	// AuditConfigs are set in the IAM policy of the project when it is deployed.
+	// If empty, the audit configs of the project are left unchanged.
+	// If set, they must include all audit logs of allServices, so only exempted members and extra services vary.
+	AuditConfigs []*AuditConfig `json:"audit_configs"`
 
 	AuditLogs *struct {
 		LogsGCSBucket struct {
@@ -59,48 +63,58 @@ type Project struct {
 	} `json:"audit_logs"`
 
 	GeneratedFields struct {
-		LogSinkServiceAccount string `json:"log_sink_service_account"`
-		GCEInstanceInfo       []struct {
-			Name string `json:"name"`
-			ID   string `json:"id"`
-		} `json:"gce_instance_info"`
+		ProjectNumber         string         `json:"project_number"`
+		LogSinkServiceAccount string         `json:"log_sink_service_account"`
+		GCEInstanceInfo       []InstanceInfo `json:"gce_instance_info"`
+
+		// AbandonedResources are the resources removed from the config that updates abandoned instead of deleting.
+		// They are stored in the logs bucket of the project by Deploy, after which they only need to be set here to
+		// mark resources as backed up.
+		AbandonedResources []*AbandonedResource `json:"abandoned_resources"`
 	} `json:"generated_fields"`
-}
 
-// BigqueryDatasetPair pairs a raw dataset with its parsed version.
-type BigqueryDatasetPair struct {
-	Raw    json.RawMessage `json:"bigquery_dataset"`
-	Parsed BigqueryDataset `json:"-"`
-}
-
-// FirewallPair pairs a raw firewall with its parsed version.
-type FirewallPair struct {
-	Raw    json.RawMessage `json:"firewall"`
-	Parsed DefaultResource `json:"-"`
-}
+	// TemplateDirs are the directories searched in order for the templates of the project's resources.
+	// Templates not found in them are taken from the built-in templates.
+	// When loaded with LoadConfig, they are set to the template_dirs of the config.
+	TemplateDirs []string `json:"-"`
 
-// GCEInstancePair pairs a raw instance with its parsed version.
-type GCEInstancePair struct {
-	Raw    json.RawMessage `json:"gce_instance"`
-	Parsed GCEInstance     `json:"-"`
+	// configDir is the directory of the config file the project was loaded from.
+	// Relative paths in the project are resolved against it.
+	configDir string
 }
 
-// GCSBucketPair pairs a raw bucket with its parsed version.
-type GCSBucketPair struct {
-	Raw    json.RawMessage `json:"gcs_bucket"`
-	Parsed GCSBucket       `json:"-"`
-}
+// LoadConfig loads the config from the projects YAML file at the given path.
+func LoadConfig(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %q: %v", path, err)
+	}
+	config := new(Config)
+	if err := yaml.Unmarshal(b, config); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %v", err)
+	}
 
-// GKEClusterPair pairs a raw cluster with its parsed version.
-type GKEClusterPair struct {
-	Raw    json.RawMessage `json:"gke_cluster"`
-	Parsed GKECluster      `json:"-"`
-}
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path for %q: %v", path, err)
+	}
+	var templateDirs []string
+	for _, d := range config.Overall.TemplateDirs {
+		if !filepath.IsAbs(d) {
+			d = filepath.Join(dir, d)
+		}
+		templateDirs = append(templateDirs, d)
+	}
 
-// PubsubPair pairs a raw pubsub with its parsed version.
-type PubsubPair struct {
-	Raw    json.RawMessage `json:"pubsub"`
-	Parsed Pubsub          `json:"-"`
+	projects := config.Projects
+	if config.AuditLogsProject != nil {
+		projects = append([]*Project{config.AuditLogsProject}, projects...)
+	}
+	for _, p := range projects {
+		p.configDir = dir
+		p.TemplateDirs = append([]string(nil), templateDirs...)
+	}
+	return config, nil
 }
 
 // Init initializes the config and all its projects.
@@ -110,6 +124,11 @@ func (c *Config) Init() error {
 			return fmt.Errorf("failed to init project %q: %v", p.ID, err)
 		}
 	}
+	if c.ServicePerimeter != nil {
+		if err := c.ServicePerimeter.Init(c); err != nil {
+			return fmt.Errorf("failed to init service perimeter: %v", err)
+		}
+	}
 	return nil
 }
 
@@ -131,6 +150,21 @@ func (p *Project) Init() error {
 	if p.AuditLogs.LogsGCSBucket.Name == "" {
 		p.AuditLogs.LogsGCSBucket.Name = p.ID + "-logs"
 	}
+	for i, c := range p.AuditConfigs {
+		if err := c.init(); err != nil {
+			return fmt.Errorf("audit_configs[%d]: %v", i, err)
+		}
+	}
+	if len(p.AuditConfigs) > 0 {
+		if err := checkAuditConfigs(p.AuditConfigs); err != nil {
+			return fmt.Errorf("audit_configs: %v", err)
+		}
+	}
+	for _, res := range p.Resources {
+		if res.Template != "" && !filepath.IsAbs(res.Template) {
+			res.Template = filepath.Join(p.configDir, res.Template)
+		}
+	}
 	for _, pair := range p.resourcePairs() {
 		if err := json.Unmarshal(pair.raw, pair.parsed); err != nil {
 			return err
@@ -139,27 +173,24 @@ func (p *Project) Init() error {
 			return err
 		}
 	}
+	if err := initNetworkReferences(p); err != nil {
+		return err
+	}
+	if err := initDeadLetterTopics(p); err != nil {
+		return err
+	}
+	if _, err := getGKEWorkloads(p); err != nil {
+		return err
+	}
 	return nil
 }
 
 func (p *Project) resourcePairs() []resourcePair {
 	var pairs []resourcePair
-	appendPair := func(raw json.RawMessage, parsed parsedResource) {
-		if len(raw) > 0 {
-			pairs = append(pairs, resourcePair{raw, parsed})
-		}
-	}
-	appendDefaultResPair := func(raw json.RawMessage, parsed *DefaultResource, path string) {
-		parsed.templatePath = path
-		appendPair(raw, parsed)
-	}
 	for _, res := range p.Resources {
-		appendPair(res.BigqueryDatasetPair.Raw, &res.BigqueryDatasetPair.Parsed)
-		appendDefaultResPair(res.FirewallPair.Raw, &res.FirewallPair.Parsed, "deploy/cft/templates/firewall.py")
-		appendPair(res.GCEInstancePair.Raw, &res.GCEInstancePair.Parsed)
-		appendPair(res.GCSBucketPair.Raw, &res.GCSBucketPair.Parsed)
-		appendPair(res.GKEClusterPair.Raw, &res.GKEClusterPair.Parsed)
-		appendPair(res.PubsubPair.Raw, &res.PubsubPair.Parsed)
+		if res.Parsed != nil {
+			pairs = append(pairs, resourcePair{raw: res.Raw, parsed: res.Parsed, template: res.Template})
+		}
 	}
 	return pairs
 }
@@ -174,14 +205,26 @@ type DataResources struct {
 // DataResources gets all data holding resources in this project.
 func (p *Project) DataResources() *DataResources {
 	rs := &DataResources{}
+	for _, r := range p.DataHoldingResources() {
+		switch parsed := r.Parsed.(type) {
+		case *BigqueryDataset:
+			rs.BigqueryDatasets = append(rs.BigqueryDatasets, parsed)
+		case *GCSBucket:
+			rs.GCSBuckets = append(rs.GCSBuckets, parsed)
+		case *GCEInstance:
+			rs.GCEInstances = append(rs.GCEInstances, parsed)
+		}
+	}
+	return rs
+}
+
+// DataHoldingResources gets the resources in this project whose kind holds data, including those of kinds
+// registered outside of this package.
+func (p *Project) DataHoldingResources() []*ProjectResource {
+	var rs []*ProjectResource
 	for _, r := range p.Resources {
-		switch {
-		case len(r.BigqueryDatasetPair.Raw) > 0:
-			rs.BigqueryDatasets = append(rs.BigqueryDatasets, &r.BigqueryDatasetPair.Parsed)
-		case len(r.GCSBucketPair.Raw) > 0:
-			rs.GCSBuckets = append(rs.GCSBuckets, &r.GCSBucketPair.Parsed)
-		case len(r.GCEInstancePair.Raw) > 0:
-			rs.GCEInstances = append(rs.GCEInstances, &r.GCEInstancePair.Parsed)
+		if r.Kind != nil && r.Kind.HoldsData {
+			rs = append(rs, r)
 		}
 	}
 	return rs
@@ -197,8 +240,8 @@ func (p *Project) InstanceID(name string) (string, error) {
 	return "", fmt.Errorf("info for instance %q not found in generated_fields", name)
 }
 
-// parsedResource is an interface that must be implemented by all concrete resource implementations.
-type parsedResource interface {
+// ParsedResource is an interface that must be implemented by all concrete resource implementations.
+type ParsedResource interface {
 	Init(*Project) error
 	Name() string
 	TemplatePath() string
@@ -206,25 +249,112 @@ type parsedResource interface {
 
 // depender is the interface that defines a method to get dependent resources.
 type depender interface {
-	DependentResources(*Project) ([]parsedResource, error)
+	DependentResources(*Project) ([]ParsedResource, error)
+}
+
+// dependsOner is the interface that defines a method to get the names of resources
+// that must be deployed before this resource.
+type dependsOner interface {
+	DependsOn() []string
+}
+
+// Backend renders the deployment of a project into the configuration of a deployment tool.
+type Backend interface {
+	Render(project *Project, deployment *Deployment) ([]byte, error)
+}
+
+// DeploymentManager renders deployments as Deployment Manager configs.
+type DeploymentManager struct{}
+
+// Render renders the deployment as a Deployment Manager config.
+func (DeploymentManager) Render(project *Project, deployment *Deployment) ([]byte, error) {
+	b, err := yaml.Marshal(deployment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal deployment: %v", err)
+	}
+	return b, nil
+}
+
+// Render renders the CFT resources in the project with the given backend without deploying them.
+func Render(project *Project, backend Backend) ([]byte, error) {
+	deployment, err := renderDeployment(project)
+	if err != nil {
+		return nil, err
+	}
+	return backend.Render(project, deployment)
 }
 
-// Deploy deploys the CFT resources in the project.
+// renderDeployment gets the deployment of the project's resources for use without deploying it.
+// The built-in templates it resolves are removed before it returns.
+func renderDeployment(project *Project) (*Deployment, error) {
+	resolver := newTemplateResolver(project.TemplateDirs)
+	defer func() {
+		if err := resolver.cleanup(); err != nil {
+			log.Printf("failed to clean up built-in templates: %v", err)
+		}
+	}()
+	return getDeployment(project, project.resourcePairs(), resolver)
+}
+
+// Deploy deploys the CFT resources and audit configs of the project.
 func Deploy(project *Project) error {
+	if len(project.AuditConfigs) > 0 {
+		if err := deployAuditConfigs(project); err != nil {
+			return fmt.Errorf("failed to deploy audit configs: %v", err)
+		}
+	}
+
 	pairs := project.resourcePairs()
 	if len(pairs) == 0 {
 		log.Println("No resources to deploy.")
 		return nil
 	}
 
-	deployment, err := getDeployment(project, pairs)
+	resolver := newTemplateResolver(project.TemplateDirs)
+	defer func() {
+		if err := resolver.cleanup(); err != nil {
+			log.Printf("failed to clean up built-in templates: %v", err)
+		}
+	}()
+
+	deployment, err := getDeployment(project, pairs, resolver)
 	if err != nil {
 		return err
 	}
-	if err := createOrUpdateDeployment(project.ID, deployment); err != nil {
+	previous, previousExpanded, err := describeManifest(project.ID, deploymentName)
+	if err != nil {
+		return fmt.Errorf("failed to get deployment manifest: %v", err)
+	}
+	abandoned, stored, err := loadAbandonedResources(project)
+	if err != nil {
+		return fmt.Errorf("failed to load abandoned resources: %v", err)
+	}
+	logTemplateChanges(previous, deployment)
+	if err := createOrUpdateDeployment(deploymentName, project.ID, deployment); err != nil {
 		return fmt.Errorf("failed to deploy deployment manager resources: %v", err)
 	}
 
+	// Compare the expanded resources, so that resources removed from the templates of resources left in the config
+	// are abandoned too. Store the abandoned resources right away so that they are not lost if a later step fails.
+	_, expanded, err := describeManifest(project.ID, deploymentName)
+	if err != nil {
+		return fmt.Errorf("failed to get updated deployment manifest: %v", err)
+	}
+	project.GeneratedFields.AbandonedResources = updateAbandonedResources(abandoned, previousExpanded, expanded)
+	if stored || len(project.GeneratedFields.AbandonedResources) > 0 {
+		if err := storeAbandonedResources(project, project.GeneratedFields.AbandonedResources); err != nil {
+			return fmt.Errorf("failed to store abandoned resources: %v", err)
+		}
+	}
+
+	if len(project.DataResources().GCEInstances) > 0 {
+		infos, err := getGCEInstanceInfo(project)
+		if err != nil {
+			return fmt.Errorf("failed to get GCE instance info: %v", err)
+		}
+		project.GeneratedFields.GCEInstanceInfo = infos
+	}
+
 	if err := deployGKEWorkloads(project); err != nil {
 		return fmt.Errorf("failed to deploy GKE workloads: %v", err)
 	}
@@ -232,13 +362,15 @@ func Deploy(project *Project) error {
 	return nil
 }
 
-func getDeployment(project *Project, pairs []resourcePair) (*Deployment, error) {
+// getDeployment gets the deployment of the given resource pairs, resolving their templates with the given resolver.
+// The hashes of the templates are recorded in the outputs of the deployment.
+func getDeployment(project *Project, pairs []resourcePair, resolver *templateResolver) (*Deployment, error) {
 	deployment := &Deployment{}
 
 	allImports := make(map[string]bool)
 
 	for _, pair := range pairs {
-		resources, importSet, err := getDeploymentResourcesAndImports(pair, project)
+		resources, importSet, err := getDeploymentResourcesAndImports(pair, project, resolver)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get resource and imports for %q: %v", pair.parsed.Name(), err)
 		}
@@ -251,18 +383,19 @@ func getDeployment(project *Project, pairs []resourcePair) (*Deployment, error)
 			allImports[imp] = true
 		}
 	}
+	deployment.Outputs = resolver.outputs()
 
 	return deployment, nil
 }
 
 // getDeploymentResourcesAndImports gets the deploment resources and imports for the given resource pair.
 // It also recursively adds the resoures and imports of any dependent resources.
-func getDeploymentResourcesAndImports(pair resourcePair, project *Project) (resources []*Resource, importSet map[string]bool, err error) {
+func getDeploymentResourcesAndImports(pair resourcePair, project *Project, resolver *templateResolver) (resources []*Resource, importSet map[string]bool, err error) {
 	importSet = make(map[string]bool)
 
-	templatePath, err := filepath.Abs(pair.parsed.TemplatePath())
+	templatePath, err := resolver.resolve(pair.parsed.TemplatePath(), pair.template)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get absolute path for %q: %v", pair.parsed.TemplatePath(), err)
+		return nil, nil, fmt.Errorf("failed to resolve template: %v", err)
 	}
 	importSet[templatePath] = true
 
@@ -272,11 +405,17 @@ func getDeploymentResourcesAndImports(pair resourcePair, project *Project) (reso
 	}
 
 	resources = []*Resource{{
-		Name:       pair.parsed.Name(),
-		Type:       templatePath,
-		Properties: merged,
+		Name:         pair.parsed.Name(),
+		Type:         templatePath,
+		Properties:   merged,
+		TemplatePath: pair.parsed.TemplatePath(),
+		Template:     pair.template,
 	}}
 
+	if do, ok := pair.parsed.(dependsOner); ok && len(do.DependsOn()) > 0 {
+		resources[0].Metadata = &Metadata{DependsOn: append([]string(nil), do.DependsOn()...)}
+	}
+
 	dr, ok := pair.parsed.(depender)
 	if !ok { // doesn't implement dependent resources method so has no dependent resources
 		return resources, importSet, nil
@@ -289,7 +428,7 @@ func getDeploymentResourcesAndImports(pair resourcePair, project *Project) (reso
 
 	for _, dep := range dependencies {
 		depPair := resourcePair{parsed: dep}
-		depResources, depImports, err := getDeploymentResourcesAndImports(depPair, project)
+		depResources, depImports, err := getDeploymentResourcesAndImports(depPair, project, resolver)
 		if err != nil {
 			return nil, nil, fmt.Errorf("failed to get resources and imports for %q: %v", dep.Name(), err)
 		}
@@ -103,10 +103,10 @@ resources:
       location: US`},
 			want: `
 imports:
-- path: {{abs "deploy/cft/templates/bigquery_dataset.py"}}
+- path: {{builtin "deploy/cft/templates/bigquery_dataset.py"}}
 resources:
 - name: foo-dataset
-  type: {{abs "deploy/cft/templates/bigquery_dataset.py"}}
+  type: {{builtin "deploy/cft/templates/bigquery_dataset.py"}}
   properties:
     name: foo-dataset
     location: US
@@ -134,16 +134,32 @@ resources:
 
 			want: `
 imports:
-- path: {{abs "deploy/cft/templates/instance.py"}}
+- path: {{builtin "deploy/cft/templates/instance.py"}}
 
 resources:
 - name: foo-instance
-  type: {{abs "deploy/cft/templates/instance.py"}}
+  type: {{builtin "deploy/cft/templates/instance.py"}}
   properties:
     name: foo-instance
     diskImage: projects/ubuntu-os-cloud/global/images/family/ubuntu-1804-lts
     zone: us-east1-a
-    machineType: f1-micro`,
+    machineType: f1-micro
+    hasExternalIp: false
+    metadata:
+      items:
+      - key: enable-oslogin
+        value: 'TRUE'
+    shieldedInstanceConfig:
+      enableSecureBoot: true
+      enableVtpm: true
+      enableIntegrityMonitoring: true
+    accessControl:
+    - role: roles/compute.osAdminLogin
+      members:
+      - 'group:my-project-owners@my-domain.com'
+    - role: roles/compute.osLogin
+      members:
+      - 'group:some-readwrite-group@my-domain.com'`,
 		},
 		{
 			name: "gcs_bucket",
@@ -157,12 +173,12 @@ resources:
       location: us-east1`},
 			want: `
 imports:
-- path: {{abs "deploy/cft/templates/gcs_bucket.py"}}
-- path: {{abs "deploy/templates/metric.py"}}
+- path: {{builtin "deploy/cft/templates/gcs_bucket.py"}}
+- path: {{builtin "deploy/templates/metric.py"}}
 
 resources:
 - name: foo-bucket
-  type: {{abs "deploy/cft/templates/gcs_bucket.py"}}
+  type: {{builtin "deploy/cft/templates/gcs_bucket.py"}}
   properties:
     name: foo-bucket
     location: us-east1
@@ -181,8 +197,11 @@ resources:
       enabled: true
     logging:
       logBucket: my-project-logs
+    iamConfiguration:
+      uniformBucketLevelAccess:
+        enabled: true
 - name: unexpected-access-foo-bucket
-  type: {{abs "deploy/templates/metric.py"}}
+  type: {{builtin "deploy/templates/metric.py"}}
   properties:
     metric: unexpected-access-foo-bucket
     description: Count of unexpected data access to foo-bucket
@@ -205,14 +224,91 @@ resources:
   metadata:
     dependsOn:
     - foo-bucket`,
+		},
+		{
+			name: "network",
+			configData: &ConfigData{`
+resources:
+- network:
+    properties:
+      name: foo-network
+- subnetwork:
+    properties:
+      name: foo-subnetwork
+      network: foo-network
+      region: us-east1
+      ipCidrRange: 10.0.0.0/24
+- gce_instance:
+    properties:
+      name: foo-instance
+      zone: us-east1-a
+      diskImage: foo-image
+      network: foo-network
+      subnetwork: foo-subnetwork`},
+			want: `
+imports:
+- path: {{builtin "deploy/cft/templates/network.py"}}
+- path: {{builtin "deploy/cft/templates/subnetwork.py"}}
+- path: {{builtin "deploy/cft/templates/instance.py"}}
+
+resources:
+- name: foo-network
+  type: {{builtin "deploy/cft/templates/network.py"}}
+  properties:
+    name: foo-network
+    autoCreateSubnetworks: false
+- name: foo-subnetwork
+  type: {{builtin "deploy/cft/templates/subnetwork.py"}}
+  properties:
+    name: foo-subnetwork
+    network: foo-network
+    region: us-east1
+    ipCidrRange: 10.0.0.0/24
+    privateIpGoogleAccess: true
+    enableFlowLogs: true
+  metadata:
+    dependsOn:
+    - foo-network
+- name: foo-instance
+  type: {{builtin "deploy/cft/templates/instance.py"}}
+  properties:
+    name: foo-instance
+    zone: us-east1-a
+    diskImage: foo-image
+    network: foo-network
+    subnetwork: regions/us-east1/subnetworks/foo-subnetwork
+    hasExternalIp: false
+    metadata:
+      items:
+      - key: enable-oslogin
+        value: 'TRUE'
+    shieldedInstanceConfig:
+      enableSecureBoot: true
+      enableVtpm: true
+      enableIntegrityMonitoring: true
+    accessControl:
+    - role: roles/compute.osAdminLogin
+      members:
+      - 'group:my-project-owners@my-domain.com'
+    - role: roles/compute.osLogin
+      members:
+      - 'group:some-readwrite-group@my-domain.com'
+  metadata:
+    dependsOn:
+    - foo-network
+    - foo-subnetwork`,
 		},
 		{
 			name: "pubsub",
 			configData: &ConfigData{`
 resources:
 - pubsub:
+    publisher_account: foo-publisher@my-project.iam.gserviceaccount.com
     properties:
       topic: foo-topic
+      messageStoragePolicy:
+        allowedPersistenceRegions:
+        - us-central1
       accessControl:
       - role: roles/pubsub.publisher
         members:
@@ -225,16 +321,27 @@ resources:
           - 'user:extra-reader@google.com'`},
 			want: `
 imports:
-- path: {{abs "deploy/cft/templates/pubsub.py"}}
+- path: {{builtin "deploy/cft/templates/pubsub.py"}}
 
 resources:
 - name: foo-topic
-  type: {{abs "deploy/cft/templates/pubsub.py"}}
+  type: {{builtin "deploy/cft/templates/pubsub.py"}}
   properties:
     topic: foo-topic
+    messageStoragePolicy:
+      allowedPersistenceRegions:
+      - us-central1
     accessControl:
+    - role: roles/pubsub.editor
+      members:
+      - 'group:some-readwrite-group@my-domain.com'
+    - role: roles/pubsub.viewer
+      members:
+      - 'group:some-readonly-group@my-domain.com'
+      - 'group:another-readonly-group@googlegroups.com'
     - role: roles/pubsub.publisher
       members:
+      - 'serviceAccount:foo-publisher@my-project.iam.gserviceaccount.com'
       - 'user:foo@user.com'
     subscriptions:
     - name: foo-subscription
@@ -273,6 +380,19 @@ resources:
 				t.Fatal(err)
 			}
 
+			// Built-in templates are written to a temp dir, so only compare their names.
+			// Their hashes are tested by TestTemplateResolver.
+			if len(got.Outputs) != len(got.Imports) {
+				t.Errorf("deployment has %d outputs for %d imports, want an output with the hash of each import", len(got.Outputs), len(got.Imports))
+			}
+			got.Outputs = nil
+			for _, imp := range got.Imports {
+				imp.Path = filepath.Base(imp.Path)
+			}
+			for _, res := range got.Resources {
+				res.Type = filepath.Base(res.Type)
+			}
+
 			want := getWantDeployment(t, tc.want)
 
 			if diff := cmp.Diff(got, want); diff != "" {
@@ -286,7 +406,7 @@ resources:
 
 func getWantDeployment(t *testing.T, yamlTemplate string) *Deployment {
 	t.Helper()
-	tmpl, err := template.New("test-deployment").Funcs(template.FuncMap{"abs": abs}).Parse(yamlTemplate)
+	tmpl, err := template.New("test-deployment").Funcs(template.FuncMap{"builtin": filepath.Base}).Parse(yamlTemplate)
 	if err != nil {
 		t.Fatalf("template Parse: %v", err)
 	}
@@ -304,14 +424,6 @@ func getWantDeployment(t *testing.T, yamlTemplate string) *Deployment {
 	return deployment
 }
 
-func abs(p string) string {
-	a, err := filepath.Abs(p)
-	if err != nil {
-		panic(err)
-	}
-	return a
-}
-
 func TestInstanceID(t *testing.T) {
 	_, project := getTestConfigAndProject(t, nil)
 
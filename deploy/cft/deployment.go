@@ -17,13 +17,15 @@ const deploymentName = "managed-data-protect-toolkit"
 var (
 	cmdRun            = (*exec.Cmd).Run
 	cmdCombinedOutput = (*exec.Cmd).CombinedOutput
+	cmdOutput         = (*exec.Cmd).Output
 )
 
 // Deployment represents a single deployment which can be used by the GCP Deployment Manager.
 // TODO: move into separate package.
 type Deployment struct {
-	Imports   []*Import   `json:"imports"`
+	Imports   []*Import   `json:"imports,omitempty"`
 	Resources []*Resource `json:"resources"`
+	Outputs   []*Output   `json:"outputs,omitempty"`
 }
 
 // Import respresents a deployment manager template import.
@@ -37,6 +39,19 @@ type Resource struct {
 	Type       string                 `json:"type"`
 	Properties map[string]interface{} `json:"properties,omitempty"`
 	Metadata   *Metadata              `json:"metadata,omitempty"`
+
+	// TemplatePath is the template path of the resource's kind before it was resolved.
+	// It identifies the kind of the resource to backends that do not use the template.
+	TemplatePath string `json:"-"`
+
+	// Template is the template set to override the template of the resource's kind, if any.
+	Template string `json:"-"`
+}
+
+// Output defines a deployment manager output.
+type Output struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
 }
 
 // Metadata contains extra metadata of the deployment.
@@ -44,8 +59,8 @@ type Metadata struct {
 	DependsOn []string `json:"dependsOn"`
 }
 
-// createOrUpdateDeployment creates the deployment if it does not exist, else updates it.
-func createOrUpdateDeployment(projectID string, deployment *Deployment) error {
+// createOrUpdateDeployment creates the deployment with the given name if it does not exist, else updates it.
+func createOrUpdateDeployment(name, projectID string, deployment *Deployment) error {
 	b, err := yaml.Marshal(deployment)
 	if err != nil {
 		return fmt.Errorf("failed to marshal deployment : %v", err)
@@ -65,7 +80,7 @@ func createOrUpdateDeployment(projectID string, deployment *Deployment) error {
 		return fmt.Errorf("failed to close temp file: %v", err)
 	}
 
-	exists, err := checkDeploymentExists(projectID, deploymentName)
+	exists, err := checkDeploymentExists(projectID, name)
 	if err != nil {
 		return fmt.Errorf("failed to check if deployment exists: %v", err)
 	}
@@ -75,9 +90,9 @@ func createOrUpdateDeployment(projectID string, deployment *Deployment) error {
 		// Due to the sensitive nature of the resources we manage, we don't want to
 		// delete any resources after they have been deployed. Instead, abandon the resource
 		// so the user can manually delete them later on.
-		args = append(args, "update", deploymentName, "--delete-policy", "ABANDON")
+		args = append(args, "update", name, "--delete-policy", "ABANDON")
 	} else {
-		args = append(args, "create", deploymentName, "--automatic-rollback-on-error")
+		args = append(args, "create", name, "--automatic-rollback-on-error")
 	}
 	args = append(args, "--project", projectID, "--config", tmp.Name())
 
@@ -119,3 +134,49 @@ func checkDeploymentExists(projectID, name string) (bool, error) {
 	}
 	return false, nil
 }
+
+// getManifest returns the config of the latest manifest of the deployment with the given name,
+// or nil if the deployment does not exist.
+func getManifest(projectID, name string) (*Deployment, error) {
+	config, _, err := describeManifest(projectID, name)
+	return config, err
+}
+
+// describeManifest returns the config and the expanded config of the latest manifest of the deployment with the
+// given name, or nils if the deployment does not exist. The expanded config holds the resources the templates of the
+// config were expanded to, which are the resources Deployment Manager deploys.
+func describeManifest(projectID, name string) (config, expanded *Deployment, err error) {
+	exists, err := checkDeploymentExists(projectID, name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to check if deployment exists: %v", err)
+	}
+	if !exists {
+		return nil, nil, nil
+	}
+
+	cmd := exec.Command("gcloud", "deployment-manager", "manifests", "describe", "--deployment", name, "--format", "json", "--project", projectID)
+
+	out, err := cmdCombinedOutput(cmd)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to run command: %v\n%v", err, string(out))
+	}
+
+	var manifest struct {
+		Config struct {
+			Content string `json:"content"`
+		} `json:"config"`
+		ExpandedConfig string `json:"expandedConfig"`
+	}
+	if err := json.Unmarshal(out, &manifest); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal manifest describe call: %v", err)
+	}
+	config = new(Deployment)
+	if err := yaml.Unmarshal([]byte(manifest.Config.Content), config); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal manifest config: %v", err)
+	}
+	expanded = new(Deployment)
+	if err := yaml.Unmarshal([]byte(manifest.ExpandedConfig), expanded); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal manifest expanded config: %v", err)
+	}
+	return config, expanded, nil
+}
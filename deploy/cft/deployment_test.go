@@ -1,6 +1,8 @@
 package cft
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os/exec"
@@ -81,7 +83,7 @@ resources:
 			cmdRun = commander.Run
 			cmdCombinedOutput = commander.CombinedOutput
 
-			if err := createOrUpdateDeployment(projID, deployment); err != nil {
+			if err := createOrUpdateDeployment(deploymentName, projID, deployment); err != nil {
 				t.Fatalf("createOrUpdateDeployment = %v", err)
 			}
 
@@ -105,7 +107,21 @@ type fakeCommander struct {
 	listDeploymentName    string
 	wantDeploymentCommand []string
 
-	gotConfigFileContents []byte
+	// manifest is the config of the latest manifest of the deployment.
+	manifest string
+
+	// expandedManifest is the expanded config of the latest manifest of the deployment until it is deployed,
+	// and deployedExpandedManifest is the one after.
+	expandedManifest, deployedExpandedManifest string
+
+	// abandonedResources are the stored abandoned resources of the project, if any.
+	abandonedResources string
+
+	// noLogsBucket is set if the logs bucket of the project does not exist.
+	noLogsBucket bool
+
+	gotConfigFileContents      []byte
+	gotAbandonedResourcesFiles [][]byte
 }
 
 func (c *fakeCommander) Run(cmd *exec.Cmd) error {
@@ -122,10 +138,43 @@ func (c *fakeCommander) Run(cmd *exec.Cmd) error {
 }
 
 func (c *fakeCommander) CombinedOutput(cmd *exec.Cmd) ([]byte, error) {
+	if cmd.Args[0] == "gsutil" && c.noLogsBucket {
+		return []byte("BucketNotFoundException: 404 gs://my-project-logs bucket does not exist."), errors.New("exit status 1")
+	}
+	if cmd.Args[0] == "gsutil" && cmd.Args[1] == "cat" {
+		if c.abandonedResources == "" {
+			return []byte("CommandException: No URLs matched: " + cmd.Args[2]), errors.New("exit status 1")
+		}
+		return []byte(c.abandonedResources), nil
+	}
+	if cmd.Args[0] == "gsutil" && cmd.Args[1] == "cp" {
+		b, err := ioutil.ReadFile(cmd.Args[2])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %q: %v", cmd.Args[2], err)
+		}
+		c.gotAbandonedResourcesFiles = append(c.gotAbandonedResourcesFiles, b)
+		return nil, nil
+	}
 	listArgs := []string{"gcloud", "deployment-manager", "deployments", "list", "--format", "json"}
 	if cmp.Equal(cmd.Args[:len(listArgs)], listArgs) {
 		out := fmt.Sprintf(`[{"name": "%s"}]`, c.listDeploymentName)
 		return []byte(out), nil
 	}
+	manifestArgs := []string{"gcloud", "deployment-manager", "manifests", "describe"}
+	if cmp.Equal(cmd.Args[:len(manifestArgs)], manifestArgs) {
+		expanded := c.expandedManifest
+		if c.gotConfigFileContents != nil {
+			expanded = c.deployedExpandedManifest
+		}
+		out, err := json.Marshal(map[string]interface{}{
+			"config":         map[string]string{"content": c.manifest},
+			"expandedConfig": expanded,
+		})
+		return out, err
+	}
+	listInstancesArgs := []string{"gcloud", "compute", "instances", "list", "--format", "json"}
+	if cmp.Equal(cmd.Args[:len(listInstancesArgs)], listInstancesArgs) {
+		return []byte(`[{"name": "foo-instance", "id": "123"}]`), nil
+	}
 	return nil, fmt.Errorf("fake cmdCombinedOutput: unexpected args: %v", cmd.Args)
 }
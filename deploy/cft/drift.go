@@ -0,0 +1,472 @@
+package cft
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// DriftClient fetches the deployed state of a project's resources.
+type DriftClient interface {
+	// Manifest returns the config of the latest manifest of the given deployment, or nil if the deployment does not exist.
+	Manifest(projectID, deployment string) (*Deployment, error)
+
+	// LiveResource returns the live state of the given deployment resource, or nil if it does not exist.
+	LiveResource(projectID string, res *Resource) (*LiveResource, error)
+}
+
+// LiveResource is the live state of a deployed resource.
+type LiveResource struct {
+	// Properties holds the live values of the properties the client fetches, keyed like the resource's properties.
+	// Only these properties are compared to the config.
+	Properties map[string]interface{}
+
+	// Bindings maps the roles of the resource's IAM policy to their members.
+	// It is nil if the client does not fetch the resource's IAM policy.
+	Bindings map[string][]string
+}
+
+// Drift statuses of resources.
+const (
+	DriftMissing  = "missing"
+	DriftExtra    = "extra"
+	DriftModified = "modified"
+)
+
+// Drift sources.
+const (
+	DriftSourceManifest = "manifest"
+	DriftSourceLive     = "live"
+)
+
+// DriftReport reports how the deployed state of a project differs from its config.
+type DriftReport struct {
+	Project   string           `json:"project"`
+	Resources []*ResourceDrift `json:"resources,omitempty"`
+}
+
+// ResourceDrift describes how a resource in the deployment manifest or live differs from its config.
+type ResourceDrift struct {
+	Name            string           `json:"name"`
+	Source          string           `json:"source"`
+	Status          string           `json:"status"`
+	Properties      []*PropertyDrift `json:"properties,omitempty"`
+	MissingBindings []*BindingMember `json:"missingBindings,omitempty"`
+	ExtraBindings   []*BindingMember `json:"extraBindings,omitempty"`
+}
+
+// PropertyDrift is a property whose deployed value differs from its config.
+type PropertyDrift struct {
+	Path string      `json:"path"`
+	Want interface{} `json:"want"`
+	Got  interface{} `json:"got"`
+}
+
+// BindingMember is a member granted a role.
+type BindingMember struct {
+	Role   string `json:"role"`
+	Member string `json:"member"`
+}
+
+// Drift compares the deployment of the project's resources to the latest deployment manifest and the live resources.
+// Resources removed from the config stay in the project as updates abandon them, but are reported as extra while they
+// are in the manifest.
+func Drift(project *Project, client DriftClient) (*DriftReport, error) {
+	deployment, err := renderDeployment(project)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deployment: %v", err)
+	}
+	manifest, err := client.Manifest(project.ID, deploymentName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deployment manifest: %v", err)
+	}
+
+	deployed := make(map[string]*Resource)
+	if manifest != nil {
+		for _, res := range manifest.Resources {
+			deployed[res.Name] = res
+		}
+	}
+
+	report := &DriftReport{Project: project.ID}
+	configured := make(map[string]bool)
+	for _, res := range deployment.Resources {
+		configured[res.Name] = true
+
+		if d, ok := deployed[res.Name]; !ok {
+			report.add(&ResourceDrift{Name: res.Name, Source: DriftSourceManifest, Status: DriftMissing})
+		} else if diffs := diffProperties(res.Properties, d.Properties, false); len(diffs) > 0 {
+			report.add(&ResourceDrift{Name: res.Name, Source: DriftSourceManifest, Status: DriftModified, Properties: diffs})
+		}
+
+		live, err := client.LiveResource(project.ID, res)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get live state of %q: %v", res.Name, err)
+		}
+		if live == nil {
+			report.add(&ResourceDrift{Name: res.Name, Source: DriftSourceLive, Status: DriftMissing})
+			continue
+		}
+		rd := &ResourceDrift{Name: res.Name, Source: DriftSourceLive, Status: DriftModified}
+		if live.Properties != nil {
+			rd.Properties = diffProperties(res.Properties, live.Properties, true)
+		}
+		if live.Bindings != nil {
+			rd.MissingBindings, rd.ExtraBindings = diffBindings(configuredBindings(res), live.Bindings)
+		}
+		if len(rd.Properties) > 0 || len(rd.MissingBindings) > 0 || len(rd.ExtraBindings) > 0 {
+			report.add(rd)
+		}
+	}
+
+	if manifest != nil {
+		for _, res := range manifest.Resources {
+			if !configured[res.Name] {
+				report.add(&ResourceDrift{Name: res.Name, Source: DriftSourceManifest, Status: DriftExtra})
+			}
+		}
+	}
+	return report, nil
+}
+
+func (r *DriftReport) add(rd *ResourceDrift) {
+	r.Resources = append(r.Resources, rd)
+}
+
+// WriteText writes the report in a human readable form.
+func (r *DriftReport) WriteText(w io.Writer) error {
+	var b strings.Builder
+	if len(r.Resources) == 0 {
+		fmt.Fprintf(&b, "Project %q: no drift\n", r.Project)
+	} else {
+		fmt.Fprintf(&b, "Project %q: %d drifted resources\n", r.Project, len(r.Resources))
+	}
+	for _, rd := range r.Resources {
+		fmt.Fprintf(&b, "%s %q (%s)\n", rd.Status, rd.Name, rd.Source)
+		for _, p := range rd.Properties {
+			fmt.Fprintf(&b, "  property %s: want %s, got %s\n", p.Path, driftValue(p.Want), driftValue(p.Got))
+		}
+		for _, m := range rd.MissingBindings {
+			fmt.Fprintf(&b, "  missing binding %s: %s\n", m.Role, m.Member)
+		}
+		for _, m := range rd.ExtraBindings {
+			fmt.Fprintf(&b, "  extra binding %s: %s\n", m.Role, m.Member)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func driftValue(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
+
+// diffProperties returns the properties whose values in got differ from those in want.
+// If onlyGot is set, got holds only some of the properties of want along with properties that are not configured,
+// as live objects do, so only the keys of want that are in got are compared, at every level.
+func diffProperties(want, got map[string]interface{}, onlyGot bool) []*PropertyDrift {
+	var diffs []*PropertyDrift
+	diffValues("", normalizeValue(want), normalizeValue(got), onlyGot, &diffs)
+	return diffs
+}
+
+func diffValues(path string, want, got interface{}, onlyGot bool, diffs *[]*PropertyDrift) {
+	wm, wok := want.(map[string]interface{})
+	gm, gok := got.(map[string]interface{})
+	if wok && gok {
+		keys := make(map[string]bool)
+		for k := range wm {
+			if _, ok := gm[k]; ok || !onlyGot {
+				keys[k] = true
+			}
+		}
+		if !onlyGot {
+			for k := range gm {
+				keys[k] = true
+			}
+		}
+		var sorted []string
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+		for _, k := range sorted {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			diffValues(p, wm[k], gm[k], onlyGot, diffs)
+		}
+		return
+	}
+	if !reflect.DeepEqual(want, got) {
+		*diffs = append(*diffs, &PropertyDrift{Path: path, Want: want, Got: got})
+	}
+}
+
+// normalizeValue converts the value to its JSON representation so values of different types compare equal.
+func normalizeValue(v interface{}) interface{} {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var n interface{}
+	if err := json.Unmarshal(b, &n); err != nil {
+		return v
+	}
+	return n
+}
+
+// configuredBindings returns the bindings set in the properties of the resource,
+// either as a list of bindings or as the access list of a BigQuery dataset.
+func configuredBindings(res *Resource) map[string][]string {
+	props, _ := normalizeValue(res.Properties).(map[string]interface{})
+	bindings := make(map[string][]string)
+	for _, key := range []string{"bindings", "accessControl"} {
+		bs, _ := props[key].([]interface{})
+		for _, b := range bs {
+			bm, _ := b.(map[string]interface{})
+			role, _ := bm["role"].(string)
+			members, _ := bm["members"].([]interface{})
+			for _, m := range members {
+				if s, ok := m.(string); ok {
+					bindings[role] = append(bindings[role], s)
+				}
+			}
+		}
+	}
+	access, _ := props["access"].([]interface{})
+	for role, members := range accessBindings(access) {
+		bindings[role] = append(bindings[role], members...)
+	}
+	return bindings
+}
+
+// ResourceAccess returns the members with access to the resource by role, including the default accesses added
+// by Init. BigQuery dataset accesses are returned with their dataset roles.
+func ResourceAccess(r *ProjectResource) (map[string][]string, error) {
+	var parsed struct {
+		Properties map[string]interface{} `json:"properties"`
+	}
+	if err := convertJSON(r.Parsed, &parsed); err != nil {
+		return nil, err
+	}
+	return configuredBindings(&Resource{Properties: parsed.Properties}), nil
+}
+
+// accessBindings converts the access list of a BigQuery dataset to bindings.
+func accessBindings(access []interface{}) map[string][]string {
+	prefixes := []struct{ key, prefix string }{
+		{"groupByEmail", "group:"},
+		{"userByEmail", "user:"},
+		{"domain", "domain:"},
+		{"specialGroup", "specialGroup:"},
+		{"iamMember", ""},
+	}
+	bindings := make(map[string][]string)
+	for _, a := range access {
+		am, _ := a.(map[string]interface{})
+		role, _ := am["role"].(string)
+		for _, p := range prefixes {
+			if s, ok := am[p.key].(string); ok {
+				bindings[role] = append(bindings[role], p.prefix+s)
+			}
+		}
+		if view, ok := am["view"].(map[string]interface{}); ok {
+			bindings[role] = append(bindings[role], fmt.Sprintf("view:%v.%v.%v", view["projectId"], view["datasetId"], view["tableId"]))
+		}
+	}
+	return bindings
+}
+
+// diffBindings returns the members of want missing from got and the members of got not in want, sorted by role and member.
+// Members GCP grants implicitly are not reported as extra.
+func diffBindings(want, got map[string][]string) (missing, extra []*BindingMember) {
+	wantSet, gotSet := bindingMembers(want), bindingMembers(got)
+	for _, m := range sortedBindingMembers(wantSet) {
+		if !gotSet[m] {
+			m := m
+			missing = append(missing, &m)
+		}
+	}
+	for _, m := range sortedBindingMembers(gotSet) {
+		if !wantSet[m] && !implicitMember(m.Member) {
+			m := m
+			extra = append(extra, &m)
+		}
+	}
+	return missing, extra
+}
+
+// implicitMember returns whether the member is granted by GCP to the roles of the project rather than by the config:
+// the convenience values of the legacy bucket bindings and the default accesses of BigQuery datasets.
+func implicitMember(member string) bool {
+	for _, prefix := range []string{"projectOwner:", "projectEditor:", "projectViewer:"} {
+		if strings.HasPrefix(member, prefix) {
+			return true
+		}
+	}
+	switch member {
+	case "specialGroup:projectOwners", "specialGroup:projectWriters", "specialGroup:projectReaders":
+		return true
+	}
+	return false
+}
+
+func bindingMembers(bindings map[string][]string) map[BindingMember]bool {
+	set := make(map[BindingMember]bool)
+	for role, members := range bindings {
+		for _, m := range members {
+			set[BindingMember{Role: role, Member: m}] = true
+		}
+	}
+	return set
+}
+
+func sortedBindingMembers(set map[BindingMember]bool) []BindingMember {
+	var ms []BindingMember
+	for m := range set {
+		ms = append(ms, m)
+	}
+	sort.Slice(ms, func(i, j int) bool {
+		if ms[i].Role != ms[j].Role {
+			return ms[i].Role < ms[j].Role
+		}
+		return ms[i].Member < ms[j].Member
+	})
+	return ms
+}
+
+// GCloudDriftClient fetches the deployed state of resources with the gcloud, gsutil and bq command line tools.
+// It fetches the IAM policies and some properties of buckets, datasets, Pub/Sub topics and GCE instances.
+// The live state of other resources is not fetched.
+type GCloudDriftClient struct{}
+
+// liveFetchers maps the template paths of resource kinds to the functions fetching their live state.
+var liveFetchers = map[string]func(projectID string, props map[string]interface{}) (*LiveResource, error){
+	"deploy/cft/templates/bigquery_dataset.py": liveBigqueryDataset,
+	"deploy/cft/templates/gcs_bucket.py":       liveGCSBucket,
+	"deploy/cft/templates/instance.py":         liveGCEInstance,
+	"deploy/cft/templates/pubsub.py":           livePubsubTopic,
+}
+
+// Manifest returns the config of the latest manifest of the deployment.
+func (GCloudDriftClient) Manifest(projectID, deployment string) (*Deployment, error) {
+	return getManifest(projectID, deployment)
+}
+
+// LiveResource returns the live state of the resource.
+// Resources whose live state is not fetched are returned without properties or bindings.
+func (GCloudDriftClient) LiveResource(projectID string, res *Resource) (*LiveResource, error) {
+	fetch, ok := liveFetchers[res.TemplatePath]
+	if !ok {
+		return &LiveResource{}, nil
+	}
+	props, _ := normalizeValue(res.Properties).(map[string]interface{})
+	return fetch(projectID, props)
+}
+
+func liveGCSBucket(projectID string, props map[string]interface{}) (*LiveResource, error) {
+	var bucket map[string]interface{}
+	cmd := exec.Command("gcloud", "storage", "buckets", "describe", fmt.Sprintf("gs://%v", props["name"]), "--raw", "--format", "json", "--project", projectID)
+	if found, err := runJSONCommand(cmd, &bucket); err != nil || !found {
+		return nil, err
+	}
+	bindings, err := liveIAMPolicy(exec.Command("gsutil", "iam", "get", fmt.Sprintf("gs://%v", props["name"])))
+	if err != nil {
+		return nil, err
+	}
+	return &LiveResource{Properties: pickProperties(bucket, "versioning", "logging"), Bindings: bindings}, nil
+}
+
+func liveBigqueryDataset(projectID string, props map[string]interface{}) (*LiveResource, error) {
+	var dataset map[string]interface{}
+	cmd := exec.Command("bq", "show", "--format=prettyjson", fmt.Sprintf("%s:%v", projectID, props["name"]))
+	if found, err := runJSONCommand(cmd, &dataset); err != nil || !found {
+		return nil, err
+	}
+	access, _ := dataset["access"].([]interface{})
+	return &LiveResource{Properties: pickProperties(dataset, "location"), Bindings: accessBindings(access)}, nil
+}
+
+func livePubsubTopic(projectID string, props map[string]interface{}) (*LiveResource, error) {
+	var topic map[string]interface{}
+	cmd := exec.Command("gcloud", "pubsub", "topics", "describe", fmt.Sprintf("%v", props["topic"]), "--format", "json", "--project", projectID)
+	if found, err := runJSONCommand(cmd, &topic); err != nil || !found {
+		return nil, err
+	}
+	bindings, err := liveIAMPolicy(exec.Command("gcloud", "pubsub", "topics", "get-iam-policy", fmt.Sprintf("%v", props["topic"]), "--format", "json", "--project", projectID))
+	if err != nil {
+		return nil, err
+	}
+	return &LiveResource{Properties: pickProperties(topic, "messageStoragePolicy"), Bindings: bindings}, nil
+}
+
+func liveGCEInstance(projectID string, props map[string]interface{}) (*LiveResource, error) {
+	var policy iamPolicy
+	cmd := exec.Command("gcloud", "compute", "instances", "get-iam-policy", fmt.Sprintf("%v", props["name"]), "--zone", fmt.Sprintf("%v", props["zone"]), "--format", "json", "--project", projectID)
+	if found, err := runJSONCommand(cmd, &policy); err != nil || !found {
+		return nil, err
+	}
+	return &LiveResource{Bindings: policy.bindings()}, nil
+}
+
+type iamPolicy struct {
+	Bindings []binding `json:"bindings"`
+}
+
+func (p iamPolicy) bindings() map[string][]string {
+	bindings := make(map[string][]string)
+	for _, b := range p.Bindings {
+		bindings[b.Role] = append(bindings[b.Role], b.Members...)
+	}
+	return bindings
+}
+
+func liveIAMPolicy(cmd *exec.Cmd) (map[string][]string, error) {
+	var policy iamPolicy
+	if _, err := runJSONCommand(cmd, &policy); err != nil {
+		return nil, err
+	}
+	return policy.bindings(), nil
+}
+
+// pickProperties returns the given properties of m that are set.
+func pickProperties(m map[string]interface{}, keys ...string) map[string]interface{} {
+	picked := make(map[string]interface{})
+	for _, k := range keys {
+		if v, ok := m[k]; ok {
+			picked[k] = v
+		}
+	}
+	return picked
+}
+
+// runJSONCommand runs the command and unmarshals its JSON output into v.
+// It returns false without an error if the command failed because the resource was not found.
+func runJSONCommand(cmd *exec.Cmd, v interface{}) (bool, error) {
+	out, err := cmdCombinedOutput(cmd)
+	if err != nil {
+		if isNotFoundOutput(out) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to run command: %v\n%v", err, string(out))
+	}
+	if err := json.Unmarshal(out, v); err != nil {
+		return false, fmt.Errorf("failed to unmarshal output of %v: %v", cmd.Args, err)
+	}
+	return true, nil
+}
+
+func isNotFoundOutput(out []byte) bool {
+	s := strings.ToLower(string(out))
+	return strings.Contains(s, "not found") || strings.Contains(s, "notfound") || strings.Contains(s, "404")
+}
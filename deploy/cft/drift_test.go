@@ -0,0 +1,255 @@
+package cft
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type fakeDriftClient struct {
+	manifest *Deployment
+	live     map[string]*LiveResource
+}
+
+func (c *fakeDriftClient) Manifest(projectID, deployment string) (*Deployment, error) {
+	if deployment != deploymentName {
+		return nil, errors.New("unexpected deployment")
+	}
+	return c.manifest, nil
+}
+
+func (c *fakeDriftClient) LiveResource(projectID string, res *Resource) (*LiveResource, error) {
+	return c.live[res.Name], nil
+}
+
+const driftConfig = `
+resources:
+- gcs_bucket:
+    properties:
+      name: foo-bucket
+      location: us-east1
+- pubsub:
+    properties:
+      topic: foo-topic
+      messageStoragePolicy:
+        allowedPersistenceRegions:
+        - us-central1
+- bigquery_dataset:
+    properties:
+      name: foo_dataset
+      location: US`
+
+func TestDrift(t *testing.T) {
+	_, project := getTestConfigAndProject(t, &ConfigData{driftConfig})
+
+	// The manifest was deployed from an older config with another bucket and topic region.
+	_, manifestProject := getTestConfigAndProject(t, &ConfigData{`
+resources:
+- gcs_bucket:
+    properties:
+      name: foo-bucket
+      location: us-east1
+- pubsub:
+    properties:
+      topic: foo-topic
+      messageStoragePolicy:
+        allowedPersistenceRegions:
+        - us-east1
+- gcs_bucket:
+    properties:
+      name: old-bucket
+      location: us-east1`})
+	manifest, err := renderDeployment(manifestProject)
+	if err != nil {
+		t.Fatalf("renderDeployment: %v", err)
+	}
+
+	client := &fakeDriftClient{
+		manifest: manifest,
+		live: map[string]*LiveResource{
+			"foo-bucket": {
+				// Keys that are not configured, such as the log object prefix, and the legacy bucket bindings are not drift.
+				Properties: map[string]interface{}{
+					"versioning": map[string]interface{}{"enabled": false},
+					"logging":    map[string]interface{}{"logBucket": "my-project-logs", "logObjectPrefix": "foo-bucket"},
+				},
+				Bindings: map[string][]string{
+					"roles/storage.admin":              {"group:my-project-owners@my-domain.com", "user:someone@my-domain.com"},
+					"roles/storage.objectAdmin":        {"group:some-readwrite-group@my-domain.com"},
+					"roles/storage.objectViewer":       {"group:some-readonly-group@my-domain.com"},
+					"roles/storage.legacyBucketOwner":  {"projectOwner:my-project", "projectEditor:my-project"},
+					"roles/storage.legacyBucketReader": {"projectViewer:my-project"},
+				},
+			},
+			"foo-topic": {
+				Properties: map[string]interface{}{
+					"messageStoragePolicy": map[string]interface{}{"allowedPersistenceRegions": []interface{}{"us-central1"}},
+				},
+				Bindings: map[string][]string{
+					"roles/pubsub.editor": {"group:some-readwrite-group@my-domain.com"},
+					"roles/pubsub.viewer": {"group:some-readonly-group@my-domain.com", "group:another-readonly-group@googlegroups.com"},
+				},
+			},
+		},
+	}
+
+	got, err := Drift(project, client)
+	if err != nil {
+		t.Fatalf("Drift: %v", err)
+	}
+
+	want := &DriftReport{
+		Project: "my-project",
+		Resources: []*ResourceDrift{
+			{
+				Name:   "foo-bucket",
+				Source: DriftSourceLive,
+				Status: DriftModified,
+				Properties: []*PropertyDrift{
+					{Path: "versioning.enabled", Want: true, Got: false},
+				},
+				MissingBindings: []*BindingMember{
+					{Role: "roles/storage.objectViewer", Member: "group:another-readonly-group@googlegroups.com"},
+				},
+				ExtraBindings: []*BindingMember{
+					{Role: "roles/storage.admin", Member: "user:someone@my-domain.com"},
+				},
+			},
+			{
+				Name:   "foo-topic",
+				Source: DriftSourceManifest,
+				Status: DriftModified,
+				Properties: []*PropertyDrift{
+					{Path: "messageStoragePolicy.allowedPersistenceRegions", Want: []interface{}{"us-central1"}, Got: []interface{}{"us-east1"}},
+				},
+			},
+			{Name: "foo_dataset", Source: DriftSourceManifest, Status: DriftMissing},
+			{Name: "foo_dataset", Source: DriftSourceLive, Status: DriftMissing},
+			{Name: "old-bucket", Source: DriftSourceManifest, Status: DriftExtra},
+		},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("drift report differs (-got +want):\n%v", diff)
+	}
+
+	var b strings.Builder
+	if err := got.WriteText(&b); err != nil {
+		t.Fatalf("WriteText: %v", err)
+	}
+	wantText := `Project "my-project": 5 drifted resources
+modified "foo-bucket" (live)
+  property versioning.enabled: want true, got false
+  missing binding roles/storage.objectViewer: group:another-readonly-group@googlegroups.com
+  extra binding roles/storage.admin: user:someone@my-domain.com
+modified "foo-topic" (manifest)
+  property messageStoragePolicy.allowedPersistenceRegions: want ["us-central1"], got ["us-east1"]
+missing "foo_dataset" (manifest)
+missing "foo_dataset" (live)
+extra "old-bucket" (manifest)
+`
+	if diff := cmp.Diff(b.String(), wantText); diff != "" {
+		t.Errorf("drift report text differs (-got +want):\n%v", diff)
+	}
+}
+
+func TestDriftNoDrift(t *testing.T) {
+	_, project := getTestConfigAndProject(t, &ConfigData{driftConfig})
+	manifest, err := renderDeployment(project)
+	if err != nil {
+		t.Fatalf("renderDeployment: %v", err)
+	}
+	live := make(map[string]*LiveResource)
+	for _, res := range manifest.Resources {
+		live[res.Name] = &LiveResource{Bindings: configuredBindings(res)}
+	}
+
+	got, err := Drift(project, &fakeDriftClient{manifest: manifest, live: live})
+	if err != nil {
+		t.Fatalf("Drift: %v", err)
+	}
+	if len(got.Resources) != 0 {
+		t.Errorf("Drift found drifted resources %v, want none", got.Resources)
+	}
+}
+
+func TestGCloudDriftClientLiveResource(t *testing.T) {
+	defer func(orig func(*exec.Cmd) ([]byte, error)) { cmdCombinedOutput = orig }(cmdCombinedOutput)
+
+	outputs := map[string]string{
+		"bq show --format=prettyjson my-project:foo_dataset": `{
+  "location": "US",
+  "access": [
+    {"role": "OWNER", "groupByEmail": "my-project-owners@my-domain.com"},
+    {"role": "READER", "specialGroup": "projectReaders"}
+  ]
+}`,
+	}
+	cmdCombinedOutput = func(cmd *exec.Cmd) ([]byte, error) {
+		out, ok := outputs[strings.Join(cmd.Args, " ")]
+		if !ok {
+			return []byte("ERROR: (gcloud.storage.buckets.describe) NotFound: 404 The specified bucket does not exist."), errors.New("exit status 1")
+		}
+		return []byte(out), nil
+	}
+
+	client := GCloudDriftClient{}
+	got, err := client.LiveResource("my-project", &Resource{
+		Name:         "foo_dataset",
+		Properties:   map[string]interface{}{"name": "foo_dataset"},
+		TemplatePath: "deploy/cft/templates/bigquery_dataset.py",
+	})
+	if err != nil {
+		t.Fatalf("LiveResource: %v", err)
+	}
+	want := &LiveResource{
+		Properties: map[string]interface{}{"location": "US"},
+		Bindings: map[string][]string{
+			"OWNER":  {"group:my-project-owners@my-domain.com"},
+			"READER": {"specialGroup:projectReaders"},
+		},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("LiveResource differs (-got +want):\n%v", diff)
+	}
+
+	got, err = client.LiveResource("my-project", &Resource{
+		Name:         "foo-bucket",
+		Properties:   map[string]interface{}{"name": "foo-bucket"},
+		TemplatePath: "deploy/cft/templates/gcs_bucket.py",
+	})
+	if err != nil {
+		t.Fatalf("LiveResource: %v", err)
+	}
+	if got != nil {
+		t.Errorf("LiveResource = %v, want nil for missing bucket", got)
+	}
+}
+
+func TestResourceAccess(t *testing.T) {
+	_, project := getTestConfigAndProject(t, &ConfigData{`
+resources:
+- gcs_bucket:
+    properties:
+      name: foo-bucket
+      location: us-east1
+      bindings:
+      - role: roles/storage.objectViewer
+        members:
+        - user:someone@my-domain.com`})
+
+	got, err := ResourceAccess(project.Resources[0])
+	if err != nil {
+		t.Fatalf("ResourceAccess: %v", err)
+	}
+	want := map[string][]string{
+		"roles/storage.admin":        {"group:my-project-owners@my-domain.com"},
+		"roles/storage.objectAdmin":  {"group:some-readwrite-group@my-domain.com"},
+		"roles/storage.objectViewer": {"group:some-readonly-group@my-domain.com", "group:another-readonly-group@googlegroups.com", "user:someone@my-domain.com"},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("access differs (-got +want):\n%v", diff)
+	}
+}
@@ -0,0 +1,47 @@
+package cft
+
+import (
+	"errors"
+)
+
+// Firewall wraps a CFT firewall.
+type Firewall struct {
+	FirewallProperties `json:"properties"`
+	dependsOn          []string
+}
+
+// FirewallProperties represents a partial CFT firewall implementation.
+type FirewallProperties struct {
+	ResourceName string `json:"name"`
+	Network      string `json:"network,omitempty"`
+}
+
+func init() {
+	RegisterResourceKind(ResourceKind{
+		Key: "firewall",
+		New: func() ParsedResource { return new(Firewall) },
+	})
+}
+
+// Init initializes the firewall with the given project.
+func (f *Firewall) Init(*Project) error {
+	if f.Name() == "" {
+		return errors.New("name must be set")
+	}
+	return nil
+}
+
+// Name returns the name of the firewall.
+func (f *Firewall) Name() string {
+	return f.ResourceName
+}
+
+// TemplatePath returns the name of the template to use for the firewall.
+func (f *Firewall) TemplatePath() string {
+	return "deploy/cft/templates/firewall.py"
+}
+
+// DependsOn returns the names of the resources the firewall depends on.
+func (f *Firewall) DependsOn() []string {
+	return f.dependsOn
+}
@@ -1,21 +1,171 @@
 package cft
 
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+)
+
 // GCEInstance wraps a CFT GCE Instance.
 type GCEInstance struct {
 	GCEInstanceProperties `json:"properties"`
+
+	// AllowExternalIP allows the instance to be reachable through an external IP.
+	AllowExternalIP bool `json:"allow_external_ip,omitempty"`
+
+	dependsOn []string
 }
 
 // GCEInstanceProperties represents a partial CFT instance implementation.
 type GCEInstanceProperties struct {
-	GCEInstanceName string `json:"name"`
-	Zone            string `json:"zone"`
+	GCEInstanceName        string                  `json:"name"`
+	Zone                   string                  `json:"zone"`
+	DiskImage              string                  `json:"diskImage"`
+	Network                string                  `json:"network,omitempty"`
+	Subnetwork             string                  `json:"subnetwork,omitempty"`
+	HasExternalIP          *bool                   `json:"hasExternalIp"`
+	NetworkInterfacePairs  []*networkInterfacePair `json:"networkInterfaces,omitempty"`
+	Metadata               metadata                `json:"metadata"`
+	ShieldedInstanceConfig shieldedInstanceConfig  `json:"shieldedInstanceConfig"`
+	Bindings               []binding               `json:"accessControl,omitempty"`
+}
+
+// networkInterfacePair is used to retain fields not defined by the parsed network interface.
+// See subscriptionPair for details.
+type networkInterfacePair struct {
+	raw    json.RawMessage
+	parsed networkInterface
+}
+
+func (p *networkInterfacePair) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &p.raw); err != nil {
+		return fmt.Errorf("failed to unmarshal network interface into raw form: %v", err)
+	}
+	if err := json.Unmarshal(data, &p.parsed); err != nil {
+		return fmt.Errorf("failed to unmarshal network interface into parsed form: %v", err)
+	}
+	return nil
+}
+
+func (p networkInterfacePair) MarshalJSON() ([]byte, error) {
+	return interfacePair{p.raw, p.parsed}.MarshalJSON()
+}
+
+type networkInterface struct {
+	Network       string        `json:"network,omitempty"`
+	Subnetwork    string        `json:"subnetwork,omitempty"`
+	AccessConfigs []interface{} `json:"accessConfigs,omitempty"`
+}
+
+type metadata struct {
+	Items []metadataItem `json:"items"`
+}
+
+type metadataItem struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+type shieldedInstanceConfig struct {
+	// Use pointers to differentiate between zero value and intentionally being set to false.
+	EnableSecureBoot          *bool `json:"enableSecureBoot"`
+	EnableVTPM                *bool `json:"enableVtpm"`
+	EnableIntegrityMonitoring *bool `json:"enableIntegrityMonitoring"`
+}
+
+// InstanceInfo contains the generated info of a deployed GCE instance.
+type InstanceInfo struct {
+	Name string `json:"name"`
+	ID   string `json:"id"`
+}
+
+func init() {
+	RegisterResourceKind(ResourceKind{
+		Key:         "gce_instance",
+		New:         func() ParsedResource { return new(GCEInstance) },
+		HoldsData:   true,
+		Location:    func(r ParsedResource) string { return r.(*GCEInstance).Zone },
+		ForsetiType: "instance",
+		ForsetiID:   func(project *Project, r ParsedResource) (string, error) { return project.InstanceID(r.Name()) },
+	})
 }
 
 // Init initializes the instance.
-func (i *GCEInstance) Init(*Project) error {
+func (i *GCEInstance) Init(project *Project) error {
+	if i.Name() == "" {
+		return errors.New("name must be set")
+	}
+	if i.Zone == "" {
+		return errors.New("zone must be set")
+	}
+	if i.DiskImage == "" {
+		return errors.New("diskImage must be set")
+	}
+
+	if !i.AllowExternalIP {
+		if i.HasExternalIP != nil && *i.HasExternalIP {
+			return errors.New("hasExternalIp must not be true unless allow_external_ip is set")
+		}
+		for _, nip := range i.NetworkInterfacePairs {
+			if len(nip.parsed.AccessConfigs) > 0 {
+				return errors.New("networkInterfaces must not have accessConfigs unless allow_external_ip is set")
+			}
+		}
+	}
+	if i.HasExternalIP == nil {
+		f := false
+		i.HasExternalIP = &f
+	}
+
+	t := true
+	for _, b := range []**bool{
+		&i.ShieldedInstanceConfig.EnableSecureBoot,
+		&i.ShieldedInstanceConfig.EnableVTPM,
+		&i.ShieldedInstanceConfig.EnableIntegrityMonitoring,
+	} {
+		if *b == nil {
+			*b = &t
+		} else if !**b {
+			log.Printf("instance %q has shielded VM options disabled: %+v", i.Name(), i.ShieldedInstanceConfig)
+		}
+	}
+
+	i.initOSLogin()
+
+	appendGroupPrefix := func(ss ...string) []string {
+		res := make([]string, 0, len(ss))
+		for _, s := range ss {
+			res = append(res, "group:"+s)
+		}
+		return res
+	}
+
+	defaultBindings := []binding{
+		{"roles/compute.osAdminLogin", appendGroupPrefix(project.OwnersGroup)},
+		{"roles/compute.osLogin", appendGroupPrefix(project.DataReadWriteGroups...)},
+	}
+	i.Bindings = mergeBindings(append(defaultBindings, i.Bindings...)...)
 	return nil
 }
 
+// initOSLogin enables OS Login through the instance metadata unless it is explicitly set.
+func (i *GCEInstance) initOSLogin() {
+	const key = "enable-oslogin"
+	for _, item := range i.Metadata.Items {
+		if item.Key != key {
+			continue
+		}
+		if v := strings.ToUpper(fmt.Sprint(item.Value)); v != "TRUE" {
+			log.Printf("instance %q has OS Login disabled: %v=%v", i.Name(), key, item.Value)
+		}
+		return
+	}
+	i.Metadata.Items = append(i.Metadata.Items, metadataItem{Key: key, Value: "TRUE"})
+}
+
 // Name returns the name of this instance.
 func (i *GCEInstance) Name() string {
 	return i.GCEInstanceName
@@ -25,3 +175,36 @@ func (i *GCEInstance) Name() string {
 func (i *GCEInstance) TemplatePath() string {
 	return "deploy/cft/templates/instance.py"
 }
+
+// DependsOn returns the names of the resources the instance depends on.
+func (i *GCEInstance) DependsOn() []string {
+	return i.dependsOn
+}
+
+// getGCEInstanceInfo gets the info of the deployed instances declared in the project.
+// Other instances, such as the nodes of GKE clusters, are skipped.
+func getGCEInstanceInfo(project *Project) ([]InstanceInfo, error) {
+	cmd := exec.Command("gcloud", "compute", "instances", "list", "--format", "json", "--project", project.ID)
+
+	out, err := cmdCombinedOutput(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run command: %v\n%v", err, string(out))
+	}
+
+	var all []InstanceInfo
+	if err := json.Unmarshal(out, &all); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal instance list call: %v", err)
+	}
+
+	declared := make(map[string]bool)
+	for _, i := range project.DataResources().GCEInstances {
+		declared[i.Name()] = true
+	}
+	var infos []InstanceInfo
+	for _, info := range all {
+		if declared[info.Name] {
+			infos = append(infos, info)
+		}
+	}
+	return infos, nil
+}
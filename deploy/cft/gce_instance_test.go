@@ -1,6 +1,7 @@
 package cft
 
 import (
+	"os/exec"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -14,6 +15,36 @@ func TestGCEInstance(t *testing.T) {
 properties:
   name: foo-instance
   zone: us-east1-a
+  diskImage: projects/ubuntu-os-cloud/global/images/family/ubuntu-1804-lts
+  metadata:
+    items:
+    - key: startup-script
+      value: echo hello
+`
+
+	wantInstanceYAML := `
+properties:
+  name: foo-instance
+  zone: us-east1-a
+  diskImage: projects/ubuntu-os-cloud/global/images/family/ubuntu-1804-lts
+  hasExternalIp: false
+  metadata:
+    items:
+    - key: startup-script
+      value: echo hello
+    - key: enable-oslogin
+      value: 'TRUE'
+  shieldedInstanceConfig:
+    enableSecureBoot: true
+    enableVtpm: true
+    enableIntegrityMonitoring: true
+  accessControl:
+  - role: roles/compute.osAdminLogin
+    members:
+    - 'group:my-project-owners@my-domain.com'
+  - role: roles/compute.osLogin
+    members:
+    - 'group:some-readwrite-group@my-domain.com'
 `
 
 	ins := &GCEInstance{}
@@ -34,7 +65,7 @@ properties:
 	if err := yaml.Unmarshal(byt, &got); err != nil {
 		t.Fatalf("yaml.Unmarshal got config: %v", err)
 	}
-	if err := yaml.Unmarshal([]byte(instanceYAML), &want); err != nil {
+	if err := yaml.Unmarshal([]byte(wantInstanceYAML), &want); err != nil {
 		t.Fatalf("yaml.Unmarshal want deployment config: %v", err)
 	}
 
@@ -46,3 +77,98 @@ properties:
 		t.Errorf("ins.Name() = %v, want %v", gotName, wantName)
 	}
 }
+
+func TestGCEInstanceExternalIP(t *testing.T) {
+	_, project := getTestConfigAndProject(t, nil)
+
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr bool
+	}{
+		{
+			name: "has_external_ip",
+			yaml: `
+properties:
+  name: foo-instance
+  zone: us-east1-a
+  diskImage: foo-image
+  hasExternalIp: true`,
+			wantErr: true,
+		},
+		{
+			name: "access_configs",
+			yaml: `
+properties:
+  name: foo-instance
+  zone: us-east1-a
+  diskImage: foo-image
+  networkInterfaces:
+  - network: default
+    accessConfigs:
+    - type: ONE_TO_ONE_NAT`,
+			wantErr: true,
+		},
+		{
+			name: "allowed",
+			yaml: `
+allow_external_ip: true
+properties:
+  name: foo-instance
+  zone: us-east1-a
+  diskImage: foo-image
+  hasExternalIp: true
+  networkInterfaces:
+  - network: default
+    accessConfigs:
+    - type: ONE_TO_ONE_NAT`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ins := &GCEInstance{}
+			if err := yaml.Unmarshal([]byte(tc.yaml), ins); err != nil {
+				t.Fatalf("yaml unmarshal: %v", err)
+			}
+			err := ins.Init(project)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("ins.Init = %v, want error: %t", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetGCEInstanceInfo(t *testing.T) {
+	_, project := getTestConfigAndProject(t, &ConfigData{`
+resources:
+- gce_instance:
+    properties:
+      name: foo-instance
+      zone: us-east1-a
+      diskImage: projects/ubuntu-os-cloud/global/images/family/ubuntu-1804-lts
+      machineType: f1-micro`})
+
+	var gotArgs []string
+	cmdCombinedOutput = func(cmd *exec.Cmd) ([]byte, error) {
+		gotArgs = cmd.Args
+		return []byte(`[
+  {"name": "foo-instance", "id": "123", "zone": "us-east1-a"},
+  {"name": "gke-foo-cluster-default-pool-1a2b3c4d-wxyz", "id": "456", "zone": "us-east1-a"}
+]`), nil
+	}
+
+	got, err := getGCEInstanceInfo(project)
+	if err != nil {
+		t.Fatalf("getGCEInstanceInfo: %v", err)
+	}
+
+	wantArgs := []string{"gcloud", "compute", "instances", "list", "--format", "json", "--project", "my-project"}
+	if diff := cmp.Diff(gotArgs, wantArgs); diff != "" {
+		t.Errorf("getGCEInstanceInfo args differ (-got +want):\n%v", diff)
+	}
+	want := []InstanceInfo{{Name: "foo-instance", ID: "123"}}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("getGCEInstanceInfo differs (-got +want):\n%v", diff)
+	}
+}
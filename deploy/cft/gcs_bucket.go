@@ -31,6 +31,7 @@ type GCSBucketProperties struct {
 	Logging       struct {
 		LogBucket string `json:"logBucket"`
 	} `json:"logging"`
+	IAMConfiguration iamConfiguration `json:"iamConfiguration"`
 }
 
 type versioning struct {
@@ -38,6 +39,26 @@ type versioning struct {
 	Enabled *bool `json:"enabled"`
 }
 
+// iamConfiguration holds the access control settings of a bucket.
+// Uniform bucket-level access is required by the baseline organization policies (see rulegen.OrgPolicies).
+type iamConfiguration struct {
+	UniformBucketLevelAccess struct {
+		// Use pointer to differentiate between zero value and intentionally being set to false.
+		Enabled *bool `json:"enabled"`
+	} `json:"uniformBucketLevelAccess"`
+}
+
+func init() {
+	RegisterResourceKind(ResourceKind{
+		Key:         "gcs_bucket",
+		New:         func() ParsedResource { return new(GCSBucket) },
+		HoldsData:   true,
+		Location:    func(r ParsedResource) string { return r.(*GCSBucket).Location },
+		ForsetiType: "bucket",
+		ForsetiID:   func(_ *Project, r ParsedResource) (string, error) { return r.Name(), nil },
+	})
+}
+
 // Init initializes the bucket with the given project.
 func (b *GCSBucket) Init(project *Project) error {
 	if b.GCSBucketName == "" {
@@ -50,8 +71,13 @@ func (b *GCSBucket) Init(project *Project) error {
 		return errors.New("versioning must not be disabled")
 	}
 
+	if ubla := b.IAMConfiguration.UniformBucketLevelAccess.Enabled; ubla != nil && !*ubla {
+		return errors.New("uniform bucket-level access must not be disabled")
+	}
+
 	t := true
 	b.Versioning.Enabled = &t
+	b.IAMConfiguration.UniformBucketLevelAccess.Enabled = &t
 
 	appendGroupPrefix := func(ss ...string) []string {
 		res := make([]string, 0, len(ss))
@@ -91,7 +117,7 @@ func (b *GCSBucket) TemplatePath() string {
 // DependentResources gets the dependent resources of this bucket.
 // If the bucket has expected users, this list will contain a metric that will detect unexpected
 // access to the bucket from users not in the expected users list.
-func (b *GCSBucket) DependentResources(project *Project) ([]parsedResource, error) {
+func (b *GCSBucket) DependentResources(project *Project) ([]ParsedResource, error) {
 	if len(b.ExpectedUsers) == 0 {
 		return nil, nil
 	}
@@ -130,5 +156,5 @@ func (b *GCSBucket) DependentResources(project *Project) ([]parsedResource, erro
 			},
 		},
 	}
-	return []parsedResource{m}, nil
+	return []ParsedResource{m}, nil
 }
@@ -41,6 +41,9 @@ properties:
     enabled: True
   logging:
     logBucket: my-project-logs
+  iamConfiguration:
+    uniformBucketLevelAccess:
+      enabled: True
 `
 
 	b := &GCSBucket{}
@@ -97,6 +100,11 @@ func TestGCSBucketErrors(t *testing.T) {
 			"properties: { name: foo-bucket, location: us-east1, versioning: { enabled: false }}",
 			"versioning must not be disabled",
 		},
+		{
+			"uniform_bucket_level_access_disabled",
+			"properties: { name: foo-bucket, location: us-east1, iamConfiguration: { uniformBucketLevelAccess: { enabled: false }}}",
+			"uniform bucket-level access must not be disabled",
+		},
 	}
 
 	for _, tc := range tests {
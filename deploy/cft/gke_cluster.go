@@ -1,20 +1,148 @@
 package cft
 
+import (
+	"errors"
+	"fmt"
+	"log"
+)
+
 // GKECluster wraps a CFT GKE cluster.
 type GKECluster struct {
 	GKEClusterProperties `json:"properties"`
+
+	// WorkloadPruneMode determines what happens to objects in the cluster that were applied as part of a gke_workload
+	// of the project but are no longer declared: "report" logs them and "delete" deletes them.
+	// By default, they are left untouched.
+	WorkloadPruneMode string `json:"workload_prune_mode,omitempty"`
+
+	dependsOn []string
 }
 
+// defaultClusterNameSuffix is appended to the cluster name by the template unless clusterNameSuffix is set.
+const defaultClusterNameSuffix = "-cluster"
+
 // GKEClusterProperties represents a partial GKE cluster implementation.
 type GKEClusterProperties struct {
-	ResourceName        string `json:"name"`
-	ClusterLocationType string `json:"clusterLocationType"`
-	Region              string `json:"region"`
-	Zone                string `json:"zone"`
+	ResourceName        string     `json:"name"`
+	ClusterLocationType string     `json:"clusterLocationType"`
+	Region              string     `json:"region"`
+	Zone                string     `json:"zone"`
+	Cluster             gkeCluster `json:"cluster"`
+
+	// ClusterNameSuffix is appended to the cluster name to form the name of the cluster in GKE.
+	// Defaults to defaultClusterNameSuffix; set it to an empty string to use the cluster name as is.
+	ClusterNameSuffix *string `json:"clusterNameSuffix"`
+}
+
+// gkeCluster represents the partial GKE cluster definition.
+// Security related fields use pointers to differentiate between zero value and intentionally being set to false.
+type gkeCluster struct {
+	Name       string `json:"name,omitempty"`
+	Network    string `json:"network,omitempty"`
+	Subnetwork string `json:"subnetwork,omitempty"`
+
+	PrivateClusterConfig struct {
+		EnablePrivateNodes  *bool  `json:"enablePrivateNodes"`
+		MasterIPv4CIDRBlock string `json:"masterIpv4CidrBlock,omitempty"`
+	} `json:"privateClusterConfig"`
+
+	IPAllocationPolicy struct {
+		UseIPAliases *bool `json:"useIpAliases"`
+	} `json:"ipAllocationPolicy"`
+
+	WorkloadIdentityConfig struct {
+		WorkloadPool *string `json:"workloadPool"`
+	} `json:"workloadIdentityConfig"`
+
+	NetworkPolicy struct {
+		Enabled  *bool  `json:"enabled"`
+		Provider string `json:"provider,omitempty"`
+	} `json:"networkPolicy"`
+
+	AddonsConfig struct {
+		NetworkPolicyConfig struct {
+			Disabled *bool `json:"disabled"`
+		} `json:"networkPolicyConfig"`
+	} `json:"addonsConfig"`
+
+	ShieldedNodes struct {
+		Enabled *bool `json:"enabled"`
+	} `json:"shieldedNodes"`
+
+	LegacyABAC struct {
+		Enabled *bool `json:"enabled"`
+	} `json:"legacyAbac"`
+
+	MasterAuthorizedNetworksConfig struct {
+		Enabled *bool `json:"enabled"`
+	} `json:"masterAuthorizedNetworksConfig"`
+}
+
+func init() {
+	RegisterResourceKind(ResourceKind{
+		Key:      "gke_cluster",
+		New:      func() ParsedResource { return new(GKECluster) },
+		Location: func(r ParsedResource) string { return r.(*GKECluster).location() },
+	})
 }
 
 // Init initializes a new GKE cluster with the given project.
+// Unless explicitly overridden, the cluster is configured with private nodes, Workload Identity,
+// network policy, shielded nodes, master authorized networks and without legacy ABAC.
 func (cluster *GKECluster) Init(proj *Project) error {
+	if cluster.Name() == "" {
+		return errors.New("name must be set")
+	}
+	if _, _, err := getLocationTypeAndValue(cluster); err != nil {
+		return err
+	}
+	switch cluster.WorkloadPruneMode {
+	case "", pruneModeReport, pruneModeDelete:
+	default:
+		return fmt.Errorf("workload_prune_mode must be one of %q or %q, got %q", pruneModeReport, pruneModeDelete, cluster.WorkloadPruneMode)
+	}
+
+	if cluster.ClusterNameSuffix == nil {
+		suffix := defaultClusterNameSuffix
+		cluster.ClusterNameSuffix = &suffix
+	}
+
+	c := &cluster.Cluster
+	setDefault := func(field string, b **bool, want bool) {
+		if *b == nil {
+			*b = &want
+		} else if **b != want {
+			log.Printf("cluster %q explicitly overrides secure default %s=%t", cluster.Name(), field, want)
+		}
+	}
+
+	setDefault("privateClusterConfig.enablePrivateNodes", &c.PrivateClusterConfig.EnablePrivateNodes, true)
+	if *c.PrivateClusterConfig.EnablePrivateNodes {
+		if c.PrivateClusterConfig.MasterIPv4CIDRBlock == "" {
+			return errors.New("privateClusterConfig.masterIpv4CidrBlock must be set for private clusters")
+		}
+		// Private clusters must be VPC-native.
+		if c.IPAllocationPolicy.UseIPAliases != nil && !*c.IPAllocationPolicy.UseIPAliases {
+			return errors.New("ipAllocationPolicy.useIpAliases must not be disabled for private clusters")
+		}
+	}
+	setDefault("ipAllocationPolicy.useIpAliases", &c.IPAllocationPolicy.UseIPAliases, true)
+
+	if c.WorkloadIdentityConfig.WorkloadPool == nil {
+		pool := fmt.Sprintf("%s.svc.id.goog", proj.ID)
+		c.WorkloadIdentityConfig.WorkloadPool = &pool
+	} else if *c.WorkloadIdentityConfig.WorkloadPool == "" {
+		log.Printf("cluster %q explicitly disables Workload Identity", cluster.Name())
+	}
+
+	setDefault("networkPolicy.enabled", &c.NetworkPolicy.Enabled, true)
+	if *c.NetworkPolicy.Enabled && c.NetworkPolicy.Provider == "" {
+		c.NetworkPolicy.Provider = "CALICO"
+	}
+	setDefault("addonsConfig.networkPolicyConfig.disabled", &c.AddonsConfig.NetworkPolicyConfig.Disabled, !*c.NetworkPolicy.Enabled)
+	setDefault("shieldedNodes.enabled", &c.ShieldedNodes.Enabled, true)
+	setDefault("legacyAbac.enabled", &c.LegacyABAC.Enabled, false)
+	setDefault("masterAuthorizedNetworksConfig.enabled", &c.MasterAuthorizedNetworksConfig.Enabled, true)
 	return nil
 }
 
@@ -23,19 +151,47 @@ func (cluster *GKECluster) Name() string {
 	return cluster.ResourceName
 }
 
+// ClusterName returns the name of the cluster in GKE.
+func (cluster *GKECluster) ClusterName() string {
+	name := cluster.Cluster.Name
+	if name == "" {
+		name = cluster.Name()
+	}
+	suffix := defaultClusterNameSuffix
+	if cluster.ClusterNameSuffix != nil {
+		suffix = *cluster.ClusterNameSuffix
+	}
+	return name + suffix
+}
+
+// location returns the region of a regional cluster or the zone of a zonal cluster.
+func (cluster *GKECluster) location() string {
+	if cluster.ClusterLocationType == "Zonal" {
+		return cluster.Zone
+	}
+	return cluster.Region
+}
+
+// ref returns the reference to the cluster deployed in the given project.
+func (cluster *GKECluster) ref(project *Project) ClusterRef {
+	return ClusterRef{ProjectID: project.ID, Location: cluster.location(), Name: cluster.ClusterName()}
+}
+
 // TemplatePath returns the name of the template to use for this cluster.
 func (cluster *GKECluster) TemplatePath() string {
 	return "deploy/cft/templates/gke.py"
 }
 
+// DependsOn returns the names of the resources the cluster depends on.
+func (cluster *GKECluster) DependsOn() []string {
+	return cluster.dependsOn
+}
+
 // getClusterByName get a cluster that has the given cluster name in a project.
-// TODO Replace this function with a general implementation.
 func getClusterByName(project *Project, clusterName string) *GKECluster {
-	for _, res := range project.Resources {
-		if len(res.GKEClusterPair.Raw) > 0 {
-			if res.GKEClusterPair.Parsed.Name() == clusterName {
-				return &res.GKEClusterPair.Parsed
-			}
+	for _, pair := range project.resourcePairs() {
+		if c, ok := pair.parsed.(*GKECluster); ok && c.Name() == clusterName {
+			return c
 		}
 	}
 	return nil
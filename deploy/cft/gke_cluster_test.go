@@ -3,6 +3,7 @@ package cft
 import (
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/ghodss/yaml"
 )
 
@@ -60,11 +61,17 @@ resources:
       name: cluster1
       clusterLocationType: Regional
       region: somewhere1
+      cluster:
+        privateClusterConfig:
+          masterIpv4CidrBlock: 172.16.0.0/28
 - gke_cluster:
     properties:
       name: cluster2
       clusterLocationType: Zonal
-      zone: somewhere2-c`,
+      zone: somewhere2-c
+      cluster:
+        privateClusterConfig:
+          masterIpv4CidrBlock: 172.16.0.16/28`,
 	}
 	_, project := getTestConfigAndProject(t, configExtend)
 	cluster := getClusterByName(project, "cluster1")
@@ -82,3 +89,266 @@ resources:
 		t.Fatalf("getClusterByName find a wrong cluster: %v", cluster.Name())
 	}
 }
+
+func TestGKEClusterInit(t *testing.T) {
+	_, project := getTestConfigAndProject(t, nil)
+
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{
+			name: "defaults",
+			yaml: `
+properties:
+  name: foo-cluster
+  clusterLocationType: Regional
+  region: us-east1
+  cluster:
+    privateClusterConfig:
+      masterIpv4CidrBlock: 172.16.0.0/28`,
+			want: `
+properties:
+  name: foo-cluster
+  clusterLocationType: Regional
+  region: us-east1
+  zone: ''
+  clusterNameSuffix: -cluster
+  cluster:
+    privateClusterConfig:
+      enablePrivateNodes: true
+      masterIpv4CidrBlock: 172.16.0.0/28
+    ipAllocationPolicy:
+      useIpAliases: true
+    workloadIdentityConfig:
+      workloadPool: my-project.svc.id.goog
+    networkPolicy:
+      enabled: true
+      provider: CALICO
+    addonsConfig:
+      networkPolicyConfig:
+        disabled: false
+    shieldedNodes:
+      enabled: true
+    legacyAbac:
+      enabled: false
+    masterAuthorizedNetworksConfig:
+      enabled: true`,
+		},
+		{
+			name: "overrides",
+			yaml: `
+properties:
+  name: foo-cluster
+  clusterLocationType: Zonal
+  zone: us-east1-a
+  cluster:
+    privateClusterConfig:
+      enablePrivateNodes: false
+    ipAllocationPolicy:
+      useIpAliases: false
+    workloadIdentityConfig:
+      workloadPool: ''
+    networkPolicy:
+      enabled: false
+    shieldedNodes:
+      enabled: false
+    legacyAbac:
+      enabled: true
+    masterAuthorizedNetworksConfig:
+      enabled: false`,
+			want: `
+properties:
+  name: foo-cluster
+  clusterLocationType: Zonal
+  region: ''
+  zone: us-east1-a
+  clusterNameSuffix: -cluster
+  cluster:
+    privateClusterConfig:
+      enablePrivateNodes: false
+    ipAllocationPolicy:
+      useIpAliases: false
+    workloadIdentityConfig:
+      workloadPool: ''
+    networkPolicy:
+      enabled: false
+    addonsConfig:
+      networkPolicyConfig:
+        disabled: true
+    shieldedNodes:
+      enabled: false
+    legacyAbac:
+      enabled: true
+    masterAuthorizedNetworksConfig:
+      enabled: false`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := new(GKECluster)
+			if err := yaml.Unmarshal([]byte(tc.yaml), cluster); err != nil {
+				t.Fatalf("yaml.Unmarshal: %v", err)
+			}
+			if err := cluster.Init(project); err != nil {
+				t.Fatalf("cluster.Init: %v", err)
+			}
+
+			b, err := yaml.Marshal(cluster)
+			if err != nil {
+				t.Fatalf("yaml.Marshal: %v", err)
+			}
+			var got, want interface{}
+			if err := yaml.Unmarshal(b, &got); err != nil {
+				t.Fatalf("yaml.Unmarshal got: %v", err)
+			}
+			if err := yaml.Unmarshal([]byte(tc.want), &want); err != nil {
+				t.Fatalf("yaml.Unmarshal want: %v", err)
+			}
+			if diff := cmp.Diff(got, want); diff != "" {
+				t.Errorf("cluster differs (-got +want):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestGKEClusterInitErrors(t *testing.T) {
+	_, project := getTestConfigAndProject(t, nil)
+
+	tests := []struct {
+		name string
+		yaml string
+	}{
+		{
+			name: "missing_name",
+			yaml: `
+properties:
+  clusterLocationType: Regional
+  region: us-east1`,
+		},
+		{
+			name: "invalid_location_type",
+			yaml: `
+properties:
+  name: foo-cluster
+  clusterLocationType: Location
+  region: us-east1`,
+		},
+		{
+			name: "missing_region",
+			yaml: `
+properties:
+  name: foo-cluster
+  clusterLocationType: Regional
+  zone: us-east1-a`,
+		},
+		{
+			name: "missing_zone",
+			yaml: `
+properties:
+  name: foo-cluster
+  clusterLocationType: Zonal
+  region: us-east1`,
+		},
+		{
+			name: "missing_master_cidr",
+			yaml: `
+properties:
+  name: foo-cluster
+  clusterLocationType: Regional
+  region: us-east1`,
+		},
+		{
+			name: "private_without_ip_aliases",
+			yaml: `
+properties:
+  name: foo-cluster
+  clusterLocationType: Regional
+  region: us-east1
+  cluster:
+    privateClusterConfig:
+      masterIpv4CidrBlock: 172.16.0.0/28
+    ipAllocationPolicy:
+      useIpAliases: false`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := new(GKECluster)
+			if err := yaml.Unmarshal([]byte(tc.yaml), cluster); err != nil {
+				t.Fatalf("yaml.Unmarshal: %v", err)
+			}
+			if err := cluster.Init(project); err == nil {
+				t.Fatalf("cluster.Init: got nil error, want non-nil error")
+			}
+		})
+	}
+}
+
+func TestGKEClusterName(t *testing.T) {
+	_, project := getTestConfigAndProject(t, nil)
+
+	tests := []struct {
+		name string
+		yaml string
+		want ClusterRef
+	}{
+		{
+			name: "default_suffix",
+			yaml: `
+properties:
+  name: foo
+  clusterLocationType: Regional
+  region: us-east1
+  cluster:
+    privateClusterConfig:
+      masterIpv4CidrBlock: 172.16.0.0/28`,
+			want: ClusterRef{ProjectID: "my-project", Location: "us-east1", Name: "foo-cluster"},
+		},
+		{
+			name: "no_suffix",
+			yaml: `
+properties:
+  name: foo
+  clusterLocationType: Zonal
+  zone: us-east1-b
+  clusterNameSuffix: ''
+  cluster:
+    privateClusterConfig:
+      masterIpv4CidrBlock: 172.16.0.0/28`,
+			want: ClusterRef{ProjectID: "my-project", Location: "us-east1-b", Name: "foo"},
+		},
+		{
+			name: "cluster_name",
+			yaml: `
+properties:
+  name: foo
+  clusterLocationType: Regional
+  region: us-east1
+  clusterNameSuffix: -gke
+  cluster:
+    name: bar
+    privateClusterConfig:
+      masterIpv4CidrBlock: 172.16.0.0/28`,
+			want: ClusterRef{ProjectID: "my-project", Location: "us-east1", Name: "bar-gke"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := new(GKECluster)
+			if err := yaml.Unmarshal([]byte(tc.yaml), cluster); err != nil {
+				t.Fatalf("yaml.Unmarshal: %v", err)
+			}
+			if err := cluster.Init(project); err != nil {
+				t.Fatalf("cluster.Init: %v", err)
+			}
+			if got := cluster.ref(project); got != tc.want {
+				t.Errorf("cluster.ref = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package cft
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+)
+
+// Labels set on every object applied to a cluster through the toolkit, used to find objects to prune.
+const (
+	managedByLabel = "app.kubernetes.io/managed-by"
+	managedByValue = "data-protect-toolkit"
+	projectLabel   = "data-protect-toolkit/project"
+)
+
+// Values of GKECluster.WorkloadPruneMode.
+const (
+	pruneModeReport = "report"
+	pruneModeDelete = "delete"
+)
+
+// defaultNamespace is the namespace of namespaced objects that do not set one.
+const defaultNamespace = "default"
+
+// derivedKinds are kinds of objects that controllers create on behalf of applied objects, copying their labels.
+// For example, the endpoints controller creates Endpoints and EndpointSlices with the labels of their Service.
+var derivedKinds = map[string]bool{
+	"Endpoints":     true,
+	"EndpointSlice": true,
+}
+
+// managedLabels returns the labels set on every object applied to a cluster on behalf of the project.
+func managedLabels(project *Project) map[string]string {
+	return map[string]string{
+		managedByLabel: managedByValue,
+		projectLabel:   project.ID,
+	}
+}
+
+// objectRef identifies an object in a cluster.
+type objectRef struct {
+	APIVersion string
+	Kind       string
+	Namespace  string
+	Name       string
+}
+
+// key returns a key identifying the object regardless of the API group version it is served from.
+func (r objectRef) key() string {
+	return strings.Join([]string{strings.ToLower(r.Kind), r.Namespace, r.Name}, "/")
+}
+
+// String returns the object in the form kubectl accepts as a resource argument, i.e. TYPE[.VERSION][.GROUP]/NAME.
+func (r objectRef) String() string {
+	typ := strings.ToLower(r.Kind)
+	if i := strings.Index(r.APIVersion, "/"); i >= 0 {
+		typ = fmt.Sprintf("%s.%s.%s", typ, r.APIVersion[i+1:], r.APIVersion[:i])
+	}
+	return typ + "/" + r.Name
+}
+
+// labelManifest sets the given labels on all objects in the manifest.
+// It returns the labeled manifest as a stream of JSON documents and references to the objects.
+func labelManifest(manifest []byte, labels map[string]string) ([]byte, []objectRef, error) {
+	var docs []string
+	var refs []objectRef
+	for _, doc := range yamlDocumentSeparatorRE.Split(string(manifest), -1) {
+		if strings.TrimSpace(doc) == "" {
+			continue
+		}
+		var obj map[string]interface{}
+		if err := yaml.Unmarshal([]byte(doc), &obj); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse manifest document: %v", err)
+		}
+		if obj == nil {
+			continue
+		}
+		docRefs, err := labelObject(obj, labels)
+		if err != nil {
+			return nil, nil, err
+		}
+		b, err := json.Marshal(obj)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal labeled object: %v", err)
+		}
+		docs = append(docs, string(b))
+		refs = append(refs, docRefs...)
+	}
+	return []byte(strings.Join(docs, "\n---\n")), refs, nil
+}
+
+// labelObject sets the given labels on the object, or on its items if it is a list.
+func labelObject(obj map[string]interface{}, labels map[string]string) ([]objectRef, error) {
+	kind, _ := obj["kind"].(string)
+	if kind == "List" {
+		items, _ := obj["items"].([]interface{})
+		var refs []objectRef
+		for _, item := range items {
+			m, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("list item is not an object: %v", item)
+			}
+			itemRefs, err := labelObject(m, labels)
+			if err != nil {
+				return nil, err
+			}
+			refs = append(refs, itemRefs...)
+		}
+		return refs, nil
+	}
+
+	meta, _ := obj["metadata"].(map[string]interface{})
+	if meta == nil {
+		meta = make(map[string]interface{})
+		obj["metadata"] = meta
+	}
+	objLabels, _ := meta["labels"].(map[string]interface{})
+	if objLabels == nil {
+		objLabels = make(map[string]interface{})
+		meta["labels"] = objLabels
+	}
+	for k, v := range labels {
+		objLabels[k] = v
+	}
+
+	ref := objectRef{Kind: kind}
+	ref.APIVersion, _ = obj["apiVersion"].(string)
+	ref.Name, _ = meta["name"].(string)
+	ref.Namespace, _ = meta["namespace"].(string)
+	return []objectRef{ref}, nil
+}
+
+// labelSelector returns the given labels as a kubectl label selector.
+func labelSelector(labels map[string]string) string {
+	var ls []string
+	for k, v := range labels {
+		ls = append(ls, k+"="+v)
+	}
+	sort.Strings(ls)
+	return strings.Join(ls, ",")
+}
+
+// listManagedObjects lists the objects in the cluster of the given kubeconfig that are labeled
+// as managed on behalf of the project.
+// Objects owned by other objects or created by controllers for them are skipped as they were not applied directly.
+func listManagedObjects(kubeconfig string, project *Project) ([]objectRef, error) {
+	cmd := kubectlCommand(kubeconfig, "api-resources", "--verbs", "list,delete", "--output", "name")
+	cmd.Stderr = os.Stderr
+	out, err := cmdOutput(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API resources: %v", err)
+	}
+	types := strings.Fields(string(out))
+	if len(types) == 0 {
+		return nil, nil
+	}
+
+	cmd = kubectlCommand(kubeconfig, "get", strings.Join(types, ","), "--all-namespaces", "--selector", labelSelector(managedLabels(project)), "--output", "json")
+	cmd.Stderr = os.Stderr
+	out, err = cmdOutput(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list managed objects: %v", err)
+	}
+
+	var list struct {
+		Items []struct {
+			APIVersion string `json:"apiVersion"`
+			Kind       string `json:"kind"`
+			Metadata   struct {
+				Name            string        `json:"name"`
+				Namespace       string        `json:"namespace"`
+				OwnerReferences []interface{} `json:"ownerReferences"`
+			} `json:"metadata"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal managed objects: %v", err)
+	}
+
+	// The same object may be served by several API groups, e.g. extensions and apps for deployments.
+	seen := make(map[string]bool)
+	var refs []objectRef
+	for _, item := range list.Items {
+		if len(item.Metadata.OwnerReferences) > 0 || derivedKinds[item.Kind] {
+			continue
+		}
+		ref := objectRef{APIVersion: item.APIVersion, Kind: item.Kind, Namespace: item.Metadata.Namespace, Name: item.Metadata.Name}
+		if seen[ref.key()] {
+			continue
+		}
+		seen[ref.key()] = true
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
+
+// pruneClusterWorkloads finds objects in the cluster that were applied on behalf of the project but are no longer
+// declared and, depending on the prune mode, reports or deletes them.
+// The kubeconfig must hold the credentials of the cluster.
+func pruneClusterWorkloads(kubeconfig string, cluster ClusterRef, mode string, project *Project, declared []objectRef) error {
+	declaredKeys := make(map[string]bool)
+	for _, r := range declared {
+		declaredKeys[r.key()] = true
+	}
+
+	objs, err := listManagedObjects(kubeconfig, project)
+	if err != nil {
+		return err
+	}
+	var stale []objectRef
+	for _, o := range objs {
+		// Namespaced objects declared without a namespace are created in the default namespace.
+		unqualified := objectRef{Kind: o.Kind, Name: o.Name}
+		if declaredKeys[o.key()] || (o.Namespace == defaultNamespace && declaredKeys[unqualified.key()]) {
+			continue
+		}
+		stale = append(stale, o)
+	}
+
+	if len(stale) == 0 {
+		return nil
+	}
+	if mode != pruneModeDelete {
+		var names []string
+		for _, o := range stale {
+			names = append(names, fmt.Sprintf("%v (namespace %q)", o, o.Namespace))
+		}
+		log.Printf("The following objects in cluster %q are no longer declared in the config and can be deleted:\n%v",
+			cluster.Name, strings.Join(names, "\n"))
+		return nil
+	}
+
+	for _, o := range stale {
+		log.Printf("Deleting %v (namespace %q) from cluster %q as it is no longer declared in the config", o, o.Namespace, cluster.Name)
+		args := []string{"delete", o.String()}
+		if o.Namespace != "" {
+			args = append(args, "--namespace", o.Namespace)
+		}
+		cmd := kubectlCommand(kubeconfig, args...)
+		cmd.Stderr = os.Stderr
+		if err := cmdRun(cmd); err != nil {
+			return fmt.Errorf("failed to delete %v: %v", o, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package cft
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ghodss/yaml"
+)
+
+func TestLabelManifest(t *testing.T) {
+	manifest := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: foo
+  namespace: ns
+  labels:
+    app: foo
+---
+{"apiVersion": "v1", "kind": "List", "items": [{"apiVersion": "v1", "kind": "Service", "metadata": {"name": "bar"}}]}
+`
+	labels := map[string]string{"managed": "true"}
+	gotManifest, gotRefs, err := labelManifest([]byte(manifest), labels)
+	if err != nil {
+		t.Fatalf("labelManifest: %v", err)
+	}
+
+	wantRefs := []objectRef{
+		{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "ns", Name: "foo"},
+		{APIVersion: "v1", Kind: "Service", Name: "bar"},
+	}
+	if diff := cmp.Diff(gotRefs, wantRefs); diff != "" {
+		t.Errorf("refs differ (-got +want):\n%v", diff)
+	}
+
+	objs, err := rolloutObjects(gotManifest)
+	if err != nil {
+		t.Fatalf("rolloutObjects: %v", err)
+	}
+	if len(objs) != 1 || objs[0].Name != "foo" {
+		t.Errorf("rollout objects of labeled manifest = %v, want deployment/foo", objs)
+	}
+
+	wantManifest := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: foo
+  namespace: ns
+  labels:
+    app: foo
+    managed: 'true'
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Service
+  metadata:
+    name: bar
+    labels:
+      managed: 'true'`
+
+	var got, want []interface{}
+	for _, m := range []struct {
+		b   []byte
+		out *[]interface{}
+	}{{gotManifest, &got}, {[]byte(wantManifest), &want}} {
+		for _, doc := range yamlDocumentSeparatorRE.Split(string(m.b), -1) {
+			var obj interface{}
+			if err := yaml.Unmarshal([]byte(doc), &obj); err != nil {
+				t.Fatalf("yaml.Unmarshal: %v", err)
+			}
+			*m.out = append(*m.out, obj)
+		}
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("labeled manifest differs (-got +want):\n%v", diff)
+	}
+}
+
+func TestPruneClusterWorkloads(t *testing.T) {
+	const listOutput = `{"items": [
+  {"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "foo", "namespace": "default"}},
+  {"apiVersion": "extensions/v1beta1", "kind": "Deployment", "metadata": {"name": "foo", "namespace": "default"}},
+  {"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "old", "namespace": "ns"}},
+  {"apiVersion": "v1", "kind": "Service", "metadata": {"name": "bar", "namespace": "ns"}},
+  {"apiVersion": "v1", "kind": "Endpoints", "metadata": {"name": "bar", "namespace": "ns"}},
+  {"apiVersion": "discovery.k8s.io/v1beta1", "kind": "EndpointSlice", "metadata": {"name": "bar-x7k2p", "namespace": "ns",
+    "ownerReferences": [{"apiVersion": "v1", "kind": "Service", "name": "bar"}]}},
+  {"apiVersion": "apps/v1", "kind": "ReplicaSet", "metadata": {"name": "foo-5d9c7b", "namespace": "default",
+    "ownerReferences": [{"apiVersion": "apps/v1", "kind": "Deployment", "name": "foo"}]}},
+  {"apiVersion": "rbac.authorization.k8s.io/v1", "kind": "ClusterRole", "metadata": {"name": "old-role"}}
+]}`
+
+	declared := []objectRef{
+		{APIVersion: "apps/v1", Kind: "Deployment", Name: "foo"},
+		{APIVersion: "v1", Kind: "Service", Namespace: "ns", Name: "bar"},
+	}
+
+	tests := []struct {
+		mode        string
+		wantRunArgs [][]string
+	}{
+		{
+			mode: pruneModeReport,
+		},
+		{
+			mode: pruneModeDelete,
+			wantRunArgs: [][]string{
+				{"kubectl", "delete", "deployment.v1.apps/old", "--namespace", "ns"},
+				{"kubectl", "delete", "clusterrole.v1.rbac.authorization.k8s.io/old-role"},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.mode, func(t *testing.T) {
+			_, project := getTestConfigAndProject(t, nil)
+			cluster := ClusterRef{ProjectID: "my-project", Location: "us-east1", Name: "cluster1-cluster"}
+
+			var gotRunArgs, gotOutputArgs [][]string
+			cmdRun = func(cmd *exec.Cmd) error {
+				gotRunArgs = append(gotRunArgs, cmd.Args)
+				return nil
+			}
+			cmdOutput = func(cmd *exec.Cmd) ([]byte, error) {
+				gotOutputArgs = append(gotOutputArgs, cmd.Args)
+				if cmd.Args[1] == "api-resources" {
+					return []byte("deployments.apps\nservices\nclusterroles.rbac.authorization.k8s.io\n"), nil
+				}
+				return []byte(listOutput), nil
+			}
+
+			if err := pruneClusterWorkloads("kubeconfig", cluster, tc.mode, project, declared); err != nil {
+				t.Fatalf("pruneClusterWorkloads: %v", err)
+			}
+
+			if diff := cmp.Diff(gotRunArgs, tc.wantRunArgs); diff != "" {
+				t.Errorf("run commands differ (-got +want):\n%v", diff)
+			}
+			wantOutputArgs := [][]string{
+				{"kubectl", "api-resources", "--verbs", "list,delete", "--output", "name"},
+				{"kubectl", "get", "deployments.apps,services,clusterroles.rbac.authorization.k8s.io", "--all-namespaces",
+					"--selector", "app.kubernetes.io/managed-by=data-protect-toolkit,data-protect-toolkit/project=my-project", "--output", "json"},
+			}
+			if diff := cmp.Diff(gotOutputArgs, wantOutputArgs); diff != "" {
+				t.Errorf("output commands differ (-got +want):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestGKEClusterWorkloadPruneModeErrors(t *testing.T) {
+	_, project := getTestConfigAndProject(t, nil)
+	cluster := &GKECluster{
+		GKEClusterProperties: GKEClusterProperties{ResourceName: "cluster1", ClusterLocationType: "Regional", Region: "us-east1"},
+		WorkloadPruneMode:    "abandon",
+	}
+	if err := cluster.Init(project); err == nil {
+		t.Fatalf("cluster.Init: got nil error, want non-nil error")
+	}
+}
@@ -0,0 +1,185 @@
+package cft
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+)
+
+// defaultWorkloadWaitTimeout is the time to wait for a workload to become ready if the workload does not set one.
+const defaultWorkloadWaitTimeout = 5 * time.Minute
+
+// workloadLogTailLines is the number of log lines of each container surfaced when a workload fails to become ready.
+const workloadLogTailLines = 50
+
+var yamlDocumentSeparatorRE = regexp.MustCompile(`(?m)^---[ \t]*$`)
+
+// rolloutObject is an object applied to a cluster whose readiness can be awaited.
+type rolloutObject struct {
+	Kind      string
+	Name      string
+	Namespace string
+
+	// selector selects the pods of the object.
+	selector map[string]string
+}
+
+// String returns the object in the form kubectl accepts as a resource argument.
+func (o rolloutObject) String() string {
+	return strings.ToLower(o.Kind) + "/" + o.Name
+}
+
+// kubectlCommand returns a kubectl command with the given args in the namespace of the object.
+func (o rolloutObject) kubectlCommand(kubeconfig string, args ...string) *exec.Cmd {
+	if o.Namespace != "" {
+		args = append(args, "--namespace", o.Namespace)
+	}
+	return kubectlCommand(kubeconfig, args...)
+}
+
+// rolloutObjects returns the Deployments, StatefulSets and Jobs defined in the given manifest.
+// The manifest may contain multiple YAML or JSON documents as well as lists.
+func rolloutObjects(manifest []byte) ([]rolloutObject, error) {
+	var objs []rolloutObject
+	for _, doc := range yamlDocumentSeparatorRE.Split(string(manifest), -1) {
+		if strings.TrimSpace(doc) == "" {
+			continue
+		}
+		j, err := yaml.YAMLToJSON([]byte(doc))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse manifest document: %v", err)
+		}
+		if bytes.Equal(j, []byte("null")) {
+			continue
+		}
+		docObjs, err := parseRolloutObjects(j)
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, docObjs...)
+	}
+	return objs, nil
+}
+
+func parseRolloutObjects(j json.RawMessage) ([]rolloutObject, error) {
+	var obj struct {
+		Kind     string `json:"kind"`
+		Metadata struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		Spec struct {
+			Selector struct {
+				MatchLabels map[string]string `json:"matchLabels"`
+			} `json:"selector"`
+		} `json:"spec"`
+		Items []json.RawMessage `json:"items"`
+	}
+	if err := json.Unmarshal(j, &obj); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal manifest object: %v", err)
+	}
+
+	ro := rolloutObject{Kind: obj.Kind, Name: obj.Metadata.Name, Namespace: obj.Metadata.Namespace}
+	switch obj.Kind {
+	case "List":
+		var objs []rolloutObject
+		for _, item := range obj.Items {
+			itemObjs, err := parseRolloutObjects(item)
+			if err != nil {
+				return nil, err
+			}
+			objs = append(objs, itemObjs...)
+		}
+		return objs, nil
+	case "Deployment", "StatefulSet":
+		ro.selector = obj.Spec.Selector.MatchLabels
+	case "Job":
+		// Pods of a job are labeled with the job name by the job controller.
+		ro.selector = map[string]string{"job-name": obj.Metadata.Name}
+	default:
+		return nil, nil
+	}
+	if ro.Name == "" {
+		return nil, fmt.Errorf("%s must have a name", obj.Kind)
+	}
+	return []rolloutObject{ro}, nil
+}
+
+// waitForRollout waits for the given objects to become ready.
+// If an object fails to become ready in time, the events and logs of its pods are returned in the error
+// and, if rollback is set, Deployments and StatefulSets are rolled back to their previous revision.
+func waitForRollout(kubeconfig string, objs []rolloutObject, timeout time.Duration, rollback bool) error {
+	for _, o := range objs {
+		log.Printf("Waiting for %v to become ready", o)
+		var cmd *exec.Cmd
+		if o.Kind == "Job" {
+			cmd = o.kubectlCommand(kubeconfig, "wait", "--for=condition=complete", o.String(), "--timeout", timeout.String())
+		} else {
+			cmd = o.kubectlCommand(kubeconfig, "rollout", "status", o.String(), "--timeout", timeout.String())
+		}
+		out, err := cmdCombinedOutput(cmd)
+		if err == nil {
+			continue
+		}
+
+		msg := fmt.Sprintf("%v failed to become ready: %v\n%s%s", o, err, out, podDiagnostics(kubeconfig, o))
+		if rollback && o.Kind != "Job" {
+			if out, err := cmdCombinedOutput(o.kubectlCommand(kubeconfig, "rollout", "undo", o.String())); err != nil {
+				msg += fmt.Sprintf("\nfailed to roll back %v: %v\n%s", o, err, out)
+			} else {
+				msg += fmt.Sprintf("\nrolled back %v to its previous revision", o)
+			}
+		}
+		return errors.New(msg)
+	}
+	return nil
+}
+
+// podDiagnostics returns the events and recent container logs of the pods of the given object.
+// Failures to get them are included in the result rather than returned, as they are only informational.
+func podDiagnostics(kubeconfig string, o rolloutObject) string {
+	if len(o.selector) == 0 {
+		return ""
+	}
+	var labels []string
+	for k, v := range o.selector {
+		labels = append(labels, k+"="+v)
+	}
+	sort.Strings(labels)
+	selector := strings.Join(labels, ",")
+
+	var buf strings.Builder
+	tail := fmt.Sprint(workloadLogTailLines)
+	for _, d := range []struct {
+		title string
+		cmd   *exec.Cmd
+
+		// optional diagnostics are omitted if they cannot be retrieved.
+		optional bool
+	}{
+		{"pod events", o.kubectlCommand(kubeconfig, "describe", "pods", "--selector", selector), false},
+		{"container logs", o.kubectlCommand(kubeconfig, "logs", "--selector", selector, "--all-containers", "--tail", tail), false},
+		// Logs of previous containers only exist for crashed containers that have been restarted.
+		{"previous container logs", o.kubectlCommand(kubeconfig, "logs", "--selector", selector, "--all-containers", "--previous", "--tail", tail), true},
+	} {
+		out, err := cmdCombinedOutput(d.cmd)
+		if err != nil {
+			if d.optional {
+				continue
+			}
+			fmt.Fprintf(&buf, "\nfailed to get %s: %v\n%s", d.title, err, out)
+			continue
+		}
+		fmt.Fprintf(&buf, "\n%s of %v:\n%s", d.title, o, out)
+	}
+	return buf.String()
+}
@@ -0,0 +1,150 @@
+package cft
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRolloutObjects(t *testing.T) {
+	manifest := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: foo
+  namespace: ns
+spec:
+  selector:
+    matchLabels:
+      app: foo
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: foo
+---
+{"apiVersion": "v1", "kind": "List", "items": [
+  {"apiVersion": "apps/v1", "kind": "StatefulSet", "metadata": {"name": "bar"}, "spec": {"selector": {"matchLabels": {"app": "bar"}}}},
+  {"apiVersion": "batch/v1", "kind": "Job", "metadata": {"name": "baz"}}
+]}
+---
+`
+	got, err := rolloutObjects([]byte(manifest))
+	if err != nil {
+		t.Fatalf("rolloutObjects: %v", err)
+	}
+	want := []rolloutObject{
+		{Kind: "Deployment", Name: "foo", Namespace: "ns", selector: map[string]string{"app": "foo"}},
+		{Kind: "StatefulSet", Name: "bar", selector: map[string]string{"app": "bar"}},
+		{Kind: "Job", Name: "baz", selector: map[string]string{"job-name": "baz"}},
+	}
+	if diff := cmp.Diff(got, want, cmp.AllowUnexported(rolloutObject{})); diff != "" {
+		t.Errorf("rollout objects differ (-got +want):\n%v", diff)
+	}
+}
+
+func TestWaitForRollout(t *testing.T) {
+	objs := []rolloutObject{
+		{Kind: "Deployment", Name: "foo", Namespace: "ns", selector: map[string]string{"app": "foo"}},
+		{Kind: "Job", Name: "baz", selector: map[string]string{"job-name": "baz"}},
+	}
+
+	var gotArgs [][]string
+	cmdCombinedOutput = func(cmd *exec.Cmd) ([]byte, error) {
+		gotArgs = append(gotArgs, cmd.Args)
+		return nil, nil
+	}
+
+	if err := waitForRollout("kubeconfig", objs, time.Minute, false); err != nil {
+		t.Fatalf("waitForRollout: %v", err)
+	}
+	wantArgs := [][]string{
+		{"kubectl", "rollout", "status", "deployment/foo", "--timeout", "1m0s", "--namespace", "ns"},
+		{"kubectl", "wait", "--for=condition=complete", "job/baz", "--timeout", "1m0s"},
+	}
+	if diff := cmp.Diff(gotArgs, wantArgs); diff != "" {
+		t.Errorf("commands differ (-got +want):\n%v", diff)
+	}
+}
+
+func TestWaitForRolloutFailure(t *testing.T) {
+	obj := rolloutObject{Kind: "Deployment", Name: "foo", selector: map[string]string{"app": "foo", "tier": "web"}}
+
+	tests := []struct {
+		name         string
+		rollback     bool
+		wantArgs     [][]string
+		wantInErr    []string
+		notWantInErr []string
+	}{
+		{
+			name: "no_rollback",
+			wantArgs: [][]string{
+				{"kubectl", "rollout", "status", "deployment/foo", "--timeout", "5m0s"},
+				{"kubectl", "describe", "pods", "--selector", "app=foo,tier=web"},
+				{"kubectl", "logs", "--selector", "app=foo,tier=web", "--all-containers", "--tail", "50"},
+				{"kubectl", "logs", "--selector", "app=foo,tier=web", "--all-containers", "--previous", "--tail", "50"},
+			},
+			wantInErr:    []string{"deployment/foo failed to become ready", "Back-off restarting failed container", "panic: boom"},
+			notWantInErr: []string{"rolled back"},
+		},
+		{
+			name:     "rollback",
+			rollback: true,
+			wantArgs: [][]string{
+				{"kubectl", "rollout", "status", "deployment/foo", "--timeout", "5m0s"},
+				{"kubectl", "describe", "pods", "--selector", "app=foo,tier=web"},
+				{"kubectl", "logs", "--selector", "app=foo,tier=web", "--all-containers", "--tail", "50"},
+				{"kubectl", "logs", "--selector", "app=foo,tier=web", "--all-containers", "--previous", "--tail", "50"},
+				{"kubectl", "rollout", "undo", "deployment/foo"},
+			},
+			wantInErr: []string{"rolled back deployment/foo to its previous revision"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotArgs [][]string
+			cmdCombinedOutput = func(cmd *exec.Cmd) ([]byte, error) {
+				gotArgs = append(gotArgs, cmd.Args)
+				switch cmd.Args[1] {
+				case "rollout":
+					if cmd.Args[2] == "status" {
+						return []byte("error: timed out waiting for the condition"), errors.New("exit status 1")
+					}
+				case "describe":
+					return []byte("Warning  BackOff  Back-off restarting failed container"), nil
+				case "logs":
+					for _, a := range cmd.Args {
+						if a == "--previous" {
+							return []byte("panic: boom"), nil
+						}
+					}
+				}
+				return nil, nil
+			}
+
+			err := waitForRollout("kubeconfig", []rolloutObject{obj}, defaultWorkloadWaitTimeout, tc.rollback)
+			if err == nil {
+				t.Fatalf("waitForRollout: got nil error, want non-nil error")
+			}
+			for _, s := range tc.wantInErr {
+				if !strings.Contains(err.Error(), s) {
+					t.Errorf("waitForRollout error %q does not contain %q", err, s)
+				}
+			}
+			for _, s := range tc.notWantInErr {
+				if strings.Contains(err.Error(), s) {
+					t.Errorf("waitForRollout error %q unexpectedly contains %q", err, s)
+				}
+			}
+			if diff := cmp.Diff(gotArgs, tc.wantArgs); diff != "" {
+				t.Errorf("commands differ (-got +want):\n%v", diff)
+			}
+		})
+	}
+}
@@ -1,18 +1,119 @@
 package cft
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 )
 
+// kustomizationFiles are the file names kustomize recognizes as a kustomization.
+var kustomizationFiles = []string{"kustomization.yaml", "kustomization.yml", "Kustomization"}
+
 // GKEWorkload represents a GKE resources, not limited to workloads.
+// The resources are either defined inline in Properties or loaded from Path.
 type GKEWorkload struct {
-	Properties  interface{} `json:"properties"`
-	ClusterName string      `json:"cluster_name"`
+	Properties interface{} `json:"properties,omitempty"`
+
+	// Path is a manifest file, a directory of manifest files or a kustomization to load the resources from.
+	// Relative paths are resolved against the directory of the config file.
+	Path string `json:"path,omitempty"`
+
+	// ClusterName is the name of the gke_cluster resource in the project to deploy the workload to.
+	ClusterName string `json:"cluster_name,omitempty"`
+
+	// Cluster references a cluster that is not defined in the project, e.g. one hosted by a platform team
+	// in another project. Exactly one of ClusterName and Cluster must be set.
+	Cluster *ClusterRef `json:"cluster,omitempty"`
+
+	// WaitTimeout is the maximum duration to wait for the Deployments, StatefulSets and Jobs in the workload
+	// to become ready after being applied, e.g. "10m". Defaults to defaultWorkloadWaitTimeout.
+	WaitTimeout string `json:"wait_timeout,omitempty"`
+
+	// RollbackOnFailure rolls back Deployments and StatefulSets that fail to become ready to their previous revision.
+	RollbackOnFailure bool `json:"rollback_on_failure,omitempty"`
+}
+
+// ClusterRef identifies an existing GKE cluster.
+type ClusterRef struct {
+	ProjectID string `json:"project_id"`
+
+	// Location is the region of a regional cluster or the zone of a zonal cluster.
+	Location string `json:"location"`
+
+	// Name is the name of the cluster in GKE.
+	Name string `json:"name"`
+}
+
+var (
+	regionRE = regexp.MustCompile(`^[a-z]+-[a-z]+[0-9]+$`)
+	zoneRE   = regexp.MustCompile(`^[a-z]+-[a-z]+[0-9]+-[a-z]$`)
+
+	// zoneSuffixRE distinguishes zones, which are named after their region followed by a letter, from regions.
+	zoneSuffixRE = regexp.MustCompile(`-[a-z]$`)
+)
+
+func (r ClusterRef) validate() error {
+	if r.ProjectID == "" || r.Name == "" {
+		return errors.New("cluster.project_id and cluster.name must be set")
+	}
+	if !regionRE.MatchString(r.Location) && !zoneRE.MatchString(r.Location) {
+		return fmt.Errorf("cluster.location must be a region or zone, got %q", r.Location)
+	}
+	return nil
+}
+
+// locationFlag returns the gcloud flag to specify the location of the cluster.
+func (r ClusterRef) locationFlag() string {
+	if zoneSuffixRE.MatchString(r.Location) {
+		return "--zone"
+	}
+	return "--region"
+}
+
+// region returns the region the cluster is located in.
+func (r ClusterRef) region() string {
+	if zoneSuffixRE.MatchString(r.Location) {
+		return r.Location[:strings.LastIndex(r.Location, "-")]
+	}
+	return r.Location
+}
+
+// clusterRef returns the reference to the cluster the workload is deployed to.
+func (w *GKEWorkload) clusterRef(project *Project) (ClusterRef, error) {
+	if w.Cluster != nil {
+		return *w.Cluster, nil
+	}
+	cluster := getClusterByName(project, w.ClusterName)
+	if cluster == nil {
+		return ClusterRef{}, fmt.Errorf("failed to find cluster: %q", w.ClusterName)
+	}
+	return cluster.ref(project), nil
+}
+
+// waitTimeout returns the parsed wait timeout of the workload.
+func (w *GKEWorkload) waitTimeout() (time.Duration, error) {
+	if w.WaitTimeout == "" {
+		return defaultWorkloadWaitTimeout, nil
+	}
+	d, err := time.ParseDuration(w.WaitTimeout)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse wait_timeout: %v", err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("wait_timeout must be positive: %v", w.WaitTimeout)
+	}
+	return d, nil
 }
 
 func getLocationTypeAndValue(cluster *GKECluster) (string, string, error) {
@@ -32,28 +133,28 @@ func getLocationTypeAndValue(cluster *GKECluster) (string, string, error) {
 	}
 }
 
-// installClusterWorkloadFromFile creates and updates (when it exists) not only workloads
-// but also all resources supported by "kubectl apply -f".
-func installClusterWorkloadFromFile(clusterName, containerYamlPath string, project *Project) error {
-	cluster := getClusterByName(project, clusterName)
-	if cluster == nil {
-		return fmt.Errorf("failed to find cluster: %q", clusterName)
-	}
-	locationType, locationValue, err := getLocationTypeAndValue(cluster)
+// withClusterCredentials gets credentials for the cluster into a kubeconfig file dedicated to this call and
+// calls f with its path. The file is removed afterwards.
+// Using a dedicated file rather than the user's kubeconfig leaves the user's current context untouched and
+// allows deploying to several clusters concurrently.
+func withClusterCredentials(cluster ClusterRef, f func(kubeconfig string) error) error {
+	dir, err := ioutil.TempDir("", "kubeconfig")
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to create temp dir: %v", err)
 	}
-	if err := getGCloudCredentials(clusterName+"-cluster", locationType, locationValue, project.ID); err != nil {
-		return err
-	}
-	if err := applyClusterWorkload(containerYamlPath); err != nil {
+	defer os.RemoveAll(dir)
+
+	kubeconfig := filepath.Join(dir, "config")
+	if err := getGCloudCredentials(kubeconfig, cluster.Name, cluster.locationFlag(), cluster.Location, cluster.ProjectID); err != nil {
 		return err
 	}
-	return nil
+	return f(kubeconfig)
 }
 
-func getGCloudCredentials(clusterName, locationType, locationValue, projectID string) error {
+// getGCloudCredentials gets the credentials of the cluster into the given kubeconfig file.
+func getGCloudCredentials(kubeconfig, clusterName, locationType, locationValue, projectID string) error {
 	cmd := exec.Command("gcloud", "container", "clusters", "get-credentials", clusterName, locationType, locationValue, "--project", projectID)
+	cmd.Env = append(os.Environ(), "KUBECONFIG="+kubeconfig)
 	cmd.Stderr = os.Stderr
 	if err := cmdRun(cmd); err != nil {
 		return fmt.Errorf("failed to get cluster credentials for %q: %v", clusterName, err)
@@ -61,10 +162,17 @@ func getGCloudCredentials(clusterName, locationType, locationValue, projectID st
 	return nil
 }
 
-func applyClusterWorkload(containerYamlPath string) error {
+// kubectlCommand returns a kubectl command with the given args that uses the given kubeconfig file.
+func kubectlCommand(kubeconfig string, args ...string) *exec.Cmd {
+	cmd := exec.Command("kubectl", args...)
+	cmd.Env = append(os.Environ(), "KUBECONFIG="+kubeconfig)
+	return cmd
+}
+
+func applyClusterWorkload(kubeconfig, containerYamlPath string) error {
 	// kubectl declarative object configuration
 	// https://kubernetes.io/docs/concepts/overview/object-management-kubectl/overview/
-	cmd := exec.Command("kubectl", "apply", "-f", containerYamlPath)
+	cmd := kubectlCommand(kubeconfig, "apply", "-f", containerYamlPath)
 	cmd.Stderr = os.Stderr
 	if err := cmdRun(cmd); err != nil {
 		return fmt.Errorf("failed to apply workloads with kubectl: %s", err)
@@ -79,39 +187,245 @@ func InstallClusterWorkload(clusterName string, project *Project, workload inter
 	if err != nil {
 		return fmt.Errorf("failed to marshal workload : %v", err)
 	}
-	log.Printf("Creating workload:\n%v", string(b))
+	w := &GKEWorkload{ClusterName: clusterName}
+	cluster, err := w.clusterRef(project)
+	if err != nil {
+		return err
+	}
+	return withClusterCredentials(cluster, func(kubeconfig string) error {
+		_, err := installClusterWorkloadManifest(kubeconfig, w, cluster, project, b)
+		return err
+	})
+}
+
+// installClusterWorkloadManifest substitutes the workload variables in the given manifest, labels its objects
+// as managed on behalf of the project, applies it to the cluster of the given kubeconfig and waits for it to
+// become ready.
+// The manifest may contain multiple YAML or JSON documents.
+// It returns references to the applied objects.
+func installClusterWorkloadManifest(kubeconfig string, w *GKEWorkload, cluster ClusterRef, project *Project, manifest []byte) ([]objectRef, error) {
+	timeout, err := w.waitTimeout()
+	if err != nil {
+		return nil, err
+	}
+	b := []byte(workloadReplacer(project, cluster).Replace(string(manifest)))
+	b, refs, err := labelManifest(b, managedLabels(project))
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Creating workload in cluster %q:\n%v", cluster.Name, string(b))
+
+	objs, err := rolloutObjects(b)
+	if err != nil {
+		return nil, err
+	}
 
 	tmp, err := ioutil.TempFile("", "")
 	if err != nil {
-		return fmt.Errorf("failed to create temp file: %v", err)
+		return nil, fmt.Errorf("failed to create temp file: %v", err)
 	}
 	defer os.Remove(tmp.Name())
 
 	if _, err := tmp.Write(b); err != nil {
-		return fmt.Errorf("failed to write deployment to file: %v", err)
+		return nil, fmt.Errorf("failed to write deployment to file: %v", err)
 	}
 	if err := tmp.Close(); err != nil {
-		return fmt.Errorf("failed to close temp file: %v", err)
+		return nil, fmt.Errorf("failed to close temp file: %v", err)
 	}
-	return installClusterWorkloadFromFile(clusterName, tmp.Name(), project)
+	if err := applyClusterWorkload(kubeconfig, tmp.Name()); err != nil {
+		return nil, err
+	}
+	if err := waitForRollout(kubeconfig, objs, timeout, w.RollbackOnFailure); err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
+
+// workloadReplacer returns a replacer for the variables that can be used in workload manifests:
+// ${PROJECT_ID}, ${CLUSTER_PROJECT_ID}, ${CLUSTER_NAME}, ${CLUSTER_LOCATION} (the region or zone of the cluster)
+// and ${CLUSTER_REGION}.
+// Other occurrences of "$" are left untouched.
+func workloadReplacer(project *Project, cluster ClusterRef) *strings.Replacer {
+	return strings.NewReplacer(
+		"${PROJECT_ID}", project.ID,
+		"${CLUSTER_PROJECT_ID}", cluster.ProjectID,
+		"${CLUSTER_NAME}", cluster.Name,
+		"${CLUSTER_LOCATION}", cluster.Location,
+		"${CLUSTER_REGION}", cluster.region(),
+	)
+}
+
+// manifest returns the manifest of the workload's resources.
+func (w *GKEWorkload) manifest(project *Project) ([]byte, error) {
+	if w.Path == "" {
+		b, err := json.Marshal(w.Properties)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal workload : %v", err)
+		}
+		return b, nil
+	}
+
+	path := w.Path
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(project.configDir, path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat workload path: %v", err)
+	}
+
+	switch {
+	case !info.IsDir() && isKustomizationFile(path):
+		return kustomizeBuild(filepath.Dir(path))
+	case !info.IsDir():
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read workload file: %v", err)
+		}
+		return b, nil
+	}
+
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workload directory: %v", err)
+	}
+	var names []string
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		if isKustomizationFile(f.Name()) {
+			return kustomizeBuild(path)
+		}
+		switch filepath.Ext(f.Name()) {
+		case ".yaml", ".yml", ".json":
+			names = append(names, f.Name())
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no manifest files found in %q", path)
+	}
+	sort.Strings(names)
+
+	// Join the files as a multi-document YAML stream, JSON documents being valid YAML.
+	var docs [][]byte
+	for _, n := range names {
+		b, err := ioutil.ReadFile(filepath.Join(path, n))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read workload file: %v", err)
+		}
+		docs = append(docs, bytes.TrimSpace(b))
+	}
+	return bytes.Join(docs, []byte("\n---\n")), nil
+}
+
+func isKustomizationFile(path string) bool {
+	base := filepath.Base(path)
+	for _, f := range kustomizationFiles {
+		if base == f {
+			return true
+		}
+	}
+	return false
+}
+
+// kustomizeBuild renders the kustomization in the given directory.
+func kustomizeBuild(dir string) ([]byte, error) {
+	cmd := exec.Command("kubectl", "kustomize", dir)
+	cmd.Stderr = os.Stderr
+	out, err := cmdOutput(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build kustomization %q: %v", dir, err)
+	}
+	return out, nil
 }
 
 // deployGKEWorkloads deploys the GKE resources (e.g., workloads, services) in the project.
+// Workloads of different clusters are deployed concurrently, workloads of the same cluster in order.
 func deployGKEWorkloads(project *Project) error {
 	workloads, err := getGKEWorkloads(project)
 	if err != nil {
 		return err
 	}
 
-	for _, workload := range workloads {
-		err := InstallClusterWorkload(workload.ClusterName, project, workload.Properties)
+	clusterWorkloads := make(map[ClusterRef][]GKEWorkload)
+	var clusters []ClusterRef
+	for _, w := range workloads {
+		cluster, err := w.clusterRef(project)
 		if err != nil {
 			return err
 		}
+		if _, ok := clusterWorkloads[cluster]; !ok {
+			clusters = append(clusters, cluster)
+		}
+		clusterWorkloads[cluster] = append(clusterWorkloads[cluster], w)
+	}
+
+	// Only clusters defined in the project are pruned, including those without workloads.
+	pruneModes := make(map[ClusterRef]string)
+	for _, pair := range project.resourcePairs() {
+		c, ok := pair.parsed.(*GKECluster)
+		if !ok || c.WorkloadPruneMode == "" {
+			continue
+		}
+		cluster := c.ref(project)
+		pruneModes[cluster] = c.WorkloadPruneMode
+		if _, ok := clusterWorkloads[cluster]; !ok {
+			clusterWorkloads[cluster] = nil
+			clusters = append(clusters, cluster)
+		}
+	}
+
+	errs := make([]error, len(clusters))
+	var wg sync.WaitGroup
+	for i, cluster := range clusters {
+		wg.Add(1)
+		go func(i int, cluster ClusterRef) {
+			defer wg.Done()
+			errs[i] = deployClusterWorkloads(cluster, pruneModes[cluster], project, clusterWorkloads[cluster])
+		}(i, cluster)
+	}
+	wg.Wait()
+
+	var msgs []string
+	for i, err := range errs {
+		if err != nil {
+			msgs = append(msgs, fmt.Sprintf("cluster %q: %v", clusters[i].Name, err))
+		}
+	}
+	if len(msgs) > 0 {
+		return fmt.Errorf("failed to deploy workloads:\n%v", strings.Join(msgs, "\n"))
 	}
 	return nil
 }
 
+// deployClusterWorkloads deploys the given workloads to the cluster and, unless pruneMode is empty,
+// prunes objects no longer declared.
+func deployClusterWorkloads(cluster ClusterRef, pruneMode string, project *Project, workloads []GKEWorkload) error {
+	return withClusterCredentials(cluster, func(kubeconfig string) error {
+		var declared []objectRef
+		for _, w := range workloads {
+			b, err := w.manifest(project)
+			if err != nil {
+				return err
+			}
+			refs, err := installClusterWorkloadManifest(kubeconfig, &w, cluster, project, b)
+			if err != nil {
+				return err
+			}
+			declared = append(declared, refs...)
+		}
+
+		if pruneMode == "" {
+			return nil
+		}
+		if err := pruneClusterWorkloads(kubeconfig, cluster, pruneMode, project, declared); err != nil {
+			return fmt.Errorf("failed to prune workloads: %v", err)
+		}
+		return nil
+	})
+}
+
 // getGKEWorkloads returns the list of all GKE resources.
 func getGKEWorkloads(project *Project) ([]GKEWorkload, error) {
 	var workloads []GKEWorkload
@@ -120,7 +434,23 @@ func getGKEWorkloads(project *Project) ([]GKEWorkload, error) {
 		if r.GKEWorkload != nil {
 			var newWorkload GKEWorkload
 			if err := json.Unmarshal(r.GKEWorkload, &newWorkload); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal %q: %v", newWorkload, err)
+				return nil, fmt.Errorf("failed to unmarshal %q: %v", string(r.GKEWorkload), err)
+			}
+			if (newWorkload.Properties == nil) == (newWorkload.Path == "") {
+				return nil, errors.New("exactly one of properties and path must be set for gke_workload")
+			}
+			if (newWorkload.ClusterName == "") == (newWorkload.Cluster == nil) {
+				return nil, errors.New("exactly one of cluster_name and cluster must be set for gke_workload")
+			}
+			if newWorkload.Cluster != nil {
+				if err := newWorkload.Cluster.validate(); err != nil {
+					return nil, err
+				}
+			} else if getClusterByName(project, newWorkload.ClusterName) == nil {
+				return nil, fmt.Errorf("failed to find cluster: %q", newWorkload.ClusterName)
+			}
+			if _, err := newWorkload.waitTimeout(); err != nil {
+				return nil, err
 			}
 			workloads = append(workloads, newWorkload)
 		}
@@ -1,10 +1,17 @@
 package cft
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/ghodss/yaml"
 )
 
 func TestGetGCloudCredentials(t *testing.T) {
@@ -18,7 +25,7 @@ func TestGetGCloudCredentials(t *testing.T) {
 	}
 	wantArgs := [][]string{{
 		"gcloud", "container", "clusters", "get-credentials", clusterName, "--region", region, "--project", projectID}}
-	if err := getGCloudCredentials(clusterName, "--region", region, projectID); err != nil {
+	if err := getGCloudCredentials("kubeconfig", clusterName, "--region", region, projectID); err != nil {
 		t.Fatalf("getGCloudCredentials error: %v", err)
 	}
 	if diff := cmp.Diff(gotArgs, wantArgs); len(diff) != 0 {
@@ -35,7 +42,7 @@ func TestApplyClusterResource(t *testing.T) {
 	}
 	wantArgs := [][]string{{
 		"kubectl", "apply", "-f", containerYamlPath}}
-	if err := applyClusterWorkload(containerYamlPath); err != nil {
+	if err := applyClusterWorkload("kubeconfig", containerYamlPath); err != nil {
 		t.Fatalf("applyClusterWorkload error: %v", err)
 	}
 	if diff := cmp.Diff(gotArgs, wantArgs); len(diff) != 0 {
@@ -46,13 +53,24 @@ func TestApplyClusterResource(t *testing.T) {
 func TestGetGKEWorkload(t *testing.T) {
 	configExtend := &ConfigData{`
 resources:
+- gke_cluster:
+    properties:
+      name: cluster1
+      clusterLocationType: Regional
+      region: us-east1
+      cluster:
+        privateClusterConfig:
+          masterIpv4CidrBlock: 172.16.0.0/28
 - gke_workload:
     cluster_name: cluster1
     properties:
       apiVersion: extensions/v1beta1
       kind: Deployment
 - gke_workload:
-    cluster_name: cluster2
+    cluster:
+      project_id: platform-project
+      location: us-central1-a
+      name: shared
     properties:
       apiVersion: extensions/v1beta1
       kind: Service`,
@@ -65,8 +83,18 @@ resources:
 	if len(workloads) != 2 {
 		t.Fatalf("workload len error: %v", len(workloads))
 	}
-	if workloads[0].ClusterName != "cluster1" || workloads[1].ClusterName != "cluster2" {
-		t.Fatalf("workload context error: %v", workloads)
+	wantRefs := []ClusterRef{
+		{ProjectID: "my-project", Location: "us-east1", Name: "cluster1-cluster"},
+		{ProjectID: "platform-project", Location: "us-central1-a", Name: "shared"},
+	}
+	for i, w := range workloads {
+		got, err := w.clusterRef(project)
+		if err != nil {
+			t.Fatalf("clusterRef: %v", err)
+		}
+		if got != wantRefs[i] {
+			t.Errorf("workload %d cluster = %+v, want %+v", i, got, wantRefs[i])
+		}
 	}
 }
 
@@ -77,7 +105,7 @@ func TestLocationTypeAndValue(t *testing.T) {
 		locationValue string
 	}{
 		{
-			in: GKECluster{GKEClusterProperties{
+			in: GKECluster{GKEClusterProperties: GKEClusterProperties{
 				ResourceName:        "cluster_with_region",
 				ClusterLocationType: "Regional",
 				Region:              "some_region",
@@ -86,7 +114,7 @@ func TestLocationTypeAndValue(t *testing.T) {
 			locationValue: "some_region",
 		},
 		{
-			in: GKECluster{GKEClusterProperties{
+			in: GKECluster{GKEClusterProperties: GKEClusterProperties{
 				ResourceName:        "cluster_with_zone",
 				ClusterLocationType: "Zonal",
 				Zone:                "some_zone",
@@ -120,6 +148,8 @@ resources:
       region: somewhere1
       cluster:
         name: cluster1
+        privateClusterConfig:
+          masterIpv4CidrBlock: 172.16.0.0/28
 - gke_workload:
     cluster_name: cluster1
     properties:
@@ -158,7 +188,7 @@ func TestLocationTypeAndValueError(t *testing.T) {
 		err string
 	}{
 		{
-			in: GKECluster{GKEClusterProperties{
+			in: GKECluster{GKEClusterProperties: GKEClusterProperties{
 				ResourceName:        "cluster_zonal_error",
 				ClusterLocationType: "Zonal",
 				Region:              "some_region",
@@ -167,7 +197,7 @@ func TestLocationTypeAndValueError(t *testing.T) {
 			err: "failed to get cluster's zone: cluster_zonal_error",
 		},
 		{
-			in: GKECluster{GKEClusterProperties{
+			in: GKECluster{GKEClusterProperties: GKEClusterProperties{
 				ResourceName:        "cluster_regional_error",
 				ClusterLocationType: "Regional",
 				Zone:                "some_zone",
@@ -175,7 +205,7 @@ func TestLocationTypeAndValueError(t *testing.T) {
 			err: "failed to get cluster's region: cluster_regional_error",
 		},
 		{
-			in: GKECluster{GKEClusterProperties{
+			in: GKECluster{GKEClusterProperties: GKEClusterProperties{
 				ResourceName:        "cluster_wrong_type",
 				ClusterLocationType: "Location",
 				Region:              "some_region",
@@ -193,59 +223,369 @@ func TestLocationTypeAndValueError(t *testing.T) {
 	}
 }
 
-func TestInstallClusterWorkloadErrors(t *testing.T) {
-	testcases := []struct {
-		in  ConfigData
-		err string
+func TestGKEWorkloadManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"single/deployment.yaml":     "kind: Deployment\n",
+		"multi/b.yaml":               "kind: Service\n",
+		"multi/a.json":               `{"kind": "Deployment"}`,
+		"multi/README.md":            "not a manifest",
+		"overlay/kustomization.yaml": "resources: []\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("os.MkdirAll: %v", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("ioutil.WriteFile: %v", err)
+		}
+	}
+
+	var gotKustomizeArgs [][]string
+	cmdOutput = func(cmd *exec.Cmd) ([]byte, error) {
+		gotKustomizeArgs = append(gotKustomizeArgs, cmd.Args)
+		return []byte("kind: ConfigMap\n"), nil
+	}
+
+	tests := []struct {
+		name              string
+		workload          GKEWorkload
+		want              string
+		wantKustomizeArgs [][]string
 	}{
 		{
-			in: ConfigData{`
+			name:     "inline",
+			workload: GKEWorkload{Properties: map[string]interface{}{"kind": "Deployment"}},
+			want:     `{"kind":"Deployment"}`,
+		},
+		{
+			name:     "file",
+			workload: GKEWorkload{Path: "single/deployment.yaml"},
+			want:     "kind: Deployment\n",
+		},
+		{
+			name:     "directory",
+			workload: GKEWorkload{Path: "multi"},
+			want:     "{\"kind\": \"Deployment\"}\n---\nkind: Service",
+		},
+		{
+			name:              "kustomization_directory",
+			workload:          GKEWorkload{Path: "overlay"},
+			want:              "kind: ConfigMap\n",
+			wantKustomizeArgs: [][]string{{"kubectl", "kustomize", filepath.Join(dir, "overlay")}},
+		},
+		{
+			name:              "kustomization_file",
+			workload:          GKEWorkload{Path: filepath.Join(dir, "overlay/kustomization.yaml")},
+			want:              "kind: ConfigMap\n",
+			wantKustomizeArgs: [][]string{{"kubectl", "kustomize", filepath.Join(dir, "overlay")}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			gotKustomizeArgs = nil
+			project := &Project{ID: "my-project", configDir: dir}
+			got, err := tc.workload.manifest(project)
+			if err != nil {
+				t.Fatalf("manifest: %v", err)
+			}
+			if diff := cmp.Diff(string(got), tc.want); diff != "" {
+				t.Errorf("manifest differs (-got +want):\n%v", diff)
+			}
+			if diff := cmp.Diff(gotKustomizeArgs, tc.wantKustomizeArgs); diff != "" {
+				t.Errorf("kustomize commands differ (-got +want):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestInstallClusterWorkloadVariables(t *testing.T) {
+	configExtend := &ConfigData{`
 resources:
+- gke_cluster:
+    properties:
+      name: cluster1
+      clusterLocationType: Zonal
+      zone: us-east1-b
+      cluster:
+        privateClusterConfig:
+          masterIpv4CidrBlock: 172.16.0.0/28`,
+	}
+	_, project := getTestConfigAndProject(t, configExtend)
+
+	var gotManifest string
+	cmdRun = func(cmd *exec.Cmd) error {
+		if cmd.Args[0] == "kubectl" {
+			b, err := ioutil.ReadFile(cmd.Args[len(cmd.Args)-1])
+			if err != nil {
+				return err
+			}
+			gotManifest = string(b)
+		}
+		return nil
+	}
+
+	manifest := "project: ${PROJECT_ID}\nclusterProject: ${CLUSTER_PROJECT_ID}\ncluster: ${CLUSTER_NAME}\nlocation: ${CLUSTER_LOCATION}\nregion: ${CLUSTER_REGION}\nother: ${OTHER}\n"
+	cluster := getClusterByName(project, "cluster1").ref(project)
+	if _, err := installClusterWorkloadManifest("kubeconfig", &GKEWorkload{ClusterName: "cluster1"}, cluster, project, []byte(manifest)); err != nil {
+		t.Fatalf("installClusterWorkloadManifest: %v", err)
+	}
+	wantManifest := `
+project: my-project
+clusterProject: my-project
+cluster: cluster1-cluster
+location: us-east1-b
+region: us-east1
+other: ${OTHER}
+metadata:
+  labels:
+    app.kubernetes.io/managed-by: data-protect-toolkit
+    data-protect-toolkit/project: my-project`
+
+	var got, want interface{}
+	if err := yaml.Unmarshal([]byte(gotManifest), &got); err != nil {
+		t.Fatalf("yaml.Unmarshal got manifest: %v", err)
+	}
+	if err := yaml.Unmarshal([]byte(wantManifest), &want); err != nil {
+		t.Fatalf("yaml.Unmarshal want manifest: %v", err)
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("applied manifest differs (-got +want):\n%v", diff)
+	}
+}
+
+func TestGetGKEWorkloadErrors(t *testing.T) {
+	const cluster = `
 - gke_cluster:
     properties:
       name: cluster1
       clusterLocationType: Regional
-      region: somewhere1
+      region: us-east1
       cluster:
-        name: cluster1
+        privateClusterConfig:
+          masterIpv4CidrBlock: 172.16.0.0/28`
+
+	testcases := []struct {
+		name string
+		in   string
+	}{
+		{
+			name: "properties_and_path",
+			in: `
+- gke_workload:
+    cluster_name: cluster1
+    path: foo.yaml
+    properties:
+      apiVersion: extensions/v1beta1`,
+		},
+		{
+			name: "invalid_wait_timeout",
+			in: `
+- gke_workload:
+    cluster_name: cluster1
+    wait_timeout: 5
+    properties:
+      apiVersion: extensions/v1beta1`,
+		},
+		{
+			name: "neither_properties_nor_path",
+			in: `
+- gke_workload:
+    cluster_name: cluster1`,
+		},
+		{
+			name: "missing_cluster",
+			in: `
 - gke_workload:
     cluster_name: clusterX
     properties:
       apiVersion: extensions/v1beta1`,
-			},
-			err: "failed to find cluster: \"clusterX\"",
 		},
 		{
-			in: ConfigData{`
+			name: "cluster_name_and_cluster",
+			in: `
+- gke_workload:
+    cluster_name: cluster1
+    cluster:
+      project_id: platform-project
+      location: us-central1
+      name: shared
+    properties:
+      apiVersion: extensions/v1beta1`,
+		},
+		{
+			name: "neither_cluster_name_nor_cluster",
+			in: `
+- gke_workload:
+    properties:
+      apiVersion: extensions/v1beta1`,
+		},
+		{
+			name: "external_cluster_missing_project",
+			in: `
+- gke_workload:
+    cluster:
+      location: us-central1
+      name: shared
+    properties:
+      apiVersion: extensions/v1beta1`,
+		},
+		{
+			name: "external_cluster_invalid_location",
+			in: `
+- gke_workload:
+    cluster:
+      project_id: platform-project
+      location: us
+      name: shared
+    properties:
+      apiVersion: extensions/v1beta1`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, project := getTestConfigAndProject(t, nil)
+			if err := yaml.Unmarshal([]byte("resources:"+cluster+tc.in), project); err != nil {
+				t.Fatalf("yaml unmarshal: %v", err)
+			}
+			if err := project.Init(); err == nil {
+				t.Fatalf("project.Init: got nil error, want non-nil error")
+			}
+		})
+	}
+}
+
+func TestDeployGKEWorkloadsKubeconfig(t *testing.T) {
+	configExtend := &ConfigData{`
 resources:
 - gke_cluster:
     properties:
       name: cluster1
-      clusterLocationType: Location
-      region: somewhere1
+      clusterLocationType: Regional
+      region: us-east1
       cluster:
-        name: cluster1
+        privateClusterConfig:
+          masterIpv4CidrBlock: 172.16.0.0/28
+- gke_cluster:
+    properties:
+      name: cluster2
+      clusterLocationType: Zonal
+      zone: us-central1-a
+      cluster:
+        privateClusterConfig:
+          masterIpv4CidrBlock: 172.16.0.16/28
 - gke_workload:
     cluster_name: cluster1
     properties:
-      apiVersion: extensions/v1beta1`,
-			},
-			err: "failed to get cluster's location: cluster1",
-		},
+      kind: ConfigMap
+- gke_workload:
+    cluster_name: cluster2
+    properties:
+      kind: ConfigMap
+- gke_workload:
+    cluster_name: cluster1
+    properties:
+      kind: Secret`,
+	}
+	_, project := getTestConfigAndProject(t, configExtend)
+
+	kubeconfigEnv := func(cmd *exec.Cmd) string {
+		for _, e := range cmd.Env {
+			if strings.HasPrefix(e, "KUBECONFIG=") {
+				return strings.TrimPrefix(e, "KUBECONFIG=")
+			}
+		}
+		return ""
 	}
 
-	for _, tc := range testcases {
-		_, project := getTestConfigAndProject(t, &tc.in)
-		var gotArgs [][]string
-		cmdRun = func(cmd *exec.Cmd) error {
-			gotArgs = append(gotArgs, cmd.Args)
-			return nil
+	var mu sync.Mutex
+	clusterKubeconfigs := make(map[string]string)
+	applied := make(map[string][]string)
+	cmdRun = func(cmd *exec.Cmd) error {
+		mu.Lock()
+		defer mu.Unlock()
+		kubeconfig := kubeconfigEnv(cmd)
+		if kubeconfig == "" {
+			return fmt.Errorf("KUBECONFIG not set for %v", cmd.Args)
 		}
-		err := deployGKEWorkloads(project)
-		if err == nil {
-			t.Errorf("TestInstallClusterWorkloadErrors should have error %v", tc.err)
-		} else if err.Error() != tc.err {
-			t.Errorf("TestInstallClusterWorkloadErrors wrong error %v; expect: %v", err, tc.err)
+		switch cmd.Args[0] {
+		case "gcloud":
+			clusterKubeconfigs[kubeconfig] = cmd.Args[4]
+		case "kubectl":
+			b, err := ioutil.ReadFile(cmd.Args[len(cmd.Args)-1])
+			if err != nil {
+				return err
+			}
+			var obj struct {
+				Kind string `json:"kind"`
+			}
+			if err := yaml.Unmarshal(b, &obj); err != nil {
+				return err
+			}
+			cluster, ok := clusterKubeconfigs[kubeconfig]
+			if !ok {
+				return fmt.Errorf("kubectl run with kubeconfig %q without credentials", kubeconfig)
+			}
+			applied[cluster] = append(applied[cluster], obj.Kind)
 		}
+		return nil
+	}
+
+	if err := deployGKEWorkloads(project); err != nil {
+		t.Fatalf("deployGKEWorkloads: %v", err)
+	}
+
+	if len(clusterKubeconfigs) != 2 {
+		t.Errorf("got %d kubeconfigs, want one per cluster: %v", len(clusterKubeconfigs), clusterKubeconfigs)
+	}
+	for kubeconfig := range clusterKubeconfigs {
+		if _, err := os.Stat(filepath.Dir(kubeconfig)); !os.IsNotExist(err) {
+			t.Errorf("kubeconfig dir of %q was not removed: %v", kubeconfig, err)
+		}
+	}
+	wantApplied := map[string][]string{
+		"cluster1-cluster": {"ConfigMap", "Secret"},
+		"cluster2-cluster": {"ConfigMap"},
+	}
+	if diff := cmp.Diff(applied, wantApplied); diff != "" {
+		t.Errorf("applied workloads differ (-got +want):\n%v", diff)
+	}
+}
+
+func TestDeployGKEWorkloadsExternalCluster(t *testing.T) {
+	configExtend := &ConfigData{`
+resources:
+- gke_workload:
+    cluster:
+      project_id: platform-project
+      location: us-central1-a
+      name: shared
+    properties:
+      kind: ConfigMap`,
+	}
+	_, project := getTestConfigAndProject(t, configExtend)
+
+	var gotArgs [][]string
+	cmdRun = func(cmd *exec.Cmd) error {
+		gotArgs = append(gotArgs, cmd.Args)
+		return nil
+	}
+	if err := deployGKEWorkloads(project); err != nil {
+		t.Fatalf("deployGKEWorkloads: %v", err)
+	}
+	if len(gotArgs) != 2 {
+		t.Fatalf("deployGKEWorkloads ran %d commands, want 2: %v", len(gotArgs), gotArgs)
+	}
+	wantArgs := []string{"gcloud", "container", "clusters", "get-credentials", "shared", "--zone", "us-central1-a", "--project", "platform-project"}
+	if diff := cmp.Diff(gotArgs[0], wantArgs); diff != "" {
+		t.Errorf("get-credentials command differs (-got +want):\n%v", diff)
 	}
 }
@@ -0,0 +1,712 @@
+package cft
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Asset is a Cloud Asset Inventory asset with its resource data and IAM policy.
+type Asset struct {
+	Name      string
+	AssetType string
+	Data      map[string]interface{}
+	Bindings  map[string][]string
+
+	// Ancestors are the resource names of the asset's ancestors, starting with its project or itself,
+	// e.g. projects/1111, folders/2222, organizations/3333.
+	Ancestors []string
+}
+
+// UnmarshalJSON unmarshals an asset from a Cloud Asset Inventory export, which uses snake case field names,
+// or from the output of gcloud, which uses camel case field names.
+func (a *Asset) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Name           string `json:"name"`
+		AssetType      string `json:"asset_type"`
+		AssetTypeCamel string `json:"assetType"`
+		Resource       *struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"resource"`
+		IAMPolicy      *iamPolicy `json:"iam_policy"`
+		IAMPolicyCamel *iamPolicy `json:"iamPolicy"`
+		Ancestors      []string   `json:"ancestors"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	a.Name = raw.Name
+	a.Ancestors = raw.Ancestors
+	a.AssetType = raw.AssetType
+	if a.AssetType == "" {
+		a.AssetType = raw.AssetTypeCamel
+	}
+	if raw.Resource != nil {
+		a.Data = raw.Resource.Data
+	}
+	policy := raw.IAMPolicy
+	if policy == nil {
+		policy = raw.IAMPolicyCamel
+	}
+	if policy != nil {
+		a.Bindings = policy.bindings()
+	}
+	return nil
+}
+
+// ReadAssets reads assets from newline delimited JSON, as written by Cloud Asset Inventory exports,
+// or from JSON lists of assets. Resource and IAM policy assets with the same name are merged.
+func ReadAssets(r io.Reader) ([]*Asset, error) {
+	var assets []*Asset
+	dec := json.NewDecoder(r)
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to decode assets: %v", err)
+		}
+		if strings.HasPrefix(strings.TrimSpace(string(raw)), "[") {
+			var list []*Asset
+			if err := json.Unmarshal(raw, &list); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal asset list: %v", err)
+			}
+			assets = append(assets, list...)
+			continue
+		}
+		a := new(Asset)
+		if err := json.Unmarshal(raw, a); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal asset: %v", err)
+		}
+		assets = append(assets, a)
+	}
+	return mergeAssets(assets), nil
+}
+
+func mergeAssets(assets []*Asset) []*Asset {
+	byName := make(map[string]*Asset)
+	var merged []*Asset
+	for _, a := range assets {
+		m, ok := byName[a.Name]
+		if !ok {
+			byName[a.Name] = a
+			merged = append(merged, a)
+			continue
+		}
+		if a.Data != nil {
+			m.Data = a.Data
+		}
+		if a.Bindings != nil {
+			m.Bindings = a.Bindings
+		}
+		if a.Ancestors != nil {
+			m.Ancestors = a.Ancestors
+		}
+	}
+	return merged
+}
+
+// AssetClient lists the assets of a project.
+type AssetClient interface {
+	Assets(projectID string) ([]*Asset, error)
+}
+
+// GCloudAssetClient lists the assets of a project with gcloud.
+type GCloudAssetClient struct{}
+
+// Assets lists the resources and IAM policies of the project.
+func (GCloudAssetClient) Assets(projectID string) ([]*Asset, error) {
+	var assets []*Asset
+	for _, contentType := range []string{"resource", "iam-policy"} {
+		cmd := exec.Command("gcloud", "asset", "list", "--content-type", contentType, "--format", "json", "--project", projectID)
+		out, err := cmdCombinedOutput(cmd)
+		if err != nil {
+			return nil, fmt.Errorf("failed to run command: %v\n%v", err, string(out))
+		}
+		var list []*Asset
+		if err := json.Unmarshal(out, &list); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal asset list call: %v", err)
+		}
+		assets = append(assets, list...)
+	}
+	return mergeAssets(assets), nil
+}
+
+// ImportedProject is the config of a project imported from its assets, in the format of the projects YAML file.
+type ImportedProject struct {
+	ID                  string                   `json:"project_id"`
+	OwnersGroup         string                   `json:"owners_group,omitempty"`
+	AuditorsGroup       string                   `json:"auditors_group,omitempty"`
+	DataReadWriteGroups []string                 `json:"data_readwrite_groups,omitempty"`
+	DataReadOnlyGroups  []string                 `json:"data_readonly_groups,omitempty"`
+	AuditLogs           importedAuditLogs        `json:"audit_logs"`
+	Resources           []map[string]interface{} `json:"resources,omitempty"`
+	GeneratedFields     *struct {
+		ProjectNumber string `json:"project_number"`
+	} `json:"generated_fields,omitempty"`
+}
+
+type importedAuditLogs struct {
+	LogsGCSBucket       *importedLogsResource `json:"logs_gcs_bucket,omitempty"`
+	LogsBigqueryDataset *importedLogsResource `json:"logs_bigquery_dataset,omitempty"`
+}
+
+type importedLogsResource struct {
+	Name     string `json:"name"`
+	Location string `json:"location,omitempty"`
+}
+
+// ImportResult is the result of importing a project.
+type ImportResult struct {
+	Project *ImportedProject
+
+	// Warnings flag anything that must be reviewed before deploying the project,
+	// such as resources Init would reject, settings deploying would change and assets that were not imported.
+	Warnings []string
+}
+
+// Asset types of the imported assets.
+const (
+	projectAssetType      = "cloudresourcemanager.googleapis.com/Project"
+	bucketAssetType       = "storage.googleapis.com/Bucket"
+	datasetAssetType      = "bigquery.googleapis.com/Dataset"
+	topicAssetType        = "pubsub.googleapis.com/Topic"
+	subscriptionAssetType = "pubsub.googleapis.com/Subscription"
+	instanceAssetType     = "compute.googleapis.com/Instance"
+	diskAssetType         = "compute.googleapis.com/Disk"
+	clusterAssetType      = "container.googleapis.com/Cluster"
+	logSinkAssetType      = "logging.googleapis.com/LogSink"
+)
+
+// importer holds the state of importing a project.
+type importer struct {
+	projectID string
+	assets    map[string][]*Asset
+	result    *ImportResult
+}
+
+// ImportProject imports the project with the given ID from its assets.
+// Groups are inferred from the IAM policies of the project and its data resources,
+// and the audit logs from the log bucket of buckets and the destinations of audit log sinks.
+func ImportProject(projectID string, assets []*Asset) (*ImportResult, error) {
+	im := &importer{
+		projectID: projectID,
+		assets:    make(map[string][]*Asset),
+		result:    &ImportResult{Project: &ImportedProject{ID: projectID}},
+	}
+	for _, a := range assets {
+		im.assets[a.AssetType] = append(im.assets[a.AssetType], a)
+	}
+	p := im.result.Project
+
+	projects := im.assets[projectAssetType]
+	if len(projects) != 1 {
+		return nil, fmt.Errorf("got %d project assets, want 1", len(projects))
+	}
+	if number := stringValue(projects[0].Data, "projectNumber"); number != "" {
+		p.GeneratedFields = &struct {
+			ProjectNumber string `json:"project_number"`
+		}{number}
+	}
+	projectBindings := projects[0].Bindings
+	p.OwnersGroup = im.singleGroup("owners_group", projectBindings["roles/owner"])
+	p.AuditorsGroup = im.singleGroup("auditors_group", projectBindings["roles/iam.securityReviewer"])
+	im.inferAuditLogs()
+	im.inferDataGroups()
+
+	im.importBuckets()
+	im.importDatasets()
+	im.importTopics()
+	im.importInstances()
+	im.importClusters()
+
+	var skipped []string
+	for typ := range im.assets {
+		switch typ {
+		case projectAssetType, bucketAssetType, datasetAssetType, topicAssetType, subscriptionAssetType,
+			instanceAssetType, diskAssetType, clusterAssetType, logSinkAssetType:
+		default:
+			skipped = append(skipped, fmt.Sprintf("%s (%d)", typ, len(im.assets[typ])))
+		}
+	}
+	if len(skipped) > 0 {
+		sort.Strings(skipped)
+		im.warnf("assets of the following types were not imported: %s", strings.Join(skipped, ", "))
+	}
+
+	if err := im.checkInit(); err != nil {
+		return nil, err
+	}
+	return im.result, nil
+}
+
+func (im *importer) warnf(format string, args ...interface{}) {
+	im.result.Warnings = append(im.result.Warnings, fmt.Sprintf(format, args...))
+}
+
+// singleGroup returns the only group among the members, warning if there is none or more than one.
+func (im *importer) singleGroup(field string, members []string) string {
+	groups := groupsOf(members)
+	switch len(groups) {
+	case 0:
+		im.warnf("%s: no group found in the project IAM policy", field)
+		return ""
+	case 1:
+		return groups[0]
+	default:
+		im.warnf("%s: found groups %v, using %q", field, groups, groups[0])
+		return groups[0]
+	}
+}
+
+// groupsOf returns the sorted groups among the members, without the group: prefix.
+func groupsOf(members []string) []string {
+	var groups []string
+	for _, m := range members {
+		if strings.HasPrefix(m, "group:") {
+			groups = append(groups, strings.TrimPrefix(m, "group:"))
+		}
+	}
+	sort.Strings(groups)
+	return dedupe(groups)
+}
+
+func dedupe(sorted []string) []string {
+	var out []string
+	for i, s := range sorted {
+		if i == 0 || s != sorted[i-1] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// inferDataGroups infers the data read-write and read-only groups from the roles granted by
+// the default bindings of data resources other than the audit logs.
+func (im *importer) inferDataGroups() {
+	var readWrite, readOnly []string
+	for _, a := range im.assets[bucketAssetType] {
+		if im.isAuditLogsResource(bucketAssetType, stringValue(a.Data, "name")) {
+			continue
+		}
+		readWrite = append(readWrite, a.Bindings["roles/storage.objectAdmin"]...)
+		readOnly = append(readOnly, a.Bindings["roles/storage.objectViewer"]...)
+	}
+	for _, a := range im.assets[datasetAssetType] {
+		if im.isAuditLogsResource(datasetAssetType, datasetID(a)) {
+			continue
+		}
+		access := datasetAccessBindings(a)
+		readWrite = append(readWrite, access["WRITER"]...)
+		readOnly = append(readOnly, access["READER"]...)
+	}
+	for _, a := range im.assets[topicAssetType] {
+		readWrite = append(readWrite, a.Bindings["roles/pubsub.editor"]...)
+		readOnly = append(readOnly, a.Bindings["roles/pubsub.viewer"]...)
+	}
+
+	p := im.result.Project
+	p.DataReadWriteGroups = groupsOf(readWrite)
+	rw := make(map[string]bool)
+	for _, g := range p.DataReadWriteGroups {
+		rw[g] = true
+	}
+	for _, g := range groupsOf(readOnly) {
+		if !rw[g] {
+			p.DataReadOnlyGroups = append(p.DataReadOnlyGroups, g)
+		}
+	}
+}
+
+// inferAuditLogs infers the logs bucket from the log bucket of the project's buckets and the logs dataset
+// from the destination of the audit logs sink.
+func (im *importer) inferAuditLogs() {
+	logs := &im.result.Project.AuditLogs
+
+	counts := make(map[string]int)
+	for _, a := range im.assets[bucketAssetType] {
+		if lb := stringValue(mapValue(a.Data, "logging"), "logBucket"); lb != "" {
+			counts[lb]++
+		}
+	}
+	var logBucket string
+	for lb, n := range counts {
+		if logBucket == "" || n > counts[logBucket] || (n == counts[logBucket] && lb < logBucket) {
+			logBucket = lb
+		}
+	}
+	if len(counts) > 1 {
+		im.warnf("audit_logs: buckets log to different buckets, using %q", logBucket)
+	}
+	if logBucket != "" {
+		logs.LogsGCSBucket = &importedLogsResource{Name: logBucket}
+		if b := im.findAsset(bucketAssetType, func(a *Asset) bool { return stringValue(a.Data, "name") == logBucket }); b != nil {
+			logs.LogsGCSBucket.Location = strings.ToLower(stringValue(b.Data, "location"))
+		} else {
+			im.warnf("audit_logs: logs bucket %q is not in project %q", logBucket, im.projectID)
+		}
+	}
+
+	datasetRE := regexp.MustCompile(`^bigquery\.googleapis\.com/projects/([^/]+)/datasets/([^/]+)$`)
+	for _, a := range im.assets[logSinkAssetType] {
+		if !strings.Contains(stringValue(a.Data, "filter"), "cloudaudit.googleapis.com") {
+			continue
+		}
+		m := datasetRE.FindStringSubmatch(stringValue(a.Data, "destination"))
+		if m == nil {
+			continue
+		}
+		if m[1] != im.projectID {
+			im.warnf("audit_logs: audit logs are exported to project %q, set it as the audit_logs_project of the config", m[1])
+			continue
+		}
+		if filter := stringValue(a.Data, "filter"); filter != AuditLogsSinkFilter {
+			im.warnf("audit_logs: sink %q has filter %q, generated rules expect %q", stringValue(a.Data, "name"), filter, AuditLogsSinkFilter)
+		}
+		logs.LogsBigqueryDataset = &importedLogsResource{Name: m[2]}
+		if d := im.findAsset(datasetAssetType, func(a *Asset) bool { return datasetID(a) == m[2] }); d != nil {
+			logs.LogsBigqueryDataset.Location = stringValue(d.Data, "location")
+		}
+		break
+	}
+	if logs.LogsBigqueryDataset == nil {
+		im.warnf("audit_logs: no audit logs sink to a BigQuery dataset found, deploying creates one")
+	}
+}
+
+func (im *importer) findAsset(typ string, match func(*Asset) bool) *Asset {
+	for _, a := range im.assets[typ] {
+		if match(a) {
+			return a
+		}
+	}
+	return nil
+}
+
+func (im *importer) isAuditLogsResource(typ, name string) bool {
+	logs := im.result.Project.AuditLogs
+	switch typ {
+	case bucketAssetType:
+		return logs.LogsGCSBucket != nil && logs.LogsGCSBucket.Name == name
+	case datasetAssetType:
+		return logs.LogsBigqueryDataset != nil && logs.LogsBigqueryDataset.Name == name
+	}
+	return false
+}
+
+func (im *importer) addResource(kind string, res map[string]interface{}) {
+	im.result.Project.Resources = append(im.result.Project.Resources, map[string]interface{}{kind: res})
+}
+
+// extraBindings returns the bindings of the live policy that are not granted by the default bindings as a list
+// of bindings, or nil if there are none.
+func extraBindings(defaults, live map[string][]string) []interface{} {
+	_, extra := diffBindings(defaults, live)
+	var bindings []interface{}
+	var role string
+	var members []interface{}
+	flush := func() {
+		if len(members) > 0 {
+			bindings = append(bindings, map[string]interface{}{"role": role, "members": members})
+		}
+	}
+	for _, m := range extra {
+		if m.Role != role {
+			flush()
+			role, members = m.Role, nil
+		}
+		members = append(members, m.Member)
+	}
+	flush()
+	return bindings
+}
+
+func groupMembers(groups ...string) []string {
+	var members []string
+	for _, g := range groups {
+		if g != "" {
+			members = append(members, "group:"+g)
+		}
+	}
+	return members
+}
+
+func (im *importer) importBuckets() {
+	p := im.result.Project
+	defaults := map[string][]string{
+		"roles/storage.admin":        groupMembers(p.OwnersGroup),
+		"roles/storage.objectAdmin":  groupMembers(p.DataReadWriteGroups...),
+		"roles/storage.objectViewer": groupMembers(p.DataReadOnlyGroups...),
+	}
+	for _, a := range im.assets[bucketAssetType] {
+		name := stringValue(a.Data, "name")
+		if im.isAuditLogsResource(bucketAssetType, name) {
+			continue
+		}
+		props := map[string]interface{}{
+			"name":     name,
+			"location": strings.ToLower(stringValue(a.Data, "location")),
+		}
+		if lifecycle, ok := a.Data["lifecycle"]; ok {
+			props["lifecycle"] = lifecycle
+		}
+		if bs := extraBindings(defaults, a.Bindings); bs != nil {
+			props["bindings"] = bs
+		}
+		if enabled, _ := mapValue(a.Data, "versioning")["enabled"].(bool); !enabled {
+			im.warnf("bucket %q: versioning is disabled, deploying enables it", name)
+		}
+		im.addResource("gcs_bucket", map[string]interface{}{"properties": props})
+	}
+}
+
+// datasetAccessBindings returns the access list of the dataset as bindings.
+func datasetAccessBindings(a *Asset) map[string][]string {
+	access, _ := a.Data["access"].([]interface{})
+	return accessBindings(access)
+}
+
+func datasetID(a *Asset) string {
+	return stringValue(mapValue(a.Data, "datasetReference"), "datasetId")
+}
+
+func (im *importer) importDatasets() {
+	p := im.result.Project
+	defaults := map[string][]string{
+		"OWNER":  groupMembers(p.OwnersGroup),
+		"WRITER": groupMembers(p.DataReadWriteGroups...),
+		"READER": groupMembers(p.DataReadOnlyGroups...),
+	}
+	memberKeys := []struct{ prefix, key string }{
+		{"group:", "groupByEmail"},
+		{"user:", "userByEmail"},
+		{"domain:", "domain"},
+		{"specialGroup:", "specialGroup"},
+	}
+	for _, a := range im.assets[datasetAssetType] {
+		name := datasetID(a)
+		if im.isAuditLogsResource(datasetAssetType, name) {
+			continue
+		}
+		props := map[string]interface{}{
+			"name":     name,
+			"location": stringValue(a.Data, "location"),
+		}
+		var access []interface{}
+		_, extra := diffBindings(defaults, datasetAccessBindings(a))
+		for _, m := range extra {
+			entry := map[string]interface{}{"role": m.Role}
+			for _, mk := range memberKeys {
+				if strings.HasPrefix(m.Member, mk.prefix) {
+					entry[mk.key] = strings.TrimPrefix(m.Member, mk.prefix)
+					break
+				}
+			}
+			if len(entry) == 1 {
+				if strings.HasPrefix(m.Member, "view:") {
+					im.warnf("dataset %q: authorized view %q was not imported", name, strings.TrimPrefix(m.Member, "view:"))
+					continue
+				}
+				entry["iamMember"] = m.Member
+			}
+			access = append(access, entry)
+		}
+		if len(access) > 0 {
+			props["access"] = access
+		}
+		im.addResource("bigquery_dataset", map[string]interface{}{"properties": props})
+	}
+}
+
+func (im *importer) importTopics() {
+	p := im.result.Project
+	defaults := map[string][]string{
+		"roles/pubsub.editor": groupMembers(p.DataReadWriteGroups...),
+		"roles/pubsub.viewer": groupMembers(p.DataReadOnlyGroups...),
+	}
+	subscriptions := make(map[string][]interface{})
+	for _, a := range im.assets[subscriptionAssetType] {
+		sub := map[string]interface{}{"name": path.Base(stringValue(a.Data, "name"))}
+		if v, ok := a.Data["ackDeadlineSeconds"]; ok {
+			sub["ackDeadlineSeconds"] = v
+		}
+		if endpoint := stringValue(mapValue(a.Data, "pushConfig"), "pushEndpoint"); endpoint != "" {
+			sub["pushConfig"] = map[string]interface{}{"pushEndpoint": endpoint}
+		}
+		if bs := extraBindings(defaults, a.Bindings); bs != nil {
+			sub["accessControl"] = bs
+		}
+		topic := stringValue(a.Data, "topic")
+		subscriptions[topic] = append(subscriptions[topic], sub)
+	}
+
+	for _, a := range im.assets[topicAssetType] {
+		fullName := stringValue(a.Data, "name")
+		props := map[string]interface{}{"topic": path.Base(fullName)}
+		if policy, ok := a.Data["messageStoragePolicy"]; ok {
+			props["messageStoragePolicy"] = policy
+		}
+		if subs := subscriptions[fullName]; len(subs) > 0 {
+			props["subscriptions"] = subs
+		}
+		if bs := extraBindings(defaults, a.Bindings); bs != nil {
+			props["accessControl"] = bs
+		}
+		im.addResource("pubsub", map[string]interface{}{"properties": props})
+	}
+}
+
+// computeURLPrefix is trimmed from the URLs of compute resources to get their partial URLs.
+const computeURLPrefix = "https://www.googleapis.com/compute/v1/"
+
+func (im *importer) importInstances() {
+	p := im.result.Project
+	defaults := map[string][]string{
+		"roles/compute.osAdminLogin": groupMembers(p.OwnersGroup),
+		"roles/compute.osLogin":      groupMembers(p.DataReadWriteGroups...),
+	}
+	for _, a := range im.assets[instanceAssetType] {
+		name := stringValue(a.Data, "name")
+		props := map[string]interface{}{
+			"name":        name,
+			"zone":        path.Base(stringValue(a.Data, "zone")),
+			"machineType": path.Base(stringValue(a.Data, "machineType")),
+		}
+
+		disks, _ := a.Data["disks"].([]interface{})
+		for _, d := range disks {
+			disk, _ := d.(map[string]interface{})
+			if boot, _ := disk["boot"].(bool); !boot {
+				continue
+			}
+			source := stringValue(disk, "source")
+			if bd := im.findAsset(diskAssetType, func(a *Asset) bool { return stringValue(a.Data, "selfLink") == source }); bd != nil {
+				if image := stringValue(bd.Data, "sourceImage"); image != "" {
+					props["diskImage"] = strings.TrimPrefix(image, computeURLPrefix)
+				}
+			}
+		}
+		if _, ok := props["diskImage"]; !ok {
+			im.warnf("instance %q: the image of the boot disk could not be inferred, set diskImage", name)
+		}
+
+		nics, _ := a.Data["networkInterfaces"].([]interface{})
+		if len(nics) > 1 {
+			im.warnf("instance %q: only the first of %d network interfaces was imported", name, len(nics))
+		}
+		if len(nics) > 0 {
+			nic, _ := nics[0].(map[string]interface{})
+			if network := stringValue(nic, "network"); network != "" {
+				props["network"] = strings.TrimPrefix(network, computeURLPrefix)
+			}
+			if subnetwork := stringValue(nic, "subnetwork"); subnetwork != "" {
+				props["subnetwork"] = strings.TrimPrefix(subnetwork, computeURLPrefix)
+			}
+			if configs, _ := nic["accessConfigs"].([]interface{}); len(configs) > 0 {
+				props["hasExternalIp"] = true
+			}
+		}
+
+		var items []interface{}
+		metadataItems, _ := mapValue(a.Data, "metadata")["items"].([]interface{})
+		for _, i := range metadataItems {
+			if item, _ := i.(map[string]interface{}); stringValue(item, "key") != "enable-oslogin" {
+				items = append(items, i)
+			}
+		}
+		if len(items) > 0 {
+			props["metadata"] = map[string]interface{}{"items": items}
+		}
+
+		if bs := extraBindings(defaults, a.Bindings); bs != nil {
+			props["accessControl"] = bs
+		}
+		im.addResource("gce_instance", map[string]interface{}{"properties": props})
+	}
+}
+
+func (im *importer) importClusters() {
+	for _, a := range im.assets[clusterAssetType] {
+		name := stringValue(a.Data, "name")
+		props := map[string]interface{}{"name": strings.TrimSuffix(name, defaultClusterNameSuffix)}
+		if !strings.HasSuffix(name, defaultClusterNameSuffix) {
+			props["clusterNameSuffix"] = ""
+		}
+		if location := stringValue(a.Data, "location"); zoneRE.MatchString(location) {
+			props["clusterLocationType"] = "Zonal"
+			props["zone"] = location
+		} else {
+			props["clusterLocationType"] = "Regional"
+			props["region"] = location
+		}
+
+		cluster := pickProperties(a.Data, "privateClusterConfig", "ipAllocationPolicy", "workloadIdentityConfig",
+			"networkPolicy", "addonsConfig", "shieldedNodes", "legacyAbac", "masterAuthorizedNetworksConfig")
+		if network := stringValue(a.Data, "network"); network != "" {
+			cluster["network"] = "global/networks/" + network
+		}
+		if subnetwork := stringValue(a.Data, "subnetwork"); subnetwork != "" {
+			cluster["subnetwork"] = fmt.Sprintf("regions/%s/subnetworks/%s", ClusterRef{Location: stringValue(a.Data, "location")}.region(), subnetwork)
+		}
+		if private := mapValue(a.Data, "privateClusterConfig"); private != nil {
+			cluster["privateClusterConfig"] = pickProperties(private, "enablePrivateNodes", "masterIpv4CidrBlock")
+		}
+		if policy := mapValue(a.Data, "ipAllocationPolicy"); policy != nil {
+			cluster["ipAllocationPolicy"] = pickProperties(policy, "useIpAliases", "clusterSecondaryRangeName", "servicesSecondaryRangeName")
+		}
+		if addons := mapValue(a.Data, "addonsConfig"); addons != nil {
+			cluster["addonsConfig"] = pickProperties(addons, "networkPolicyConfig")
+		}
+		props["cluster"] = cluster
+
+		if enabled, _ := mapValue(a.Data, "legacyAbac")["enabled"].(bool); enabled {
+			im.warnf("cluster %q: legacy ABAC is enabled", name)
+		}
+		if enabled, _ := mapValue(a.Data, "privateClusterConfig")["enablePrivateNodes"].(bool); !enabled {
+			im.warnf("cluster %q: private nodes are disabled", name)
+		}
+		if pools, _ := a.Data["nodePools"].([]interface{}); len(pools) > 0 {
+			im.warnf("cluster %q: %d node pools were not imported", name, len(pools))
+		}
+		im.addResource("gke_cluster", map[string]interface{}{"properties": props})
+	}
+}
+
+// checkInit initializes the imported resources and flags those Init would reject.
+func (im *importer) checkInit() error {
+	b, err := json.Marshal(im.result.Project)
+	if err != nil {
+		return fmt.Errorf("failed to marshal imported project: %v", err)
+	}
+	project := new(Project)
+	if err := json.Unmarshal(b, project); err != nil {
+		return fmt.Errorf("failed to unmarshal imported project: %v", err)
+	}
+	for _, res := range project.Resources {
+		if res.Parsed == nil {
+			continue
+		}
+		if err := json.Unmarshal(res.Raw, res.Parsed); err != nil {
+			return fmt.Errorf("failed to unmarshal imported resource: %v", err)
+		}
+		if err := res.Parsed.Init(project); err != nil {
+			im.warnf("%s %q: Init rejects the resource: %v", res.Kind.Key, res.Parsed.Name(), err)
+		}
+	}
+	return nil
+}
+
+func mapValue(m map[string]interface{}, key string) map[string]interface{} {
+	v, _ := m[key].(map[string]interface{})
+	return v
+}
+
+func stringValue(m map[string]interface{}, key string) string {
+	v, _ := m[key].(string)
+	return v
+}
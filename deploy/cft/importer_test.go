@@ -0,0 +1,173 @@
+package cft
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ghodss/yaml"
+)
+
+// assetExport is a Cloud Asset Inventory export of a project with resource and IAM policy assets on separate lines.
+const assetExport = `
+{"name":"//cloudresourcemanager.googleapis.com/projects/1111","asset_type":"cloudresourcemanager.googleapis.com/Project","resource":{"data":{"projectId":"my-project","projectNumber":"1111"}}}
+{"name":"//cloudresourcemanager.googleapis.com/projects/1111","asset_type":"cloudresourcemanager.googleapis.com/Project","iam_policy":{"bindings":[{"role":"roles/owner","members":["group:my-project-owners@my-domain.com"]},{"role":"roles/iam.securityReviewer","members":["group:my-project-auditors@my-domain.com"]}]}}
+{"name":"//storage.googleapis.com/my-project-logs","asset_type":"storage.googleapis.com/Bucket","resource":{"data":{"name":"my-project-logs","location":"US-EAST1","versioning":{"enabled":true}}},"iam_policy":{"bindings":[{"role":"roles/storage.objectViewer","members":["group:my-project-auditors@my-domain.com"]}]}}
+{"name":"//storage.googleapis.com/foo-bucket","asset_type":"storage.googleapis.com/Bucket","resource":{"data":{"name":"foo-bucket","location":"US-EAST1","logging":{"logBucket":"my-project-logs"}}},"iam_policy":{"bindings":[{"role":"roles/storage.admin","members":["group:my-project-owners@my-domain.com"]},{"role":"roles/storage.objectAdmin","members":["group:some-readwrite-group@my-domain.com"]},{"role":"roles/storage.objectViewer","members":["group:some-readonly-group@my-domain.com","user:someone@my-domain.com"]},{"role":"roles/storage.legacyBucketOwner","members":["projectOwner:my-project","projectEditor:my-project"]},{"role":"roles/storage.legacyBucketReader","members":["projectViewer:my-project"]}]}}
+{"name":"//bigquery.googleapis.com/projects/my-project/datasets/audit_logs","asset_type":"bigquery.googleapis.com/Dataset","resource":{"data":{"datasetReference":{"datasetId":"audit_logs"},"location":"US","access":[{"role":"READER","groupByEmail":"my-project-auditors@my-domain.com"}]}}}
+{"name":"//bigquery.googleapis.com/projects/my-project/datasets/foo_dataset","asset_type":"bigquery.googleapis.com/Dataset","resource":{"data":{"datasetReference":{"datasetId":"foo_dataset"},"location":"US","access":[{"role":"OWNER","groupByEmail":"my-project-owners@my-domain.com"},{"role":"WRITER","groupByEmail":"some-readwrite-group@my-domain.com"},{"role":"READER","groupByEmail":"some-readonly-group@my-domain.com"},{"role":"READER","specialGroup":"projectReaders"},{"role":"READER","userByEmail":"someone@my-domain.com"}]}}}
+{"name":"//logging.googleapis.com/projects/my-project/sinks/audit-logs-to-bigquery","asset_type":"logging.googleapis.com/LogSink","resource":{"data":{"name":"audit-logs-to-bigquery","destination":"bigquery.googleapis.com/projects/my-project/datasets/audit_logs","filter":"logName:\"logs/cloudaudit.googleapis.com\""}}}
+{"name":"//pubsub.googleapis.com/projects/my-project/topics/foo-topic","asset_type":"pubsub.googleapis.com/Topic","resource":{"data":{"name":"projects/my-project/topics/foo-topic"}},"iam_policy":{"bindings":[{"role":"roles/pubsub.editor","members":["group:some-readwrite-group@my-domain.com"]}]}}
+{"name":"//pubsub.googleapis.com/projects/my-project/subscriptions/foo-subscription","asset_type":"pubsub.googleapis.com/Subscription","resource":{"data":{"name":"projects/my-project/subscriptions/foo-subscription","topic":"projects/my-project/topics/foo-topic","ackDeadlineSeconds":60}}}
+{"name":"//compute.googleapis.com/projects/my-project/zones/us-east1-a/disks/foo-instance","asset_type":"compute.googleapis.com/Disk","resource":{"data":{"selfLink":"https://www.googleapis.com/compute/v1/projects/my-project/zones/us-east1-a/disks/foo-instance","sourceImage":"https://www.googleapis.com/compute/v1/projects/ubuntu-os-cloud/global/images/ubuntu-1804-bionic-v20190404"}}}
+{"name":"//compute.googleapis.com/projects/my-project/zones/us-east1-a/instances/foo-instance","asset_type":"compute.googleapis.com/Instance","resource":{"data":{"name":"foo-instance","zone":"https://www.googleapis.com/compute/v1/projects/my-project/zones/us-east1-a","machineType":"https://www.googleapis.com/compute/v1/projects/my-project/zones/us-east1-a/machineTypes/f1-micro","disks":[{"boot":true,"source":"https://www.googleapis.com/compute/v1/projects/my-project/zones/us-east1-a/disks/foo-instance"}],"networkInterfaces":[{"network":"https://www.googleapis.com/compute/v1/projects/my-project/global/networks/default","accessConfigs":[{"type":"ONE_TO_ONE_NAT"}]}],"metadata":{"items":[{"key":"enable-oslogin","value":"TRUE"}]}}}}
+{"name":"//container.googleapis.com/projects/my-project/locations/us-central1/clusters/foo-cluster-cluster","asset_type":"container.googleapis.com/Cluster","resource":{"data":{"name":"foo-cluster-cluster","location":"us-central1","network":"default","privateClusterConfig":{"enablePrivateNodes":true,"masterIpv4CidrBlock":"172.16.0.0/28","privateEndpoint":"172.16.0.2"},"legacyAbac":{"enabled":true},"nodePools":[{"name":"default-pool"}]}}}
+{"name":"//compute.googleapis.com/projects/my-project/global/firewalls/default-allow-ssh","asset_type":"compute.googleapis.com/Firewall","resource":{"data":{"name":"default-allow-ssh"}}}
+`
+
+func TestImport(t *testing.T) {
+	assets, err := ReadAssets(strings.NewReader(assetExport))
+	if err != nil {
+		t.Fatalf("ReadAssets: %v", err)
+	}
+
+	got, err := ImportProject("my-project", assets)
+	if err != nil {
+		t.Fatalf("ImportProject: %v", err)
+	}
+
+	wantYAML := `
+project_id: my-project
+owners_group: my-project-owners@my-domain.com
+auditors_group: my-project-auditors@my-domain.com
+data_readwrite_groups:
+- some-readwrite-group@my-domain.com
+data_readonly_groups:
+- some-readonly-group@my-domain.com
+audit_logs:
+  logs_gcs_bucket:
+    name: my-project-logs
+    location: us-east1
+  logs_bigquery_dataset:
+    name: audit_logs
+    location: US
+resources:
+- gcs_bucket:
+    properties:
+      name: foo-bucket
+      location: us-east1
+      bindings:
+      - role: roles/storage.objectViewer
+        members:
+        - user:someone@my-domain.com
+- bigquery_dataset:
+    properties:
+      name: foo_dataset
+      location: US
+      access:
+      - role: READER
+        userByEmail: someone@my-domain.com
+- pubsub:
+    properties:
+      topic: foo-topic
+      subscriptions:
+      - name: foo-subscription
+        ackDeadlineSeconds: 60
+- gce_instance:
+    properties:
+      name: foo-instance
+      zone: us-east1-a
+      machineType: f1-micro
+      diskImage: projects/ubuntu-os-cloud/global/images/ubuntu-1804-bionic-v20190404
+      network: projects/my-project/global/networks/default
+      hasExternalIp: true
+- gke_cluster:
+    properties:
+      name: foo-cluster
+      clusterLocationType: Regional
+      region: us-central1
+      cluster:
+        network: global/networks/default
+        privateClusterConfig:
+          enablePrivateNodes: true
+          masterIpv4CidrBlock: 172.16.0.0/28
+        legacyAbac:
+          enabled: true
+generated_fields:
+  project_number: '1111'
+`
+	b, err := json.Marshal(got.Project)
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+	var gotProject, wantProject interface{}
+	if err := json.Unmarshal(b, &gotProject); err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+	}
+	if err := yaml.Unmarshal([]byte(wantYAML), &wantProject); err != nil {
+		t.Fatalf("yaml.Unmarshal: %v", err)
+	}
+	if diff := cmp.Diff(gotProject, wantProject); diff != "" {
+		t.Errorf("imported project differs (-got +want):\n%v", diff)
+	}
+
+	wantWarnings := []string{
+		`bucket "foo-bucket": versioning is disabled, deploying enables it`,
+		`cluster "foo-cluster-cluster": legacy ABAC is enabled`,
+		`cluster "foo-cluster-cluster": 1 node pools were not imported`,
+		`assets of the following types were not imported: compute.googleapis.com/Firewall (1)`,
+		`pubsub "foo-topic": Init rejects the resource: messageStoragePolicy.allowedPersistenceRegions must be set`,
+		`gce_instance "foo-instance": Init rejects the resource: hasExternalIp must not be true unless allow_external_ip is set`,
+	}
+	if diff := cmp.Diff(got.Warnings, wantWarnings); diff != "" {
+		t.Errorf("warnings differ (-got +want):\n%v", diff)
+	}
+}
+
+func TestImportAuditLogsSinkFilter(t *testing.T) {
+	// A sink exporting all logs to the audit logs dataset.
+	export := strings.Replace(assetExport, `"filter":"logName:\"logs/cloudaudit.googleapis.com\""`, `"filter":"logName:\"logs/cloudaudit.googleapis.com\" OR severity>=ERROR"`, 1)
+	assets, err := ReadAssets(strings.NewReader(export))
+	if err != nil {
+		t.Fatalf("ReadAssets: %v", err)
+	}
+	got, err := ImportProject("my-project", assets)
+	if err != nil {
+		t.Fatalf("ImportProject: %v", err)
+	}
+
+	want := `audit_logs: sink "audit-logs-to-bigquery" has filter "logName:\"logs/cloudaudit.googleapis.com\" OR severity>=ERROR", generated rules expect "logName:\"logs/cloudaudit.googleapis.com\""`
+	for _, w := range got.Warnings {
+		if w == want {
+			return
+		}
+	}
+	t.Errorf("warnings = %q, want warning %q", got.Warnings, want)
+}
+
+func TestReadAssetsList(t *testing.T) {
+	// gcloud asset list prints a JSON list with camel case field names.
+	got, err := ReadAssets(strings.NewReader(`[
+  {"name": "//storage.googleapis.com/foo-bucket", "assetType": "storage.googleapis.com/Bucket", "resource": {"data": {"name": "foo-bucket"}}},
+  {"name": "//storage.googleapis.com/foo-bucket", "assetType": "storage.googleapis.com/Bucket", "iamPolicy": {"bindings": [{"role": "roles/storage.admin", "members": ["group:foo@my-domain.com"]}]}}
+]`))
+	if err != nil {
+		t.Fatalf("ReadAssets: %v", err)
+	}
+	want := []*Asset{{
+		Name:      "//storage.googleapis.com/foo-bucket",
+		AssetType: "storage.googleapis.com/Bucket",
+		Data:      map[string]interface{}{"name": "foo-bucket"},
+		Bindings:  map[string][]string{"roles/storage.admin": {"group:foo@my-domain.com"}},
+	}}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("assets differ (-got +want):\n%v", diff)
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	if _, err := ImportProject("my-project", nil); err == nil {
+		t.Fatalf("ImportProject: got nil error for assets without a project, want non-nil error")
+	}
+}
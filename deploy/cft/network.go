@@ -0,0 +1,135 @@
+package cft
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Network wraps a VPC network.
+type Network struct {
+	NetworkProperties `json:"properties"`
+}
+
+// NetworkProperties represents a partial network implementation.
+type NetworkProperties struct {
+	NetworkName           string `json:"name"`
+	AutoCreateSubnetworks *bool  `json:"autoCreateSubnetworks"`
+}
+
+func init() {
+	RegisterResourceKind(ResourceKind{
+		Key: "network",
+		New: func() ParsedResource { return new(Network) },
+	})
+}
+
+// Init initializes the network with the given project.
+func (n *Network) Init(*Project) error {
+	if n.Name() == "" {
+		return errors.New("name must be set")
+	}
+	if n.AutoCreateSubnetworks != nil && *n.AutoCreateSubnetworks {
+		return errors.New("autoCreateSubnetworks must not be true, auto mode networks are not allowed")
+	}
+
+	f := false
+	n.AutoCreateSubnetworks = &f
+	return nil
+}
+
+// Name returns the name of the network.
+func (n *Network) Name() string {
+	return n.NetworkName
+}
+
+// TemplatePath returns the name of the template to use for the network.
+func (n *Network) TemplatePath() string {
+	return "deploy/cft/templates/network.py"
+}
+
+// isResourceURL determines whether the given network or subnetwork reference is a (partial) URL
+// rather than the name of a resource declared in the project.
+// This follows the convention of the CFT templates.
+func isResourceURL(ref string) bool {
+	return strings.Contains(ref, "/") || strings.Contains(ref, ".")
+}
+
+// initNetworkReferences checks that every network and subnetwork referenced by name from a resource
+// in the project is declared in the project and records the referenced resources as dependencies.
+// References given as URLs are assumed to point at networks managed outside of the project config.
+func initNetworkReferences(project *Project) error {
+	networks := make(map[string]bool)
+	subnetworks := make(map[string]*Subnetwork)
+	for _, pair := range project.resourcePairs() {
+		switch r := pair.parsed.(type) {
+		case *Network:
+			networks[r.Name()] = true
+		case *Subnetwork:
+			subnetworks[r.Name()] = r
+		}
+	}
+
+	// getDeps returns the declared network and subnetwork resources that are referenced by name.
+	getDeps := func(network, subnetwork string) ([]string, error) {
+		var deps []string
+		if network != "" && !isResourceURL(network) {
+			if !networks[network] {
+				return nil, fmt.Errorf("network %q not found in project", network)
+			}
+			deps = append(deps, network)
+		}
+		if subnetwork != "" && !isResourceURL(subnetwork) {
+			s, ok := subnetworks[subnetwork]
+			if !ok {
+				return nil, fmt.Errorf("subnetwork %q not found in project", subnetwork)
+			}
+			if network != "" && s.Network != network {
+				return nil, fmt.Errorf("subnetwork %q does not belong to network %q", subnetwork, network)
+			}
+			deps = append(deps, subnetwork)
+		}
+		return deps, nil
+	}
+
+	// subnetworkURL expands the name of a subnetwork declared in the project to a partial URL,
+	// as required by instances.
+	subnetworkURL := func(subnetwork string) string {
+		if subnetwork == "" || isResourceURL(subnetwork) {
+			return subnetwork
+		}
+		s := subnetworks[subnetwork]
+		return fmt.Sprintf("regions/%s/subnetworks/%s", s.Region, s.Name())
+	}
+
+	for _, pair := range project.resourcePairs() {
+		var err error
+		switch r := pair.parsed.(type) {
+		case *Subnetwork:
+			r.dependsOn, err = getDeps(r.Network, "")
+		case *Firewall:
+			r.dependsOn, err = getDeps(r.Network, "")
+		case *GCEInstance:
+			i := r
+			if i.dependsOn, err = getDeps(i.Network, i.Subnetwork); err != nil {
+				break
+			}
+			i.Subnetwork = subnetworkURL(i.Subnetwork)
+			for _, nip := range i.NetworkInterfacePairs {
+				ni := &nip.parsed
+				var deps []string
+				if deps, err = getDeps(ni.Network, ni.Subnetwork); err != nil {
+					break
+				}
+				i.dependsOn = append(i.dependsOn, deps...)
+				ni.Subnetwork = subnetworkURL(ni.Subnetwork)
+			}
+		case *GKECluster:
+			r.dependsOn, err = getDeps(r.Cluster.Network, r.Cluster.Subnetwork)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package cft
+
+import (
+	"testing"
+
+	"github.com/ghodss/yaml"
+)
+
+func TestNetworkInit(t *testing.T) {
+	_, project := getTestConfigAndProject(t, nil)
+
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr bool
+	}{
+		{
+			name: "custom_mode",
+			yaml: `
+properties:
+  name: foo-network`,
+		},
+		{
+			name: "auto_mode",
+			yaml: `
+properties:
+  name: foo-network
+  autoCreateSubnetworks: true`,
+			wantErr: true,
+		},
+		{
+			name:    "missing_name",
+			yaml:    "properties: {}",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			n := new(Network)
+			if err := yaml.Unmarshal([]byte(tc.yaml), n); err != nil {
+				t.Fatalf("yaml unmarshal: %v", err)
+			}
+			err := n.Init(project)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("n.Init = %v, want error: %t", err, tc.wantErr)
+			}
+			if err == nil && (n.AutoCreateSubnetworks == nil || *n.AutoCreateSubnetworks) {
+				t.Errorf("n.AutoCreateSubnetworks = %v, want false", n.AutoCreateSubnetworks)
+			}
+		})
+	}
+}
+
+func TestSubnetworkInit(t *testing.T) {
+	_, project := getTestConfigAndProject(t, nil)
+
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+			yaml: `
+properties:
+  name: foo-subnetwork
+  network: foo-network
+  region: us-east1
+  ipCidrRange: 10.0.0.0/24`,
+		},
+		{
+			name: "private_google_access_disabled",
+			yaml: `
+properties:
+  name: foo-subnetwork
+  network: foo-network
+  region: us-east1
+  ipCidrRange: 10.0.0.0/24
+  privateIpGoogleAccess: false`,
+			wantErr: true,
+		},
+		{
+			name: "flow_logs_disabled",
+			yaml: `
+properties:
+  name: foo-subnetwork
+  network: foo-network
+  region: us-east1
+  ipCidrRange: 10.0.0.0/24
+  enableFlowLogs: false`,
+			wantErr: true,
+		},
+		{
+			name: "missing_region",
+			yaml: `
+properties:
+  name: foo-subnetwork
+  network: foo-network
+  ipCidrRange: 10.0.0.0/24`,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := new(Subnetwork)
+			if err := yaml.Unmarshal([]byte(tc.yaml), s); err != nil {
+				t.Fatalf("yaml unmarshal: %v", err)
+			}
+			err := s.Init(project)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("s.Init = %v, want error: %t", err, tc.wantErr)
+			}
+			if err == nil && (!*s.PrivateIPGoogleAccess || !*s.EnableFlowLogs) {
+				t.Errorf("subnetwork defaults not set: privateIpGoogleAccess=%v, enableFlowLogs=%v", *s.PrivateIPGoogleAccess, *s.EnableFlowLogs)
+			}
+		})
+	}
+}
+
+func TestNetworkReferenceErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data *ConfigData
+	}{
+		{
+			name: "missing_subnetwork_network",
+			data: &ConfigData{`
+resources:
+- subnetwork:
+    properties:
+      name: foo-subnetwork
+      network: dne
+      region: us-east1
+      ipCidrRange: 10.0.0.0/24`},
+		},
+		{
+			name: "missing_firewall_network",
+			data: &ConfigData{`
+resources:
+- firewall:
+    properties:
+      name: foo-firewall
+      network: dne
+      rules: []`},
+		},
+		{
+			name: "missing_cluster_subnetwork",
+			data: &ConfigData{`
+resources:
+- network:
+    properties:
+      name: foo-network
+- gke_cluster:
+    properties:
+      name: foo-cluster
+      clusterLocationType: Zonal
+      zone: us-east1-a
+      cluster:
+        network: foo-network
+        subnetwork: dne
+        privateClusterConfig:
+          masterIpv4CidrBlock: 172.16.0.0/28`},
+		},
+		{
+			name: "subnetwork_of_other_network",
+			data: &ConfigData{`
+resources:
+- network:
+    properties:
+      name: foo-network
+- network:
+    properties:
+      name: bar-network
+- subnetwork:
+    properties:
+      name: bar-subnetwork
+      network: bar-network
+      region: us-east1
+      ipCidrRange: 10.0.0.0/24
+- gce_instance:
+    properties:
+      name: foo-instance
+      zone: us-east1-a
+      diskImage: foo-image
+      network: foo-network
+      subnetwork: bar-subnetwork`},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, project := getTestConfigAndProject(t, nil)
+			if err := yaml.Unmarshal([]byte(tc.data.ExtraProjectConfig), project); err != nil {
+				t.Fatalf("yaml unmarshal: %v", err)
+			}
+			if err := project.Init(); err == nil {
+				t.Fatalf("project.Init: got nil error, want non-nil error")
+			}
+		})
+	}
+}
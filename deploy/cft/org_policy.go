@@ -0,0 +1,91 @@
+package cft
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"regexp"
+)
+
+// OrgPolicy is an organization policy set on an organization, folder or project.
+// See https://cloud.google.com/resource-manager/reference/rest/v1/Policy.
+type OrgPolicy struct {
+	// Resource is the organization, folder or project the policy is set on, e.g. organizations/12345678.
+	Resource string `json:"-"`
+
+	Constraint    string            `json:"constraint"`
+	BooleanPolicy *OrgBooleanPolicy `json:"booleanPolicy,omitempty"`
+	ListPolicy    *OrgListPolicy    `json:"listPolicy,omitempty"`
+}
+
+// OrgBooleanPolicy enforces or disables a boolean constraint.
+type OrgBooleanPolicy struct {
+	Enforced bool `json:"enforced"`
+}
+
+// OrgListPolicy determines the values allowed by a list constraint.
+type OrgListPolicy struct {
+	AllowedValues []string `json:"allowedValues,omitempty"`
+
+	// AllValues is ALLOW or DENY to allow or deny all values.
+	AllValues string `json:"allValues,omitempty"`
+}
+
+var orgPolicyResourceRE = regexp.MustCompile(`^(organizations|folders|projects)/([^/]+)$`)
+
+// orgPolicyResourceFlags maps the collection of an org policy resource to the gcloud flag selecting it.
+var orgPolicyResourceFlags = map[string]string{
+	"organizations": "--organization",
+	"folders":       "--folder",
+	"projects":      "--project",
+}
+
+// DeployOrgPolicies sets the organization policies on their resources.
+// Policies set previously on the resources for other constraints are left unchanged.
+func DeployOrgPolicies(policies []*OrgPolicy) error {
+	for _, p := range policies {
+		if err := setOrgPolicy(p); err != nil {
+			return fmt.Errorf("failed to set policy %q on %q: %v", p.Constraint, p.Resource, err)
+		}
+	}
+	return nil
+}
+
+func setOrgPolicy(p *OrgPolicy) error {
+	m := orgPolicyResourceRE.FindStringSubmatch(p.Resource)
+	if m == nil {
+		return fmt.Errorf("invalid resource %q, want organizations/ID, folders/ID or projects/ID", p.Resource)
+	}
+
+	b, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("failed to marshal policy: %v", err)
+	}
+
+	tmp, err := ioutil.TempFile("", "")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		return fmt.Errorf("failed to write policy to file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %v", err)
+	}
+
+	args := []string{"resource-manager", "org-policies", "set-policy", tmp.Name(), orgPolicyResourceFlags[m[1]], m[2]}
+	log.Printf("Running gcloud command with args: %v", args)
+
+	cmd := exec.Command("gcloud", args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stdout
+	if err := cmdRun(cmd); err != nil {
+		return fmt.Errorf("failed to run command: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package cft
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDeployOrgPolicies(t *testing.T) {
+	defer func(orig func(*exec.Cmd) error) { cmdRun = orig }(cmdRun)
+
+	var gotCommands, gotPolicies []string
+	cmdRun = func(cmd *exec.Cmd) error {
+		// The policy file is removed after the command runs.
+		b, err := ioutil.ReadFile(cmd.Args[4])
+		if err != nil {
+			return err
+		}
+		gotPolicies = append(gotPolicies, string(b))
+		args := append(cmd.Args[:4:4], cmd.Args[5:]...)
+		gotCommands = append(gotCommands, strings.Join(args, " "))
+		return nil
+	}
+
+	policies := []*OrgPolicy{
+		{
+			Resource:   "organizations/12345678",
+			Constraint: "constraints/gcp.resourceLocations",
+			ListPolicy: &OrgListPolicy{AllowedValues: []string{"us-central1"}},
+		},
+		{
+			Resource:      "projects/my-project",
+			Constraint:    "constraints/iam.disableServiceAccountKeyCreation",
+			BooleanPolicy: &OrgBooleanPolicy{Enforced: true},
+		},
+	}
+	if err := DeployOrgPolicies(policies); err != nil {
+		t.Fatalf("DeployOrgPolicies: %v", err)
+	}
+
+	wantCommands := []string{
+		"gcloud resource-manager org-policies set-policy --organization 12345678",
+		"gcloud resource-manager org-policies set-policy --project my-project",
+	}
+	if diff := cmp.Diff(gotCommands, wantCommands); diff != "" {
+		t.Errorf("commands differ (-got +want):\n%v", diff)
+	}
+	wantPolicies := []string{
+		`{"constraint":"constraints/gcp.resourceLocations","listPolicy":{"allowedValues":["us-central1"]}}`,
+		`{"constraint":"constraints/iam.disableServiceAccountKeyCreation","booleanPolicy":{"enforced":true}}`,
+	}
+	if diff := cmp.Diff(gotPolicies, wantPolicies); diff != "" {
+		t.Errorf("policies differ (-got +want):\n%v", diff)
+	}
+}
+
+func TestDeployOrgPoliciesErrors(t *testing.T) {
+	err := DeployOrgPolicies([]*OrgPolicy{{Resource: "billingAccounts/123", Constraint: "constraints/foo"}})
+	if err == nil {
+		t.Fatalf("DeployOrgPolicies: got nil error for invalid resource, want non-nil error")
+	}
+}
@@ -4,17 +4,32 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"regexp"
+	"strings"
+	"time"
 )
 
 // Pubsub represents a GCP pubsub channel resource.
 type Pubsub struct {
 	PubsubProperties `json:"properties"`
+
+	// PublisherAccount is a service account that is granted publisher access to the topic.
+	PublisherAccount string `json:"publisher_account,omitempty"`
+
+	// AckDeadlineSec is the default ack deadline for subscriptions that don't set their own.
+	AckDeadlineSec int `json:"ack_deadline_sec,omitempty"`
 }
 
 // PubsubProperties represents a partial CFT pubsub implementation.
 type PubsubProperties struct {
-	TopicName         string              `json:"topic"`
-	SubscriptionPairs []*subscriptionPair `json:"subscriptions"`
+	TopicName            string               `json:"topic"`
+	Bindings             []binding            `json:"accessControl,omitempty"`
+	MessageStoragePolicy messageStoragePolicy `json:"messageStoragePolicy"`
+	SubscriptionPairs    []*subscriptionPair  `json:"subscriptions"`
+}
+
+type messageStoragePolicy struct {
+	AllowedPersistenceRegions []string `json:"allowedPersistenceRegions"`
 }
 
 // subscriptionPair is used to retain fields not defined by the parsed subscription.
@@ -40,7 +55,28 @@ func (p subscriptionPair) MarshalJSON() ([]byte, error) {
 }
 
 type subscription struct {
-	Bindings []binding `json:"accessControl,omitempty"`
+	Name               string            `json:"name,omitempty"`
+	AckDeadlineSeconds int               `json:"ackDeadlineSeconds,omitempty"`
+	Bindings           []binding         `json:"accessControl,omitempty"`
+	DeadLetterPolicy   *deadLetterPolicy `json:"deadLetterPolicy,omitempty"`
+	RetryPolicy        *retryPolicy      `json:"retryPolicy,omitempty"`
+}
+
+type deadLetterPolicy struct {
+	DeadLetterTopic     string `json:"deadLetterTopic"`
+	MaxDeliveryAttempts int    `json:"maxDeliveryAttempts,omitempty"`
+}
+
+type retryPolicy struct {
+	MinimumBackoff string `json:"minimumBackoff,omitempty"`
+	MaximumBackoff string `json:"maximumBackoff,omitempty"`
+}
+
+func init() {
+	RegisterResourceKind(ResourceKind{
+		Key: "pubsub",
+		New: func() ParsedResource { return new(Pubsub) },
+	})
 }
 
 // Init initializes a new pubsub with the given project.
@@ -48,6 +84,9 @@ func (p *Pubsub) Init(project *Project) error {
 	if p.Name() == "" {
 		return errors.New("topic must be set")
 	}
+	if len(p.MessageStoragePolicy.AllowedPersistenceRegions) == 0 {
+		return errors.New("messageStoragePolicy.allowedPersistenceRegions must be set")
+	}
 
 	appendGroupPrefix := func(ss ...string) []string {
 		res := make([]string, 0, len(ss))
@@ -62,13 +101,98 @@ func (p *Pubsub) Init(project *Project) error {
 		{"roles/pubsub.viewer", appendGroupPrefix(project.DataReadOnlyGroups...)},
 	}
 
+	topicBindings := defaultBindings
+	if p.PublisherAccount != "" {
+		topicBindings = append(topicBindings, binding{"roles/pubsub.publisher", []string{"serviceAccount:" + p.PublisherAccount}})
+	}
+	p.Bindings = mergeBindings(append(topicBindings, p.Bindings...)...)
+
 	for _, subp := range p.SubscriptionPairs {
-		subp.parsed.Bindings = mergeBindings(append(defaultBindings, subp.parsed.Bindings...)...)
+		sub := &subp.parsed
+		if sub.AckDeadlineSeconds == 0 {
+			sub.AckDeadlineSeconds = p.AckDeadlineSec
+		}
+		if sub.AckDeadlineSeconds != 0 && (sub.AckDeadlineSeconds < 10 || sub.AckDeadlineSeconds > 600) {
+			return fmt.Errorf("subscription %q: ackDeadlineSeconds must be between 10 and 600, got %d", sub.Name, sub.AckDeadlineSeconds)
+		}
+		if err := sub.initDeadLetterPolicy(project); err != nil {
+			return fmt.Errorf("subscription %q: %v", sub.Name, err)
+		}
+		if err := sub.RetryPolicy.validate(); err != nil {
+			return fmt.Errorf("subscription %q: %v", sub.Name, err)
+		}
+		sub.Bindings = mergeBindings(append(defaultBindings, sub.Bindings...)...)
+	}
+
+	return nil
+}
+
+// initDeadLetterPolicy validates the dead letter policy and expands the dead letter topic to its full path.
+// Whether the topic exists is checked once all resources in the project have been initialized.
+func (s *subscription) initDeadLetterPolicy(project *Project) error {
+	dlp := s.DeadLetterPolicy
+	if dlp == nil {
+		return nil
+	}
+	if dlp.DeadLetterTopic == "" {
+		return errors.New("deadLetterPolicy.deadLetterTopic must be set")
+	}
+	if dlp.MaxDeliveryAttempts != 0 && (dlp.MaxDeliveryAttempts < 5 || dlp.MaxDeliveryAttempts > 100) {
+		return fmt.Errorf("deadLetterPolicy.maxDeliveryAttempts must be between 5 and 100, got %d", dlp.MaxDeliveryAttempts)
+	}
+	if !strings.Contains(dlp.DeadLetterTopic, "/") {
+		dlp.DeadLetterTopic = fmt.Sprintf("projects/%s/topics/%s", project.ID, dlp.DeadLetterTopic)
+	}
+
+	// The Pub/Sub service agent must be able to acknowledge messages it forwards to the dead letter topic.
+	if sa := pubsubServiceAgent(project); sa != "" {
+		s.Bindings = append(s.Bindings, binding{"roles/pubsub.subscriber", []string{sa}})
 	}
+	return nil
+}
+
+// backoffRE matches the durations accepted by the Pub/Sub API: seconds with up to nine fractional digits, e.g. "10s".
+var backoffRE = regexp.MustCompile(`^[0-9]+(\.[0-9]{1,9})?s$`)
 
+// maxBackoff is the largest backoff accepted by the Pub/Sub API.
+const maxBackoff = 600 * time.Second
+
+func (r *retryPolicy) validate() error {
+	if r == nil {
+		return nil
+	}
+	min, err := parseBackoff("minimumBackoff", r.MinimumBackoff)
+	if err != nil {
+		return err
+	}
+	max, err := parseBackoff("maximumBackoff", r.MaximumBackoff)
+	if err != nil {
+		return err
+	}
+	if r.MinimumBackoff != "" && r.MaximumBackoff != "" && min > max {
+		return fmt.Errorf("retryPolicy.minimumBackoff %q must not exceed retryPolicy.maximumBackoff %q", r.MinimumBackoff, r.MaximumBackoff)
+	}
 	return nil
 }
 
+// parseBackoff parses a backoff of the retry policy, which is zero if unset.
+func parseBackoff(field, v string) (time.Duration, error) {
+	if v == "" {
+		return 0, nil
+	}
+	if !backoffRE.MatchString(v) {
+		return 0, fmt.Errorf("retryPolicy.%s must be a number of seconds ending in \"s\", e.g. \"10s\", got %q", field, v)
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse retryPolicy.%s: %v", field, err)
+	}
+	if d > maxBackoff {
+		return 0, fmt.Errorf("retryPolicy.%s must be between 0s and 600s, got %q", field, v)
+	}
+	return d, nil
+}
+
 // Name returns the name of this pubsub.
 func (p *Pubsub) Name() string {
 	return p.TopicName
@@ -78,3 +202,45 @@ func (p *Pubsub) Name() string {
 func (p *Pubsub) TemplatePath() string {
 	return "deploy/cft/templates/pubsub.py"
 }
+
+// pubsubServiceAgent returns the Pub/Sub service agent member of the project.
+// It is empty if the project number has not been generated yet.
+func pubsubServiceAgent(project *Project) string {
+	if project.GeneratedFields.ProjectNumber == "" {
+		return ""
+	}
+	return fmt.Sprintf("serviceAccount:service-%s@gcp-sa-pubsub.iam.gserviceaccount.com", project.GeneratedFields.ProjectNumber)
+}
+
+// initDeadLetterTopics checks that every dead letter topic referenced by a subscription in the project is
+// declared by a pubsub resource of the project and grants the Pub/Sub service agent permission to publish to it.
+func initDeadLetterTopics(project *Project) error {
+	var pubsubs []*Pubsub
+	topics := make(map[string]*Pubsub)
+	for _, pair := range project.resourcePairs() {
+		if p, ok := pair.parsed.(*Pubsub); ok {
+			pubsubs = append(pubsubs, p)
+			topics[fmt.Sprintf("projects/%s/topics/%s", project.ID, p.Name())] = p
+		}
+	}
+
+	for _, p := range pubsubs {
+		for _, subp := range p.SubscriptionPairs {
+			dlp := subp.parsed.DeadLetterPolicy
+			if dlp == nil {
+				continue
+			}
+			dlt, ok := topics[dlp.DeadLetterTopic]
+			if !ok {
+				return fmt.Errorf("dead letter topic %q of subscription %q not found in project", dlp.DeadLetterTopic, subp.parsed.Name)
+			}
+			if dlt == p {
+				return fmt.Errorf("subscription %q must not use its own topic as dead letter topic", subp.parsed.Name)
+			}
+			if sa := pubsubServiceAgent(project); sa != "" {
+				dlt.Bindings = mergeBindings(append(dlt.Bindings, binding{"roles/pubsub.publisher", []string{sa}})...)
+			}
+		}
+	}
+	return nil
+}
@@ -11,8 +11,13 @@ func TestPubsub(t *testing.T) {
 	_, project := getTestConfigAndProject(t, nil)
 
 	pubsubYAML := `
+publisher_account: foo-publisher@my-project.iam.gserviceaccount.com
+ack_deadline_sec: 60
 properties:
   topic: foo-topic
+  messageStoragePolicy:
+    allowedPersistenceRegions:
+    - us-central1
   accessControl:
   - role: roles/pubsub.publisher
     members:
@@ -23,13 +28,39 @@ properties:
     - role: roles/pubsub.viewer
       members:
       - 'user:extra-reader@google.com'
+  - name: bar-subscription
+    ackDeadlineSeconds: 30
+    retryPolicy:
+      minimumBackoff: 10s
+      maximumBackoff: 600s
+    deadLetterPolicy:
+      deadLetterTopic: dead-letter-topic
+      maxDeliveryAttempts: 5
 `
 
 	wantPubsubYAML := `
+publisher_account: foo-publisher@my-project.iam.gserviceaccount.com
+ack_deadline_sec: 60
 properties:
   topic: foo-topic
+  messageStoragePolicy:
+    allowedPersistenceRegions:
+    - us-central1
+  accessControl:
+  - role: roles/pubsub.editor
+    members:
+    - 'group:some-readwrite-group@my-domain.com'
+  - role: roles/pubsub.viewer
+    members:
+    - 'group:some-readonly-group@my-domain.com'
+    - 'group:another-readonly-group@googlegroups.com'
+  - role: roles/pubsub.publisher
+    members:
+    - 'serviceAccount:foo-publisher@my-project.iam.gserviceaccount.com'
+    - 'user:foo@user.com'
   subscriptions:
   - name: foo-subscription
+    ackDeadlineSeconds: 60
     accessControl:
     - role: roles/pubsub.editor
       members:
@@ -39,6 +70,25 @@ properties:
       - 'group:some-readonly-group@my-domain.com'
       - 'group:another-readonly-group@googlegroups.com'
       - 'user:extra-reader@google.com'
+  - name: bar-subscription
+    ackDeadlineSeconds: 30
+    retryPolicy:
+      minimumBackoff: 10s
+      maximumBackoff: 600s
+    deadLetterPolicy:
+      deadLetterTopic: projects/my-project/topics/dead-letter-topic
+      maxDeliveryAttempts: 5
+    accessControl:
+    - role: roles/pubsub.editor
+      members:
+      - 'group:some-readwrite-group@my-domain.com'
+    - role: roles/pubsub.viewer
+      members:
+      - 'group:some-readonly-group@my-domain.com'
+      - 'group:another-readonly-group@googlegroups.com'
+    - role: roles/pubsub.subscriber
+      members:
+      - 'serviceAccount:service-1111@gcp-sa-pubsub.iam.gserviceaccount.com'
 `
 
 	p := &Pubsub{}
@@ -71,3 +121,141 @@ properties:
 		t.Errorf("d.ResourceName() = %v, want %v", gotName, wantName)
 	}
 }
+
+func TestPubsubErrors(t *testing.T) {
+	_, project := getTestConfigAndProject(t, nil)
+
+	tests := []struct {
+		name       string
+		pubsubYAML string
+	}{
+		{
+			name: "missing_message_storage_policy",
+			pubsubYAML: `
+properties:
+  topic: foo-topic`,
+		},
+		{
+			name: "invalid_ack_deadline",
+			pubsubYAML: `
+ack_deadline_sec: 5
+properties:
+  topic: foo-topic
+  messageStoragePolicy:
+    allowedPersistenceRegions:
+    - us-central1
+  subscriptions:
+  - name: foo-subscription`,
+		},
+		{
+			name: "missing_dead_letter_topic",
+			pubsubYAML: `
+properties:
+  topic: foo-topic
+  messageStoragePolicy:
+    allowedPersistenceRegions:
+    - us-central1
+  subscriptions:
+  - name: foo-subscription
+    deadLetterPolicy:
+      maxDeliveryAttempts: 5`,
+		},
+		{
+			name: "invalid_retry_policy",
+			pubsubYAML: `
+properties:
+  topic: foo-topic
+  messageStoragePolicy:
+    allowedPersistenceRegions:
+    - us-central1
+  subscriptions:
+  - name: foo-subscription
+    retryPolicy:
+      minimumBackoff: 600s
+      maximumBackoff: 10s`,
+		},
+		{
+			name: "retry_policy_not_in_seconds",
+			pubsubYAML: `
+properties:
+  topic: foo-topic
+  messageStoragePolicy:
+    allowedPersistenceRegions:
+    - us-central1
+  subscriptions:
+  - name: foo-subscription
+    retryPolicy:
+      maximumBackoff: 1m`,
+		},
+		{
+			name: "retry_policy_too_long",
+			pubsubYAML: `
+properties:
+  topic: foo-topic
+  messageStoragePolicy:
+    allowedPersistenceRegions:
+    - us-central1
+  subscriptions:
+  - name: foo-subscription
+    retryPolicy:
+      maximumBackoff: 601s`,
+		},
+		{
+			name: "retry_policy_negative",
+			pubsubYAML: `
+properties:
+  topic: foo-topic
+  messageStoragePolicy:
+    allowedPersistenceRegions:
+    - us-central1
+  subscriptions:
+  - name: foo-subscription
+    retryPolicy:
+      minimumBackoff: -1s`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &Pubsub{}
+			if err := yaml.Unmarshal([]byte(tc.pubsubYAML), p); err != nil {
+				t.Fatalf("yaml unmarshal: %v", err)
+			}
+			if err := p.Init(project); err == nil {
+				t.Fatalf("p.Init: got nil error, want non-nil error")
+			}
+		})
+	}
+}
+
+func TestDeadLetterTopics(t *testing.T) {
+	_, project := getTestConfigAndProject(t, &ConfigData{`
+resources:
+- pubsub:
+    properties:
+      topic: foo-topic
+      messageStoragePolicy:
+        allowedPersistenceRegions:
+        - us-central1
+      subscriptions:
+      - name: foo-subscription
+        deadLetterPolicy:
+          deadLetterTopic: dead-letter-topic
+- pubsub:
+    properties:
+      topic: dead-letter-topic
+      messageStoragePolicy:
+        allowedPersistenceRegions:
+        - us-central1`})
+
+	dlt := project.Resources[1].Parsed.(*Pubsub)
+	want := binding{"roles/pubsub.publisher", []string{"serviceAccount:service-1111@gcp-sa-pubsub.iam.gserviceaccount.com"}}
+	if diff := cmp.Diff(dlt.Bindings[len(dlt.Bindings)-1], want); diff != "" {
+		t.Errorf("dead letter topic publisher binding differs (-got +want):\n%v", diff)
+	}
+
+	project.Resources = project.Resources[:1]
+	if err := project.Init(); err == nil {
+		t.Fatalf("project.Init: got nil error for missing dead letter topic, want non-nil error")
+	}
+}
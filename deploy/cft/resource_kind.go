@@ -0,0 +1,136 @@
+package cft
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// ResourceKind describes a kind of resource that can be set in the resources of a project.
+type ResourceKind struct {
+	// Key is the key of the resource in an item of the project's resources, e.g. "gcs_bucket".
+	Key string
+
+	// New returns a new parsed resource to unmarshal a resource of this kind into.
+	// The template used to deploy the resource is given by its TemplatePath.
+	New func() ParsedResource
+
+	// HoldsData is set for kinds of resources that hold data and are monitored as such.
+	// Data holding kinds must set Location, ForsetiType and ForsetiID.
+	HoldsData bool
+
+	// Location returns the location of a resource of this kind.
+	// It should be set for all kinds of resources deployed in a location, not only data holding ones,
+	// as the resource locations organization policy only allows the locations it returns.
+	Location func(ParsedResource) string
+
+	// ForsetiType is the type of resources of this kind as known to Forseti, e.g. "bucket".
+	ForsetiType string
+
+	// ForsetiID returns the ID of a resource of this kind in the given project as known to Forseti.
+	ForsetiID func(*Project, ParsedResource) (string, error)
+}
+
+// resourceKinds maps the keys of registered resource kinds to the kinds.
+var resourceKinds = make(map[string]*ResourceKind)
+
+// RegisterResourceKind registers a kind of resource so that it can be set in the resources of projects.
+// It is meant to be called from init functions and panics if the kind is incomplete or its key is already registered.
+func RegisterResourceKind(kind ResourceKind) {
+	if kind.Key == "" || kind.New == nil {
+		panic("cft: resource kind must set Key and New")
+	}
+	if kind.Key == gkeWorkloadKey {
+		panic(fmt.Sprintf("cft: resource kind %q is reserved", kind.Key))
+	}
+	if kind.HoldsData && (kind.Location == nil || kind.ForsetiType == "" || kind.ForsetiID == nil) {
+		panic(fmt.Sprintf("cft: data holding resource kind %q must set Location, ForsetiType and ForsetiID", kind.Key))
+	}
+	if _, ok := resourceKinds[kind.Key]; ok {
+		panic(fmt.Sprintf("cft: resource kind %q registered twice", kind.Key))
+	}
+	resourceKinds[kind.Key] = &kind
+}
+
+// RegisterDefaultResourceKind registers a kind of resource that is parsed as a DefaultResource and deployed
+// with the given CFT template, e.g. RegisterDefaultResourceKind("cloud_router", "path/to/cloud_router.py").
+func RegisterDefaultResourceKind(key, templatePath string) {
+	RegisterResourceKind(ResourceKind{
+		Key: key,
+		New: func() ParsedResource { return &DefaultResource{templatePath: templatePath} },
+	})
+}
+
+// ResourceKinds returns all registered resource kinds sorted by key.
+func ResourceKinds() []*ResourceKind {
+	kinds := make([]*ResourceKind, 0, len(resourceKinds))
+	for _, k := range resourceKinds {
+		kinds = append(kinds, k)
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i].Key < kinds[j].Key })
+	return kinds
+}
+
+// gkeWorkloadKey is the key of GKE workloads, which are applied to clusters rather than deployed with a template.
+const gkeWorkloadKey = "gke_workload"
+
+// ProjectResource is an item of a project's resources.
+// Exactly one resource must be set per item, keyed by the key of its kind.
+type ProjectResource struct {
+	// Kind is the kind of the resource. It is nil for GKE workloads.
+	Kind *ResourceKind
+
+	// Raw is the resource as given in the config and Parsed its parsed version.
+	Raw    json.RawMessage
+	Parsed ParsedResource
+
+	// Template overrides the template of the resource's kind if set.
+	// It is given by the template field of the resource and relative paths are resolved against the config directory.
+	Template string
+
+	// TODO: make this behave more like standard deployment manager resources
+	GKEWorkload json.RawMessage
+}
+
+// UnmarshalJSON looks up the kind of the resource by its key and unmarshals the raw resource.
+// The parsed resource is only allocated; it is unmarshaled when the project is initialized.
+func (r *ProjectResource) UnmarshalJSON(data []byte) error {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	if len(m) != 1 {
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		return fmt.Errorf("exactly one resource must be set per item, got %v", keys)
+	}
+	for key, raw := range m {
+		if key == gkeWorkloadKey {
+			r.GKEWorkload = raw
+			return nil
+		}
+		kind, ok := resourceKinds[key]
+		if !ok {
+			return fmt.Errorf("unknown resource kind %q", key)
+		}
+		var t struct {
+			Template string `json:"template"`
+		}
+		if err := json.Unmarshal(raw, &t); err != nil {
+			return fmt.Errorf("failed to unmarshal %q resource: %v", key, err)
+		}
+		r.Kind, r.Raw, r.Parsed, r.Template = kind, raw, kind.New(), t.Template
+	}
+	return nil
+}
+
+// MarshalJSON marshals the raw resource keyed by the key of its kind.
+func (r ProjectResource) MarshalJSON() ([]byte, error) {
+	if r.Kind == nil {
+		return json.Marshal(map[string]json.RawMessage{gkeWorkloadKey: r.GKEWorkload})
+	}
+	return json.Marshal(map[string]json.RawMessage{r.Kind.Key: r.Raw})
+}
@@ -0,0 +1,129 @@
+package cft
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ghodss/yaml"
+)
+
+func TestDefaultResourceKind(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	templatePath := filepath.Join(dir, "dns_zone.py")
+	if err := ioutil.WriteFile(templatePath, []byte("# dns zone template"), 0644); err != nil {
+		t.Fatalf("ioutil.WriteFile: %v", err)
+	}
+
+	RegisterDefaultResourceKind("dns_zone", templatePath)
+	defer delete(resourceKinds, "dns_zone")
+
+	_, project := getTestConfigAndProject(t, &ConfigData{`
+resources:
+- dns_zone:
+    properties:
+      name: foo-zone
+      dnsName: foo.example.com.
+- gcs_bucket:
+    properties:
+      name: foo-bucket
+      location: us-east1`})
+
+	resolver := newTemplateResolver(nil)
+	defer resolver.cleanup()
+	deployment, err := getDeployment(project, project.resourcePairs(), resolver)
+	if err != nil {
+		t.Fatalf("getDeployment: %v", err)
+	}
+	want := &Resource{
+		Name:         "foo-zone",
+		Type:         templatePath,
+		Properties:   map[string]interface{}{"name": "foo-zone", "dnsName": "foo.example.com."},
+		TemplatePath: templatePath,
+	}
+	if diff := cmp.Diff(deployment.Resources[0], want); diff != "" {
+		t.Errorf("dns zone deployment resource differs (-got +want):\n%v", diff)
+	}
+
+	var got []string
+	for _, r := range project.DataHoldingResources() {
+		got = append(got, r.Kind.Key+"/"+r.Parsed.Name())
+	}
+	if diff := cmp.Diff(got, []string{"gcs_bucket/foo-bucket"}); diff != "" {
+		t.Errorf("data holding resources differ (-got +want):\n%v", diff)
+	}
+}
+
+func TestProjectResourceErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+	}{
+		{
+			name: "unknown_kind",
+			yaml: `
+cloud_router:
+  properties:
+    name: foo-router`,
+		},
+		{
+			name: "multiple_kinds",
+			yaml: `
+network:
+  properties:
+    name: foo-network
+firewall:
+  properties:
+    name: foo-firewall`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := new(ProjectResource)
+			if err := yaml.Unmarshal([]byte(tc.yaml), r); err == nil {
+				t.Fatalf("yaml.Unmarshal: got nil error, want non-nil error")
+			}
+		})
+	}
+}
+
+func TestRegisterResourceKindErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		kind ResourceKind
+	}{
+		{
+			name: "duplicate",
+			kind: ResourceKind{Key: "gcs_bucket", New: func() ParsedResource { return new(GCSBucket) }},
+		},
+		{
+			name: "reserved",
+			kind: ResourceKind{Key: "gke_workload", New: func() ParsedResource { return new(DefaultResource) }},
+		},
+		{
+			name: "data_without_location",
+			kind: ResourceKind{Key: "spanner_instance", New: func() ParsedResource { return new(DefaultResource) }, HoldsData: true},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterResourceKind: got no panic, want panic")
+				}
+			}()
+			RegisterResourceKind(tc.kind)
+		})
+	}
+	if _, ok := resourceKinds["spanner_instance"]; ok {
+		t.Errorf("invalid resource kind was registered")
+	}
+}
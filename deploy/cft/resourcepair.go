@@ -11,7 +11,10 @@ import (
 // resourcePair groups the raw resource with its parsed version.
 type resourcePair struct {
 	raw    json.RawMessage
-	parsed parsedResource
+	parsed ParsedResource
+
+	// template overrides the template of the parsed resource if set.
+	template string
 }
 
 // MergedPropertiesMap merges the raw and parsed resources and extracts their properties map.
@@ -0,0 +1,179 @@
+package cft
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+const servicePerimeterDeploymentName = "managed-data-protect-toolkit-perimeter"
+
+// alwaysRestrictedServices are the services that are always restricted by the service perimeter
+// as they are the services that host data.
+var alwaysRestrictedServices = []string{
+	"bigquery.googleapis.com",
+	"healthcare.googleapis.com",
+	"storage.googleapis.com",
+}
+
+// perimeterSupportedServices are the services supported by VPC Service Controls.
+// Services enabled in a project of the perimeter that are in this list are restricted in addition to alwaysRestrictedServices.
+// See https://cloud.google.com/vpc-service-controls/docs/supported-products.
+var perimeterSupportedServices = map[string]bool{
+	"bigquery.googleapis.com":          true,
+	"bigtable.googleapis.com":          true,
+	"cloudfunctions.googleapis.com":    true,
+	"cloudkms.googleapis.com":          true,
+	"container.googleapis.com":         true,
+	"containerregistry.googleapis.com": true,
+	"dataflow.googleapis.com":          true,
+	"dataproc.googleapis.com":          true,
+	"dlp.googleapis.com":               true,
+	"healthcare.googleapis.com":        true,
+	"logging.googleapis.com":           true,
+	"ml.googleapis.com":                true,
+	"monitoring.googleapis.com":        true,
+	"pubsub.googleapis.com":            true,
+	"spanner.googleapis.com":           true,
+	"sqladmin.googleapis.com":          true,
+	"storage.googleapis.com":           true,
+}
+
+var perimeterNameRE = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{0,49}$`)
+
+// ServicePerimeter defines a VPC Service Controls perimeter around all projects in the config.
+type ServicePerimeter struct {
+	// AccessPolicyID is the ID of the organization's access policy the perimeter is created in.
+	AccessPolicyID string `json:"access_policy_id"`
+
+	// Name is the short name of the perimeter.
+	Name string `json:"name"`
+
+	// ProjectID is the project that hosts the deployment managing the perimeter.
+	// Defaults to the remote audit logs project.
+	ProjectID string `json:"project_id"`
+
+	// DryRun determines whether the perimeter is only evaluated in dry run mode rather than enforced.
+	DryRun bool `json:"dry_run"`
+}
+
+// perimeter represents an Access Context Manager service perimeter.
+// See https://cloud.google.com/access-context-manager/docs/reference/rest/v1/accessPolicies.servicePerimeters.
+type perimeter struct {
+	Parent                string           `json:"parent"`
+	Name                  string           `json:"name"`
+	Title                 string           `json:"title"`
+	PerimeterType         string           `json:"perimeterType"`
+	Status                *perimeterConfig `json:"status,omitempty"`
+	Spec                  *perimeterConfig `json:"spec,omitempty"`
+	UseExplicitDryRunSpec bool             `json:"useExplicitDryRunSpec,omitempty"`
+}
+
+type perimeterConfig struct {
+	Resources          []string `json:"resources"`
+	RestrictedServices []string `json:"restrictedServices"`
+}
+
+// Init validates the service perimeter.
+func (sp *ServicePerimeter) Init(config *Config) error {
+	if sp.AccessPolicyID == "" {
+		return errors.New("access_policy_id must be set")
+	}
+	if !perimeterNameRE.MatchString(sp.Name) {
+		return fmt.Errorf("name %q must start with a letter and only contain letters, digits and underscores", sp.Name)
+	}
+	if sp.ProjectID == "" && config.AuditLogsProject != nil {
+		sp.ProjectID = config.AuditLogsProject.ID
+	}
+	if sp.ProjectID == "" {
+		return errors.New("project_id must be set when there is no remote audit logs project")
+	}
+	return nil
+}
+
+// perimeterProjects returns all projects that are part of the perimeter.
+func (sp *ServicePerimeter) perimeterProjects(config *Config) []*Project {
+	projects := append([]*Project(nil), config.Projects...)
+	if config.AuditLogsProject != nil {
+		projects = append(projects, config.AuditLogsProject)
+	}
+	return projects
+}
+
+// perimeter builds the Access Context Manager perimeter.
+func (sp *ServicePerimeter) perimeter(config *Config) (*perimeter, error) {
+	var resources []string
+	services := make(map[string]bool)
+	for _, s := range alwaysRestrictedServices {
+		services[s] = true
+	}
+
+	for _, p := range sp.perimeterProjects(config) {
+		if p.GeneratedFields.ProjectNumber == "" {
+			return nil, fmt.Errorf("project number of %q not found in generated_fields, deploy the project first", p.ID)
+		}
+		resources = append(resources, "projects/"+p.GeneratedFields.ProjectNumber)
+		for _, api := range p.EnabledAPIs {
+			if perimeterSupportedServices[api] {
+				services[api] = true
+			}
+		}
+	}
+
+	restricted := make([]string, 0, len(services))
+	for s := range services {
+		restricted = append(restricted, s)
+	}
+	sort.Strings(restricted)
+
+	parent := "accessPolicies/" + sp.AccessPolicyID
+	pc := &perimeterConfig{Resources: resources, RestrictedServices: restricted}
+	p := &perimeter{
+		Parent:        parent,
+		Name:          fmt.Sprintf("%s/servicePerimeters/%s", parent, sp.Name),
+		Title:         sp.Name,
+		PerimeterType: "PERIMETER_TYPE_REGULAR",
+	}
+	if sp.DryRun {
+		p.Spec = pc
+		p.UseExplicitDryRunSpec = true
+	} else {
+		p.Status = pc
+	}
+	return p, nil
+}
+
+// getServicePerimeterDeployment gets the deployment of the service perimeter defined in the config.
+func getServicePerimeterDeployment(config *Config) (*Deployment, error) {
+	p, err := config.ServicePerimeter.perimeter(config)
+	if err != nil {
+		return nil, err
+	}
+	props := make(map[string]interface{})
+	if err := convertJSON(p, &props); err != nil {
+		return nil, err
+	}
+	return &Deployment{
+		Resources: []*Resource{{
+			Name:       config.ServicePerimeter.Name,
+			Type:       "gcp-types/accesscontextmanager-v1:accessPolicies.servicePerimeters",
+			Properties: props,
+		}},
+	}, nil
+}
+
+// DeployServicePerimeter deploys the service perimeter defined in the config.
+func DeployServicePerimeter(config *Config) error {
+	if config.ServicePerimeter == nil {
+		return errors.New("service_perimeter is not set in the config")
+	}
+	deployment, err := getServicePerimeterDeployment(config)
+	if err != nil {
+		return fmt.Errorf("failed to get service perimeter deployment: %v", err)
+	}
+	if err := createOrUpdateDeployment(servicePerimeterDeploymentName, config.ServicePerimeter.ProjectID, deployment); err != nil {
+		return fmt.Errorf("failed to deploy service perimeter: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package cft
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ghodss/yaml"
+)
+
+func TestServicePerimeterDeployment(t *testing.T) {
+	tests := []struct {
+		name      string
+		perimeter string
+		want      string
+	}{
+		{
+			name: "enforced",
+			perimeter: `
+access_policy_id: '555'
+name: my_perimeter
+project_id: my-project`,
+			want: `
+resources:
+- name: my_perimeter
+  type: gcp-types/accesscontextmanager-v1:accessPolicies.servicePerimeters
+  properties:
+    parent: accessPolicies/555
+    name: accessPolicies/555/servicePerimeters/my_perimeter
+    title: my_perimeter
+    perimeterType: PERIMETER_TYPE_REGULAR
+    status:
+      resources:
+      - projects/1111
+      restrictedServices:
+      - bigquery.googleapis.com
+      - healthcare.googleapis.com
+      - pubsub.googleapis.com
+      - storage.googleapis.com`,
+		},
+		{
+			name: "dry_run",
+			perimeter: `
+access_policy_id: '555'
+name: my_perimeter
+project_id: my-project
+dry_run: true`,
+			want: `
+resources:
+- name: my_perimeter
+  type: gcp-types/accesscontextmanager-v1:accessPolicies.servicePerimeters
+  properties:
+    parent: accessPolicies/555
+    name: accessPolicies/555/servicePerimeters/my_perimeter
+    title: my_perimeter
+    perimeterType: PERIMETER_TYPE_REGULAR
+    useExplicitDryRunSpec: true
+    spec:
+      resources:
+      - projects/1111
+      restrictedServices:
+      - bigquery.googleapis.com
+      - healthcare.googleapis.com
+      - pubsub.googleapis.com
+      - storage.googleapis.com`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			config, project := getTestConfigAndProject(t, nil)
+			project.EnabledAPIs = []string{"pubsub.googleapis.com", "unsupported.googleapis.com"}
+
+			config.ServicePerimeter = new(ServicePerimeter)
+			if err := yaml.Unmarshal([]byte(tc.perimeter), config.ServicePerimeter); err != nil {
+				t.Fatalf("yaml.Unmarshal: %v", err)
+			}
+			if err := config.ServicePerimeter.Init(config); err != nil {
+				t.Fatalf("config.ServicePerimeter.Init: %v", err)
+			}
+
+			got, err := getServicePerimeterDeployment(config)
+			if err != nil {
+				t.Fatalf("getServicePerimeterDeployment: %v", err)
+			}
+
+			want := new(Deployment)
+			if err := yaml.Unmarshal([]byte(tc.want), want); err != nil {
+				t.Fatalf("yaml.Unmarshal: %v", err)
+			}
+
+			if diff := cmp.Diff(got, want); diff != "" {
+				t.Errorf("deployment differs (-got +want):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestServicePerimeterErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		perimeter string
+	}{
+		{
+			name: "missing_access_policy",
+			perimeter: `
+name: my_perimeter
+project_id: my-project`,
+		},
+		{
+			name: "invalid_name",
+			perimeter: `
+access_policy_id: '555'
+name: my-perimeter
+project_id: my-project`,
+		},
+		{
+			name: "missing_project",
+			perimeter: `
+access_policy_id: '555'
+name: my_perimeter`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			config, _ := getTestConfigAndProject(t, nil)
+			sp := new(ServicePerimeter)
+			if err := yaml.Unmarshal([]byte(tc.perimeter), sp); err != nil {
+				t.Fatalf("yaml.Unmarshal: %v", err)
+			}
+			if err := sp.Init(config); err == nil {
+				t.Fatalf("sp.Init: got nil error, want non-nil error")
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package cft
+
+import (
+	"errors"
+)
+
+// Subnetwork wraps a subnetwork of a VPC network.
+type Subnetwork struct {
+	SubnetworkProperties `json:"properties"`
+	dependsOn            []string
+}
+
+// SubnetworkProperties represents a partial subnetwork implementation.
+type SubnetworkProperties struct {
+	SubnetworkName        string `json:"name"`
+	Network               string `json:"network"`
+	Region                string `json:"region"`
+	IPCIDRRange           string `json:"ipCidrRange"`
+	PrivateIPGoogleAccess *bool  `json:"privateIpGoogleAccess"`
+	EnableFlowLogs        *bool  `json:"enableFlowLogs"`
+}
+
+func init() {
+	RegisterResourceKind(ResourceKind{
+		Key:      "subnetwork",
+		New:      func() ParsedResource { return new(Subnetwork) },
+		Location: func(r ParsedResource) string { return r.(*Subnetwork).Region },
+	})
+}
+
+// Init initializes the subnetwork with the given project.
+func (s *Subnetwork) Init(*Project) error {
+	if s.Name() == "" {
+		return errors.New("name must be set")
+	}
+	if s.Network == "" {
+		return errors.New("network must be set")
+	}
+	if s.Region == "" {
+		return errors.New("region must be set")
+	}
+	if s.IPCIDRRange == "" {
+		return errors.New("ipCidrRange must be set")
+	}
+	if s.PrivateIPGoogleAccess != nil && !*s.PrivateIPGoogleAccess {
+		return errors.New("privateIpGoogleAccess must not be disabled")
+	}
+	if s.EnableFlowLogs != nil && !*s.EnableFlowLogs {
+		return errors.New("enableFlowLogs must not be disabled")
+	}
+
+	t := true
+	s.PrivateIPGoogleAccess = &t
+	s.EnableFlowLogs = &t
+	return nil
+}
+
+// Name returns the name of the subnetwork.
+func (s *Subnetwork) Name() string {
+	return s.SubnetworkName
+}
+
+// TemplatePath returns the name of the template to use for the subnetwork.
+func (s *Subnetwork) TemplatePath() string {
+	return "deploy/cft/templates/subnetwork.py"
+}
+
+// DependsOn returns the names of the resources the subnetwork depends on.
+func (s *Subnetwork) DependsOn() []string {
+	return s.dependsOn
+}
@@ -0,0 +1,186 @@
+package cft
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	cfttemplates "github.com/GoogleCloudPlatform/healthcare/deploy/cft/templates"
+	"github.com/GoogleCloudPlatform/healthcare/deploy/templates"
+)
+
+// builtinTemplates maps the directories of the templates referenced by TemplatePath to the bundled templates.
+var builtinTemplates = map[string]map[string][]byte{
+	"deploy/cft/templates": cfttemplates.Files,
+	"deploy/templates":     templates.Files,
+}
+
+// templateResolver resolves the templates of a deployment to files and records their hashes.
+// Templates are looked up by base name in the search path before falling back to the built-in templates.
+type templateResolver struct {
+	dirs []string
+
+	// builtinDir is the directory the built-in templates are written to when first used.
+	builtinDir string
+
+	// hashes maps the resolved templates to the SHA-256 hashes of their content.
+	hashes map[string]string
+}
+
+func newTemplateResolver(dirs []string) *templateResolver {
+	return &templateResolver{dirs: dirs, hashes: make(map[string]string)}
+}
+
+// resolve returns the absolute path of the template to use for the given template path.
+// If override is set, it is used as is.
+func (r *templateResolver) resolve(templatePath, override string) (string, error) {
+	path, err := r.find(templatePath, override)
+	if err != nil {
+		return "", err
+	}
+	if path, err = filepath.Abs(path); err != nil {
+		return "", fmt.Errorf("failed to get absolute path for %q: %v", path, err)
+	}
+	if _, ok := r.hashes[path]; !ok {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read template %q: %v", path, err)
+		}
+		r.hashes[path] = fmt.Sprintf("%x", sha256.Sum256(b))
+	}
+	return path, nil
+}
+
+func (r *templateResolver) find(templatePath, override string) (string, error) {
+	if override != "" {
+		if !fileExists(override) {
+			return "", fmt.Errorf("template %q not found", override)
+		}
+		return override, nil
+	}
+
+	for _, dir := range r.dirs {
+		if path := filepath.Join(dir, filepath.Base(templatePath)); fileExists(path) {
+			return path, nil
+		}
+	}
+
+	dir := filepath.Dir(templatePath)
+	if files, ok := builtinTemplates[dir]; ok {
+		if _, ok := files[filepath.Base(templatePath)]; ok {
+			outDir, err := r.extractBuiltins(dir, files)
+			if err != nil {
+				return "", err
+			}
+			return filepath.Join(outDir, filepath.Base(templatePath)), nil
+		}
+	}
+
+	// Templates of resource kinds registered outside of this package may be given as paths.
+	if fileExists(templatePath) {
+		return templatePath, nil
+	}
+	return "", fmt.Errorf("template %q not found in search path %v or built-in templates", templatePath, r.dirs)
+}
+
+// extractBuiltins writes the built-in templates of the given directory, including their schemas,
+// to the builtin directory and returns the directory they were written to.
+func (r *templateResolver) extractBuiltins(dir string, files map[string][]byte) (string, error) {
+	if r.builtinDir == "" {
+		tmp, err := ioutil.TempDir("", "templates")
+		if err != nil {
+			return "", fmt.Errorf("failed to create temp dir: %v", err)
+		}
+		r.builtinDir = tmp
+	}
+	outDir := filepath.Join(r.builtinDir, dir)
+	if fileExists(outDir) {
+		return outDir, nil
+	}
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create dir for built-in templates: %v", err)
+	}
+	for name, b := range files {
+		if err := ioutil.WriteFile(filepath.Join(outDir, name), b, 0644); err != nil {
+			return "", fmt.Errorf("failed to write built-in template %q: %v", name, err)
+		}
+	}
+	return outDir, nil
+}
+
+// outputs returns the hashes of the resolved templates as deployment outputs, keyed by the template base names.
+// Deployment Manager requires the base names of imports to be unique.
+// The hashes record which template versions a deployment used. They are not verified before deploying, but
+// changedTemplates reports the templates whose hashes differ from the previous deployment.
+func (r *templateResolver) outputs() []*Output {
+	var outputs []*Output
+	for path, hash := range r.hashes {
+		outputs = append(outputs, &Output{Name: templateHashOutputPrefix + filepath.Base(path), Value: hash})
+	}
+	sort.Slice(outputs, func(i, j int) bool { return outputs[i].Name < outputs[j].Name })
+	return outputs
+}
+
+// cleanup removes the built-in templates written by the resolver.
+func (r *templateResolver) cleanup() error {
+	if r.builtinDir == "" {
+		return nil
+	}
+	return os.RemoveAll(r.builtinDir)
+}
+
+// changedTemplates returns the base names of the templates whose hashes differ between the previous and current
+// deployment, including templates added or removed.
+func changedTemplates(previous, current *Deployment) []string {
+	hashes := func(d *Deployment) map[string]string {
+		m := make(map[string]string)
+		if d == nil {
+			return m
+		}
+		for _, o := range d.Outputs {
+			if strings.HasPrefix(o.Name, templateHashOutputPrefix) {
+				m[strings.TrimPrefix(o.Name, templateHashOutputPrefix)] = o.Value
+			}
+		}
+		return m
+	}
+	prev, cur := hashes(previous), hashes(current)
+
+	var changed []string
+	for name, h := range cur {
+		if prev[name] != h {
+			changed = append(changed, name)
+		}
+	}
+	for name := range prev {
+		if _, ok := cur[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// logTemplateChanges logs the templates that changed since the previous deployment, so that template updates
+// applied by a deployment are visible to the operator.
+func logTemplateChanges(previous, current *Deployment) {
+	if previous == nil {
+		return
+	}
+	for _, name := range changedTemplates(previous, current) {
+		log.Printf("Template %q changed since the previous deployment", name)
+	}
+}
+
+// templateHashOutputPrefix prefixes the names of outputs that record the SHA-256 hash of a template.
+const templateHashOutputPrefix = "template-sha256-"
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
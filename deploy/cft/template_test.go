@@ -0,0 +1,187 @@
+package cft
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTemplateResolver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	customBucket := filepath.Join(dir, "gcs_bucket.py")
+	override := filepath.Join(dir, "my_network.py")
+	for _, path := range []string{customBucket, override} {
+		if err := ioutil.WriteFile(path, []byte("# "+filepath.Base(path)), 0644); err != nil {
+			t.Fatalf("ioutil.WriteFile: %v", err)
+		}
+	}
+
+	r := newTemplateResolver([]string{filepath.Join(dir, "dne"), dir})
+	defer r.cleanup()
+
+	got, err := r.resolve("deploy/cft/templates/gcs_bucket.py", "")
+	if err != nil {
+		t.Fatalf("resolve search path template: %v", err)
+	}
+	if got != customBucket {
+		t.Errorf("resolve search path template: got %q, want %q", got, customBucket)
+	}
+
+	got, err = r.resolve("deploy/cft/templates/network.py", override)
+	if err != nil {
+		t.Fatalf("resolve overridden template: %v", err)
+	}
+	if got != override {
+		t.Errorf("resolve overridden template: got %q, want %q", got, override)
+	}
+
+	got, err = r.resolve("deploy/cft/templates/pubsub.py", "")
+	if err != nil {
+		t.Fatalf("resolve built-in template: %v", err)
+	}
+	b, err := ioutil.ReadFile(got)
+	if err != nil {
+		t.Fatalf("read built-in template: %v", err)
+	}
+	want, err := ioutil.ReadFile("templates/pubsub.py")
+	if err != nil {
+		t.Fatalf("read template: %v", err)
+	}
+	if string(b) != string(want) {
+		t.Errorf("built-in template %q differs from templates/pubsub.py", got)
+	}
+	if _, err := os.Stat(got + ".schema"); err != nil {
+		t.Errorf("schema of built-in template not found: %v", err)
+	}
+
+	if _, err := r.resolve("deploy/cft/templates/dne.py", ""); err == nil {
+		t.Errorf("resolve missing template: got nil error, want non-nil error")
+	}
+	if _, err := r.resolve("deploy/cft/templates/network.py", filepath.Join(dir, "dne.py")); err == nil {
+		t.Errorf("resolve missing override: got nil error, want non-nil error")
+	}
+
+	hash := func(b []byte) string { return fmt.Sprintf("%x", sha256.Sum256(b)) }
+	wantOutputs := []*Output{
+		{Name: "template-sha256-gcs_bucket.py", Value: hash([]byte("# gcs_bucket.py"))},
+		{Name: "template-sha256-my_network.py", Value: hash([]byte("# my_network.py"))},
+		{Name: "template-sha256-pubsub.py", Value: hash(want)},
+	}
+	if diff := cmp.Diff(r.outputs(), wantOutputs); diff != "" {
+		t.Errorf("outputs differ (-got +want):\n%v", diff)
+	}
+
+	builtinDir := r.builtinDir
+	if err := r.cleanup(); err != nil {
+		t.Fatalf("cleanup: %v", err)
+	}
+	if _, err := os.Stat(builtinDir); !os.IsNotExist(err) {
+		t.Errorf("built-in templates dir %q still exists after cleanup", builtinDir)
+	}
+}
+
+func TestLoadConfigTemplates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"project.yaml": `
+overall:
+  template_dirs:
+  - templates
+projects:
+- project_id: my-project
+  audit_logs:
+    logs_gcs_bucket:
+      location: US
+    logs_bigquery_dataset:
+      location: US
+  resources:
+  - network:
+      template: custom/my_network.py
+      properties:
+        name: foo-network
+  - firewall:
+      properties:
+        name: foo-firewall
+        network: foo-network
+  - pubsub:
+      properties:
+        topic: foo-topic
+        messageStoragePolicy:
+          allowedPersistenceRegions:
+          - us-central1`,
+		"custom/my_network.py":  "# network",
+		"templates/firewall.py": "# firewall",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("os.MkdirAll: %v", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("ioutil.WriteFile: %v", err)
+		}
+	}
+
+	config, err := LoadConfig(filepath.Join(dir, "project.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	project := config.Projects[0]
+	if err := project.Init(); err != nil {
+		t.Fatalf("project.Init: %v", err)
+	}
+
+	r := newTemplateResolver(project.TemplateDirs)
+	defer r.cleanup()
+	deployment, err := getDeployment(project, project.resourcePairs(), r)
+	if err != nil {
+		t.Fatalf("getDeployment: %v", err)
+	}
+
+	var got []string
+	for _, res := range deployment.Resources {
+		got = append(got, res.Type)
+	}
+	want := []string{
+		filepath.Join(dir, "custom/my_network.py"),
+		filepath.Join(dir, "templates/firewall.py"),
+		filepath.Join(r.builtinDir, "deploy/cft/templates/pubsub.py"),
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("resource types differ (-got +want):\n%v", diff)
+	}
+}
+
+func TestChangedTemplates(t *testing.T) {
+	previous := &Deployment{Outputs: []*Output{
+		{Name: "template-sha256-gcs_bucket.py", Value: "a"},
+		{Name: "template-sha256-network.py", Value: "b"},
+		{Name: "template-sha256-pubsub.py", Value: "c"},
+	}}
+	current := &Deployment{Outputs: []*Output{
+		{Name: "template-sha256-firewall.py", Value: "d"},
+		{Name: "template-sha256-gcs_bucket.py", Value: "a"},
+		{Name: "template-sha256-network.py", Value: "e"},
+	}}
+
+	got := changedTemplates(previous, current)
+	want := []string{"firewall.py", "network.py", "pubsub.py"}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("changed templates differ (-got +want):\n%v", diff)
+	}
+}
@@ -36,13 +36,20 @@ def generate_config(context):
 
   optional_props = [
       'location', 'versioning', 'storageClass', 'predefinedAcl',
-      'predefinedDefaultObjectAcl', 'logging', 'lifecycle', 'labels', 'website'
+      'predefinedDefaultObjectAcl', 'logging', 'lifecycle', 'labels', 'website',
+      'iamConfiguration'
   ]
 
   for prop in optional_props:
     if prop in context.properties:
       bucket['properties'][prop] = context.properties[prop]
 
+  # ACLs can't be set on buckets with uniform bucket-level access.
+  iam_configuration = context.properties.get('iamConfiguration', {})
+  if iam_configuration.get('uniformBucketLevelAccess', {}).get('enabled'):
+    bucket['properties'].pop('predefinedAcl', None)
+    bucket['properties'].pop('predefinedDefaultObjectAcl', None)
+
   resources.append(bucket)
 
   # If IAM policy bindings are defined, apply these bindings.
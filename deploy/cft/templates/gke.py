@@ -32,7 +32,8 @@ def generate_config(context):
                 'cluster':
                     {
                         'name':
-                            name + '-cluster',
+                            name + properties.get('clusterNameSuffix',
+                                                  '-cluster'),
                         'initialNodeCount':
                             propc.get('initialNodeCount'),
                         'initialClusterVersion':
@@ -82,11 +83,14 @@ def generate_config(context):
         'legacyAbac',
         'networkPolicy',
         'ipAllocationPolicy',
-        'masterAuthorizedNetworksConfig'
+        'masterAuthorizedNetworksConfig',
         'maintenancePolicy',
         'podSecurityPolicyConfig',
         'privateCluster',
-        'masterIpv4CidrBlock'
+        'masterIpv4CidrBlock',
+        'privateClusterConfig',
+        'workloadIdentityConfig',
+        'shieldedNodes'
     ]
 
     cluster_props = gke_cluster['properties']['cluster']
@@ -43,18 +43,37 @@ def create_boot_disk(properties, zone, instance_name):
 
     return boot_disk
 
+def get_network_url(network_name):
+    """ Get the URL of a network given by its name or URL. """
+
+    if not '.' in network_name and not '/' in network_name:
+        network_name = 'global/networks/{}'.format(network_name)
+    return network_name
+
+def get_network_interfaces(properties):
+    """ Get the network interfaces of the instance. If networkInterfaces is
+        not set, a single interface is built from the network properties.
+    """
+
+    if 'networkInterfaces' not in properties:
+        return [get_network(properties)]
+
+    network_interfaces = []
+    for network_interface in properties['networkInterfaces']:
+        network_interface = dict(network_interface)
+        if 'network' in network_interface:
+            network_interface['network'] = get_network_url(
+                network_interface['network'])
+        network_interfaces.append(network_interface)
+    return network_interfaces
+
 def get_network(properties):
     """ Get the configuration that connects the instance to an existing network
         and assigns to it an ephemeral public IP.
     """
 
-    network_name = properties.get('network')
-
-    if not '.' in network_name and not '/' in network_name:
-        network_name = 'global/networks/{}'.format(network_name)
-
     network_interfaces = {
-        'network': network_name,
+        'network': get_network_url(properties['network']),
     }
 
     if properties['hasExternalIp']:
@@ -84,7 +103,7 @@ def generate_config(context):
     machine_type = context.properties['machineType']
 
     boot_disk = create_boot_disk(context.properties, zone, vm_name)
-    network = get_network(context.properties)
+    network_interfaces = get_network_interfaces(context.properties)
     instance = {
         'name': vm_name,
         'type': 'compute.v1.instance',
@@ -93,13 +112,22 @@ def generate_config(context):
             'machineType': 'zones/{}/machineTypes/{}'.format(zone,
                                                              machine_type),
             'disks': [boot_disk],
-            'networkInterfaces': [network]
+            'networkInterfaces': network_interfaces
         }
     }
 
-    for name in ['metadata', 'serviceAccounts', 'canIpForward', 'tags']:
+    for name in ['metadata', 'serviceAccounts', 'canIpForward', 'tags',
+                 'shieldedInstanceConfig', 'labels']:
         set_optional_property(instance['properties'], context.properties, name)
 
+    access_control = context.properties.get('accessControl')
+    if access_control is not None:
+        instance['accessControl'] = {
+            'gcpIamPolicy': {
+                'bindings': access_control
+            }
+        }
+
     outputs = [
         {
             'name': 'internalIp',
@@ -115,7 +143,7 @@ def generate_config(context):
         }
     ]
 
-    if context.properties['hasExternalIp']:
+    if 'accessConfigs' in network_interfaces[0]:
         outputs.append(
             {
                 'name': 'externalIp',
@@ -0,0 +1,50 @@
+# Copyright 2018 Google Inc. All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+""" This template creates a VPC network. """
+
+
+def generate_config(context):
+    """ Entry point for the deployment resources. """
+
+    properties = context.properties
+    resource_name = context.env['name']
+    name = properties.get('name', resource_name)
+
+    network = {
+        'name': resource_name,
+        'type': 'compute.v1.network',
+        'properties': {
+            'name': name,
+            'autoCreateSubnetworks': properties.get('autoCreateSubnetworks',
+                                                    False)
+        }
+    }
+
+    optional_props = ['description', 'routingConfig']
+    for prop in optional_props:
+        if prop in properties:
+            network['properties'][prop] = properties[prop]
+
+    outputs = [
+        {
+            'name': 'name',
+            'value': name
+        },
+        {
+            'name': 'selfLink',
+            'value': '$(ref.{}.selfLink)'.format(resource_name)
+        }
+    ]
+
+    return {'resources': [network], 'outputs': outputs}
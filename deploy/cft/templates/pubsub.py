@@ -35,6 +35,10 @@ def create_subscription(resource_name, spec, topic_resource_name, spec_index):
     if ack_deadline_seconds is not None:
         subscription['properties']['ackDeadlineSeconds'] = ack_deadline_seconds
 
+    for prop in ['deadLetterPolicy', 'retryPolicy']:
+        if prop in spec:
+            subscription['properties'][prop] = spec[prop]
+
     set_access_control(subscription, spec)
 
     return subscription
@@ -68,6 +72,10 @@ def create_pubsub(resource_name, pubsub_spec):
         }
     }
 
+    message_storage_policy = pubsub_spec.get('messageStoragePolicy')
+    if message_storage_policy is not None:
+        topic['properties']['messageStoragePolicy'] = message_storage_policy
+
     set_access_control(topic, pubsub_spec)
 
     subscription_specs = pubsub_spec.get('subscriptions', [])
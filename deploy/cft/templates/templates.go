@@ -0,0 +1,5 @@
+// Package templates bundles the CFT templates so that the deployer does not depend on the working directory.
+// The contents of the templates are generated into templates_data.go, which must be regenerated when they change.
+package templates
+
+//go:generate go run ../../cmd/embed_templates/embed_templates.go *.py *.py.schema
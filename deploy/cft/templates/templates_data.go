@@ -0,0 +1,23 @@
+// Code generated by embed_templates. DO NOT EDIT.
+
+package templates
+
+// Files maps the base names of the templates and their schemas to their contents.
+var Files = map[string][]byte{
+	"bigquery_dataset.py":        []byte("# Copyright 2018 Google Inc. All rights reserved.\n#\n# Licensed under the Apache License, Version 2.0 (the \"License\");\n# you may not use this file except in compliance with the License.\n# You may obtain a copy of the License at\n#\n#     http://www.apache.org/licenses/LICENSE-2.0\n#\n# Unless required by applicable law or agreed to in writing, software\n# distributed under the License is distributed on an \"AS IS\" BASIS,\n# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.\n# See the License for the specific language governing permissions and\n# limitations under the License.\n\"\"\" This template creates a BigQuery dataset. \"\"\"\n\n\ndef generate_config(context):\n    \"\"\" Entry point for the deployment resources. \"\"\"\n\n    # You can modify the roles you wish to whitelist.\n    whitelisted_roles = ['READER', 'WRITER', 'OWNER']\n\n    name = context.properties['name']\n\n    properties = {\n        'datasetReference':\n            {\n                'datasetId': name,\n                'projectId': context.env['project']\n            },\n        'location': context.properties['location']\n    }\n\n    optional_properties = ['description', 'defaultTableExpirationMs']\n\n    for prop in optional_properties:\n        if prop in context.properties:\n            properties[prop] = context.properties[prop]\n\n    if 'access' in context.properties:\n        # Validate access roles.\n        for access_role in context.properties['access']:\n            if 'role' in access_role:\n                role = access_role['role']\n                if role not in whitelisted_roles:\n                    raise ValueError(\n                        'Role supplied \\\"{}\\\" for dataset \\\"{}\\\" not '\n                        ' within the whitelist: {} '.format(\n                            role,\n                            context.properties['name'],\n                            whitelisted_roles\n                        )\n                    )\n\n        properties['access'] = context.properties['access']\n\n        if context.properties.get('setDefaultOwner', False):\n            # Build the default owner for the dataset.\n            base = '@cloudservices.gserviceaccount.com'\n            default_dataset_owner = context.env['project_number'] + base\n\n            # Build the default access for the owner.\n            owner_access = {\n                'role': 'OWNER',\n                'userByEmail': default_dataset_owner\n            }\n            properties['access'].append(owner_access)\n\n    resources = [\n        {\n            'type': 'bigquery.v2.dataset',\n            'name': name,\n            'properties': properties\n        }\n    ]\n\n    outputs = [\n        {\n            'name': 'selfLink',\n            'value': '$(ref.{}.selfLink)'.format(name)\n        },\n        {\n            'name': 'datasetId',\n            'value': name\n        },\n        {\n            'name': 'etag',\n            'value': '$(ref.{}.etag)'.format(name)\n        },\n        {\n            'name': 'creationTime',\n            'value': '$(ref.{}.creationTime)'.format(name)\n        },\n        {\n            'name': 'lastModifiedTime',\n            'value': '$(ref.{}.lastModifiedTime)'.format(name)\n        }\n    ]\n\n    return {'resources': resources, 'outputs': outputs}"),
+	"bigquery_dataset.py.schema": []byte("# Copyright 2018 Google Inc. All rights reserved.\n#\n# Licensed under the Apache License, Version 2.0 (the \"License\");\n# you may not use this file except in compliance with the License.\n# You may obtain a copy of the License at\n#\n#     http://www.apache.org/licenses/LICENSE-2.0\n#\n# Unless required by applicable law or agreed to in writing, software\n# distributed under the License is distributed on an \"AS IS\" BASIS,\n# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.\n# See the License for the specific language governing permissions and\n# limitations under the License.\n\ninfo:\n  title: BigQuery Dataset\n  author: Sourced Group Inc.\n  description: |\n    Creates a BigQuery dataset.\n    For information on this resource:\n    https://cloud.google.com/bigquery/docs/.\n\nimports:\n  - path: bigquery_dataset.py\n\nrequired:\n  - name\n\nproperties:\n  name:\n    type: string\n    description: The resource name.\n  location:\n    type: string\n    description: |\n      The geographic location where the dataset resides.\n      The default value is US. See details at\n      https://cloud.google.com/bigquery/docs/dataset-locations.\n    default: 'US'\n    enum:\n      - Asia\n      - EU\n      - US\n  access:\n    type: array\n    description: |\n      An array of objects that define dataset access for one or more\n      entities. You can set this property when inserting or updating\n      the dataset to control who is allowed to access the data. If not \n      specified at the dataset creation time, BigQuery defines default\n      dataset access for the following entities:\n        access.specialGroup: projectReaders; access.role: READER\n        access.specialGroup: projectWriters; access.role: WRITER\n        access.specialGroup: projectOwners; access.role: OWNER\n        access.userByEmail: [dataset creator email]; access.role: OWNER\n    items:\n      role:\n        type: string\n        description: |\n          The role (rights) granted to the user specified by the other\n          member of the access object. The following string values are\n          supported: READER, WRITER, OWNER. See details at \n          https://cloud.google.com/bigquery/docs/access-control.\n        enum:\n          - READER\n          - WRITER\n          - OWNER\n      oneOf:\n        - domain:\n          type: string\n          description: |\n            The domain to grant access to. All users signed in with the \n            specified domain are granted the corresponding access.\n            Example: \"example.com\".\n        - userByEmail:\n          type: string\n          description: |\n            The email address of a user to grant access to. For example:\n            fred@example.com.\n        - groupByEmail:\n          type: string\n          description: The email address of a Google Group to grant access to.\n        - specialGroup:\n          type: string\n          description: |\n            The special group to grant access to. Possible values include:\n              projectOwners: owners of the enclosing project\n              projectReaders: readers of the enclosing project\n              projectWriters: writers of the enclosing project\n              allAuthenticatedUsers: all authenticated BigQuery users\n        - view:\n          type: object\n          description: |\n            A view from a different dataset to grant access to. Queries\n            executed against that view have the Read access to tables in that\n            dataset. The Role value is not required when this field is set. If\n            the view is updated, access to that view must be granted again \n            via an Update operation.\n          properties:\n            datasetId:\n              type: string\n              description: The ID of the dataset containing the table.\n            projectId:\n              type: string\n              description: The ID fo the project containing the table.\n            tableId:\n              type: string\n              pattern: ^[0-9a-zA-Z][0-9a-zA-Z_]{4,1023}$\n              description: |\n                The table ID. The ID must contain only letters\n                (a-z, A-Z), numbers (0-9), or underscores (_). The maximum\n                length is 1,024 characters.\n  description:\n    type: string\n    description: A user-friendly description of the dataset.\n  setDefaultOwner:\n    type: boolean\n    default: False\n    description: |\n      Defines whether the default project service is granted the IAM owner \n      permissions.\n  defaultTableExpirationMs:\n    type: string\n    format: int64\n    description: |\n      The default lifetime of all tables in the dataset, in milliseconds. The\n      minimum value is 3600000 milliseconds (one hour). Once this property is\n      set, all newly-created tables in the dataset get their expirationTime\n      property set to the creation time plus the value of this property. \n      Changes to the value affect only new tables, not the existing ones. When\n      expirationTime for a given table is reached, that table is deleted \n      automatically. If a table's expirationTime is modified or\n      removed before the table expires, or if you provide an explicit\n      expirationTime while creating the table, that value takes precedence over\n      the default expiration time indicated by this property.\n    minimum: 3600000\n\noutputs:\n  properties:\n    - selfLink:\n        type: string\n        description: The URI of the created resource.\n    - etag:\n        type: string\n        description: The hash of the resource.\n    - creationTime:\n        type: string\n        description: |\n          The time when the dataset was created, in milliseconds since\n          epoch. For example, 1535739430.\n    - lastModifiedTime:\n        type: string\n        description: |\n          The time when the dataset or any of its tables was last\n          modified, in milliseconds since the epoch. For example,\n          1535739430.\n\ndocumentation:\n  - templates/bigquery/README.md\n\nexamples:\n  - templates/bigquery/examples/bigquery.yaml"),
+	"firewall.py":                []byte("# Copyright 2018 Google Inc. All rights reserved.\n#\n# Licensed under the Apache License, Version 2.0 (the \"License\");\n# you may not use this file except in compliance with the License.\n# You may obtain a copy of the License at\n#\n#     http://www.apache.org/licenses/LICENSE-2.0\n#\n# Unless required by applicable law or agreed to in writing, software\n# distributed under the License is distributed on an \"AS IS\" BASIS,\n# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.\n# See the License for the specific language governing permissions and\n# limitations under the License.\n\"\"\" This template creates firewall rules for a network. \"\"\"\n\n\ndef get_network(properties):\n    \"\"\" Gets a network name. \"\"\"\n\n    network_name = properties.get('network')\n    if network_name:\n        is_self_link = '/' in network_name or '.' in network_name\n\n        if is_self_link:\n            network_url = network_name\n        else:\n            network_url = 'global/networks/{}'.format(network_name)\n\n    return network_url\n\n\ndef generate_config(context):\n    \"\"\" Entry point for the deployment resources. \"\"\"\n\n    network = context.properties.get('network')\n\n    resources = []\n    out = {}\n    for i, rule in enumerate(context.properties['rules'], 1000):\n        # Use VPC if specified in the properties. Otherwise, specify\n        # the network URL in the config. If the network is not specified in\n        # the config, the API defaults to 'global/networks/default'.\n        if network and not rule.get('network'):\n            rule['network'] = get_network(context.properties)\n\n        rule['priority'] = rule.get('priority', i)\n        resources.append(\n            {\n                'name': rule['name'],\n                'type': 'compute.beta.firewall',\n                'properties': rule\n            }\n        )\n\n        out[rule['name']] = {\n            'selfLink': '$(ref.' + rule['name'] + '.selfLink)',\n            'creationTimestamp': '$(ref.' + rule['name']\n                                 + '.creationTimestamp)',\n        }\n\n    outputs = [{'name': 'rules', 'value': out}]\n\n    return {'resources': resources, 'outputs': outputs}"),
+	"firewall.py.schema":         []byte("# Copyright 2018 Google Inc. All rights reserved.\n#\n# Licensed under the Apache License, Version 2.0 (the \"License\");\n# you may not use this file except in compliance with the License.\n# You may obtain a copy of the License at\n#\n#     http://www.apache.org/licenses/LICENSE-2.0\n#\n# Unless required by applicable law or agreed to in writing, software\n# distributed under the License is distributed on an \"AS IS\" BASIS,\n# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.\n# See the License for the specific language governing permissions and\n# limitations under the License.\n\ninfo:\n  title: Firewall\n  author: Sourced Group Inc.\n  description: Deploys firewall rules\n\nrequired:\n  - rules\n\nproperties:\n  name:\n    type: string\n    description: |\n      The (optional) firewall name. This is only for documentation purposes and\n      is not sent to Deployment Manager.\n  network:\n    type: string\n    description: |\n      The network name. Defaults to 'global/networks/default'.\n  rules:\n    type: array\n    description: |\n      An array of firewall rules as defined in the documentation:\n      https://cloud.google.com/compute/docs/reference/rest/beta/firewalls.\n\n      If the 'priority' field value is set in a rule, that value is used \"as is\".\n      If the 'priority' field value is not set in the rule, the template sets\n      the priority to the same value as the rule's index in the array +1000.\n      For example, the priority for the first rule in the array becomes '1000', \n      for the second rule '1001', and so on. If the 'priority' field is not set in \n      any of the rules in the array, the ruleset is sorted by priority automatically. \n      We strongly advise being consistent in your use of the 'priority' field: \n      either provide or skip values in all instances throughout the ruleset.\n\n      Example:\n        - name: allow-proxy-from-inside\n          allowed:\n            - IPProtocol: tcp\n              ports:\n                - \"80\"\n                - \"443\"\n          description: This rule allows connectivity to HTTP proxies.\n          direction: INGRESS\n          sourceRanges:\n            - 10.0.0.0/8\n        - name: allow-dns-from-inside\n          allowed:\n            - IPProtocol: udp\n              ports:\n                - \"53\"\n            - IPProtocol: tcp\n              ports:\n                - \"53\"\n          description: This rule allows DNS queries to Google's 8.8.8.8\n          direction: EGRESS\n          destinationRanges:\n            - 8.8.8.8/32\n\noutputs:\n  properties:\n    rules:\n      type: array\n      description: |\n        Array of firewall rule details. For example, the output can be\n        referenced as:\n        $(ref.<my-firewall>.rules.<firewall-rule-name>.selfLink)\n      items:\n        description: The name of the firewall rule resource.\n        patternProperties:\n          \".*\":\n            type: object\n            description: Details for a firewall rule resource.\n            properties:\n              selfLink:\n                type: string\n                description: The URI (SelfLink) of the firewall rule resource.\n              creationTimestamp:\n                type: string\n                description: Creation timestamp in RFC3339 text format.\n\ndocumentation:\n  - templates/firewall/README.md\n\nexamples:\n  - templates/firewall/examples/firewall.yaml"),
+	"gcs_bucket.py":              []byte("# Copyright 2018 Google Inc. All rights reserved.\n#\n# Licensed under the Apache License, Version 2.0 (the \"License\");\n# you may not use this file except in compliance with the License.\n# You may obtain a copy of the License at\n#\n#     http://www.apache.org/licenses/LICENSE-2.0\n#\n# Unless required by applicable law or agreed to in writing, software\n# distributed under the License is distributed on an \"AS IS\" BASIS,\n# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.\n# See the License for the specific language governing permissions and\n# limitations under the License.\n\"\"\" This template creates a Google Cloud Storage bucket. \"\"\"\n\n\ndef generate_config(context):\n  \"\"\" Entry point for the deployment resources. \"\"\"\n\n  resources = []\n  project_id = context.env['project']\n  bucket_name = context.properties.get('name', context.env['name'])\n\n  # output variables\n  bucket_selflink = '$(ref.{}.selfLink)'.format(bucket_name)\n  bucket_uri = 'gs://' + bucket_name + '/'\n\n  bucket = {\n      'name': bucket_name,\n      'type': 'storage.v1.bucket',\n      'properties': {\n          'project': project_id,\n          'name': bucket_name\n      }\n  }\n\n  optional_props = [\n      'location', 'versioning', 'storageClass', 'predefinedAcl',\n      'predefinedDefaultObjectAcl', 'logging', 'lifecycle', 'labels', 'website',\n      'iamConfiguration'\n  ]\n\n  for prop in optional_props:\n    if prop in context.properties:\n      bucket['properties'][prop] = context.properties[prop]\n\n  # ACLs can't be set on buckets with uniform bucket-level access.\n  iam_configuration = context.properties.get('iamConfiguration', {})\n  if iam_configuration.get('uniformBucketLevelAccess', {}).get('enabled'):\n    bucket['properties'].pop('predefinedAcl', None)\n    bucket['properties'].pop('predefinedDefaultObjectAcl', None)\n\n  resources.append(bucket)\n\n  # If IAM policy bindings are defined, apply these bindings.\n  storage_provider_type = 'gcp-types/storage-v1:storage.buckets.setIamPolicy'\n  bindings = context.properties.get('bindings', [])\n  if bindings:\n    iam_policy = {\n        'name': bucket_name + '-iampolicy',\n        'action': (storage_provider_type),\n        'properties': {\n            'bucket': '$(ref.' + bucket_name + '.name)',\n            'project': project_id,\n            'bindings': bindings\n        }\n    }\n    resources.append(iam_policy)\n\n  return {\n      'resources':\n          resources,\n      'outputs': [{\n          'name': 'name',\n          'value': bucket_name\n      }, {\n          'name': 'selfLink',\n          'value': bucket_selflink\n      }, {\n          'name': 'url',\n          'value': bucket_uri\n      }]\n  }\n"),
+	"gcs_bucket.py.schema":       []byte("# Copyright 2018 Google Inc. All rights reserved.\n#\n# Licensed under the Apache License, Version 2.0 (the \"License\");\n# you may not use this file except in compliance with the License.\n# You may obtain a copy of the License at\n#\n#    http://www.apache.org/licenses/LICENSE-2.0\n#\n# Unless required by applicable law or agreed to in writing, software\n# distributed under the License is distributed on an \"AS IS\" BASIS,\n# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.\n# See the License for the specific language governing permissions and\n# limitations under the License.\n\ninfo:\n  title: Google Cloud Storage Bucket\n  author: Sourced Group Inc.\n  description: |\n    Supports creation of a Google Cloud Storage bucket.\n    For more information on this resource:\n    https://cloud.google.com/storage/docs/json_api/.\n\nimports:\n  - path: gcs_bucket.py\n\nrequired:\n  - name\n\nproperties:\n  name:\n    type: string\n    description: The name of the bucket.\n  location:\n    type: string\n    default: us-east1\n    description: The region name where the bucket is deployed.\n  storageClass:\n    type: string\n    default: STANDARD\n    description: |\n      The bucket's default storage class. Defines how objects\n      in the bucket are stored; determines the SLA and the \n      cost of storage.\n    enum:\n      - REGIONAL\n      - MULTI_REGIONAL\n      - STANDARD\n      - NEARLINE\n      - COLDLINE\n      - DURABLE_REDUCED_AVAILABILITY\n  versioning:\n    type: object\n    description: Enables/disables object versioning.\n    required:\n      - enabled\n    properties:\n      enabled:\n        type: boolean\n        description: Enables/disables object versioning.\n  predefinedAcl:\n    type: string\n    default: private\n    description: |\n      The predefined or \"canned\" ACL - an alias for a set of specific\n      ACL entries that you can use to quickly apply multiple ACL entries\n      to a bucket or object in a single operation.\n      Ref: https://cloud.google.com/storage/docs/access-control/lists.\n    enum:\n      - authenticatedRead\n      - private\n      - projectPrivate\n      - publicRead\n      - publicReadWrite\n  predefinedDefaultObjectAcl:\n    type: string\n    default: private\n    enum:\n      - authenticatedRead\n      - bucketOwnerFullControl\n      - bucketOwnerRead\n      - private\n      - projectPrivate\n      - publicRead\n    description: |\n      The predefined or \"canned\" ACL for the default object in the bucket -\n      an alias for a set of specific ACL entries that you can use to quickly\n      apply multiple ACL entries to a bucket or object in a single operation.\n      Ref: https://cloud.google.com/storage/docs/access-control/lists.\n  logging:\n    type: object\n    required:\n      - logBucket\n    properties:\n      logBucket:\n        type: string\n        description: |\n          The destination bucket where the current bucket's logs \n          must be placed.\n      logObjectPrefix:\n        type: string\n        description: The prefix for log object names.\n  bindings:\n    type: array\n    description: IAM bindings for the bucket.\n    items:\n      type: object\n      required:\n        - role\n        - members\n      properties:\n        role:\n          type: string\n          pattern: ^roles\\/\n          description: The role to assign to members.\n        members:\n          type: array\n          items:\n            type: string\n            description: |\n              The member to add the binding for. Must be in the form user|\n              group|serviceAccount:email or domain:domain.\n              Can also be one of the following special values: allUsers,\n              allAuthenticatedUsers.\n  lifecycle:\n    type: object\n    description: The storage object's lifecycle actions and conditions.\n    properties:\n      rule:\n        type: array\n        description: The lifecycle action and condition.\n        items:\n          type: object\n          required:\n            - action\n            - condition\n          properties:\n            action:\n              type: object\n              description: The action to be taken if the condition is met.\n              required:\n                - type\n              properties:\n                storageClass:\n                  type: string\n                  description: \n                    The storage class to switch on if the condition is met.\n                  enum:\n                    - NEARLINE\n                    - COLDLINE\n                type:\n                  type: string\n                  description: The action type - setStorageClass or Delete.\n                  enum:\n                    - SetStorageClass\n                    - Delete\n            condition:\n              type: object\n              description: The lifecycle condition.\n              properties:\n                age:\n                  type: number\n                  description: |\n                    The object age. Selects all objects of this age or older.\n                createdBefore:\n                  type: string\n                  description: |\n                    The date part of a date in the RFC 3339 format.\n                    For example, \"2013-01-15\".\n                matchesStorageClass:\n                  type: array\n                  description: |\n                    All objects with any of the selected storage classes.\n                  items:\n                    type: string\n                    enum:\n                      - MULTI_REGIONAL\n                      - REGIONAL\n                      - STANDARD\n                      - DURABLE_REDUCED_AVAILABILITY\n                      - NEARLINE\n                      - COLDLINE\n                isLive:\n                  type: boolean\n                  description: |\n                    Defines whether the object is live. Applies only to \n                    versioned objects.\n                numNewerVersions:\n                  type: number\n                  description: |\n                    The number of newer versions. Selects all objects with\n                    at least that many newer versions. Applies only to\n                    versioned objects. \n  labels:\n    type: object\n    description: User-provided labels in key/value pairs.\n  website:\n    type: object\n    description: |\n      The bucket's website configuration, controlling how the service behaves\n      when accessing the bucket contents as a web site.\n    properties:\n      mainPageSuffix:\n        type: string\n        description: |\n          The suffix that allows creation of index.html objects to represent\n          directory pages. If the requested object path is missing, the service\n          ensures that the trailing '/' is present, appends this suffix, and\n          attempt to retrieve the resulting object. \n      notFoundPage:\n        type: string\n        description: |\n          The named object from the bucket that the service returns as the\n          content for the 404 Not Found result if the requested object path\n          is missing, and no mainPageSuffix object is provided.\n  iamConfiguration:\n    type: object\n    description: |\n      The bucket's IAM configuration. ACLs are ignored when uniform bucket-level\n      access is enabled.\n    properties:\n      uniformBucketLevelAccess:\n        type: object\n        description: |\n          The bucket's uniform bucket-level access configuration.\n          Ref: https://cloud.google.com/storage/docs/uniform-bucket-level-access.\n        properties:\n          enabled:\n            type: boolean\n            description: |\n              If True, access to the bucket and its objects is granted by IAM\n              only.\noutputs:\n  properties:\n    - name:\n        type: string\n        description: The name of the storage bucket resource.\n    - selfLink:\n        type: string\n        description: The URI (SelfLink) of the storage bucket resource.\n    - url:\n        type: string\n        description: |\n          The base URL of the bucket in the gs://<bucket-name> format.\n\ndocumentation:\n  - templates/gcs_bucket/README.md\n\nexamples:\n  - templates/gcs_bucket/examples/gcs_bucket.yaml\n  - templates/gcs_bucket/examples/gcs_bucket_iam_bindings.yaml\n  - templates/gcs_bucket/examples/gcs_bucket_lifecycle.yaml"),
+	"gke.py":                     []byte("# Copyright 2018 Google Inc. All rights reserved.\n#\n# Licensed under the Apache License, Version 2.0 (the \"License\");\n# you may not use this file except in compliance with the License.\n# You may obtain a copy of the License at\n#\n#     http://www.apache.org/licenses/LICENSE-2.0\n#\n# Unless required by applicable law or agreed to in writing, software\n# distributed under the License is distributed on an \"AS IS\" BASIS,\n# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.\n# See the License for the specific language governing permissions and\n# limitations under the License.\n\"\"\" This template creates a Google Kubernetes Engine cluster. \"\"\"\n\n\ndef generate_config(context):\n    \"\"\" Entry point for the deployment resources. \"\"\"\n\n    resources = []\n    outputs = []\n    project_id = context.env['project']\n    properties = context.properties\n    cluster_type = properties.get('clusterLocationType')\n    propc = properties['cluster']\n    name = propc.get('name') or context.env['name']\n    gke_cluster = {\n        'name': name,\n        'type': '',\n        'properties':\n            {\n                'cluster':\n                    {\n                        'name':\n                            name + properties.get('clusterNameSuffix',\n                                                  '-cluster'),\n                        'initialNodeCount':\n                            propc.get('initialNodeCount'),\n                        'initialClusterVersion':\n                            propc.get('initialClusterVersion')\n                    }\n            }\n    }\n\n    if cluster_type == 'Regional':\n        provider = 'gcp-types/container-v1beta1:projects.locations.clusters'\n        if not properties.get('region'):\n            raise KeyError(\n                \"region is a required property for a {} Cluster.\"\n                .format(cluster_type)\n            )\n        parent = 'projects/{}/locations/{}'.format(\n            project_id,\n            properties.get('region')\n        )\n        gke_cluster['properties']['parent'] = parent\n\n    elif cluster_type == 'Zonal':\n        provider = 'container.v1.cluster'\n        if not properties.get('zone'):\n            raise KeyError(\n                \"zone is a required property for a {} Cluster.\"\n                .format(cluster_type)\n            )\n        gke_cluster['properties']['zone'] = properties.get('zone')\n\n    gke_cluster['type'] = provider\n\n    req_props = ['network', 'subnetwork']\n\n    optional_props = [\n        'description',\n        'nodeConfig',\n        'masterAuth',\n        'loggingService',\n        'monitoringService',\n        'clusterIpv4Cidr',\n        'addonsConfig',\n        'locations',\n        'enableKubernetesAlpha',\n        'resourceLabels',\n        'labelFingerprint',\n        'legacyAbac',\n        'networkPolicy',\n        'ipAllocationPolicy',\n        'masterAuthorizedNetworksConfig',\n        'maintenancePolicy',\n        'podSecurityPolicyConfig',\n        'privateCluster',\n        'masterIpv4CidrBlock',\n        'privateClusterConfig',\n        'workloadIdentityConfig',\n        'shieldedNodes'\n    ]\n\n    cluster_props = gke_cluster['properties']['cluster']\n\n    for prop in req_props:\n        cluster_props[prop] = propc.get(prop)\n        if prop not in propc:\n            raise KeyError(\n                \"{} is a required cluster property for a {} Cluster.\"\n                .format(prop,\n                        cluster_type)\n            )\n\n    for oprop in optional_props:\n        if oprop in propc:\n            cluster_props[oprop] = propc[oprop]\n\n    resources.append(gke_cluster)\n\n    # Output variables\n    output_props = [\n        'selfLink',\n        'endpoint',\n        'instanceGroupUrls',\n        'clusterCaCertificate',\n        'clientCertificate',\n        'clientKey',\n        'currentMasterVersion',\n        'currentNodeVersion',\n        'nodeIpv4CidrSize',\n        'servicesIpv4Cidr'\n    ]\n\n    for outprop in output_props:\n        output_obj = {}\n        output_obj['name'] = outprop\n        ma_props = ['clusterCaCertificate', 'clientCertificate', 'clientKey']\n        if outprop in ma_props:\n            output_obj['value'] = '$(ref.' + name + \\\n                '.masterAuth.' + outprop + ')'\n        elif outprop == 'instanceGroupUrls':\n            output_obj['value'] = '$(ref.' + name + \\\n                '.nodePools[0].' + outprop + ')'\n        else:\n            output_obj['value'] = '$(ref.' + name + '.' + outprop + ')'\n\n        outputs.append(output_obj)\n\n    return {'resources': resources, 'outputs': outputs}\n"),
+	"gke.py.schema":              []byte("# Copyright 2018 Google Inc. All rights reserved.\n#\n# Licensed under the Apache License, Version 2.0 (the \"License\");\n# you may not use this file except in compliance with the License.\n# You may obtain a copy of the License at\n#\n#    http://www.apache.org/licenses/LICENSE-2.0\n#\n# Unless required by applicable law or agreed to in writing, software\n# distributed under the License is distributed on an \"AS IS\" BASIS,\n# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.\n# See the License for the specific language governing permissions and\n# limitations under the License.\n\ninfo:\n  title: Google Kubernetes Engine (GKE)\n  author: Sourced Group Inc.\n  description: |\n    Schema for deploying a GKE cluster.\n    For more information on this resource\n    https://cloud.google.com/kubernetes-engine/docs\n\nimports:\n  - path: gke.py\n\nrequired:\n  - cluster\n\nproperties:\n  clusterLocationType:\n    type: string\n    default: Zonal\n    description: Location type for the cluster Zonal or Regional.\n    enum:\n      - Regional\n      - Zonal \n  region:\n    type: string\n    default: us-east1\n    description: |\n      The region the cluster belongs to. Should be set when clusterLocationType\n      is set to Regional\n  zone:\n    type: string\n    default: us-east1-b\n    description: The zone the cluster belongs to.\n  clusterNameSuffix:\n    type: string\n    default: -cluster\n    description: |\n      The suffix appended to the cluster name to form the name of the cluster\n      in GKE. Set to an empty string to use the cluster name as is.\n  cluster:\n    type: object\n    description: The cluster configuration.\n    required:\n      - network\n      - subnetwork\n    properties:\n      name:\n        type: string\n        description: The name of the cluster.\n      description:\n        type: string\n        description: An optional description of the cluster.\n      initialNodeCount:\n        type: number\n        default: 1\n        description: |\n          The number of nodes to create in this cluster. You must ensure that\n          your Compute Engine resource quota is sufficient for this number of\n          instances. You must also have available firewall and routes quota.\n        minimum: 1\n      nodeConfig:\n        type: object\n        description: Parameters used in creating the cluster's nodes.\n        required:\n          - oauthScopes\n        properties:\n          machineType:\n            type: string\n            default: n1-standard-1\n            description: |\n              The name of the Google Compute Engine machine type.\n          diskSizeGb:\n            type: number\n            default: 100\n            minimum: 10\n            description: |\n              Size of the disk attached to each node, specified in GB. \n              The smallest allowed disk size is 10GB. \n          imageType:\n            type: string\n            default: cos\n            description: The image type to use for the node.\n            enum:\n              - cos\n              - Ubuntu\n          oauthScopes:\n            type: array\n            description: |\n              The set of Google API scopes to be made available on all \n              of the node VMs under the \"default\" service account.\n              E.g., scopes\n              https://www.googleapis.com/auth/compute\n              https://www.googleapis.com/auth/devstorage.read_only\n              https://www.googleapis.com/auth/logging.write\n              https://www.googleapis.com/auth/monitoring\n            items:\n              type: string\n          serviceAccount:\n            type: string\n            description: |\n              The GCP Service Account to be used by the node VMs.\n          metadata:\n            type: object\n            pattern: \"[a-zA-Z0-9-_]+\"\n            description: |\n              The metadata key/value pairs assigned to instances in the\n              cluster. Keys must conform to the regexp [a-zA-Z0-9-_]+ and be\n              less than 128 bytes in length. Additionally, to avoid ambiguity,\n              keys must neiter conflict with any other metadata keys for the \n              project nor be one of the reserved keys \"cluster-location\", \n              \"cluster-name\", \"cluster-uid\", \"configure-sh\", \n              \"gci-update-strategy\", \"gci-ensure-gke-docker\", \n              \"instance-template\", \"kube-env\", \"startup-script\", or \n              \"user-data\". The total size of all keys and values must be less\n              than 512 KB.\n          labels:\n            type: object\n            description: |\n              The map of Kubernetes labels (key/value pairs) to be applied to each \n              node. These are added to the default label(s) that \n              Kubernetes may apply to the nodes.\n          localSsdCount:\n            type: number\n            description: The number of local SSD disks to be attached to the node.\n          tags:\n            type: array\n            description: |\n              A list of instance tags applied to all nodes. Tags are used to\n              identify valid sources or targets for network firewalls, and are\n              specified by the client during the cluster or node pool creation.\n              All tags must comply with RFC1035.\n            items:\n              type: string\n          preemptible:\n            type: boolean\n            default: False\n            description: |\n              Defines whether the nodes are created as preemptible VM instances.\n              https://cloud.google.com/compute/docs/instances/preemptible\n          accelerators:\n            type: array\n            description: |\n              A list of hardware accelerators to be attached to each node. \n              See https://cloud.google.com/compute/docs/gpus for more \n              information about support for GPUs.\n            items:\n              type: object\n              description: The Hardware Accelerator request object.\n              required:\n                - acceleratorCount\n                - acceleratorType\n              properties:\n                acceleratorCount:\n                  type: string\n                  description: |\n                    The number of the accelerator cards exposed to an instance.\n                acceleratorType:\n                  type: string\n                  description: |\n                    The accelerator type resource name. The list of supported\n                    accelerator types can be found here\n                    https://cloud.google.com/compute/docs/gpus/#Introduction\n          minCpuPlatform:\n            type: string\n            description: |\n              The minimum CPU platform to be used by the instance. \n              The instance may be scheduled on the specified or newer CPU\n              platform. Applicable values are the friendly names of CPU \n              platforms, such as \"Intel Haswell\" or \"Intel Sandy Bridge\".\n          workloadMetadataConfig:\n            type: object\n            description: The workload metadata configuration for the node.\n            items:\n              type: object\n              required:\n                - nodeMetadata\n              properties:\n                nodeMetadata:\n                  type: array\n                  description: |\n                    Configuration that defines how to expose the node \n                    metadata to the workload running on the node.\n                  items:\n                    type: string\n                    enum:\n                      - UNSPECIFIED\n                      - SECURE\n                      - EXPOSE\n          taints:\n            type: array\n            description: |\n              A list of Kubernetes taints to be applied to each node.\n            items:\n              type: object\n              description: The taint object's key, value, and effect.\n              required:\n                - key\n                - value\n                - effect\n              properties:\n                key:\n                  type: string\n                  description: The taint object's key.\n                value:\n                  type: string\n                  description: The taint object's value.\n                effect:\n                  type: string\n                  enum:\n                    - EFFECT_UNSPECIFIED\n                    - NO_SCHEDULE\n                    - PREFER_NO_SCHEDULE\n                    - NO_EXECUTE\n      masterAuth:\n        type: object\n        description: |\n          The authentication information for accessing the master endpoint.\n        properties:\n          username:\n            type: string\n            description: |\n              The username for HTTP basic authentication to the master\n              endpoint. For clusters v1.6.0 and later, you can disable basic\n              authentication by providing an empty username.\n          password:\n            type: string\n            description: |\n              The password to use for HTTP basic authentication to the master\n              endpoint. Because the master endpoint is open to the Internet, \n              you must create a strong password. If a password is provided,\n              'username' must be also provided (non-empty).\n            minLength: 16\n          clientCertificateConfig:\n            type: object\n            description: The configuration for client certificates on the cluster.\n            properties:\n              issueClientCertificate:\n                type: boolean\n      initialClusterVersion:\n        type: string\n        default: 1.9.7-gke.6\n        description: |\n          The initial Kubernetes version for the cluster. \n          The version can be upgraded later; the upgrades are reflected by the\n          currentMasterVersion and currentNodeVersion values.\n      loggingService:\n        type: string\n        default: logging.googleapis.com\n        description: |\n          The logging service the cluster uses. Currently \n          available options\n          logging.googleapis.com (default) - the Google Cloud Logging service\n          none - no logs\n          If left empty, the default option is used.\n      monitoringService:\n        type: string\n        default: monitoring.googleapis.com\n        description: |\n          The monitoring service the cluster uses.\n          The currently available options are\n          monitoring.googleapis.com (default) - the Google Cloud monitoring service\n          none - no metrics are exported from the cluster\n          If left empty, the default option is used.\n      network:\n        type: string\n        default: default\n        description: |\n          The name of the Google Compute Engine network to which the cluster is\n          connected. If left unspecified, the default network is used.\n      subnetwork:\n        type: string\n        description: |\n          The name of the Google Compute Engine subnetwork to which the \n          cluster is connected.\n      clusterIpv4Cidr:\n        type: string\n        description: |\n          The IP address range of the container pods in the cluster, \n          in the CIDR notation (e.g. 10.96.0.0/14). Leave blank to have one \n          automatically chosen or specify a /14 block in 10.0.0.0/8.\n      locations:\n        type: array\n        description: |\n          The list of the Google Compute Engine locations in which the cluster's\n          nodes should be located.\n        items:\n          type: string\n      enableKubernetesAlpha:\n        type: boolean\n        description: |\n          Specifies whether Kubernetes alpha features are enabled on the \n          cluster, including alpha API groups (e.g., v1beta1) and features\n          that may not be production-ready.\n      resourceLabels:\n        type: object\n        description: |\n          The resource labels for the cluster to use to annotate any related GCE\n          resources.\n      labelFingerprint:\n        type: string\n        description: The fingerprint of the set of labels for the cluster.\n      legacyAbac:\n        type: object\n        description: The configuration for the legacy ABAC authorization mode.\n        required:\n          - enabled\n        properties:\n          enabled:\n            type: boolean\n            default: False\n            description: |\n              Defines whether the ABAC authorizer is enabled for this cluster.\n              When enabled, it identities wheter the system, including its \n              service accounts, nodes, and controllers, has statically granted\n              permissions beyond those provided by the RBAC configuration\n              or IAM.\n      networkPolicy:\n        type: object\n        description: |\n          The configuration options for the NetworkPolicy feature \n          https://kubernetes.io/docs/concepts/services-networking/networkpolicies/\n        properties:\n          provider:\n            type: string\n            description: The selected network policy provider.\n            default: PROVIDER_UNSPECIFIED\n            enum:\n              - PROVIDER_UNSPECIFIED\n              - CALICO\n          enabled:\n            type: boolean\n            default: False\n            description: |\n              Defines whether the network policy is enabled on the cluster.\n      ipAllocationPolicy:\n        type: object\n        description: The configuration for the cluster IP allocation.\n        properties:\n          useIpAliases:\n            type: boolean\n            description: |\n              Defines whether alias IPs are used for pod IPs in the cluster.\n          createSubnetwork: \n            type: boolean\n            description: |\n              Defines whether a new subnetwork is created automatically for the\n              cluster. This field is only applicable is useIpAliases is True.\n          subnetworkName:\n            type: string\n            description: |\n              A custom subnetwork name to be used if createSubnetwork is True.\n              If this field is empty, a name is automatically generated for\n              the new subnetwork.\n          clusterSecondaryRangeName: \n            type: string\n            description: |\n              The name of the secondary range to be used for the cluster CIDR \n              block. The secondary range is used for pod IP addresses. \n              This must be an existing secondary range associated with the \n              cluster subnetwork.This is only applicable if both\n              useIpAliases and createSubnetwork are False.\n          servicesSecondaryRangeName: \n            type: string\n            description: |\n              The name of the secondary range to be used as for the service \n              CIDR block. The secondary range is used for service ClusterIPs.\n              This must be an existing secondary range associated with \n              the cluster subnetwork. This is only applicable if both\n              useIpAliases and createSubnetwork are False.\n          clusterIpv4CidrBlock: \n            type: string\n            description: |\n              The IP address range for the cluster pod IPs. If this field is set,\n              then cluster.cluster_ipv4_cidr must be left blank.\n              This field is only applicable when useIpAliases is True.\n              Set to blank to have a range chosen with the default size.\n              Set to /netmask (e.g., /14) to have a range chosen with a specific\n              netmask. Set to a CIDR notation (e.g., 10.96.0.0/14) from the\n              RFC-1918 private networks \n              (e.g., 10.0.0.0/8, 172.16.0.0/12, 192.168.0.0/16) to pick a specific\n              range to use.\n          nodeIpv4CidrBlock: \n            type: string\n            description: |\n              The IP address range of the instance IPs in this cluster.\n              This is applicable only if createSubnetwork is True.\n              Set to blank to have a range chosen with the default size.\n              Set to /netmask (e.g., /14) to have a range chosen with a specific \n              netmask. Set to a CIDR notation (e.g., 10.96.0.0/14) from the \n              RFC-1918 private networks\n              (e.g., 10.0.0.0/8, 172.16.0.0/12, 192.168.0.0/16) to pick a specific\n              range to use.\n          servicesIpv4CidrBlock: \n            type: string\n            description: |\n              The IP address range of the services IPs in the cluster. \n              This field is only applicable when useIpAliases is True.\n              Set to blank to have a range chosen with the default size.\n              Set to /netmask (e.g., /14) to have a range chosen with a specific\n              netmask. Set to a CIDR notation (e.g., 10.96.0.0/14) from the \n              RFC-1918 private networks \n              (e.g., 10.0.0.0/8, 172.16.0.0/12, 192.168.0.0/16) to pick a specific\n              range to use.\n          allowRouteOverlap: \n            type: boolean\n            description: |\n              If True, allows allocation of cluster CIDR ranges that overlap with\n              certain kinds of network routes (with CIDR ranges that are larger \n              than the cluster CIDR range). By default, we do not allow cluster\n              CIDR ranges to intersect with any user-declared routes.\n      masterAuthorizedNetworksConfig:\n        type: object\n        description: |\n          The configuration for the master authorized networks feature.\n        required:\n          - enabled\n        properties:\n          enabled:\n            type: boolean\n            description: |\n              Defines whether the master authorized networks feature is enabled.\n          cidrBlocks:\n            type: array\n            description: |\n              A list of cidrBlocks in the CIDR notation.\n            items:\n              type: object\n              description: The CIDR block object.\n              required:\n                - cidrBlock\n              properties:\n                displayName:\n                  type: string\n                  description: An optional display name for the CIDR block.\n                cidrBlock:\n                  type: string\n                  description: The cidrBlock in the CIDR notation.\n      addonsConfig:\n        type: object\n        description: |\n          Configurations for the various add-ons available to run in the cluster.\n        properties:\n          httpLoadBalancing:\n            type: object\n            description: |\n              Configuration options for the HTTP (L7) Load Balancing Controller\n              add-on, which simplifies setting up HTTP load balancers for\n              services in the cluster.\n            properties:\n              disabled:\n                type: boolean\n                default: False\n                description: |\n                  Specifies whether the HTTP Load Balancing controller is\n                  enabled in the cluster. If enabled, it runs a small pod\n                  in the cluster that manages the load balancers.\n          horizontalPodAutoscaling:\n            type: object\n            description: |\n              Configuration options for the Horizontal Pod Autoscaling feature,\n              which increases or decreases the number of replica pods the\n              replication controller has, based on the resource usage of the\n              existing pods.\n            properties:\n              disabled:\n                type: boolean\n                description: |\n                  Specifies whether the Horizontal Pod Autoscaling feature is\n                  enabled in the cluster. When enabled, it ensures that a\n                  Heapster pod is running in the cluster, which is also used\n                  by the Cloud Monitoring service.\n          kubernetesDashboard:\n            type: object\n            description: The configuration for the Kubernetes Dashboard.\n            properties:\n              disabled:\n                type: boolean\n                default: False\n                description: |\n                  Defines whether the Kubernetes Dashboard is enabled for\n                  the cluster.\n          networkPolicyConfig:\n            type: object\n            description: |\n              The configuration for the NetworkPolicy add-on. This only tracks\n              whether the add-on is enabled on the Master. It does not track \n              whether network policy is enabled for the nodes.\n            properties:\n              disabled:\n                type: boolean\n                description: |\n                  Defines whether the NetworkPolicy add-on is enabled for\n                  the cluster.\n      maintenancePolicy:\n        type: object\n        description: |\n          The configuration of the maintenance policy for the cluster.\n        properties:\n          window:\n            type: object\n            description: |\n              The time window within which maintenance may be performed.\n            properties:\n              dailyMaintenanceWindow:\n                type: object\n                description: The daily maintenance operation window.\n                properties:\n                  startTime:\n                    type: string\n                    description: |\n                      Time within the maintenance window to start the \n                      maintenance operations. It must be in the HH:MM\n                      format, where HH 00-23 and MM 00-59 GMT.\n      podSecurityPolicyConfig:\n        type: object\n        description: The configuration for the PodSecurityPolicy feature.\n        required:\n          - enabled\n        properties:\n          enabled:\n            type: boolean\n            description: |\n              If True, enables the PodSecurityPolicy controller for the\n              cluster. If enabled, pods must be valid under PodSecurityPolicy\n              to be created.\n      privateCluster:\n        type: boolean\n        description: |\n          Defines whether the cluster is private. Private clusters,\n          by default, have no external IP addresses on the nodes. The nodes\n          and the master communicate over private IP addresses.\n      masterIpv4CidrBlock:\n        type: string\n        description: |\n          The IP prefix in the CIDR notation to use for the hosted \n          master network. This prefix is used for assigning private IP \n          addresses to the master or set of masters, as well as the ILB VIP.\n      privateClusterConfig:\n        type: object\n        description: The configuration for private clusters.\n        properties:\n          enablePrivateNodes:\n            type: boolean\n            description: |\n              If True, nodes only have internal IP addresses and communicate\n              with the master over private networking.\n          enablePrivateEndpoint:\n            type: boolean\n            description: |\n              If True, the internal IP address of the master is used as the\n              cluster endpoint.\n          masterIpv4CidrBlock:\n            type: string\n            description: |\n              The IP prefix in the CIDR notation to use for the hosted master\n              network. Must be a /28 range.\n      workloadIdentityConfig:\n        type: object\n        description: The configuration for the use of Workload Identity.\n        properties:\n          workloadPool:\n            type: string\n            description: |\n              The workload pool to attach all Kubernetes service accounts to,\n              in the form <project>.svc.id.goog.\n      shieldedNodes:\n        type: object\n        description: The configuration of Shielded Nodes.\n        properties:\n          enabled:\n            type: boolean\n            description: If True, Shielded Nodes are enabled on all nodes.\noutputs:\n  properties:\n    - selfLink:\n        type: string\n        description: The server-defined resource URL.\n    - endpoint:\n        type: string\n        description: The IP address of the cluster's Kubernetes Master.\n    - currentMasterVersion:\n        type: string\n        description: The current version of the master in the cluster.\n    - currentNodeVersion:\n        type: string\n        description: |\n          The current version of the node software components. In case of \n          multiple versions (e.g., when the components are in the process\n          of being upgraded), this parameter reflects the minimum version\n          among all nodes.\n    - nodeIpv4CidrSize:\n        type: number\n        description: |\n          The size of the address space on each node for hosting containers.\n          This is provisioned from within the container_ipv4_cidr range.\n    - servicesIpv4Cidr:\n        type: string\n        description: |\n          The IP address range of the Kubernetes services in the cluster, \n          in the CIDR notation (e.g., 1.2.3.4/29). Service addresses are\n          typically put in the last /16 of the container CIDR.\n    - instanceGroupUrls:\n        type: array\n        items:\n          type: string\n          description: |\n            A list of instance group URLs that have been assigned to the\n            cluster.\n    - clientCertificate:\n        type: string\n        description: |\n          The Base64-encoded public certificate the clients use to authenticate\n          to the cluster endpoint.\n    - clientKey:\n        type: string\n        description: |\n          The Base64-encoded private key the clients use to authenticate\n          to the cluster endpoint.\n    - clusterCaCertificate:\n        type: string\n        description: |\n          The Base64-encoded public certificate that is the root of trust for\n          the cluster.\n    - maintenanceWindowDuration:\n        type: string\n        description: |\n          Duration of the maintenance time window; automatically chosen to be\n          the smallest possible in the given scenario. The duration is in the\n          RFC3339 format PTnHnMnS e.g., \"PT4H0M0S\".\n\ndocumentation:\n  - templates/gke/README.md\n\nexamples:\n  - templates/gke/examples/gke.yaml\n"),
+	"instance.py":                []byte("# Copyright 2018 Google Inc. All rights reserved.\n#\n# Licensed under the Apache License, Version 2.0 (the \"License\");\n# you may not use this file except in compliance with the License.\n# You may obtain a copy of the License at\n#\n#     http://www.apache.org/licenses/LICENSE-2.0\n#\n# Unless required by applicable law or agreed to in writing, software\n# distributed under the License is distributed on an \"AS IS\" BASIS,\n# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.\n# See the License for the specific language governing permissions and\n# limitations under the License.\n\"\"\" This template creates a Compute Instance.\"\"\"\n\ndef set_optional_property(receiver, source, property_name):\n    \"\"\" If set, copies the given property value from one object to another. \"\"\"\n\n    if property_name in source:\n        receiver[property_name] = source[property_name]\n\ndef create_boot_disk(properties, zone, instance_name):\n    \"\"\" Create a boot disk configuration. \"\"\"\n\n    disk_name = instance_name\n    boot_disk = {\n        'deviceName': disk_name,\n        'type': 'PERSISTENT',\n        'boot': True,\n        'autoDelete': True,\n        'initializeParams': {\n            'sourceImage': properties['diskImage']\n        }\n    }\n\n    disk_params = boot_disk['initializeParams']\n    set_optional_property(disk_params, properties, 'diskSizeGb')\n\n    disk_type = properties.get('diskType')\n    if disk_type:\n        disk_params['diskType'] = 'zones/{}/diskTypes/{}'.format(zone,\n                                                                 disk_type)\n\n    return boot_disk\n\ndef get_network_url(network_name):\n    \"\"\" Get the URL of a network given by its name or URL. \"\"\"\n\n    if not '.' in network_name and not '/' in network_name:\n        network_name = 'global/networks/{}'.format(network_name)\n    return network_name\n\ndef get_network_interfaces(properties):\n    \"\"\" Get the network interfaces of the instance. If networkInterfaces is\n        not set, a single interface is built from the network properties.\n    \"\"\"\n\n    if 'networkInterfaces' not in properties:\n        return [get_network(properties)]\n\n    network_interfaces = []\n    for network_interface in properties['networkInterfaces']:\n        network_interface = dict(network_interface)\n        if 'network' in network_interface:\n            network_interface['network'] = get_network_url(\n                network_interface['network'])\n        network_interfaces.append(network_interface)\n    return network_interfaces\n\ndef get_network(properties):\n    \"\"\" Get the configuration that connects the instance to an existing network\n        and assigns to it an ephemeral public IP.\n    \"\"\"\n\n    network_interfaces = {\n        'network': get_network_url(properties['network']),\n    }\n\n    if properties['hasExternalIp']:\n        access_configs = {\n            'name': 'External NAT',\n            'type': 'ONE_TO_ONE_NAT'\n        }\n\n        if 'natIP' in properties:\n            access_configs['natIP'] = properties['natIP']\n\n        network_interfaces['accessConfigs'] = [access_configs]\n\n    netif_optional_props = ['subnetwork', 'networkIP']\n    for prop in netif_optional_props:\n        if prop in properties:\n            network_interfaces[prop] = properties[prop]\n\n    return network_interfaces\n\n\ndef generate_config(context):\n    \"\"\" Entry point for the deployment resources. \"\"\"\n\n    zone = context.properties['zone']\n    vm_name = context.properties.get('name', context.env['name'])\n    machine_type = context.properties['machineType']\n\n    boot_disk = create_boot_disk(context.properties, zone, vm_name)\n    network_interfaces = get_network_interfaces(context.properties)\n    instance = {\n        'name': vm_name,\n        'type': 'compute.v1.instance',\n        'properties':{\n            'zone': zone,\n            'machineType': 'zones/{}/machineTypes/{}'.format(zone,\n                                                             machine_type),\n            'disks': [boot_disk],\n            'networkInterfaces': network_interfaces\n        }\n    }\n\n    for name in ['metadata', 'serviceAccounts', 'canIpForward', 'tags',\n                 'shieldedInstanceConfig', 'labels']:\n        set_optional_property(instance['properties'], context.properties, name)\n\n    access_control = context.properties.get('accessControl')\n    if access_control is not None:\n        instance['accessControl'] = {\n            'gcpIamPolicy': {\n                'bindings': access_control\n            }\n        }\n\n    outputs = [\n        {\n            'name': 'internalIp',\n            'value': '$(ref.{}.networkInterfaces[0].networkIP)'.format(vm_name) # pylint: disable=line-too-long\n        },\n        {\n            'name': 'name',\n            'value': '$(ref.{}.name)'.format(vm_name)\n        },\n        {\n            'name': 'selfLink',\n            'value': '$(ref.{}.selfLink)'.format(vm_name)\n        }\n    ]\n\n    if 'accessConfigs' in network_interfaces[0]:\n        outputs.append(\n            {\n                'name': 'externalIp',\n                'value': '$(ref.{}.networkInterfaces[0].accessConfigs[0].natIP)'.format(vm_name) # pylint: disable=line-too-long\n            }\n        )\n\n    return {'resources': [instance], 'outputs': outputs}"),
+	"instance.py.schema":         []byte("# Copyright 2018 Google Inc. All rights reserved.\n#\n# Licensed under the Apache License, Version 2.0 (the \"License\");\n# you may not use this file except in compliance with the License.\n# You may obtain a copy of the License at\n#\n#     http://www.apache.org/licenses/LICENSE-2.0\n#\n# Unless required by applicable law or agreed to in writing, software\n# distributed under the License is distributed on an \"AS IS\" BASIS,\n# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.\n# See the License for the specific language governing permissions and\n# limitations under the License.\n\ninfo:\n  title: Compute Instance\n  author: Sourced Group Inc.\n  description: |\n    Deploys a Compute Instance connected to a custom (or default) network.\n\nimports:\n  - path: instance.py\n\nrequired:\n  - zone\n  - machineType\n  - diskImage\n\nproperties:\n  name:\n    type: string\n    description: The name of the Instance resource.\n  network:\n    type: string\n    description: |\n      Name of the network the instance will be connected to;\n      e.g., 'my-custom-network' or 'default'.\n      Either network or networkInterfaces must be set.\n  networkInterfaces:\n    type: array\n    description: |\n      The network interfaces of the instance, as defined in\n      https://cloud.google.com/compute/docs/reference/rest/v1/instances.\n      If set, network, subnetwork, networkIp, hasExternalIp and natIp are\n      ignored.\n    items:\n      type: object\n  zone:\n    type: string\n    description: Availability zone. E.g. 'us-central1-a'\n  hasExternalIp:\n    type: boolean\n    default: true\n    description: |\n      Defines wether the instance will use an external IP from a shared\n      ephemeral IP address pool. If this is set to false, the instance\n      will not have an external IP.\n  natIp:\n    type: string\n    description: |\n      An external IP address associated with this instance. Specify an unused\n      static external IP address available to the project or leave this field\n      undefined to use an IP from a shared ephemeral IP address pool. If you\n      specify a static external IP address, it must live in the same region\n      as the zone of the instance.\n      If hasExternalIp is false this field is ignored.\n  subnetwork:\n    type: string\n    description: |\n      The URL of the Subnetwork resource for this instance. If the network\n      resource is in legacy mode, do not provide this property. If the network\n      is in auto subnet mode, providing the subnetwork is optional. If the\n      network is in custom subnet mode, then this field should be specified.\n      If you specify this property, you can specify the subnetwork as a full\n      or partial URL. For example, the following are all valid URLs:\n        - https://www.googleapis.com/compute/v1/projects/project/regions/region/subnetworks/subnetwork\n        - regions/region/subnetworks/subnetwork\n  networkIp:\n    type: string\n    description: |\n      An IPv4 internal network address to assign to the instance for this\n      network interface. If not specified by the user, an unused internal IP\n      is assigned by the system.\n  tags:\n    type: object\n    description: |\n      Tags to apply to this instance. Tags are used to identify valid sources\n      or targets for network firewalls and are specified by the client during\n      instance creation. The tags can be later modified by the setTags\n      method. Each tag within the list must comply with RFC1035. Multiple tags\n      can be specified via the 'tags.items' field.\n    properties:\n      items:\n        type: array\n        description: |\n          An array of tags. Each tag must be 1-63 characters long, and comply\n          with RFC1035.\n        items:\n          type: string\n  machineType:\n    type: string\n    description: |\n      The Compute Instance type; e.g., 'n1-standard-1'.\n      See https://cloud.google.com/compute/docs/machine-types for details.\n  canIpForward:\n    type: boolean\n    description: |\n      If \"True\". allows the instance to send and receive packets with non-matching destination\n      and source IPs.\n  diskType:\n    type: string\n    description: The boot disk type.\n    enum:\n      - pd-ssd\n      - pd-standard\n      - local-ssd\n  diskImage:\n    type: string\n    description: |\n      The source image for the disk. To create the disk with one of the\n      public operating system images, specify the image by its family name.\n      For example, specify family/debian-9 to use the latest Debian 9 image\n      projects/debian-cloud/global/images/family/debian-9.\n      To create a disk with a custom image (that you created), specify the image\n      name in the following format: global/images/my-custom-image.\n      See https://cloud.google.com/compute/docs/images for details.\n  diskSizeGb:\n    type: integer\n    minimum: 10\n  metadata:\n    type: object\n    required:\n      - items\n    description: |\n      The instance metadata. For example:\n      metadata:\n        items:\n          - key: startup-script\n          - value: sudo apt-get update\n    properties:\n      items:\n        type: array\n        description: A collection of metadata key-value pairs.\n        items:\n          type: object\n          properties:\n            key:\n              type: string\n            value:\n              type: [string, number, boolean]\n  shieldedInstanceConfig:\n    type: object\n    description: The Shielded VM options of the instance.\n    properties:\n      enableSecureBoot:\n        type: boolean\n      enableVtpm:\n        type: boolean\n      enableIntegrityMonitoring:\n        type: boolean\n  labels:\n    type: object\n    description: Labels to apply to the instance.\n  accessControl:\n    type: array\n    description: |\n      The instance's IAM policy bindings.\n      For details, see https://cloud.google.com/compute/docs/reference/rest/v1/instances/setIamPolicy.\n    items:\n      type: object\n      properties:\n        role:\n          type: string\n        members:\n          type: array\n          items:\n            type: string\n  serviceAccounts:\n    type: array\n    description: |\n      A list of service accounts, with their specified scopes, authorized for\n      this instance. Only one service account per VM instance is supported.\n    items:\n      type: object\n      properties:\n        email:\n          type: string\n          description: Email address of the service account\n        scopes:\n          type: array\n          description: The list of scopes to be made available for this service account\n          items:\n            type: string\n            description: |\n              Access scope, e.g. 'https://www.googleapis.com/auth/compute.readonly'\n              Visit https://cloud.google.com/compute/docs/access/service-accounts#accesscopesiam\n              for more details\n\noutputs:\n  properties:\n    - externalIp:\n        type: string\n        description: Reference to the external ip address of the new instance\n    - internalIp:\n        type: string\n        description: Reference to tbe internal ip address of the new instance\n    - name:\n        type: string\n        description: A name of the instance resource\n    - selfLink:\n        type: string\n        description: The URI (SelfLink) of the instance resource.\n\ndocumentation:\n  - templates/instance/README.md\n\nexamples:\n  - templates/instance/examples/instance.yaml\n"),
+	"network.py":                 []byte("# Copyright 2018 Google Inc. All rights reserved.\n#\n# Licensed under the Apache License, Version 2.0 (the \"License\");\n# you may not use this file except in compliance with the License.\n# You may obtain a copy of the License at\n#\n#     http://www.apache.org/licenses/LICENSE-2.0\n#\n# Unless required by applicable law or agreed to in writing, software\n# distributed under the License is distributed on an \"AS IS\" BASIS,\n# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.\n# See the License for the specific language governing permissions and\n# limitations under the License.\n\"\"\" This template creates a VPC network. \"\"\"\n\n\ndef generate_config(context):\n    \"\"\" Entry point for the deployment resources. \"\"\"\n\n    properties = context.properties\n    resource_name = context.env['name']\n    name = properties.get('name', resource_name)\n\n    network = {\n        'name': resource_name,\n        'type': 'compute.v1.network',\n        'properties': {\n            'name': name,\n            'autoCreateSubnetworks': properties.get('autoCreateSubnetworks',\n                                                    False)\n        }\n    }\n\n    optional_props = ['description', 'routingConfig']\n    for prop in optional_props:\n        if prop in properties:\n            network['properties'][prop] = properties[prop]\n\n    outputs = [\n        {\n            'name': 'name',\n            'value': name\n        },\n        {\n            'name': 'selfLink',\n            'value': '$(ref.{}.selfLink)'.format(resource_name)\n        }\n    ]\n\n    return {'resources': [network], 'outputs': outputs}\n"),
+	"network.py.schema":          []byte("# Copyright 2018 Google Inc. All rights reserved.\n#\n# Licensed under the Apache License, Version 2.0 (the \"License\");\n# you may not use this file except in compliance with the License.\n# You may obtain a copy of the License at\n#\n#     http://www.apache.org/licenses/LICENSE-2.0\n#\n# Unless required by applicable law or agreed to in writing, software\n# distributed under the License is distributed on an \"AS IS\" BASIS,\n# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.\n# See the License for the specific language governing permissions and\n# limitations under the License.\n\ninfo:\n  title: Network\n  description: Creates a VPC network.\n\nproperties:\n  name:\n    type: string\n    description: |\n      The network name. If not specified, the deployment name is used.\n  description:\n    type: string\n    description: An optional description of the network.\n  autoCreateSubnetworks:\n    type: boolean\n    default: false\n    description: |\n      If true, a subnetwork is created in each region automatically (auto\n      mode). If false, subnetworks must be created explicitly (custom mode).\n  routingConfig:\n    type: object\n    description: The network-level routing configuration.\n    properties:\n      routingMode:\n        type: string\n        enum:\n          - GLOBAL\n          - REGIONAL\n\noutputs:\n  properties:\n    - name:\n        type: string\n        description: The network name.\n    - selfLink:\n        type: string\n        description: The URI (SelfLink) of the network resource.\n"),
+	"pubsub.py":                  []byte("# Copyright 2018 Google Inc. All rights reserved.\n#\n# Licensed under the Apache License, Version 2.0 (the \"License\");\n# you may not use this file except in compliance with the License.\n# You may obtain a copy of the License at\n#\n#     http://www.apache.org/licenses/LICENSE-2.0\n#\n# Unless required by applicable law or agreed to in writing, software\n# distributed under the License is distributed on an \"AS IS\" BASIS,\n# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.\n# See the License for the specific language governing permissions and\n# limitations under the License.\n\"\"\" This template creates a Pub/Sub (publish-subscribe) service. \"\"\"\n\ndef create_subscription(resource_name, spec, topic_resource_name, spec_index):\n    \"\"\" Create a pull/push subscription from the simplified spec. \"\"\"\n\n    subscription = {\n        'name': '{}-subscription-{}'.format(resource_name, spec_index),\n        'type': 'pubsub.v1.subscription',\n        'properties':{\n            'subscription': spec['name'],\n            'topic': '$(ref.{}.name)'.format(topic_resource_name)\n        }\n    }\n\n    push_endpoint = spec.get('pushEndpoint')\n    if push_endpoint is not None:\n        subscription['properties']['pushConfig'] = {\n            'pushEndpoint': push_endpoint\n        }\n\n    ack_deadline_seconds = spec.get('ackDeadlineSeconds')\n    if ack_deadline_seconds is not None:\n        subscription['properties']['ackDeadlineSeconds'] = ack_deadline_seconds\n\n    for prop in ['deadLetterPolicy', 'retryPolicy']:\n        if prop in spec:\n            subscription['properties'][prop] = spec[prop]\n\n    set_access_control(subscription, spec)\n\n    return subscription\n\ndef create_iam_policy(bindings_spec):\n    \"\"\" Create an IAM policy for the resource. \"\"\"\n\n    return {\n        'gcpIamPolicy': {\n            'bindings': bindings_spec\n        }\n    }\n\ndef set_access_control(resource, context):\n    \"\"\" If necessary, define access control for the resource \"\"\"\n\n    access_control = context.get('accessControl')\n    if access_control is not None:\n        resource['accessControl'] = create_iam_policy(access_control)\n\ndef create_pubsub(resource_name, pubsub_spec):\n    \"\"\" Create a topic with subscriptions. \"\"\"\n\n    topic_name = pubsub_spec.get('topic', resource_name)\n    topic_resource_name = '{}-topic'.format(resource_name)\n    topic = {\n        'name': topic_resource_name,\n        'type': 'pubsub.v1.topic',\n        'properties':{\n            'topic': topic_name\n        }\n    }\n\n    message_storage_policy = pubsub_spec.get('messageStoragePolicy')\n    if message_storage_policy is not None:\n        topic['properties']['messageStoragePolicy'] = message_storage_policy\n\n    set_access_control(topic, pubsub_spec)\n\n    subscription_specs = pubsub_spec.get('subscriptions', [])\n    subscriptions = [create_subscription(resource_name, spec,\n                                         topic_resource_name, index)\n                     for (index, spec)\n                     in enumerate(subscription_specs, 1)]\n\n    return [topic] + subscriptions\n\ndef create_topic_outputs(topic_resource):\n    \"\"\" Create outputs for the topic. \"\"\"\n\n    return [\n        {\n            'name': 'topicName',\n            'value': '$(ref.{}.name)'.format(topic_resource['name'])\n        }\n    ]\n\ndef generate_config(context):\n    \"\"\" Entry point for the deployment resources. \"\"\"\n\n    resource_name = context.env['name']\n    pubsub_resources = create_pubsub(resource_name, context.properties)\n    pubsub_outputs = create_topic_outputs(pubsub_resources[0])\n\n    return {\n        'resources': pubsub_resources,\n        'outputs': pubsub_outputs\n    }"),
+	"pubsub.py.schema":           []byte("# Copyright 2018 Google Inc. All rights reserved.\n#\n# Licensed under the Apache License, Version 2.0 (the \"License\");\n# you may not use this file except in compliance with the License.\n# You may obtain a copy of the License at\n#\n#     http://www.apache.org/licenses/LICENSE-2.0\n#\n# Unless required by applicable law or agreed to in writing, software\n# distributed under the License is distributed on an \"AS IS\" BASIS,\n# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.\n# See the License for the specific language governing permissions and\n# limitations under the License.\n\ninfo:\n  title: Pub/Sub (publish-subscribe) service\n  author: Sourced Group Inc.\n  description: |\n    Creates a topic, optionally with multiple subscriptions.\n\nproperties:\n  topic:\n    type: string\n    description: |\n      The name of the topic that will publish messages. If not specified,\n      the deployment name is used.\n  subscriptions:\n    type: array\n    description: A list of topic's subscriptions.\n    item:\n      type: object\n      description: The topic's subscription.\n      properties:\n        name:\n          type: string\n          description: The subscription name.\n        pushEndpoint:\n          type: string\n          description: |\n            The URL of the endpoint to push the messages to.\n        ackDeadlineSeconds:\n          type: integer\n          description: |\n            The maximum time to acknowledge a message receipt before retry.\n          minimum: 10\n          maximum: 600\n        deadLetterPolicy:\n          type: object\n          description: |\n            The policy for forwarding undeliverable messages to a dead letter\n            topic.\n          properties:\n            deadLetterTopic:\n              type: string\n              description: |\n                The full name of the dead letter topic, in the form\n                projects/{project}/topics/{topic}.\n            maxDeliveryAttempts:\n              type: integer\n              description: |\n                The maximum number of delivery attempts before the message is\n                forwarded to the dead letter topic.\n              minimum: 5\n              maximum: 100\n        retryPolicy:\n          type: object\n          description: The policy for retrying message delivery.\n          properties:\n            minimumBackoff:\n              type: string\n              description: The minimum delay between retries, e.g. 10s.\n            maximumBackoff:\n              type: string\n              description: The maximum delay between retries, e.g. 600s.\n        accessControl:\n          type: array\n          description: |\n            The subscription's IAM policy.\n            For details, see https://cloud.google.com/pubsub/docs/reference/rest/v1/Policy.\n          item:\n            type: object\n            properties:\n              role:\n                type: string\n                description: |\n                  The IAM role. \n                  For details, see https://cloud.google.com/iam/docs/understanding-roles\n              members:\n                type: array\n                description: A list of identities of the members to be granted access to the resource.\n                item:\n                  type: string\n  messageStoragePolicy:\n    type: object\n    description: The policy constraining where messages may be stored.\n    properties:\n      allowedPersistenceRegions:\n        type: array\n        description: The list of GCP regions where messages may be persisted.\n        item:\n          type: string\n  accessControl:\n    type: array\n    description: |\n      The subscription's IAM policy.\n      For details, see https://cloud.google.com/pubsub/docs/reference/rest/v1/Policy\n    item:\n      type: object\n      properties:\n        role:\n          type: string\n          description: |\n            The IAM role. \n            For details, see https://cloud.google.com/iam/docs/understanding-roles\n        members:\n          type: array\n          description: A list of identities of the members to be granted access to the resource.\n          item:\n            type: string\n\noutputs:\n  properties:\n    - topicName:\n        type: string\n        description: The created topic's name.\n\ndocumentation:\n  - templates/pubsub/README.md\n\nexamples:\n  - templates/pubsub/examples/pubsub.yaml\n  - templates/pubsub/examples/pubsub_push.yaml"),
+	"subnetwork.py":              []byte("# Copyright 2018 Google Inc. All rights reserved.\n#\n# Licensed under the Apache License, Version 2.0 (the \"License\");\n# you may not use this file except in compliance with the License.\n# You may obtain a copy of the License at\n#\n#     http://www.apache.org/licenses/LICENSE-2.0\n#\n# Unless required by applicable law or agreed to in writing, software\n# distributed under the License is distributed on an \"AS IS\" BASIS,\n# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.\n# See the License for the specific language governing permissions and\n# limitations under the License.\n\"\"\" This template creates a subnetwork of a VPC network. \"\"\"\n\n\ndef generate_config(context):\n    \"\"\" Entry point for the deployment resources. \"\"\"\n\n    properties = context.properties\n    resource_name = context.env['name']\n    name = properties.get('name', resource_name)\n    project_id = context.env['project']\n\n    network = properties['network']\n    if not '.' in network and not '/' in network:\n        network = 'projects/{}/global/networks/{}'.format(project_id, network)\n\n    subnetwork = {\n        'name': resource_name,\n        'type': 'compute.v1.subnetwork',\n        'properties': {\n            'name': name,\n            'network': network,\n            'region': properties['region'],\n            'ipCidrRange': properties['ipCidrRange'],\n            'privateIpGoogleAccess': properties.get('privateIpGoogleAccess',\n                                                    True),\n            'enableFlowLogs': properties.get('enableFlowLogs', True)\n        }\n    }\n\n    optional_props = ['description', 'secondaryIpRanges']\n    for prop in optional_props:\n        if prop in properties:\n            subnetwork['properties'][prop] = properties[prop]\n\n    outputs = [\n        {\n            'name': 'name',\n            'value': name\n        },\n        {\n            'name': 'selfLink',\n            'value': '$(ref.{}.selfLink)'.format(resource_name)\n        },\n        {\n            'name': 'gatewayAddress',\n            'value': '$(ref.{}.gatewayAddress)'.format(resource_name)\n        }\n    ]\n\n    return {'resources': [subnetwork], 'outputs': outputs}\n"),
+	"subnetwork.py.schema":       []byte("# Copyright 2018 Google Inc. All rights reserved.\n#\n# Licensed under the Apache License, Version 2.0 (the \"License\");\n# you may not use this file except in compliance with the License.\n# You may obtain a copy of the License at\n#\n#     http://www.apache.org/licenses/LICENSE-2.0\n#\n# Unless required by applicable law or agreed to in writing, software\n# distributed under the License is distributed on an \"AS IS\" BASIS,\n# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.\n# See the License for the specific language governing permissions and\n# limitations under the License.\n\ninfo:\n  title: Subnetwork\n  description: Creates a subnetwork in a VPC network.\n\nrequired:\n  - network\n  - region\n  - ipCidrRange\n\nproperties:\n  name:\n    type: string\n    description: |\n      The subnetwork name. If not specified, the deployment name is used.\n  description:\n    type: string\n    description: An optional description of the subnetwork.\n  network:\n    type: string\n    description: |\n      The name or URL of the network the subnetwork belongs to.\n  region:\n    type: string\n    description: The region of the subnetwork.\n  ipCidrRange:\n    type: string\n    description: The primary internal IP range of the subnetwork.\n  privateIpGoogleAccess:\n    type: boolean\n    default: true\n    description: |\n      Whether VMs in this subnetwork can access Google services without\n      external IP addresses.\n  enableFlowLogs:\n    type: boolean\n    default: true\n    description: Whether to enable VPC flow logging for the subnetwork.\n  secondaryIpRanges:\n    type: array\n    description: Secondary IP ranges, e.g. for GKE pods and services.\n    items:\n      type: object\n      required:\n        - rangeName\n        - ipCidrRange\n      properties:\n        rangeName:\n          type: string\n        ipCidrRange:\n          type: string\n\noutputs:\n  properties:\n    - name:\n        type: string\n        description: The subnetwork name.\n    - selfLink:\n        type: string\n        description: The URI (SelfLink) of the subnetwork resource.\n    - gatewayAddress:\n        type: string\n        description: The gateway address for the subnetwork.\n"),
+}
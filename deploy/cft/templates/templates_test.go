@@ -0,0 +1,50 @@
+package templates
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFilesUpToDate(t *testing.T) {
+	for name, b := range Files {
+		want, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatalf("ioutil.ReadFile: %v", err)
+		}
+		if !bytes.Equal(b, want) {
+			t.Errorf("%q differs from its generated contents, run go generate", name)
+		}
+	}
+}
+
+func TestFilesComplete(t *testing.T) {
+	var want []string
+	for _, pattern := range []string{"*.py", "*.py.schema"} {
+		names, err := filepath.Glob(pattern)
+		if err != nil {
+			t.Fatalf("filepath.Glob: %v", err)
+		}
+		for _, name := range names {
+			if !strings.HasSuffix(name, "_test.py") {
+				want = append(want, name)
+			}
+		}
+	}
+	sort.Strings(want)
+
+	var got []string
+	for name := range Files {
+		got = append(got, name)
+	}
+	sort.Strings(got)
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("generated files differ from the templates on disk (-got +want), run go generate:\n%v", diff)
+	}
+}
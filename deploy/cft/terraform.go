@@ -0,0 +1,588 @@
+package cft
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Terraform renders deployments as Terraform JSON configurations (*.tf.json) using resources of the google provider.
+// The rendered resources are converted from the merged properties of the deployment, so they carry the same
+// defaults, such as bindings, versioning and default owners, as those deployed with Deployment Manager.
+type Terraform struct{}
+
+// terraformConfig is a Terraform JSON configuration.
+type terraformConfig struct {
+	Data     map[string]map[string]map[string]interface{} `json:"data,omitempty"`
+	Resource map[string]map[string]map[string]interface{} `json:"resource"`
+}
+
+// terraformConverter converts the resource with the given name and properties to Terraform resources and returns
+// the address of the resource that other resources depending on it depend on.
+type terraformConverter func(c *terraformConfig, project *Project, name string, props *terraformProps) (string, error)
+
+// terraformConverters maps the template paths of resource kinds to their converters.
+var terraformConverters = map[string]terraformConverter{
+	"deploy/cft/templates/bigquery_dataset.py": convertBigqueryDataset,
+	"deploy/cft/templates/gcs_bucket.py":       convertGCSBucket,
+	"deploy/cft/templates/gke.py":              convertGKECluster,
+	"deploy/cft/templates/instance.py":         convertGCEInstance,
+	"deploy/cft/templates/pubsub.py":           convertPubsub,
+	"deploy/templates/metric.py":               convertMetric,
+}
+
+// Render renders the deployment as Terraform JSON.
+func (Terraform) Render(project *Project, deployment *Deployment) ([]byte, error) {
+	c := &terraformConfig{Resource: make(map[string]map[string]map[string]interface{})}
+	addresses := make(map[string]string)
+	for _, res := range deployment.Resources {
+		// Resources are converted by kind, so the behavior of other templates would be silently lost.
+		if res.Template != "" {
+			return nil, fmt.Errorf("resource %q: template overrides are not supported by the Terraform backend, got %q", res.Name, res.Template)
+		}
+		convert, ok := terraformConverters[res.TemplatePath]
+		if !ok {
+			return nil, fmt.Errorf("resource %q: template %q is not supported by the Terraform backend", res.Name, res.TemplatePath)
+		}
+		props := newTerraformProps(res.Properties)
+		addr, err := convert(c, project, res.Name, props)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert resource %q: %v", res.Name, err)
+		}
+		if err := props.check(); err != nil {
+			return nil, fmt.Errorf("failed to convert resource %q: %v", res.Name, err)
+		}
+		addresses[res.Name] = addr
+	}
+
+	for _, res := range deployment.Resources {
+		if res.Metadata == nil || len(res.Metadata.DependsOn) == 0 {
+			continue
+		}
+		var deps []string
+		for _, d := range res.Metadata.DependsOn {
+			addr, ok := addresses[d]
+			if !ok {
+				return nil, fmt.Errorf("resource %q depends on unknown resource %q", res.Name, d)
+			}
+			deps = append(deps, addr)
+		}
+		c.block(addresses[res.Name])["depends_on"] = deps
+	}
+
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal Terraform config: %v", err)
+	}
+	return b, nil
+}
+
+// add adds a resource of the given type and name and returns its address.
+func (c *terraformConfig) add(typ, name string, block map[string]interface{}) (string, error) {
+	if c.Resource[typ] == nil {
+		c.Resource[typ] = make(map[string]map[string]interface{})
+	}
+	name = terraformName(name)
+	if _, ok := c.Resource[typ][name]; ok {
+		return "", fmt.Errorf("duplicate Terraform resource %s.%s", typ, name)
+	}
+	c.Resource[typ][name] = block
+	return typ + "." + name, nil
+}
+
+// block returns the resource with the given address.
+func (c *terraformConfig) block(addr string) map[string]interface{} {
+	parts := strings.SplitN(addr, ".", 2)
+	return c.Resource[parts[0]][parts[1]]
+}
+
+// addIAMPolicy sets the bindings as the authoritative IAM policy of the resource identified by target,
+// like the setIamPolicy calls of the Deployment Manager templates.
+func (c *terraformConfig) addIAMPolicy(typ, name string, target map[string]interface{}, bindings interface{}) error {
+	bs, ok := bindings.([]interface{})
+	if !ok {
+		return fmt.Errorf("bindings are not a list: %v", bindings)
+	}
+	var policyBindings []interface{}
+	for _, b := range bs {
+		m, ok := b.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("binding is not an object: %v", b)
+		}
+		policyBindings = append(policyBindings, map[string]interface{}{"role": m["role"], "members": m["members"]})
+	}
+
+	name = terraformName(name)
+	if c.Data == nil {
+		c.Data = make(map[string]map[string]map[string]interface{})
+	}
+	if c.Data["google_iam_policy"] == nil {
+		c.Data["google_iam_policy"] = make(map[string]map[string]interface{})
+	}
+	c.Data["google_iam_policy"][name] = map[string]interface{}{"binding": policyBindings}
+
+	target["policy_data"] = fmt.Sprintf("${data.google_iam_policy.%s.policy_data}", name)
+	_, err := c.add(typ, name, target)
+	return err
+}
+
+func convertGCSBucket(c *terraformConfig, project *Project, name string, props *terraformProps) (string, error) {
+	bucket := map[string]interface{}{
+		"name":    props.str("name"),
+		"project": project.ID,
+	}
+	props.copy(bucket, "location", "location")
+	props.copy(bucket, "storageClass", "storage_class")
+	props.copy(bucket, "versioning", "versioning")
+	props.copy(bucket, "logging", "logging")
+	props.copy(bucket, "website", "website")
+	if v, ok := props.get("iamConfiguration"); ok {
+		c, ok := v.(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("iamConfiguration is not an object: %v", v)
+		}
+		if ubla, ok := c["uniformBucketLevelAccess"].(map[string]interface{}); ok {
+			bucket["uniform_bucket_level_access"] = ubla["enabled"]
+		}
+	}
+	if v, ok := props.get("labels"); ok {
+		bucket["labels"] = v
+	}
+	if v, ok := props.get("lifecycle"); ok {
+		lifecycle, ok := v.(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("lifecycle is not an object: %v", v)
+		}
+		bucket["lifecycle_rule"] = terraformValue(lifecycle["rule"])
+	}
+	addr, err := c.add("google_storage_bucket", name, bucket)
+	if err != nil {
+		return "", err
+	}
+
+	if bindings, ok := props.get("bindings"); ok {
+		target := map[string]interface{}{"bucket": fmt.Sprintf("${%s.name}", addr)}
+		if err := c.addIAMPolicy("google_storage_bucket_iam_policy", name, target, bindings); err != nil {
+			return "", err
+		}
+	}
+	return addr, nil
+}
+
+func convertBigqueryDataset(c *terraformConfig, project *Project, name string, props *terraformProps) (string, error) {
+	if v, ok := props.get("setDefaultOwner"); ok && v == true {
+		return "", fmt.Errorf("setDefaultOwner is not supported")
+	}
+	dataset := map[string]interface{}{
+		"dataset_id": props.str("name"),
+		"project":    project.ID,
+	}
+	props.copy(dataset, "location", "location")
+	props.copy(dataset, "description", "description")
+	props.copy(dataset, "defaultTableExpirationMs", "default_table_expiration_ms")
+	props.copy(dataset, "access", "access")
+	return c.add("google_bigquery_dataset", name, dataset)
+}
+
+func convertPubsub(c *terraformConfig, project *Project, name string, props *terraformProps) (string, error) {
+	topicName := props.str("topic")
+	if topicName == "" {
+		topicName = name
+	}
+	topic := map[string]interface{}{
+		"name":    topicName,
+		"project": project.ID,
+	}
+	props.copy(topic, "messageStoragePolicy", "message_storage_policy")
+	topicAddr, err := c.add("google_pubsub_topic", name, topic)
+	if err != nil {
+		return "", err
+	}
+	if bindings, ok := props.get("accessControl"); ok {
+		target := map[string]interface{}{"project": project.ID, "topic": fmt.Sprintf("${%s.name}", topicAddr)}
+		if err := c.addIAMPolicy("google_pubsub_topic_iam_policy", name, target, bindings); err != nil {
+			return "", err
+		}
+	}
+
+	subs, _ := props.get("subscriptions")
+	subList, _ := subs.([]interface{})
+	for _, s := range subList {
+		m, ok := s.(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("subscription is not an object: %v", s)
+		}
+		sp := newTerraformProps(m)
+		subName := sp.str("name")
+		sub := map[string]interface{}{
+			"name":    subName,
+			"project": project.ID,
+			"topic":   fmt.Sprintf("${%s.name}", topicAddr),
+		}
+		sp.copy(sub, "ackDeadlineSeconds", "ack_deadline_seconds")
+		sp.copy(sub, "deadLetterPolicy", "dead_letter_policy")
+		sp.copy(sub, "retryPolicy", "retry_policy")
+		if v, ok := sp.get("pushEndpoint"); ok {
+			sub["push_config"] = map[string]interface{}{"push_endpoint": v}
+		}
+		subResName := name + "_" + subName
+		subAddr, err := c.add("google_pubsub_subscription", subResName, sub)
+		if err != nil {
+			return "", err
+		}
+		if bindings, ok := sp.get("accessControl"); ok {
+			target := map[string]interface{}{"project": project.ID, "subscription": fmt.Sprintf("${%s.name}", subAddr)}
+			if err := c.addIAMPolicy("google_pubsub_subscription_iam_policy", subResName, target, bindings); err != nil {
+				return "", err
+			}
+		}
+		if err := sp.check(); err != nil {
+			return "", fmt.Errorf("subscription %q: %v", subName, err)
+		}
+	}
+	return topicAddr, nil
+}
+
+func convertGCEInstance(c *terraformConfig, project *Project, name string, props *terraformProps) (string, error) {
+	zone := props.str("zone")
+	instance := map[string]interface{}{
+		"name":         props.str("name"),
+		"project":      project.ID,
+		"zone":         zone,
+		"machine_type": props.str("machineType"),
+	}
+
+	disk := map[string]interface{}{"image": props.str("diskImage")}
+	props.copy(disk, "diskSizeGb", "size")
+	props.copy(disk, "diskType", "type")
+	instance["boot_disk"] = map[string]interface{}{"initialize_params": disk}
+
+	var nis []interface{}
+	if v, ok := props.get("networkInterfaces"); ok {
+		l, ok := v.([]interface{})
+		if !ok {
+			return "", fmt.Errorf("networkInterfaces is not a list: %v", v)
+		}
+		for _, ni := range l {
+			m, ok := ni.(map[string]interface{})
+			if !ok {
+				return "", fmt.Errorf("network interface is not an object: %v", ni)
+			}
+			nip := newTerraformProps(m)
+			tfni := make(map[string]interface{})
+			nip.copy(tfni, "network", "network")
+			nip.copy(tfni, "subnetwork", "subnetwork")
+			nip.copy(tfni, "networkIP", "network_ip")
+			if acs, ok := nip.get("accessConfigs"); ok {
+				var tfacs []interface{}
+				acList, _ := acs.([]interface{})
+				for _, ac := range acList {
+					acm, _ := ac.(map[string]interface{})
+					tfac := make(map[string]interface{})
+					if ip, ok := acm["natIP"]; ok {
+						tfac["nat_ip"] = ip
+					}
+					tfacs = append(tfacs, tfac)
+				}
+				tfni["access_config"] = tfacs
+			}
+			if err := nip.check(); err != nil {
+				return "", fmt.Errorf("network interface: %v", err)
+			}
+			nis = append(nis, tfni)
+		}
+	} else {
+		ni := map[string]interface{}{"network": props.str("network")}
+		props.copy(ni, "subnetwork", "subnetwork")
+		props.copy(ni, "networkIP", "network_ip")
+		if v, ok := props.get("hasExternalIp"); ok && v == true {
+			ac := make(map[string]interface{})
+			props.copy(ac, "natIP", "nat_ip")
+			ni["access_config"] = []interface{}{ac}
+		}
+		nis = append(nis, ni)
+	}
+	instance["network_interface"] = nis
+
+	if v, ok := props.get("metadata"); ok {
+		m, _ := v.(map[string]interface{})
+		items, _ := m["items"].([]interface{})
+		md := make(map[string]interface{})
+		for _, item := range items {
+			im, _ := item.(map[string]interface{})
+			key, _ := im["key"].(string)
+			md[key] = im["value"]
+		}
+		if len(md) > 0 {
+			instance["metadata"] = md
+		}
+	}
+	if v, ok := props.get("serviceAccounts"); ok {
+		l, _ := v.([]interface{})
+		if len(l) != 1 {
+			return "", fmt.Errorf("exactly one service account is supported, got %v", v)
+		}
+		instance["service_account"] = terraformValue(l[0])
+	}
+	if v, ok := props.get("tags"); ok {
+		m, _ := v.(map[string]interface{})
+		instance["tags"] = m["items"]
+	}
+	props.copy(instance, "canIpForward", "can_ip_forward")
+	props.copy(instance, "shieldedInstanceConfig", "shielded_instance_config")
+	if v, ok := props.get("labels"); ok {
+		instance["labels"] = v
+	}
+
+	addr, err := c.add("google_compute_instance", name, instance)
+	if err != nil {
+		return "", err
+	}
+	if bindings, ok := props.get("accessControl"); ok {
+		target := map[string]interface{}{
+			"project":       project.ID,
+			"zone":          zone,
+			"instance_name": fmt.Sprintf("${%s.name}", addr),
+		}
+		if err := c.addIAMPolicy("google_compute_instance_iam_policy", name, target, bindings); err != nil {
+			return "", err
+		}
+	}
+	return addr, nil
+}
+
+func convertGKECluster(c *terraformConfig, project *Project, name string, props *terraformProps) (string, error) {
+	v, _ := props.get("cluster")
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("cluster is not an object: %v", v)
+	}
+	cp := newTerraformProps(m)
+
+	clusterName := cp.str("name")
+	if clusterName == "" {
+		clusterName = props.str("name")
+	}
+	// An empty suffix is not the same as an unset one, so the suffix is read as is.
+	suffix, ok := props.m["clusterNameSuffix"].(string)
+	if !ok {
+		suffix = defaultClusterNameSuffix
+	}
+	props.used["clusterNameSuffix"] = true
+
+	location := props.str("region")
+	if props.str("clusterLocationType") == "Zonal" {
+		location = props.str("zone")
+	}
+	cluster := map[string]interface{}{
+		"name":     clusterName + suffix,
+		"project":  project.ID,
+		"location": location,
+	}
+	cp.copy(cluster, "network", "network")
+	cp.copy(cluster, "subnetwork", "subnetwork")
+	cp.copy(cluster, "description", "description")
+	cp.copy(cluster, "initialNodeCount", "initial_node_count")
+	cp.copy(cluster, "initialClusterVersion", "min_master_version")
+	cp.copy(cluster, "nodeConfig", "node_config")
+	cp.copy(cluster, "loggingService", "logging_service")
+	cp.copy(cluster, "monitoringService", "monitoring_service")
+	cp.copy(cluster, "clusterIpv4Cidr", "cluster_ipv4_cidr")
+	cp.copy(cluster, "addonsConfig", "addons_config")
+	cp.copy(cluster, "locations", "node_locations")
+	cp.copy(cluster, "enableKubernetesAlpha", "enable_kubernetes_alpha")
+	cp.copy(cluster, "networkPolicy", "network_policy")
+	cp.copy(cluster, "maintenancePolicy", "maintenance_policy")
+	cp.copy(cluster, "privateClusterConfig", "private_cluster_config")
+	cp.copy(cluster, "workloadIdentityConfig", "workload_identity_config")
+	if v, ok := cp.get("resourceLabels"); ok {
+		cluster["resource_labels"] = v
+	}
+
+	enabled := func(key string) (interface{}, bool) {
+		v, ok := cp.get(key)
+		if !ok {
+			return nil, false
+		}
+		m, _ := v.(map[string]interface{})
+		e, ok := m["enabled"]
+		return e, ok
+	}
+	if e, ok := enabled("legacyAbac"); ok {
+		cluster["enable_legacy_abac"] = e
+	}
+	if e, ok := enabled("shieldedNodes"); ok {
+		cluster["enable_shielded_nodes"] = e
+	}
+
+	// The google provider makes clusters VPC-native by setting ip_allocation_policy rather than useIpAliases.
+	if v, ok := cp.get("ipAllocationPolicy"); ok {
+		m, _ := v.(map[string]interface{})
+		if m["useIpAliases"] == true {
+			policy := make(map[string]interface{})
+			for k, v := range m {
+				if k != "useIpAliases" {
+					policy[k] = v
+				}
+			}
+			cluster["ip_allocation_policy"] = terraformValue(policy)
+		}
+	}
+	if v, ok := cp.get("masterAuthorizedNetworksConfig"); ok {
+		m, _ := v.(map[string]interface{})
+		if m["enabled"] == true {
+			config := map[string]interface{}{}
+			if blocks, ok := m["cidrBlocks"]; ok {
+				config["cidr_blocks"] = terraformValue(blocks)
+			}
+			cluster["master_authorized_networks_config"] = config
+		}
+	}
+	if err := cp.check(); err != nil {
+		return "", fmt.Errorf("cluster: %v", err)
+	}
+	return c.add("google_container_cluster", name, cluster)
+}
+
+func convertMetric(c *terraformConfig, project *Project, name string, props *terraformProps) (string, error) {
+	metric := map[string]interface{}{
+		"name":    props.str("metric"),
+		"project": project.ID,
+	}
+	props.copy(metric, "description", "description")
+	props.copy(metric, "filter", "filter")
+	props.copy(metric, "metricDescriptor", "metric_descriptor")
+	if v, ok := props.get("labelExtractors"); ok {
+		metric["label_extractors"] = v
+	}
+	return c.add("google_logging_metric", name, metric)
+}
+
+// terraformProps holds the properties of a resource and tracks which of them were converted,
+// so that properties without a Terraform equivalent are reported rather than silently dropped.
+type terraformProps struct {
+	m    map[string]interface{}
+	used map[string]bool
+}
+
+func newTerraformProps(m map[string]interface{}) *terraformProps {
+	return &terraformProps{m: m, used: make(map[string]bool)}
+}
+
+// get returns the property with the given key if it is set and marks it as converted.
+func (p *terraformProps) get(key string) (interface{}, bool) {
+	p.used[key] = true
+	v, ok := p.m[key]
+	if !ok || isZeroValue(v) {
+		return nil, false
+	}
+	return v, true
+}
+
+// str returns the string property with the given key.
+func (p *terraformProps) str(key string) string {
+	v, _ := p.get(key)
+	s, _ := v.(string)
+	return s
+}
+
+// copy copies the property with the given key, if set, to the Terraform block with its keys converted to snake case.
+func (p *terraformProps) copy(block map[string]interface{}, key, tfKey string) {
+	if v, ok := p.get(key); ok {
+		if v := terraformValue(v); !isZeroValue(v) {
+			block[tfKey] = v
+		}
+	}
+}
+
+// check returns an error if any property that is set was not converted.
+func (p *terraformProps) check() error {
+	var unused []string
+	for k, v := range p.m {
+		if !p.used[k] && !isZeroValue(v) {
+			unused = append(unused, k)
+		}
+	}
+	if len(unused) > 0 {
+		sort.Strings(unused)
+		return fmt.Errorf("properties %v are not supported by the Terraform backend", unused)
+	}
+	return nil
+}
+
+// isZeroValue returns whether the value of a property is equivalent to the property not being set.
+// Booleans are never zero values: properties that must be distinguished from false are pointers in the parsed
+// resources, so a false value in the merged properties was set on purpose.
+func isZeroValue(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return true
+	case string:
+		return t == ""
+	case []interface{}:
+		return len(t) == 0
+	case map[string]interface{}:
+		for _, v := range t {
+			if !isZeroValue(v) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// terraformValue converts the keys of objects in the value to snake case and drops unset fields,
+// following the convention of the google provider to name fields after the snake cased API fields.
+func terraformValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{})
+		for k, v := range t {
+			if isZeroValue(v) {
+				continue
+			}
+			m[snakeCase(k)] = terraformValue(v)
+		}
+		return m
+	case []interface{}:
+		var l []interface{}
+		for _, v := range t {
+			l = append(l, terraformValue(v))
+		}
+		return l
+	}
+	return v
+}
+
+// snakeCase converts a camel case name, such as masterIpv4CidrBlock or networkIP, to snake case.
+func snakeCase(s string) string {
+	rs := []rune(s)
+	var b strings.Builder
+	for i, r := range rs {
+		if unicode.IsUpper(r) {
+			prevLower := i > 0 && (unicode.IsLower(rs[i-1]) || unicode.IsDigit(rs[i-1]))
+			nextLower := i > 0 && i+1 < len(rs) && unicode.IsLower(rs[i+1])
+			if prevLower || nextLower {
+				b.WriteRune('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+var terraformNameRE = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// terraformName converts a resource name to a valid Terraform resource name.
+func terraformName(name string) string {
+	name = terraformNameRE.ReplaceAllString(name, "_")
+	if name == "" || !(unicode.IsLetter(rune(name[0])) || name[0] == '_') {
+		name = "_" + name
+	}
+	return name
+}
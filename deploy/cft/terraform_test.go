@@ -0,0 +1,344 @@
+package cft
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ghodss/yaml"
+)
+
+func TestTerraformRender(t *testing.T) {
+	_, project := getTestConfigAndProject(t, &ConfigData{`
+resources:
+- gcs_bucket:
+    expected_users:
+    - some-expected-user@my-domain.com
+    properties:
+      name: foo-bucket
+      location: us-east1
+      lifecycle:
+        rule:
+        - action:
+            type: Delete
+          condition:
+            age: 30
+- bigquery_dataset:
+    properties:
+      name: foo_dataset
+      location: US
+- pubsub:
+    properties:
+      topic: foo-topic
+      messageStoragePolicy:
+        allowedPersistenceRegions:
+        - us-central1
+      subscriptions:
+      - name: foo-subscription
+        ackDeadlineSeconds: 60
+- gce_instance:
+    properties:
+      name: foo-instance
+      zone: us-east1-a
+      machineType: f1-micro
+      diskImage: projects/ubuntu-os-cloud/global/images/family/ubuntu-1804-lts
+      network: global/networks/default
+      metadata:
+        items:
+        - key: startup-script
+          value: echo hi
+- gke_cluster:
+    properties:
+      name: foo-cluster
+      clusterLocationType: Regional
+      region: us-central1
+      cluster:
+        network: global/networks/default
+        initialNodeCount: 1
+        privateClusterConfig:
+          masterIpv4CidrBlock: 172.16.0.0/28
+        masterAuthorizedNetworksConfig:
+          cidrBlocks:
+          - cidrBlock: 10.0.0.0/8
+            displayName: internal`})
+
+	b, err := Render(project, Terraform{})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+
+	var got, want interface{}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+	}
+	if err := yaml.Unmarshal([]byte(`
+data:
+  google_iam_policy:
+    foo-bucket:
+      binding:
+      - role: roles/storage.admin
+        members:
+        - group:my-project-owners@my-domain.com
+      - role: roles/storage.objectAdmin
+        members:
+        - group:some-readwrite-group@my-domain.com
+      - role: roles/storage.objectViewer
+        members:
+        - group:some-readonly-group@my-domain.com
+        - group:another-readonly-group@googlegroups.com
+    foo-instance:
+      binding:
+      - role: roles/compute.osAdminLogin
+        members:
+        - group:my-project-owners@my-domain.com
+      - role: roles/compute.osLogin
+        members:
+        - group:some-readwrite-group@my-domain.com
+    foo-topic:
+      binding:
+      - role: roles/pubsub.editor
+        members:
+        - group:some-readwrite-group@my-domain.com
+      - role: roles/pubsub.viewer
+        members:
+        - group:some-readonly-group@my-domain.com
+        - group:another-readonly-group@googlegroups.com
+    foo-topic_foo-subscription:
+      binding:
+      - role: roles/pubsub.editor
+        members:
+        - group:some-readwrite-group@my-domain.com
+      - role: roles/pubsub.viewer
+        members:
+        - group:some-readonly-group@my-domain.com
+        - group:another-readonly-group@googlegroups.com
+resource:
+  google_storage_bucket:
+    foo-bucket:
+      name: foo-bucket
+      project: my-project
+      location: us-east1
+      versioning:
+        enabled: true
+      logging:
+        log_bucket: my-project-logs
+      uniform_bucket_level_access: true
+      lifecycle_rule:
+      - action:
+          type: Delete
+        condition:
+          age: 30
+  google_storage_bucket_iam_policy:
+    foo-bucket:
+      bucket: ${google_storage_bucket.foo-bucket.name}
+      policy_data: ${data.google_iam_policy.foo-bucket.policy_data}
+  google_logging_metric:
+    unexpected-access-foo-bucket:
+      name: unexpected-access-foo-bucket
+      project: my-project
+      description: Count of unexpected data access to foo-bucket
+      filter: |
+        resource.type=gcs_bucket AND
+        logName=projects/my-project/logs/cloudaudit.googleapis.com%2Fdata_access AND
+        protoPayload.resourceName=projects/_/buckets/foo-bucket AND
+        protoPayload.status.code!=7 AND
+        protoPayload.authenticationInfo.principalEmail!=(some-expected-user@my-domain.com)
+      metric_descriptor:
+        metric_kind: DELTA
+        value_type: INT64
+        unit: '1'
+        labels:
+        - key: user
+          value_type: STRING
+          description: Unexpected user
+      label_extractors:
+        user: EXTRACT(protoPayload.authenticationInfo.principalEmail)
+      depends_on:
+      - google_storage_bucket.foo-bucket
+  google_bigquery_dataset:
+    foo_dataset:
+      dataset_id: foo_dataset
+      project: my-project
+      location: US
+      access:
+      - role: OWNER
+        group_by_email: my-project-owners@my-domain.com
+      - role: WRITER
+        group_by_email: some-readwrite-group@my-domain.com
+      - role: READER
+        group_by_email: some-readonly-group@my-domain.com
+      - role: READER
+        group_by_email: another-readonly-group@googlegroups.com
+  google_pubsub_topic:
+    foo-topic:
+      name: foo-topic
+      project: my-project
+      message_storage_policy:
+        allowed_persistence_regions:
+        - us-central1
+  google_pubsub_topic_iam_policy:
+    foo-topic:
+      project: my-project
+      topic: ${google_pubsub_topic.foo-topic.name}
+      policy_data: ${data.google_iam_policy.foo-topic.policy_data}
+  google_pubsub_subscription:
+    foo-topic_foo-subscription:
+      name: foo-subscription
+      project: my-project
+      topic: ${google_pubsub_topic.foo-topic.name}
+      ack_deadline_seconds: 60
+  google_pubsub_subscription_iam_policy:
+    foo-topic_foo-subscription:
+      project: my-project
+      subscription: ${google_pubsub_subscription.foo-topic_foo-subscription.name}
+      policy_data: ${data.google_iam_policy.foo-topic_foo-subscription.policy_data}
+  google_compute_instance:
+    foo-instance:
+      name: foo-instance
+      project: my-project
+      zone: us-east1-a
+      machine_type: f1-micro
+      boot_disk:
+        initialize_params:
+          image: projects/ubuntu-os-cloud/global/images/family/ubuntu-1804-lts
+      network_interface:
+      - network: global/networks/default
+      metadata:
+        enable-oslogin: 'TRUE'
+        startup-script: echo hi
+      shielded_instance_config:
+        enable_secure_boot: true
+        enable_vtpm: true
+        enable_integrity_monitoring: true
+  google_compute_instance_iam_policy:
+    foo-instance:
+      project: my-project
+      zone: us-east1-a
+      instance_name: ${google_compute_instance.foo-instance.name}
+      policy_data: ${data.google_iam_policy.foo-instance.policy_data}
+  google_container_cluster:
+    foo-cluster:
+      name: foo-cluster-cluster
+      project: my-project
+      location: us-central1
+      network: global/networks/default
+      initial_node_count: 1
+      private_cluster_config:
+        enable_private_nodes: true
+        master_ipv4_cidr_block: 172.16.0.0/28
+      ip_allocation_policy: {}
+      workload_identity_config:
+        workload_pool: my-project.svc.id.goog
+      network_policy:
+        enabled: true
+        provider: CALICO
+      addons_config:
+        network_policy_config:
+          disabled: false
+      enable_shielded_nodes: true
+      enable_legacy_abac: false
+      master_authorized_networks_config:
+        cidr_blocks:
+        - cidr_block: 10.0.0.0/8
+          display_name: internal`), &want); err != nil {
+		t.Fatalf("yaml.Unmarshal: %v", err)
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Terraform config differs (-got +want):\n%v", diff)
+	}
+}
+
+func TestTerraformRenderErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		configData *ConfigData
+	}{
+		{
+			name: "unsupported_template",
+			configData: &ConfigData{`
+resources:
+- network:
+    properties:
+      name: foo-network`},
+		},
+		{
+			name: "unsupported_property",
+			configData: &ConfigData{`
+resources:
+- gcs_bucket:
+    properties:
+      name: foo-bucket
+      location: us-east1
+      predefinedAcl: publicRead`},
+		},
+		{
+			name: "unsupported_cluster_property",
+			configData: &ConfigData{`
+resources:
+- gke_cluster:
+    properties:
+      name: foo-cluster
+      clusterLocationType: Regional
+      region: us-central1
+      cluster:
+        network: global/networks/default
+        privateClusterConfig:
+          masterIpv4CidrBlock: 172.16.0.0/28
+        podSecurityPolicyConfig:
+          enabled: true`},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, project := getTestConfigAndProject(t, tc.configData)
+			if _, err := Render(project, Terraform{}); err == nil {
+				t.Fatalf("Render: got nil error, want non-nil error")
+			}
+		})
+	}
+}
+
+func TestTerraformRenderTemplateOverride(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	template := filepath.Join(dir, "custom_bucket.py")
+	if err := ioutil.WriteFile(template, nil, 0644); err != nil {
+		t.Fatalf("ioutil.WriteFile: %v", err)
+	}
+
+	_, project := getTestConfigAndProject(t, &ConfigData{fmt.Sprintf(`
+resources:
+- gcs_bucket:
+    template: %s
+    properties:
+      name: foo-bucket
+      location: us-east1`, template)})
+
+	want := "template overrides are not supported"
+	if _, err := Render(project, Terraform{}); err == nil || !strings.Contains(err.Error(), want) {
+		t.Fatalf("Render: got error %v, want error with substring %q", err, want)
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	for in, want := range map[string]string{
+		"location":            "location",
+		"masterIpv4CidrBlock": "master_ipv4_cidr_block",
+		"networkIP":           "network_ip",
+		"enableVtpm":          "enable_vtpm",
+		"IPAddress":           "ip_address",
+	} {
+		if got := snakeCase(in); got != want {
+			t.Errorf("snakeCase(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -2,43 +2,150 @@
 //
 // Usage:
 //   $ bazel run :cft -- --project_yaml_path=${PROJECT_YAML_PATH?} --project=${PROJECT_ID?}
+//
+// To deploy the VPC Service Controls perimeter defined in the projects yaml file:
+//   $ bazel run :cft -- --project_yaml_path=${PROJECT_YAML_PATH?} --service_perimeter
+//
+// To set the organization policies derived from the projects yaml file on the organization, folder or projects:
+//   $ bazel run :cft -- --project_yaml_path=${PROJECT_YAML_PATH?} --org_policies
+//
+// To render a project's resources as Terraform JSON instead of deploying them:
+//   $ bazel run :cft -- --project_yaml_path=${PROJECT_YAML_PATH?} --project=${PROJECT_ID?} --backend=terraform --output_path=${PROJECT_ID?}.tf.json
+//
+// To report how the deployed resources of a project drifted from the projects yaml file:
+//   $ bazel run :cft -- --project_yaml_path=${PROJECT_YAML_PATH?} --project=${PROJECT_ID?} drift --format=json
+//
+// To delete the abandoned resources of a project that hold no data or are backed up:
+//   $ bazel run :cft -- --project_yaml_path=${PROJECT_YAML_PATH?} --project=${PROJECT_ID?} cleanup --confirm
+//
+// To import an existing project into a project config from a Cloud Asset Inventory export, or from the live
+// project if --asset_export_path is not set:
+//   $ bazel run :cft -- --project=${PROJECT_ID?} import --asset_export_path=${EXPORT_PATH?} --output_path=${PROJECT_ID?}.yaml
+//
+// Templates are searched for in the directories given by --template_dirs and the template_dirs of the config
+// before falling back to the built-in templates, so the binary can be run from any directory.
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"os"
+	"strings"
 
 	"flag"
 	
 	"github.com/GoogleCloudPlatform/healthcare/deploy/cft"
+	"github.com/GoogleCloudPlatform/healthcare/deploy/rulegen"
 	"github.com/ghodss/yaml"
 )
 
 var (
 	projectYAMLPath = flag.String("project_yaml_path", "", "Path to project yaml file")
 	projectID       = flag.String("project", "", "Project within the project yaml file to deploy CFT resources for")
+	perimeter       = flag.Bool("service_perimeter", false, "Deploy the service perimeter defined in the project yaml file instead of a project's resources")
+	orgPolicies     = flag.Bool("org_policies", false, "Set the organization policies derived from the project yaml file instead of deploying a project's resources")
+	backend         = flag.String("backend", "deployment_manager", "Backend to render the project's resources with: deployment_manager or terraform")
+	outputPath      = flag.String("output_path", "", "If set, write the rendered resources to this path instead of deploying them, or the imported project config for the import command. Required for the terraform backend")
+	templateDirs    = flag.String("template_dirs", "", "Comma separated list of directories to search for templates before those in the project yaml file and the built-in templates")
+	format          = flag.String("format", "text", "Format of the drift report: text or json")
+	assetExportPath = flag.String("asset_export_path", "", "Path to a Cloud Asset Inventory export of resources and IAM policies to import the project from. If unset, the project is imported from its live assets")
+	confirm         = flag.Bool("confirm", false, "Delete the abandoned resources found by the cleanup command instead of only listing them")
 )
 
 func main() {
 	flag.Parse()
 
+	// Flags may also be given after the command.
+	command := flag.Arg(0)
+	if command != "" {
+		flag.CommandLine.Parse(flag.Args()[1:])
+		if flag.NArg() > 0 {
+			log.Fatalf("unexpected arguments %v", flag.Args())
+		}
+	}
+	switch command {
+	case "":
+	case "drift":
+		if *perimeter {
+			log.Fatal("--service_perimeter is not supported by the drift command")
+		}
+		if *orgPolicies {
+			log.Fatal("--org_policies is not supported by the drift command")
+		}
+		if *format != "text" && *format != "json" {
+			log.Fatalf("unknown --format %q", *format)
+		}
+	case "cleanup":
+		if *perimeter {
+			log.Fatal("--service_perimeter is not supported by the cleanup command")
+		}
+		if *orgPolicies {
+			log.Fatal("--org_policies is not supported by the cleanup command")
+		}
+	case "import":
+		if *projectID == "" {
+			log.Fatal("--project must be set")
+		}
+		if err := importProject(*projectID); err != nil {
+			log.Fatalf("failed to import %q: %v", *projectID, err)
+		}
+		return
+	default:
+		log.Fatalf("unknown command %q", command)
+	}
+
 	if *projectYAMLPath == "" {
 		log.Fatal("--project_yaml_path must be set")
 	}
-	if *projectID == "" {
+	if *projectID == "" && !*perimeter && !*orgPolicies {
 		log.Fatal("--project must be set")
 	}
 
+	var b cft.Backend
+	switch *backend {
+	case "deployment_manager":
+		b = cft.DeploymentManager{}
+	case "terraform":
+		if *outputPath == "" {
+			log.Fatal("--output_path must be set for the terraform backend")
+		}
+		b = cft.Terraform{}
+	default:
+		log.Fatalf("unknown --backend %q", *backend)
+	}
+
 	// TODO: handle split yaml configs
-	b, err := ioutil.ReadFile(*projectYAMLPath)
+	conf, err := cft.LoadConfig(*projectYAMLPath)
 	if err != nil {
-		log.Fatalf("failed to read input projects yaml file at path %q: %v", *projectYAMLPath, err)
+		log.Fatalf("failed to load config: %v", err)
 	}
 
-	conf := new(cft.Config)
-	if err := yaml.Unmarshal(b, conf); err != nil {
-		log.Fatalf("failed to unmarshal config: %v", err)
+	if *perimeter {
+		if err := conf.Init(); err != nil {
+			log.Fatalf("failed to initialize config: %v", err)
+		}
+		if err := cft.DeployServicePerimeter(conf); err != nil {
+			log.Fatalf("failed to deploy service perimeter: %v", err)
+		}
+		log.Println("Service perimeter deployment successful")
+		return
+	}
+
+	if *orgPolicies {
+		if err := conf.Init(); err != nil {
+			log.Fatalf("failed to initialize config: %v", err)
+		}
+		policies, err := rulegen.OrgPolicies(conf)
+		if err != nil {
+			log.Fatalf("failed to generate organization policies: %v", err)
+		}
+		if err := cft.DeployOrgPolicies(policies); err != nil {
+			log.Fatalf("failed to deploy organization policies: %v", err)
+		}
+		log.Println("Organization policy deployment successful")
+		return
 	}
 
 	proj, err := findProject(*projectID, conf)
@@ -46,14 +153,66 @@ func main() {
 		log.Fatal(err)
 	}
 
+	if *templateDirs != "" {
+		proj.TemplateDirs = append(strings.Split(*templateDirs, ","), proj.TemplateDirs...)
+	}
+
 	if err := proj.Init(); err != nil {
 		log.Fatalf("failed to initialize project: %v", err)
 	}
 
+	if command == "drift" {
+		report, err := cft.Drift(proj, cft.GCloudDriftClient{})
+		if err != nil {
+			log.Fatalf("failed to detect drift of %q resources: %v", *projectID, err)
+		}
+		if err := writeDriftReport(report, *format); err != nil {
+			log.Fatalf("failed to write drift report: %v", err)
+		}
+		return
+	}
+
+	if command == "cleanup" {
+		left, err := cft.CleanupAbandonedResources(proj, *confirm)
+		if err != nil {
+			log.Fatalf("failed to clean up abandoned %q resources: %v", *projectID, err)
+		}
+		if !*confirm {
+			log.Println("Rerun with --confirm to delete the resources")
+			return
+		}
+		logAbandonedResources(*projectID, left)
+		return
+	}
+
+	if *outputPath != "" {
+		out, err := cft.Render(proj, b)
+		if err != nil {
+			log.Fatalf("failed to render %q resources: %v", *projectID, err)
+		}
+		if err := ioutil.WriteFile(*outputPath, out, 0644); err != nil {
+			log.Fatalf("failed to write rendered resources: %v", err)
+		}
+		log.Printf("Rendered %q resources to %s", *projectID, *outputPath)
+		return
+	}
+
 	if err := cft.Deploy(proj); err != nil {
 		log.Fatalf("failed to deploy %q resources: %v", *projectID, err)
 	}
 
+	if info := proj.GeneratedFields.GCEInstanceInfo; len(info) > 0 {
+		b, err := yaml.Marshal(info)
+		if err != nil {
+			log.Fatalf("failed to marshal GCE instance info: %v", err)
+		}
+		log.Printf("Set gce_instance_info in the generated_fields of project %q to:\n%s", *projectID, string(b))
+	}
+
+	if abandoned := proj.GeneratedFields.AbandonedResources; len(abandoned) > 0 {
+		logAbandonedResources(*projectID, abandoned)
+	}
+
 	log.Println("CFT deployment successful")
 }
 
@@ -65,3 +224,69 @@ func findProject(id string, c *cft.Config) (*cft.Project, error) {
 	}
 	return nil, fmt.Errorf("failed to find project %q", id)
 }
+
+func writeDriftReport(report *cft.DriftReport, format string) error {
+	if format == "text" {
+		return report.WriteText(os.Stdout)
+	}
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal drift report: %v", err)
+	}
+	_, err = fmt.Println(string(b))
+	return err
+}
+
+func logAbandonedResources(projectID string, abandoned []*cft.AbandonedResource) {
+	if len(abandoned) == 0 {
+		log.Printf("No abandoned resources are left in project %q, remove abandoned_resources from its generated_fields", projectID)
+		return
+	}
+	b, err := yaml.Marshal(abandoned)
+	if err != nil {
+		log.Fatalf("failed to marshal abandoned resources: %v", err)
+	}
+	log.Printf("Abandoned resources of project %q:\n%s", projectID, string(b))
+	log.Printf("Set backed_up for the resources that were backed up in the abandoned_resources of its generated_fields")
+}
+
+func importProject(id string) error {
+	var assets []*cft.Asset
+	if *assetExportPath != "" {
+		f, err := os.Open(*assetExportPath)
+		if err != nil {
+			return fmt.Errorf("failed to open asset export: %v", err)
+		}
+		defer f.Close()
+		if assets, err = cft.ReadAssets(f); err != nil {
+			return err
+		}
+	} else {
+		var err error
+		if assets, err = (cft.GCloudAssetClient{}).Assets(id); err != nil {
+			return err
+		}
+	}
+
+	res, err := cft.ImportProject(id, assets)
+	if err != nil {
+		return err
+	}
+	for _, w := range res.Warnings {
+		log.Printf("WARNING: %s", w)
+	}
+
+	b, err := yaml.Marshal(res.Project)
+	if err != nil {
+		return fmt.Errorf("failed to marshal imported project: %v", err)
+	}
+	if *outputPath == "" {
+		_, err = os.Stdout.Write(b)
+		return err
+	}
+	if err := ioutil.WriteFile(*outputPath, b, 0644); err != nil {
+		return fmt.Errorf("failed to write imported project: %v", err)
+	}
+	log.Printf("Imported %q to %s", id, *outputPath)
+	return nil
+}
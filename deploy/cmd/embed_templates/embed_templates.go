@@ -0,0 +1,63 @@
+// Embed_templates generates a Go source file holding the contents of Deployment Manager templates, so that the
+// deployer can bundle them without depending on the working directory.
+//
+// It is run by go generate in the template directories:
+//   $ go generate ./deploy/cft/templates ./deploy/templates
+//
+// Arguments are file names or glob patterns relative to the working directory.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+var (
+	pkg    = flag.String("package", "templates", "Package of the generated file")
+	output = flag.String("output", "templates_data.go", "Path of the generated file")
+)
+
+func main() {
+	flag.Parse()
+
+	var files []string
+	for _, pattern := range flag.Args() {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			log.Fatalf("invalid pattern %q: %v", pattern, err)
+		}
+		if len(matches) == 0 {
+			log.Fatalf("no files match %q", pattern)
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by embed_templates. DO NOT EDIT.\n\npackage %s\n\n", *pkg)
+	fmt.Fprintln(&buf, "// Files maps the base names of the templates and their schemas to their contents.")
+	fmt.Fprintln(&buf, "var Files = map[string][]byte{")
+	for _, f := range files {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			log.Fatalf("failed to read %q: %v", f, err)
+		}
+		fmt.Fprintf(&buf, "%q: []byte(%s),\n", filepath.Base(f), strconv.Quote(string(b)))
+	}
+	fmt.Fprintln(&buf, "}")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatalf("failed to format generated source: %v", err)
+	}
+	if err := ioutil.WriteFile(*output, src, 0644); err != nil {
+		log.Fatalf("failed to write %q: %v", *output, err)
+	}
+}
@@ -2,24 +2,79 @@
 //
 // Usage:
 //   $ bazel run :rule_generator -- --projects_yaml_path=${PROJECTS_YAML_PATH?}
+//
+// To evaluate the generated rules against a Cloud Asset Inventory export instead of printing them:
+//   $ bazel run :rule_generator -- --projects_yaml_path=${PROJECTS_YAML_PATH?} evaluate --asset_export_path=${EXPORT_PATH?}
+//
+// The evaluate command exits with a non-zero status if any resource violates the rules.
+//
+// To write a compliance report per project mapping the rules and deploy-time checks to HIPAA safeguards:
+//   $ bazel run :rule_generator -- --projects_yaml_path=${PROJECTS_YAML_PATH?} report --format=html --output_dir=${REPORT_DIR?}
+//
+// To print a semantic diff of the generated rules against the deployed rules instead of printing them:
+//   $ bazel run :rule_generator -- --projects_yaml_path=${PROJECTS_YAML_PATH?} --diff=${RULES_DIR?}
+//
+// The deployed rules may also be read from the rules directory of the Forseti server bucket, e.g.
+// --diff=gs://${FORSETI_SERVER_BUCKET?}/rules.
+//
+// To also write an index tracing each rule to the parts of the projects yaml file it was generated from:
+//   $ bazel run :rule_generator -- --projects_yaml_path=${PROJECTS_YAML_PATH?} --rule_index_path=${RULES_DIR?}/rule_index.yaml
+//
+// To also write Config Validator constraints generated from the same rules:
+//   $ bazel run :rule_generator -- --projects_yaml_path=${PROJECTS_YAML_PATH?} --config_validator_dir=${POLICY_LIBRARY?}/policies/constraints
 package main
 
 import (
+	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"flag"
-	
+
 	"github.com/GoogleCloudPlatform/healthcare/deploy/cft"
 	"github.com/GoogleCloudPlatform/healthcare/deploy/rulegen"
 	"github.com/ghodss/yaml"
 )
 
-var projectsYAMLPath = flag.String("projects_yaml_path", "", "Path to projects yaml file")
+var (
+	projectsYAMLPath = flag.String("projects_yaml_path", "", "Path to projects yaml file")
+	assetExportPath  = flag.String("asset_export_path", "", "Path to a Cloud Asset Inventory export of resources to evaluate the rules against")
+	diff             = flag.String("diff", "", "If set, print a diff of the generated rules against the rules in this directory or GCS path instead of generating them")
+	cvDir            = flag.String("config_validator_dir", "", "If set, write Config Validator constraints generated from the rules to this directory")
+	ruleIndexPath    = flag.String("rule_index_path", "", "If set, write the IDs of the rules and the parts of the projects yaml file they were generated from to this path")
+	format           = flag.String("format", "markdown", "Format of the compliance report: markdown or html")
+	outputDir        = flag.String("output_dir", "", "If set, write the compliance report of each project to this directory instead of stdout")
+)
 
 func main() {
 	flag.Parse()
 
+	// Flags may also be given after the command.
+	command := flag.Arg(0)
+	if command != "" {
+		flag.CommandLine.Parse(flag.Args()[1:])
+		if flag.NArg() > 0 {
+			log.Fatalf("unexpected arguments %v", flag.Args())
+		}
+	}
+	switch command {
+	case "":
+	case "evaluate":
+		if *assetExportPath == "" {
+			log.Fatal("--asset_export_path must be set")
+		}
+	case "report":
+		if *format != "markdown" && *format != "html" {
+			log.Fatalf("unknown --format %q", *format)
+		}
+	default:
+		log.Fatalf("unknown command %q", command)
+	}
+
 	if *projectsYAMLPath == "" {
 		log.Fatal("--projects_yaml_path must be set")
 	}
@@ -38,9 +93,135 @@ func main() {
 		log.Fatal(err)
 	}
 
+	if command == "evaluate" {
+		vs, err := evaluate(conf)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(vs) > 0 {
+			os.Exit(1)
+		}
+		log.Println("No rule violations found")
+		return
+	}
+
+	if command == "report" {
+		if err := writeReports(conf); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if *diff != "" {
+		if err := diffRules(conf); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	if err := rulegen.Run(conf); err != nil {
 		log.Fatal(err)
 	}
 
+	if *ruleIndexPath != "" {
+		refs, err := rulegen.RuleIndex(conf)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := rulegen.WriteRuleIndex(*ruleIndexPath, refs); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *cvDir != "" {
+		cs, err := rulegen.ConfigValidatorConstraints(conf)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := rulegen.WriteConstraints(*cvDir, cs); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Wrote %d Config Validator constraints to %s", len(cs), *cvDir)
+	}
+
 	log.Println("Rule generation successful")
 }
+
+func evaluate(conf *cft.Config) ([]*rulegen.Violation, error) {
+	f, err := os.Open(*assetExportPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	assets, err := cft.ReadAssets(f)
+	if err != nil {
+		return nil, err
+	}
+
+	vs, err := rulegen.Evaluate(conf, assets)
+	if err != nil {
+		return nil, err
+	}
+	if err := rulegen.WriteViolations(os.Stdout, vs); err != nil {
+		return nil, err
+	}
+	return vs, nil
+}
+
+func diffRules(conf *cft.Config) error {
+	var r rulegen.RulesReader = rulegen.DirReader(*diff)
+	if strings.HasPrefix(*diff, "gs://") {
+		r = rulegen.GCSReader(*diff)
+	}
+	diffs, err := rulegen.DiffRules(conf, r)
+	if err != nil {
+		return err
+	}
+	if len(diffs) == 0 {
+		log.Println("No rule changes")
+		return nil
+	}
+	return rulegen.WriteRuleDiffs(os.Stdout, diffs)
+}
+
+func writeReports(conf *cft.Config) error {
+	reports, err := rulegen.BuildReports(conf)
+	if err != nil {
+		return err
+	}
+	for _, r := range reports {
+		write := r.WriteMarkdown
+		ext := ".md"
+		if *format == "html" {
+			write = r.WriteHTML
+			ext = ".html"
+		}
+
+		if *outputDir == "" {
+			if err := write(os.Stdout); err != nil {
+				return fmt.Errorf("failed to write report of project %q: %v", r.ID, err)
+			}
+			continue
+		}
+
+		path := filepath.Join(*outputDir, r.ID+ext)
+		if err := writeFile(path, write); err != nil {
+			return fmt.Errorf("failed to write report of project %q: %v", r.ID, err)
+		}
+		log.Printf("Wrote report of project %q to %s", r.ID, path)
+	}
+	return nil
+}
+
+func writeFile(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -1,18 +1,30 @@
 package rulegen
 
-import "github.com/GoogleCloudPlatform/healthcare/deploy/cft"
+import (
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/healthcare/deploy/cft"
+)
 
 // AuditLoggingRule represents a forseti audit logging rule.
 type AuditLoggingRule struct {
-	Name      string     `yaml:"name"`
-	Resources []resource `yaml:"resource"`
-	Service   string     `yaml:"service"`
-	LogTypes  []string   `yaml:"log_types"`
+	Name              string     `yaml:"name"`
+	Resources         []resource `yaml:"resource"`
+	Service           string     `yaml:"service"`
+	LogTypes          []string   `yaml:"log_types"`
+	AllowedExemptions []string   `yaml:"allowed_exemptions,omitempty"`
 }
 
 // AuditLoggingRules builds audit logging scanner rules for the given config.
+// All logs are required in all projects. Projects that declare audit configs, which must keep all logs of
+// allServices, also get rules matching them, so that only the declared exempted members and services vary.
 func AuditLoggingRules(config *cft.Config) ([]AuditLoggingRule, error) {
-	return []AuditLoggingRule{{
+	projects := config.Projects
+	if config.AuditLogsProject != nil {
+		projects = append([]*cft.Project{config.AuditLogsProject}, projects...)
+	}
+
+	global := AuditLoggingRule{
 		Name: "Require all Cloud Audit logs.",
 		Resources: []resource{{
 			Type: "project",
@@ -24,5 +36,33 @@ func AuditLoggingRules(config *cft.Config) ([]AuditLoggingRule, error) {
 			"DATA_READ",
 			"DATA_WRITE",
 		},
-	}}, nil
+	}
+	rules := []AuditLoggingRule{global}
+
+	// Rules can't exclude projects, so the global rule allows the members exempted from allServices in any project.
+	// The rules of each project only allow its own exempted members.
+	exempted := make(map[string]bool)
+	for _, project := range projects {
+		for _, c := range project.AuditConfigs {
+			if c.Service == "allServices" {
+				for _, m := range c.ExemptedMembers {
+					if !exempted[m] {
+						exempted[m] = true
+						rules[0].AllowedExemptions = append(rules[0].AllowedExemptions, m)
+					}
+				}
+			}
+			rules = append(rules, AuditLoggingRule{
+				Name: fmt.Sprintf("Require Cloud Audit logs of %s in project %s.", c.Service, project.ID),
+				Resources: []resource{{
+					Type: "project",
+					IDs:  []string{project.ID},
+				}},
+				Service:           c.Service,
+				LogTypes:          c.LogTypes,
+				AllowedExemptions: c.ExemptedMembers,
+			})
+		}
+	}
+	return rules, nil
 }
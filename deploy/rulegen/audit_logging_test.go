@@ -3,6 +3,7 @@ package rulegen
 import (
 	"testing"
 
+	"github.com/GoogleCloudPlatform/healthcare/deploy/cft"
 	"github.com/google/go-cmp/cmp"
 	"gopkg.in/yaml.v2"
 )
@@ -35,3 +36,67 @@ func TestAudLoggingRules(t *testing.T) {
 		t.Errorf("rules differ (-got, +want):\n%v", diff)
 	}
 }
+
+func TestAuditLoggingRulesDeclaredConfigs(t *testing.T) {
+	config, _ := getTestConfigAndProject(t, &ConfigData{`
+audit_configs:
+- service: allServices
+  log_types:
+  - ADMIN_READ
+  - DATA_READ
+  - DATA_WRITE
+  exempted_members:
+  - serviceAccount:pipeline@my-project.iam.gserviceaccount.com
+- service: storage.googleapis.com
+  log_types:
+  - DATA_READ`})
+	config.Projects = append(config.Projects, &cft.Project{ID: "other-project"})
+
+	got, err := AuditLoggingRules(config)
+	if err != nil {
+		t.Fatalf("AuditLoggingRules = %v", err)
+	}
+
+	wantYAML := `
+- name: Require all Cloud Audit logs.
+  resource:
+  - type: project
+    resource_ids:
+    - '*'
+  service: allServices
+  log_types:
+  - ADMIN_READ
+  - DATA_READ
+  - DATA_WRITE
+  allowed_exemptions:
+  - serviceAccount:pipeline@my-project.iam.gserviceaccount.com
+- name: Require Cloud Audit logs of allServices in project my-project.
+  resource:
+  - type: project
+    resource_ids:
+    - my-project
+  service: allServices
+  log_types:
+  - ADMIN_READ
+  - DATA_READ
+  - DATA_WRITE
+  allowed_exemptions:
+  - serviceAccount:pipeline@my-project.iam.gserviceaccount.com
+- name: Require Cloud Audit logs of storage.googleapis.com in project my-project.
+  resource:
+  - type: project
+    resource_ids:
+    - my-project
+  service: storage.googleapis.com
+  log_types:
+  - DATA_READ
+`
+	var want []AuditLoggingRule
+	if err := yaml.Unmarshal([]byte(wantYAML), &want); err != nil {
+		t.Fatalf("yaml.Unmarshal = %v", err)
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("rules differ (-got, +want):\n%v", diff)
+	}
+}
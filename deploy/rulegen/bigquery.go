@@ -24,12 +24,26 @@ type bigqueryBinding struct {
 }
 
 type bigqueryMember struct {
-	Domain       string `yaml:"domain,omitempty" `
-	UserEmail    string `yaml:"user_email,omitempty"`
-	GroupEmail   string `yaml:"group_email,omitempty"`
-	SpecialGroup string `yaml:"special_group,omitempty"`
+	Domain       string       `yaml:"domain,omitempty" `
+	UserEmail    string       `yaml:"user_email,omitempty"`
+	GroupEmail   string       `yaml:"group_email,omitempty"`
+	SpecialGroup string       `yaml:"special_group,omitempty"`
+	View         bigqueryView `yaml:"view,omitempty"`
 }
 
+type bigqueryView struct {
+	ProjectID string `yaml:"project_id"`
+	DatasetID string `yaml:"dataset_id"`
+	TableID   string `yaml:"table_id"`
+}
+
+// viewRole is the role of authorized view accesses in rules, as authorized views have no role.
+const viewRole = "*"
+
+// datasetsRuleNamePrefix prefixes the names of the rules whitelisting the accesses of datasets, followed by the IDs
+// of the datasets.
+const datasetsRuleNamePrefix = "Whitelist for dataset(s): "
+
 // BigqueryRules builds bigquery scanner rules for the given config.
 func BigqueryRules(config *cft.Config) ([]BigqueryRule, error) {
 	global := BigqueryRule{
@@ -86,13 +100,8 @@ func getProjectDatasetsRules(project *cft.Project) ([]BigqueryRule, error) {
 			ids = append(ids, fmt.Sprintf("%s:%s", project.ID, d.Name()))
 		}
 
-		joined := strings.Join(ids, ", ")
-		if len(joined) > 127 {
-			joined = joined[:122] + ", ..."
-		}
-
 		rules = append(rules, BigqueryRule{
-			Name:       "Whitelist for dataset(s): " + joined,
+			Name:       datasetsRuleNamePrefix + strings.Join(ids, ", "),
 			Mode:       "whitelist",
 			DatasetIDs: ids,
 			Resources: []resource{{
@@ -116,14 +125,19 @@ func accessesToBindings(accesses []cft.Access) []bigqueryBinding {
 			GroupEmail:   access.GroupByEmail,
 			SpecialGroup: access.SpecialGroup,
 		}
+		role := access.Role
+		if v := access.View; v != nil {
+			member.View = bigqueryView{v.ProjectID, v.DatasetID, v.TableID}
+			role = viewRole
+		}
 		if member == (bigqueryMember{}) {
 			log.Printf("unmonitored access: %v", access)
 			continue
 		}
-		if _, ok := roleToMembers[access.Role]; !ok {
-			roles = append(roles, access.Role)
+		if _, ok := roleToMembers[role]; !ok {
+			roles = append(roles, role)
 		}
-		roleToMembers[access.Role] = append(roleToMembers[access.Role], member)
+		roleToMembers[role] = append(roleToMembers[role], member)
 	}
 	var bs []bigqueryBinding
 	for _, role := range roles {
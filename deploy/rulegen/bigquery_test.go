@@ -83,6 +83,70 @@ resources:
     members:
     - group_email: my-project-readonly@my-domain.com
     - group_email: another-readonly-group@googlegroups.com
+`,
+		},
+		{
+			name: "authorized_views",
+			configData: &ConfigData{`
+resources:
+- bigquery_dataset:
+    properties:
+      name: foo-dataset
+      location: US
+      access:
+      - view:
+          projectId: my-project
+          datasetId: views
+          tableId: foo_view
+- bigquery_dataset:
+    properties:
+      name: bar-dataset
+      location: US`},
+			wantYAML: `
+- name: 'Whitelist for dataset(s): my-project:foo-dataset'
+  mode: whitelist
+  resource:
+  - type: project
+    resource_ids:
+    - my-project
+  dataset_ids:
+  - my-project:foo-dataset
+  bindings:
+  - role: '*'
+    members:
+    - view:
+        project_id: my-project
+        dataset_id: views
+        table_id: foo_view
+  - role: OWNER
+    members:
+    - group_email: my-project-owners@my-domain.com
+  - role: WRITER
+    members:
+    - group_email: my-project-readwrite@my-domain.com
+  - role: READER
+    members:
+    - group_email: my-project-readonly@my-domain.com
+    - group_email: another-readonly-group@googlegroups.com
+- name: 'Whitelist for dataset(s): my-project:bar-dataset'
+  mode: whitelist
+  resource:
+  - type: project
+    resource_ids:
+    - my-project
+  dataset_ids:
+  - my-project:bar-dataset
+  bindings:
+  - role: OWNER
+    members:
+    - group_email: my-project-owners@my-domain.com
+  - role: WRITER
+    members:
+    - group_email: my-project-readwrite@my-domain.com
+  - role: READER
+    members:
+    - group_email: my-project-readonly@my-domain.com
+    - group_email: another-readonly-group@googlegroups.com
 `,
 		},
 	}
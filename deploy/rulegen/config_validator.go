@@ -0,0 +1,386 @@
+package rulegen
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/healthcare/deploy/cft"
+	"gopkg.in/yaml.v2"
+)
+
+// Config Validator constraints (https://github.com/forseti-security/config-validator) are generated from the same
+// rules as the Forseti scanners, using the constraint templates of the policy library
+// (https://github.com/forseti-security/policy-library).
+// Constraints cannot be scoped to individual resources, so rules that Forseti applies to specific resources of a
+// project are merged into a single constraint per project and template.
+
+const constraintAPIVersion = "constraints.gatekeeper.sh/v1alpha1"
+
+// Constraint represents a Config Validator constraint.
+type Constraint struct {
+	APIVersion string             `yaml:"apiVersion"`
+	Kind       string             `yaml:"kind"`
+	Metadata   constraintMetadata `yaml:"metadata"`
+	Spec       constraintSpec     `yaml:"spec"`
+}
+
+type constraintMetadata struct {
+	Name        string            `yaml:"name"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+type constraintSpec struct {
+	Severity   string                 `yaml:"severity"`
+	Match      constraintMatch        `yaml:"match"`
+	Parameters map[string]interface{} `yaml:"parameters"`
+}
+
+type constraintMatch struct {
+	Target []string `yaml:"target"`
+}
+
+// locationKinds maps Forseti resource types to the kind of their location constraint.
+var locationKinds = map[string]string{
+	"bucket":   "GCPStorageLocationConstraintV1",
+	"dataset":  "GCPBigQueryDatasetLocationConstraintV1",
+	"instance": "GCPComputeZoneConstraintV1",
+}
+
+// bigqueryRoles maps BigQuery dataset access roles to the IAM roles Cloud Asset Inventory reports them as.
+var bigqueryRoles = map[string]string{
+	"OWNER":  "roles/bigquery.dataOwner",
+	"WRITER": "roles/bigquery.dataEditor",
+	"READER": "roles/bigquery.dataViewer",
+	"*":      "roles/*",
+}
+
+// specialGroupMembers maps BigQuery special groups to the IAM members Cloud Asset Inventory reports them as.
+var specialGroupMembers = map[string][]string{
+	"allAuthenticatedUsers": {"allAuthenticatedUsers"},
+	"projectOwners":         {"projectOwner:*"},
+	"projectWriters":        {"projectEditor:*"},
+	"projectReaders":        {"projectViewer:*"},
+	"*":                     {"allUsers", "allAuthenticatedUsers", "projectOwner:*", "projectEditor:*", "projectViewer:*"},
+}
+
+// ConfigValidatorConstraints builds Config Validator constraints for the given config.
+func ConfigValidatorConstraints(config *cft.Config) ([]*Constraint, error) {
+	gens, err := allGenerators(config)
+	if err != nil {
+		return nil, err
+	}
+	b := &constraintBuilder{config: config, byKey: make(map[string]*Constraint)}
+	for _, gen := range gens {
+		for _, r := range gen.rules {
+			if err := b.addRule(r); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return b.constraints, nil
+}
+
+// addRule adds the constraints of a rule of any scanner. Rules of scanners without constraint templates are skipped.
+func (b *constraintBuilder) addRule(r rule) error {
+	switch r := r.(type) {
+	case *LocationRule:
+		return b.addLocationRule(*r)
+	case *BigqueryRule:
+		return b.addBigqueryRule(*r)
+	case *EnabledAPIsRule:
+		mode := "allow"
+		if r.Mode == "blacklist" {
+			mode = "deny"
+		}
+		return b.add("GCPServiceUsageConstraintV1", r.Name, r.Resources, map[string]interface{}{
+			"mode":     mode,
+			"services": r.Services,
+		})
+	case *LogSinkRule:
+		return b.add("GCPLoggingSinkConstraintV1", r.Name, r.Resources, map[string]interface{}{
+			"mode":             r.Mode,
+			"destination":      r.Sink.Destination,
+			"filter":           r.Sink.Filter,
+			"include_children": r.Sink.IncludeChildren,
+		})
+	case *AuditLoggingRule:
+		params := map[string]interface{}{
+			"service":   r.Service,
+			"log_types": r.LogTypes,
+		}
+		if len(r.AllowedExemptions) > 0 {
+			params["allowed_exemptions"] = r.AllowedExemptions
+		}
+		return b.add("GCPAuditLogConstraintV1", r.Name, r.Resources, params)
+	case *LienRule:
+		return b.add("GCPResourceManagerLienConstraintV1", r.Name, r.Resources, map[string]interface{}{
+			"mode":         r.Mode,
+			"restrictions": r.Restrictions,
+		})
+	}
+	return nil
+}
+
+// WriteConstraints writes each constraint to its own file in dir, as expected by the policy library.
+func WriteConstraints(dir string, cs []*Constraint) error {
+	for _, c := range cs {
+		b, err := yaml.Marshal(c)
+		if err != nil {
+			return fmt.Errorf("failed to marshal constraint %q: %v", c.Metadata.Name, err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, c.Metadata.Name+".yaml"), b, 0644); err != nil {
+			return fmt.Errorf("failed to write constraint %q: %v", c.Metadata.Name, err)
+		}
+	}
+	return nil
+}
+
+type constraintBuilder struct {
+	config      *cft.Config
+	constraints []*Constraint
+
+	// byKey holds the constraints that rules of the same kind and target are merged into.
+	byKey map[string]*Constraint
+	names map[string]bool
+}
+
+// add adds a constraint of the given kind for a rule.
+func (b *constraintBuilder) add(kind, ruleName string, rs []resource, params map[string]interface{}) error {
+	targets, err := b.targets(rs)
+	if err != nil {
+		return fmt.Errorf("failed to get targets of rule %q: %v", ruleName, err)
+	}
+	b.newConstraint(kind, ruleName, ruleName, targets, params)
+	return nil
+}
+
+func (b *constraintBuilder) newConstraint(kind, name, ruleName string, targets []string, params map[string]interface{}) *Constraint {
+	c := &Constraint{
+		APIVersion: constraintAPIVersion,
+		Kind:       kind,
+		Metadata: constraintMetadata{
+			Name:        b.uniqueName(name),
+			Annotations: map[string]string{"description": ruleName},
+		},
+		Spec: constraintSpec{
+			Severity:   "high",
+			Match:      constraintMatch{Target: targets},
+			Parameters: params,
+		},
+	}
+	b.constraints = append(b.constraints, c)
+	return c
+}
+
+// merge returns the constraint named after the key that rules with the given key are merged into, creating it if
+// necessary.
+func (b *constraintBuilder) merge(key, kind, ruleName string, targets []string, params map[string]interface{}) (c *Constraint, created bool) {
+	if c, ok := b.byKey[key]; ok {
+		if desc := c.Metadata.Annotations["description"]; !strings.HasSuffix(desc, ruleName) {
+			c.Metadata.Annotations["description"] = desc + "; " + ruleName
+		}
+		return c, false
+	}
+	c = b.newConstraint(kind, key, ruleName, targets, params)
+	b.byKey[key] = c
+	return c, true
+}
+
+func (b *constraintBuilder) addLocationRule(r LocationRule) error {
+	targets, err := b.targets(r.Resources)
+	if err != nil {
+		return fmt.Errorf("failed to get targets of rule %q: %v", r.Name, err)
+	}
+	for _, at := range r.AppliesTo {
+		types := []string{at.Type}
+		if at.Type == "*" {
+			types = []string{"bucket", "dataset", "instance"}
+		}
+		for _, typ := range types {
+			kind, ok := locationKinds[typ]
+			if !ok {
+				return fmt.Errorf("rule %q: unsupported resource type %q", r.Name, typ)
+			}
+			locsKey := "locations"
+			locs := r.Locations
+			if typ == "instance" {
+				locsKey = "zones"
+				locs = zonePatterns(locs)
+			}
+			key := strings.Join([]string{scopeName(r.Resources), typ, "location", r.Mode}, " ")
+			c, created := b.merge(key, kind, r.Name, targets, map[string]interface{}{
+				"mode":  r.Mode,
+				locsKey: locs,
+			})
+			if !created {
+				c.Spec.Parameters[locsKey] = appendUnique(c.Spec.Parameters[locsKey].([]string), locs...)
+			}
+		}
+	}
+	return nil
+}
+
+// zonePatterns converts Forseti instance locations to zone patterns, as instances are only located in zones.
+func zonePatterns(locs []string) []string {
+	var zones []string
+	for _, l := range locs {
+		l = strings.ToLower(l)
+		if strings.Count(l, "-") < 2 {
+			// Multi-regions and regions match all of their zones.
+			l += "-*"
+		}
+		zones = append(zones, l)
+	}
+	return zones
+}
+
+func (b *constraintBuilder) addBigqueryRule(r BigqueryRule) error {
+	targets, err := b.targets(r.Resources)
+	if err != nil {
+		return fmt.Errorf("failed to get targets of rule %q: %v", r.Name, err)
+	}
+	for _, binding := range r.Bindings {
+		role, ok := bigqueryRoles[binding.Role]
+		if !ok {
+			return fmt.Errorf("rule %q: unsupported role %q", r.Name, binding.Role)
+		}
+		var members []string
+		for _, m := range binding.Members {
+			ms, err := iamMembers(m)
+			if err != nil {
+				return fmt.Errorf("rule %q: %v", r.Name, err)
+			}
+			members = append(members, ms...)
+		}
+		if len(members) == 0 {
+			continue
+		}
+		key := strings.Join([]string{scopeName(r.Resources), "dataset", binding.Role, "access", r.Mode}, " ")
+		if binding.Role == "*" {
+			key = strings.Join([]string{scopeName(r.Resources), "dataset access", r.Mode}, " ")
+		}
+		c, created := b.merge(key, "GCPIAMAllowedBindingsConstraintV1", r.Name, targets, map[string]interface{}{
+			"mode":      r.Mode,
+			"assetType": "bigquery.googleapis.com/Dataset",
+			"role":      role,
+			"members":   members,
+		})
+		if !created {
+			c.Spec.Parameters["members"] = appendUnique(c.Spec.Parameters["members"].([]string), members...)
+		}
+	}
+	return nil
+}
+
+// iamMembers returns the IAM members matching a BigQuery access member.
+// Authorized views are not IAM members, so they have no members.
+func iamMembers(m bigqueryMember) ([]string, error) {
+	switch {
+	case m.View != (bigqueryView{}):
+		return nil, nil
+	case m.Domain != "":
+		return []string{"domain:" + m.Domain}, nil
+	case m.UserEmail != "":
+		return []string{"user:" + m.UserEmail}, nil
+	case m.GroupEmail != "":
+		return []string{"group:" + m.GroupEmail}, nil
+	}
+	ms, ok := specialGroupMembers[m.SpecialGroup]
+	if !ok {
+		return nil, fmt.Errorf("unsupported special group %q", m.SpecialGroup)
+	}
+	return ms, nil
+}
+
+// targets returns the Config Validator targets matching the rule resources.
+// Targets are matched against the ancestry path of assets, which holds project numbers rather than IDs.
+func (b *constraintBuilder) targets(rs []resource) ([]string, error) {
+	var targets []string
+	for _, r := range rs {
+		for _, id := range r.IDs {
+			switch {
+			case id == "*":
+				// Target the broadest scope of the config rather than all assets.
+				ts, err := b.targets([]resource{globalResource(b.config)})
+				if err != nil {
+					return nil, err
+				}
+				targets = append(targets, ts...)
+			case r.Type == "organization":
+				targets = append(targets, fmt.Sprintf("organization/%s/**", id))
+			case r.Type == "folder":
+				targets = append(targets, fmt.Sprintf("**/folder/%s/**", id))
+			default:
+				num, err := b.projectNumber(id)
+				if err != nil {
+					return nil, err
+				}
+				targets = append(targets, fmt.Sprintf("**/project/%s", num))
+			}
+		}
+	}
+	return targets, nil
+}
+
+func (b *constraintBuilder) projectNumber(id string) (string, error) {
+	projects := b.config.Projects
+	if b.config.AuditLogsProject != nil {
+		projects = append([]*cft.Project{b.config.AuditLogsProject}, projects...)
+	}
+	for _, p := range projects {
+		if p.ID != id {
+			continue
+		}
+		if p.GeneratedFields.ProjectNumber == "" {
+			return "", fmt.Errorf("project %q has no project_number in its generated_fields, deploy it first", id)
+		}
+		return p.GeneratedFields.ProjectNumber, nil
+	}
+	return "", fmt.Errorf("failed to find project %q", id)
+}
+
+// scopeName describes the resources a rule applies to, to name the constraints rules are merged into.
+func scopeName(rs []resource) string {
+	var parts []string
+	for _, r := range rs {
+		typ := r.Type
+		if typ == "" {
+			typ = "project"
+		}
+		parts = append(parts, typ+" "+strings.Join(r.IDs, " "))
+	}
+	return strings.Join(parts, " ")
+}
+
+var nonNameCharRE = regexp.MustCompile(`[^a-z0-9]+`)
+
+// uniqueName returns a constraint name for the rule that is a valid Kubernetes resource name.
+func (b *constraintBuilder) uniqueName(ruleName string) string {
+	if b.names == nil {
+		b.names = make(map[string]bool)
+	}
+	base := strings.Trim(nonNameCharRE.ReplaceAllString(strings.ToLower(ruleName), "-"), "-")
+	name := base
+	for i := 2; b.names[name]; i++ {
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+	b.names[name] = true
+	return name
+}
+
+func appendUnique(list []string, vals ...string) []string {
+	seen := make(map[string]bool)
+	for _, v := range list {
+		seen[v] = true
+	}
+	for _, v := range vals {
+		if !seen[v] {
+			seen[v] = true
+			list = append(list, v)
+		}
+	}
+	return list
+}
@@ -0,0 +1,377 @@
+package rulegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gopkg.in/yaml.v2"
+)
+
+const wantConstraintsYAML = `
+- apiVersion: constraints.gatekeeper.sh/v1alpha1
+  kind: GCPAuditLogConstraintV1
+  metadata:
+    name: require-all-cloud-audit-logs
+    annotations:
+      description: Require all Cloud Audit logs.
+  spec:
+    severity: high
+    match:
+      target:
+      - organization/12345678/**
+    parameters:
+      log_types:
+      - ADMIN_READ
+      - DATA_READ
+      - DATA_WRITE
+      service: allServices
+- apiVersion: constraints.gatekeeper.sh/v1alpha1
+  kind: GCPIAMAllowedBindingsConstraintV1
+  metadata:
+    name: organization-12345678-dataset-access-blacklist
+    annotations:
+      description: No public, domain or special group dataset access.
+  spec:
+    severity: high
+    match:
+      target:
+      - organization/12345678/**
+    parameters:
+      assetType: bigquery.googleapis.com/Dataset
+      members:
+      - domain:*
+      - allUsers
+      - allAuthenticatedUsers
+      - projectOwner:*
+      - projectEditor:*
+      - projectViewer:*
+      mode: blacklist
+      role: roles/*
+- apiVersion: constraints.gatekeeper.sh/v1alpha1
+  kind: GCPIAMAllowedBindingsConstraintV1
+  metadata:
+    name: project-my-project-dataset-owner-access-whitelist
+    annotations:
+      description: 'Whitelist for dataset(s): my-project:foo-dataset; Whitelist for
+        project my-project audit logs'
+  spec:
+    severity: high
+    match:
+      target:
+      - '**/project/1111'
+    parameters:
+      assetType: bigquery.googleapis.com/Dataset
+      members:
+      - group:my-project-owners@my-domain.com
+      mode: whitelist
+      role: roles/bigquery.dataOwner
+- apiVersion: constraints.gatekeeper.sh/v1alpha1
+  kind: GCPIAMAllowedBindingsConstraintV1
+  metadata:
+    name: project-my-project-dataset-writer-access-whitelist
+    annotations:
+      description: 'Whitelist for dataset(s): my-project:foo-dataset; Whitelist for
+        project my-project audit logs'
+  spec:
+    severity: high
+    match:
+      target:
+      - '**/project/1111'
+    parameters:
+      assetType: bigquery.googleapis.com/Dataset
+      members:
+      - group:my-project-readwrite@my-domain.com
+      - user:audit-logs-bq@logging-1111.iam.gserviceaccount.com
+      mode: whitelist
+      role: roles/bigquery.dataEditor
+- apiVersion: constraints.gatekeeper.sh/v1alpha1
+  kind: GCPIAMAllowedBindingsConstraintV1
+  metadata:
+    name: project-my-project-dataset-reader-access-whitelist
+    annotations:
+      description: 'Whitelist for dataset(s): my-project:foo-dataset; Whitelist for
+        project my-project audit logs'
+  spec:
+    severity: high
+    match:
+      target:
+      - '**/project/1111'
+    parameters:
+      assetType: bigquery.googleapis.com/Dataset
+      members:
+      - group:my-project-readonly@my-domain.com
+      - group:another-readonly-group@googlegroups.com
+      - group:my-project-auditors@my-domain.com
+      mode: whitelist
+      role: roles/bigquery.dataViewer
+- apiVersion: constraints.gatekeeper.sh/v1alpha1
+  kind: GCPServiceUsageConstraintV1
+  metadata:
+    name: global-api-whitelist
+    annotations:
+      description: Global API whitelist.
+  spec:
+    severity: high
+    match:
+      target:
+      - organization/12345678/**
+    parameters:
+      mode: allow
+      services:
+      - foo-api.googleapis.com
+      - bar-api.googleapis.com
+- apiVersion: constraints.gatekeeper.sh/v1alpha1
+  kind: GCPServiceUsageConstraintV1
+  metadata:
+    name: api-whitelist-for-my-project
+    annotations:
+      description: API whitelist for my-project.
+  spec:
+    severity: high
+    match:
+      target:
+      - '**/project/1111'
+    parameters:
+      mode: allow
+      services:
+      - foo-api.googleapis.com
+- apiVersion: constraints.gatekeeper.sh/v1alpha1
+  kind: GCPResourceManagerLienConstraintV1
+  metadata:
+    name: require-project-deletion-liens-for-all-projects
+    annotations:
+      description: Require project deletion liens for all projects.
+  spec:
+    severity: high
+    match:
+      target:
+      - organization/12345678/**
+    parameters:
+      mode: required
+      restrictions:
+      - resourcemanager.projects.delete
+- apiVersion: constraints.gatekeeper.sh/v1alpha1
+  kind: GCPStorageLocationConstraintV1
+  metadata:
+    name: organization-12345678-bucket-location-whitelist
+    annotations:
+      description: Global location whitelist.
+  spec:
+    severity: high
+    match:
+      target:
+      - organization/12345678/**
+    parameters:
+      locations:
+      - US
+      - US-CENTRAL1
+      - US-CENTRAL1-F
+      mode: whitelist
+- apiVersion: constraints.gatekeeper.sh/v1alpha1
+  kind: GCPBigQueryDatasetLocationConstraintV1
+  metadata:
+    name: organization-12345678-dataset-location-whitelist
+    annotations:
+      description: Global location whitelist.
+  spec:
+    severity: high
+    match:
+      target:
+      - organization/12345678/**
+    parameters:
+      locations:
+      - US
+      - US-CENTRAL1
+      - US-CENTRAL1-F
+      mode: whitelist
+- apiVersion: constraints.gatekeeper.sh/v1alpha1
+  kind: GCPComputeZoneConstraintV1
+  metadata:
+    name: organization-12345678-instance-location-whitelist
+    annotations:
+      description: Global location whitelist.
+  spec:
+    severity: high
+    match:
+      target:
+      - organization/12345678/**
+    parameters:
+      mode: whitelist
+      zones:
+      - us-*
+      - us-central1-*
+      - us-central1-f
+- apiVersion: constraints.gatekeeper.sh/v1alpha1
+  kind: GCPBigQueryDatasetLocationConstraintV1
+  metadata:
+    name: project-my-project-dataset-location-whitelist
+    annotations:
+      description: Project my-project resource whitelist for location US.; Project
+        my-project audit logs dataset location whitelist.
+  spec:
+    severity: high
+    match:
+      target:
+      - '**/project/1111'
+    parameters:
+      locations:
+      - US
+      mode: whitelist
+- apiVersion: constraints.gatekeeper.sh/v1alpha1
+  kind: GCPStorageLocationConstraintV1
+  metadata:
+    name: project-my-project-bucket-location-whitelist
+    annotations:
+      description: Project my-project resource whitelist for location US-CENTRAL1.;
+        Project my-project audit logs bucket location whitelist.
+  spec:
+    severity: high
+    match:
+      target:
+      - '**/project/1111'
+    parameters:
+      locations:
+      - US-CENTRAL1
+      - US
+      mode: whitelist
+- apiVersion: constraints.gatekeeper.sh/v1alpha1
+  kind: GCPComputeZoneConstraintV1
+  metadata:
+    name: project-my-project-instance-location-whitelist
+    annotations:
+      description: Project my-project resource whitelist for location US-CENTRAL1-F.
+  spec:
+    severity: high
+    match:
+      target:
+      - '**/project/1111'
+    parameters:
+      mode: whitelist
+      zones:
+      - us-central1-f
+- apiVersion: constraints.gatekeeper.sh/v1alpha1
+  kind: GCPLoggingSinkConstraintV1
+  metadata:
+    name: require-a-bigquery-log-sink-in-all-projects
+    annotations:
+      description: Require a BigQuery Log sink in all projects.
+  spec:
+    severity: high
+    match:
+      target:
+      - organization/12345678/**
+    parameters:
+      destination: bigquery.googleapis.com/*
+      filter: 'logName:"logs/cloudaudit.googleapis.com"'
+      include_children: '*'
+      mode: required
+- apiVersion: constraints.gatekeeper.sh/v1alpha1
+  kind: GCPLoggingSinkConstraintV1
+  metadata:
+    name: only-allow-bigquery-log-sinks-in-all-projects
+    annotations:
+      description: Only allow BigQuery Log sinks in all projects.
+  spec:
+    severity: high
+    match:
+      target:
+      - organization/12345678/**
+    parameters:
+      destination: bigquery.googleapis.com/*
+      filter: 'logName:"logs/cloudaudit.googleapis.com"'
+      include_children: '*'
+      mode: whitelist
+- apiVersion: constraints.gatekeeper.sh/v1alpha1
+  kind: GCPLoggingSinkConstraintV1
+  metadata:
+    name: require-log-sink-for-project-my-project
+    annotations:
+      description: Require Log sink for project my-project.
+  spec:
+    severity: high
+    match:
+      target:
+      - '**/project/1111'
+    parameters:
+      destination: bigquery.googleapis.com/projects/my-project/datasets/audit_logs
+      filter: 'logName:"logs/cloudaudit.googleapis.com"'
+      include_children: '*'
+      mode: required
+- apiVersion: constraints.gatekeeper.sh/v1alpha1
+  kind: GCPLoggingSinkConstraintV1
+  metadata:
+    name: whitelist-log-sink-for-project-my-project
+    annotations:
+      description: Whitelist Log sink for project my-project.
+  spec:
+    severity: high
+    match:
+      target:
+      - '**/project/1111'
+    parameters:
+      destination: bigquery.googleapis.com/projects/my-project/datasets/audit_logs
+      filter: 'logName:"logs/cloudaudit.googleapis.com"'
+      include_children: '*'
+      mode: whitelist
+`
+
+func TestConfigValidatorConstraints(t *testing.T) {
+	config, _ := getTestConfigAndProject(t, locConfigData)
+	got, err := ConfigValidatorConstraints(config)
+	if err != nil {
+		t.Fatalf("ConfigValidatorConstraints = %v", err)
+	}
+
+	// Compare generic values as parameters hold string lists that unmarshal as interface lists.
+	b, err := yaml.Marshal(got)
+	if err != nil {
+		t.Fatalf("yaml.Marshal = %v", err)
+	}
+	var gotConstraints, wantConstraints interface{}
+	if err := yaml.Unmarshal(b, &gotConstraints); err != nil {
+		t.Fatalf("yaml.Unmarshal = %v", err)
+	}
+	if err := yaml.Unmarshal([]byte(wantConstraintsYAML), &wantConstraints); err != nil {
+		t.Fatalf("yaml.Unmarshal = %v", err)
+	}
+
+	if diff := cmp.Diff(gotConstraints, wantConstraints); diff != "" {
+		t.Errorf("constraints differ (-got, +want):\n%v", diff)
+	}
+}
+
+func TestConfigValidatorConstraintsErrors(t *testing.T) {
+	config, project := getTestConfigAndProject(t, nil)
+	project.GeneratedFields.ProjectNumber = ""
+	if _, err := ConfigValidatorConstraints(config); err == nil {
+		t.Fatalf("ConfigValidatorConstraints: got nil error for project without a project number, want non-nil error")
+	}
+}
+
+func TestWriteConstraints(t *testing.T) {
+	config, _ := getTestConfigAndProject(t, nil)
+	cs, err := ConfigValidatorConstraints(config)
+	if err != nil {
+		t.Fatalf("ConfigValidatorConstraints = %v", err)
+	}
+
+	dir, err := ioutil.TempDir("", "constraints")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir = %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := WriteConstraints(dir, cs); err != nil {
+		t.Fatalf("WriteConstraints = %v", err)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		t.Fatalf("filepath.Glob = %v", err)
+	}
+	if len(files) != len(cs) {
+		t.Errorf("WriteConstraints wrote %d files, want %d", len(files), len(cs))
+	}
+}
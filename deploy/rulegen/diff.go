@@ -0,0 +1,323 @@
+package rulegen
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/healthcare/deploy/cft"
+	"gopkg.in/yaml.v2"
+)
+
+// RulesReader reads the currently deployed rules files, e.g. bigquery_rules.yaml.
+type RulesReader interface {
+	// ReadRules returns the contents of the rules file, or nil if it does not exist.
+	ReadRules(filename string) ([]byte, error)
+}
+
+// DirReader reads rules files from a local directory.
+type DirReader string
+
+// ReadRules implements RulesReader.
+func (d DirReader) ReadRules(filename string) ([]byte, error) {
+	b, err := ioutil.ReadFile(filepath.Join(string(d), filename))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return b, err
+}
+
+// GCSReader reads rules files from a GCS path, e.g. gs://my-forseti-server-bucket/rules, using gsutil.
+type GCSReader string
+
+// ReadRules implements RulesReader.
+func (g GCSReader) ReadRules(filename string) ([]byte, error) {
+	path := strings.TrimSuffix(string(g), "/") + "/" + filename
+	b, err := exec.Command("gsutil", "cat", path).Output()
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok {
+			if strings.Contains(string(ee.Stderr), "No URLs matched") {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("failed to read %q: %v\n%s", path, err, ee.Stderr)
+		}
+		return nil, fmt.Errorf("failed to read %q: %v", path, err)
+	}
+	return b, nil
+}
+
+// RuleDiff is the difference between the deployed and generated rules of a scanner.
+// Rules are matched by the project and kind of rule they were generated for (see rule.key) and their ordinal among
+// the rules of the same kind, so that rules whose names list their resources or location show up as changed rather
+// than as removed and added. Deployed rules with the same name are named by their ordinal, like generated rules with
+// the same name, e.g. "Rule (2)" for the second rule named "Rule", and the duplicated names are reported.
+type RuleDiff struct {
+	Scanner    string
+	Duplicates []string
+	Added      []string
+	Removed    []string
+	Changed    []*RuleChange
+}
+
+// RuleChange is the difference between a deployed rule and the generated rule it matches, named after the generated
+// rule. A change of name is reported as a change of the name field.
+type RuleChange struct {
+	Name   string
+	Fields []*FieldChange
+}
+
+// FieldChange holds the values added to or removed from a field of a rule.
+// Lists are compared as sets so reordering does not show up as a change. Elements of lists that are maps are
+// identified by their scalar fields, e.g. bindings[role=OWNER].members.
+type FieldChange struct {
+	Path    string
+	Added   []string
+	Removed []string
+}
+
+// DiffRules compares the rules generated for the config with the rules read by the reader.
+// Only scanners with differences are returned.
+func DiffRules(config *cft.Config, r RulesReader) ([]*RuleDiff, error) {
+	gens, err := allGenerators(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var diffs []*RuleDiff
+	for _, gen := range gens {
+		b, err := yaml.Marshal(map[string]interface{}{"rules": gen.rules})
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s rules: %v", gen.name, err)
+		}
+		generated, _, err := parseRules(b, gen.ruleType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse generated %s rules: %v", gen.name, err)
+		}
+
+		filename := gen.name + "_rules.yaml"
+		b, err = r.ReadRules(filename)
+		if err != nil {
+			return nil, err
+		}
+		deployed, duplicates, err := parseRules(b, gen.ruleType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", filename, err)
+		}
+
+		if d := diffRules(gen.name, deployed, generated, duplicates); d != nil {
+			diffs = append(diffs, d)
+		}
+	}
+	return diffs, nil
+}
+
+// WriteRuleDiffs writes the diffs in a human readable format.
+func WriteRuleDiffs(w io.Writer, diffs []*RuleDiff) error {
+	var b strings.Builder
+	for _, d := range diffs {
+		fmt.Fprintf(&b, "%s:\n", d.Scanner)
+		for _, name := range d.Duplicates {
+			fmt.Fprintf(&b, "  ! %q is duplicated in the deployed rules\n", name)
+		}
+		for _, name := range d.Added {
+			fmt.Fprintf(&b, "  + %q\n", name)
+		}
+		for _, name := range d.Removed {
+			fmt.Fprintf(&b, "  - %q\n", name)
+		}
+		for _, c := range d.Changed {
+			fmt.Fprintf(&b, "  ~ %q\n", c.Name)
+			for _, f := range c.Fields {
+				fmt.Fprintf(&b, "      %s:\n", f.Path)
+				for _, v := range f.Added {
+					fmt.Fprintf(&b, "        + %s\n", v)
+				}
+				for _, v := range f.Removed {
+					fmt.Fprintf(&b, "        - %s\n", v)
+				}
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ruleFields maps the paths of the fields of a rule to their values.
+type ruleFields map[string]map[string]bool
+
+// parsedRule is a rule parsed from a rules file.
+type parsedRule struct {
+	name   string
+	fields ruleFields
+}
+
+// parseRules parses the contents of a rules file of the given type of rules into the name and other fields of each
+// rule, keyed by the key of the rule followed by its ordinal among the rules with the same key if it is not the first.
+// Rules with the same name as a previous rule are named by their ordinal and their names are returned as duplicates.
+func parseRules(b []byte, ruleType reflect.Type) (map[string]*parsedRule, []string, error) {
+	var f struct {
+		Rules []map[interface{}]interface{} `yaml:"rules"`
+	}
+	if err := yaml.Unmarshal(b, &f); err != nil {
+		return nil, nil, err
+	}
+
+	rules := make(map[string]*parsedRule)
+	names := make(ruleNames)
+	nameCounts := make(map[string]int)
+	keyCounts := make(map[string]int)
+	var duplicates []string
+	for i, r := range f.Rules {
+		name := fmt.Sprint(r["name"])
+		if r["name"] == nil || name == "" {
+			return nil, nil, fmt.Errorf("rule %d has no name", i)
+		}
+		if nameCounts[name]++; nameCounts[name] == 2 {
+			duplicates = append(duplicates, name)
+		}
+
+		// Parse the rule again into its type to get its key.
+		rb, err := yaml.Marshal(r)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal rule %q: %v", name, err)
+		}
+		typed := reflect.New(ruleType)
+		if err := yaml.Unmarshal(rb, typed.Interface()); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse rule %q: %v", name, err)
+		}
+		key := typed.Interface().(rule).key()
+		if keyCounts[key]++; keyCounts[key] > 1 {
+			key = fmt.Sprintf("%s (%d)", key, keyCounts[key])
+		}
+
+		fields := make(ruleFields)
+		for k, v := range r {
+			if k != "name" {
+				fields.add(fmt.Sprint(k), v)
+			}
+		}
+		rules[key] = &parsedRule{names.unique(name), fields}
+	}
+	sort.Strings(duplicates)
+	return rules, duplicates, nil
+}
+
+// add adds the values of v at the path.
+func (f ruleFields) add(path string, v interface{}) {
+	switch v := v.(type) {
+	case nil:
+	case []interface{}:
+		for _, e := range v {
+			f.add(path, e)
+		}
+	case map[interface{}]interface{}:
+		label, nested := splitScalars(v)
+		f.addValue(path, label)
+		for _, k := range sortedKeys(nested) {
+			f.add(fmt.Sprintf("%s[%s].%s", path, label, k), nested[k])
+		}
+	default:
+		f.addValue(path, fmt.Sprint(v))
+	}
+}
+
+func (f ruleFields) addValue(path, v string) {
+	if f[path] == nil {
+		f[path] = make(map[string]bool)
+	}
+	f[path][v] = true
+}
+
+// splitScalars returns the scalar fields of the map formatted as a label, and the remaining fields.
+func splitScalars(m map[interface{}]interface{}) (string, map[string]interface{}) {
+	var scalars []string
+	nested := make(map[string]interface{})
+	for k, v := range m {
+		switch v.(type) {
+		case []interface{}, map[interface{}]interface{}:
+			nested[fmt.Sprint(k)] = v
+		default:
+			scalars = append(scalars, fmt.Sprintf("%v=%v", k, v))
+		}
+	}
+	sort.Strings(scalars)
+	return strings.Join(scalars, ", "), nested
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func diffRules(scanner string, deployed, generated map[string]*parsedRule, duplicates []string) *RuleDiff {
+	d := &RuleDiff{Scanner: scanner, Duplicates: duplicates}
+	for key, gen := range generated {
+		if _, ok := deployed[key]; !ok {
+			d.Added = append(d.Added, gen.name)
+		}
+	}
+	for key, dep := range deployed {
+		gen, ok := generated[key]
+		if !ok {
+			d.Removed = append(d.Removed, dep.name)
+			continue
+		}
+		fs := diffFields(dep.fields, gen.fields)
+		if dep.name != gen.name {
+			fs = append(fs, &FieldChange{Path: "name", Added: []string{gen.name}, Removed: []string{dep.name}})
+			sort.Slice(fs, func(i, j int) bool { return fs[i].Path < fs[j].Path })
+		}
+		if len(fs) > 0 {
+			d.Changed = append(d.Changed, &RuleChange{Name: gen.name, Fields: fs})
+		}
+	}
+	if len(d.Duplicates) == 0 && len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0 {
+		return nil
+	}
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	sort.Slice(d.Changed, func(i, j int) bool { return d.Changed[i].Name < d.Changed[j].Name })
+	return d
+}
+
+func diffFields(deployed, generated ruleFields) []*FieldChange {
+	paths := make(map[string]bool)
+	for p := range deployed {
+		paths[p] = true
+	}
+	for p := range generated {
+		paths[p] = true
+	}
+
+	var changes []*FieldChange
+	for p := range paths {
+		c := &FieldChange{Path: p}
+		for v := range generated[p] {
+			if !deployed[p][v] {
+				c.Added = append(c.Added, v)
+			}
+		}
+		for v := range deployed[p] {
+			if !generated[p][v] {
+				c.Removed = append(c.Removed, v)
+			}
+		}
+		if len(c.Added) > 0 || len(c.Removed) > 0 {
+			sort.Strings(c.Added)
+			sort.Strings(c.Removed)
+			changes = append(changes, c)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
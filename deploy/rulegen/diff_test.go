@@ -0,0 +1,274 @@
+package rulegen
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gopkg.in/yaml.v2"
+)
+
+// deployedBigqueryRules differ from the generated rules in the datasets and members of the dataset rule, and have an
+// extra rule and a duplicated rule. Their bindings and members are reordered, which is not a change.
+const deployedBigqueryRules = `
+rules:
+- name: 'Whitelist for dataset(s): my-project:foo-dataset, my-project:bar-dataset'
+  mode: whitelist
+  resource:
+  - type: project
+    resource_ids:
+    - my-project
+  dataset_ids:
+  - my-project:foo-dataset
+  - my-project:bar-dataset
+  bindings:
+  - role: READER
+    members:
+    - group_email: my-project-readonly@my-domain.com
+    - user_email: someone@my-domain.com
+  - role: WRITER
+    members:
+    - group_email: my-project-readwrite@my-domain.com
+  - role: OWNER
+    members:
+    - group_email: my-project-owners@my-domain.com
+- name: No public, domain or special group dataset access.
+  mode: blacklist
+  resource:
+  - type: organization
+    resource_ids:
+    - "12345678"
+  dataset_ids:
+  - '*'
+  bindings:
+  - role: '*'
+    members:
+    - special_group: '*'
+    - domain: '*'
+- name: No public, domain or special group dataset access.
+  mode: blacklist
+  resource:
+  - type: organization
+    resource_ids:
+    - "12345678"
+  dataset_ids:
+  - '*'
+  bindings:
+  - role: '*'
+    members:
+    - special_group: '*'
+- name: 'Whitelist for dataset(s): my-project:old-dataset'
+  mode: whitelist
+  resource:
+  - type: project
+    resource_ids:
+    - my-project
+  dataset_ids:
+  - my-project:old-dataset
+  bindings:
+  - role: OWNER
+    members:
+    - group_email: my-project-owners@my-domain.com
+`
+
+// deployedLocationRules differ from the generated rules in the global locations and the location of the instance,
+// and miss the audit logs rules.
+const deployedLocationRules = `
+rules:
+- name: Project my-project resource whitelist for location US.
+  mode: whitelist
+  resource:
+  - type: project
+    resource_ids:
+    - my-project
+  applies_to:
+  - type: dataset
+    resource_ids:
+    - my-project:foo-dataset
+  locations:
+  - US
+- name: Global location whitelist.
+  mode: whitelist
+  resource:
+  - type: organization
+    resource_ids:
+    - "12345678"
+  applies_to:
+  - type: '*'
+    resource_ids:
+    - '*'
+  locations:
+  - US-CENTRAL1
+  - US
+  - EU
+- name: Project my-project resource whitelist for location US-CENTRAL1.
+  mode: whitelist
+  resource:
+  - type: project
+    resource_ids:
+    - my-project
+  applies_to:
+  - type: bucket
+    resource_ids:
+    - my-project-foo-bucket
+  locations:
+  - US-CENTRAL1
+- name: Project my-project resource whitelist for location US-CENTRAL1-B.
+  mode: whitelist
+  resource:
+  - type: project
+    resource_ids:
+    - my-project
+  applies_to:
+  - type: instance
+    resource_ids:
+    - "123"
+  locations:
+  - US-CENTRAL1-B
+`
+
+func TestDiffRules(t *testing.T) {
+	config, _ := getTestConfigAndProject(t, locConfigData)
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir = %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	gens, err := allGenerators(config)
+	if err != nil {
+		t.Fatalf("allGenerators = %v", err)
+	}
+	for _, gen := range gens {
+		b, err := yaml.Marshal(map[string]interface{}{"rules": gen.rules})
+		if err != nil {
+			t.Fatalf("yaml.Marshal = %v", err)
+		}
+		switch gen.name {
+		case "bigquery":
+			b = []byte(deployedBigqueryRules)
+		case "location":
+			b = []byte(deployedLocationRules)
+		case "lien":
+			// Missing files hold no rules.
+			continue
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, gen.name+"_rules.yaml"), b, 0644); err != nil {
+			t.Fatalf("ioutil.WriteFile = %v", err)
+		}
+	}
+
+	got, err := DiffRules(config, DirReader(dir))
+	if err != nil {
+		t.Fatalf("DiffRules = %v", err)
+	}
+
+	want := []*RuleDiff{
+		{
+			Scanner:    "bigquery",
+			Duplicates: []string{"No public, domain or special group dataset access."},
+			Added:      []string{"Whitelist for project my-project audit logs"},
+			Removed: []string{
+				"No public, domain or special group dataset access. (2)",
+				"Whitelist for dataset(s): my-project:old-dataset",
+			},
+			Changed: []*RuleChange{{
+				Name: "Whitelist for dataset(s): my-project:foo-dataset",
+				Fields: []*FieldChange{
+					{
+						Path:    "bindings[role=READER].members",
+						Added:   []string{"group_email=another-readonly-group@googlegroups.com"},
+						Removed: []string{"user_email=someone@my-domain.com"},
+					},
+					{
+						Path:    "dataset_ids",
+						Removed: []string{"my-project:bar-dataset"},
+					},
+					{
+						Path:    "name",
+						Added:   []string{"Whitelist for dataset(s): my-project:foo-dataset"},
+						Removed: []string{"Whitelist for dataset(s): my-project:foo-dataset, my-project:bar-dataset"},
+					},
+				},
+			}},
+		},
+		{
+			Scanner: "lien",
+			Added:   []string{"Require project deletion liens for all projects."},
+		},
+		{
+			Scanner: "location",
+			Added: []string{
+				"Project my-project audit logs bucket location whitelist.",
+				"Project my-project audit logs dataset location whitelist.",
+			},
+			Changed: []*RuleChange{
+				{
+					Name: "Global location whitelist.",
+					Fields: []*FieldChange{{
+						Path:    "locations",
+						Added:   []string{"US-CENTRAL1-F"},
+						Removed: []string{"EU"},
+					}},
+				},
+				{
+					Name: "Project my-project resource whitelist for location US-CENTRAL1-F.",
+					Fields: []*FieldChange{
+						{
+							Path:    "locations",
+							Added:   []string{"US-CENTRAL1-F"},
+							Removed: []string{"US-CENTRAL1-B"},
+						},
+						{
+							Path:    "name",
+							Added:   []string{"Project my-project resource whitelist for location US-CENTRAL1-F."},
+							Removed: []string{"Project my-project resource whitelist for location US-CENTRAL1-B."},
+						},
+					},
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("diffs differ (-got, +want):\n%v", diff)
+	}
+}
+
+func TestWriteRuleDiffs(t *testing.T) {
+	diffs := []*RuleDiff{{
+		Scanner:    "bigquery",
+		Duplicates: []string{"duplicated rule"},
+		Added:      []string{"added rule"},
+		Removed:    []string{"removed rule"},
+		Changed: []*RuleChange{{
+			Name: "changed rule",
+			Fields: []*FieldChange{{
+				Path:    "bindings[role=READER].members",
+				Added:   []string{"group_email=a@my-domain.com"},
+				Removed: []string{"user_email=b@my-domain.com"},
+			}},
+		}},
+	}}
+
+	var b bytes.Buffer
+	if err := WriteRuleDiffs(&b, diffs); err != nil {
+		t.Fatalf("WriteRuleDiffs = %v", err)
+	}
+
+	want := `bigquery:
+  ! "duplicated rule" is duplicated in the deployed rules
+  + "added rule"
+  - "removed rule"
+  ~ "changed rule"
+      bindings[role=READER].members:
+        + group_email=a@my-domain.com
+        - user_email=b@my-domain.com
+`
+	if diff := cmp.Diff(b.String(), want); diff != "" {
+		t.Errorf("output differs (-got, +want):\n%v", diff)
+	}
+}
@@ -0,0 +1,486 @@
+package rulegen
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/healthcare/deploy/cft"
+)
+
+// Violation is a resource that violates a rule.
+type Violation struct {
+	Scanner  string `json:"scanner"`
+	Rule     string `json:"rule"`
+	Resource string `json:"resource"`
+	Message  string `json:"message"`
+}
+
+// Asset types of the evaluated assets.
+const (
+	projectAssetType  = "cloudresourcemanager.googleapis.com/Project"
+	bucketAssetType   = "storage.googleapis.com/Bucket"
+	datasetAssetType  = "bigquery.googleapis.com/Dataset"
+	instanceAssetType = "compute.googleapis.com/Instance"
+	logSinkAssetType  = "logging.googleapis.com/LogSink"
+	serviceAssetType  = "serviceusage.googleapis.com/Service"
+)
+
+// Evaluate generates the rules of the config and evaluates them against the assets of a Cloud Asset Inventory export,
+// so rules can be checked without a Forseti server. It evaluates the bigquery, bucket, enabled APIs, location,
+// log sink and resource rules.
+func Evaluate(config *cft.Config, assets []*cft.Asset) ([]*Violation, error) {
+	gens, err := allGenerators(config)
+	if err != nil {
+		return nil, err
+	}
+	inv := newInventory(assets)
+	var vs []*Violation
+	for _, gen := range gens {
+		for _, r := range gen.rules {
+			vs = append(vs, inv.evaluate(r)...)
+		}
+	}
+	return vs, nil
+}
+
+// evaluate evaluates a rule of any scanner. Rules of scanners that are not evaluated have no violations.
+func (inv *inventory) evaluate(r rule) []*Violation {
+	switch r := r.(type) {
+	case *BigqueryRule:
+		return inv.evaluateBigqueryRules([]BigqueryRule{*r})
+	case *BucketRule:
+		return inv.evaluateBucketRules([]BucketRule{*r})
+	case *EnabledAPIsRule:
+		return inv.evaluateEnabledAPIsRules([]EnabledAPIsRule{*r})
+	case *LocationRule:
+		return inv.evaluateLocationRules([]LocationRule{*r})
+	case *LogSinkRule:
+		return inv.evaluateLogSinkRules([]LogSinkRule{*r})
+	case *ResourceRule:
+		return inv.evaluateResourceRules([]ResourceRule{*r})
+	}
+	return nil
+}
+
+// WriteViolations writes the violations grouped by rule.
+func WriteViolations(w io.Writer, vs []*Violation) error {
+	var b strings.Builder
+	var last *Violation
+	for _, v := range vs {
+		if last == nil || v.Scanner != last.Scanner || v.Rule != last.Rule {
+			fmt.Fprintf(&b, "[%s] %s\n", v.Scanner, v.Rule)
+		}
+		fmt.Fprintf(&b, "  %s: %s\n", v.Resource, v.Message)
+		last = v
+	}
+	fmt.Fprintf(&b, "%d violations\n", len(vs))
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// inventory indexes the assets of an export.
+type inventory struct {
+	byType map[string][]*cft.Asset
+
+	// projectIDs maps project numbers to project IDs.
+	projectIDs map[string]string
+}
+
+func newInventory(assets []*cft.Asset) *inventory {
+	inv := &inventory{
+		byType:     make(map[string][]*cft.Asset),
+		projectIDs: make(map[string]string),
+	}
+	for _, a := range assets {
+		inv.byType[a.AssetType] = append(inv.byType[a.AssetType], a)
+		if a.AssetType == projectAssetType {
+			inv.projectIDs[stringField(a.Data, "projectNumber")] = stringField(a.Data, "projectId")
+		}
+	}
+	return inv
+}
+
+var projectInNameRE = regexp.MustCompile(`/projects/([^/]+)`)
+
+// projectID returns the ID of the project the asset belongs to.
+func (inv *inventory) projectID(a *cft.Asset) string {
+	if a.AssetType == projectAssetType {
+		return stringField(a.Data, "projectId")
+	}
+	for _, anc := range a.Ancestors {
+		if strings.HasPrefix(anc, "projects/") {
+			if id, ok := inv.projectIDs[strings.TrimPrefix(anc, "projects/")]; ok {
+				return id
+			}
+		}
+	}
+	if m := projectInNameRE.FindStringSubmatch(a.Name); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// inScope determines whether the asset is in the scope of any of the rule resources.
+func (inv *inventory) inScope(rs []resource, a *cft.Asset) bool {
+	for _, r := range rs {
+		for _, id := range r.IDs {
+			switch r.Type {
+			case "", "project":
+				if id == "*" || id == inv.projectID(a) {
+					return true
+				}
+			case "organization", "folder":
+				for _, anc := range a.Ancestors {
+					if anc == r.Type+"s/"+id {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// forsetiResource returns the Forseti type, ID and location of data holding assets.
+func (inv *inventory) forsetiResource(a *cft.Asset) (typ, id, location string, ok bool) {
+	switch a.AssetType {
+	case bucketAssetType:
+		return "bucket", stringField(a.Data, "name"), stringField(a.Data, "location"), true
+	case datasetAssetType:
+		ref, _ := a.Data["datasetReference"].(map[string]interface{})
+		return "dataset", inv.projectID(a) + ":" + stringField(ref, "datasetId"), stringField(a.Data, "location"), true
+	case instanceAssetType:
+		return "instance", stringField(a.Data, "id"), path.Base(stringField(a.Data, "zone")), true
+	}
+	return "", "", "", false
+}
+
+func (inv *inventory) dataAssets() []*cft.Asset {
+	var assets []*cft.Asset
+	for _, typ := range []string{bucketAssetType, datasetAssetType, instanceAssetType} {
+		assets = append(assets, inv.byType[typ]...)
+	}
+	return assets
+}
+
+// violates determines whether a match of a whitelist or blacklist rule is a violation.
+func violates(mode string, matched bool) bool {
+	if mode == "blacklist" {
+		return matched
+	}
+	return !matched
+}
+
+func (inv *inventory) evaluateBigqueryRules(rules []BigqueryRule) []*Violation {
+	var vs []*Violation
+	for _, rule := range rules {
+		for _, a := range inv.byType[datasetAssetType] {
+			_, id, _, _ := inv.forsetiResource(a)
+			if !inv.inScope(rule.Resources, a) || !matchesAny(rule.DatasetIDs, id) {
+				continue
+			}
+			access, _ := a.Data["access"].([]interface{})
+			for _, e := range access {
+				entry, _ := e.(map[string]interface{})
+				m := bigqueryMember{
+					Domain:       stringField(entry, "domain"),
+					UserEmail:    stringField(entry, "userByEmail"),
+					GroupEmail:   stringField(entry, "groupByEmail"),
+					SpecialGroup: stringField(entry, "specialGroup"),
+				}
+				if view, ok := entry["view"].(map[string]interface{}); ok {
+					m.View = bigqueryView{
+						ProjectID: stringField(view, "projectId"),
+						DatasetID: stringField(view, "datasetId"),
+						TableID:   stringField(view, "tableId"),
+					}
+				}
+				if m == (bigqueryMember{}) {
+					continue
+				}
+				role := stringField(entry, "role")
+				if violates(rule.Mode, bindingsMatch(rule.Bindings, role, m)) {
+					desc := describeMember(m)
+					if role != "" {
+						desc = role + " " + desc
+					}
+					vs = append(vs, &Violation{
+						Scanner:  "bigquery",
+						Rule:     rule.Name,
+						Resource: a.Name,
+						Message:  fmt.Sprintf("access %s violates %s", desc, rule.Mode),
+					})
+				}
+			}
+		}
+	}
+	return vs
+}
+
+func bindingsMatch(bindings []bigqueryBinding, role string, m bigqueryMember) bool {
+	for _, b := range bindings {
+		if !matches(b.Role, role) {
+			continue
+		}
+		for _, rm := range b.Members {
+			if memberMatches(rm, m) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func memberMatches(rule, m bigqueryMember) bool {
+	switch {
+	case rule.Domain != "":
+		return m.Domain != "" && matches(rule.Domain, m.Domain)
+	case rule.UserEmail != "":
+		return m.UserEmail != "" && matches(rule.UserEmail, m.UserEmail)
+	case rule.GroupEmail != "":
+		return m.GroupEmail != "" && matches(rule.GroupEmail, m.GroupEmail)
+	case rule.SpecialGroup != "":
+		return m.SpecialGroup != "" && matches(rule.SpecialGroup, m.SpecialGroup)
+	case rule.View != (bigqueryView{}):
+		return m.View != (bigqueryView{}) && matches(rule.View.ProjectID, m.View.ProjectID) &&
+			matches(rule.View.DatasetID, m.View.DatasetID) && matches(rule.View.TableID, m.View.TableID)
+	}
+	return false
+}
+
+func describeMember(m bigqueryMember) string {
+	switch {
+	case m.Domain != "":
+		return "domain:" + m.Domain
+	case m.UserEmail != "":
+		return "user:" + m.UserEmail
+	case m.GroupEmail != "":
+		return "group:" + m.GroupEmail
+	case m.View != (bigqueryView{}):
+		return fmt.Sprintf("view:%s.%s.%s", m.View.ProjectID, m.View.DatasetID, m.View.TableID)
+	}
+	return "specialGroup:" + m.SpecialGroup
+}
+
+// evaluateBucketRules evaluates bucket ACL rules, which disallow matching ACL entries.
+func (inv *inventory) evaluateBucketRules(rules []BucketRule) []*Violation {
+	var vs []*Violation
+	for _, rule := range rules {
+		for _, a := range inv.byType[bucketAssetType] {
+			if !inv.inScope(rule.Resources, a) || !matches(rule.Bucket, stringField(a.Data, "name")) {
+				continue
+			}
+			acl, _ := a.Data["acl"].([]interface{})
+			for _, e := range acl {
+				entry, _ := e.(map[string]interface{})
+				if matches(rule.Entity, stringField(entry, "entity")) &&
+					matches(rule.Email, stringField(entry, "email")) &&
+					matches(rule.Domain, stringField(entry, "domain")) &&
+					matches(rule.Role, stringField(entry, "role")) {
+					vs = append(vs, &Violation{
+						Scanner:  "bucket",
+						Rule:     rule.Name,
+						Resource: a.Name,
+						Message:  fmt.Sprintf("ACL entry %s %s is not allowed", stringField(entry, "role"), stringField(entry, "entity")),
+					})
+				}
+			}
+		}
+	}
+	return vs
+}
+
+func (inv *inventory) evaluateEnabledAPIsRules(rules []EnabledAPIsRule) []*Violation {
+	var vs []*Violation
+	for _, rule := range rules {
+		for _, a := range inv.byType[serviceAssetType] {
+			if stringField(a.Data, "state") != "ENABLED" || !inv.inScope(rule.Resources, a) {
+				continue
+			}
+			service := path.Base(a.Name)
+			if violates(rule.Mode, matchesAny(rule.Services, service)) {
+				vs = append(vs, &Violation{
+					Scanner:  "enabled_apis",
+					Rule:     rule.Name,
+					Resource: a.Name,
+					Message:  fmt.Sprintf("API %s is enabled in project %s", service, inv.projectID(a)),
+				})
+			}
+		}
+	}
+	return vs
+}
+
+func (inv *inventory) evaluateLocationRules(rules []LocationRule) []*Violation {
+	var vs []*Violation
+	for _, rule := range rules {
+		for _, a := range inv.dataAssets() {
+			typ, id, loc, _ := inv.forsetiResource(a)
+			if !inv.inScope(rule.Resources, a) || !appliesToResource(rule.AppliesTo, typ, id) {
+				continue
+			}
+			loc = strings.ToUpper(loc)
+			matched := false
+			for _, l := range rule.Locations {
+				matched = matched || matches(strings.ToUpper(l), loc)
+			}
+			if violates(rule.Mode, matched) {
+				vs = append(vs, &Violation{
+					Scanner:  "location",
+					Rule:     rule.Name,
+					Resource: a.Name,
+					Message:  fmt.Sprintf("%s %s is in location %s", typ, id, loc),
+				})
+			}
+		}
+	}
+	return vs
+}
+
+func appliesToResource(applies []appliesTo, typ, id string) bool {
+	for _, at := range applies {
+		if (at.Type == "*" || at.Type == typ) && matchesAny(at.ResourceIDs, id) {
+			return true
+		}
+	}
+	return false
+}
+
+func (inv *inventory) evaluateLogSinkRules(rules []LogSinkRule) []*Violation {
+	sinks := make(map[string][]*cft.Asset)
+	for _, a := range inv.byType[logSinkAssetType] {
+		sinks[inv.projectID(a)] = append(sinks[inv.projectID(a)], a)
+	}
+
+	var vs []*Violation
+	for _, rule := range rules {
+		for _, p := range inv.byType[projectAssetType] {
+			if !inv.inScope(rule.Resources, p) {
+				continue
+			}
+			projectSinks := sinks[inv.projectID(p)]
+			if rule.Mode == "required" {
+				found := false
+				for _, s := range projectSinks {
+					found = found || sinkMatches(rule.Sink, s)
+				}
+				if !found {
+					vs = append(vs, &Violation{
+						Scanner:  "log_sink",
+						Rule:     rule.Name,
+						Resource: p.Name,
+						Message:  fmt.Sprintf("no log sink with destination %q and filter %q", rule.Sink.Destination, rule.Sink.Filter),
+					})
+				}
+				continue
+			}
+			for _, s := range projectSinks {
+				if violates(rule.Mode, sinkMatches(rule.Sink, s)) {
+					vs = append(vs, &Violation{
+						Scanner:  "log_sink",
+						Rule:     rule.Name,
+						Resource: s.Name,
+						Message:  fmt.Sprintf("log sink to %q violates %s", stringField(s.Data, "destination"), rule.Mode),
+					})
+				}
+			}
+		}
+	}
+	return vs
+}
+
+func sinkMatches(rule sink, a *cft.Asset) bool {
+	includeChildren, _ := a.Data["includeChildren"].(bool)
+	return matches(rule.Destination, stringField(a.Data, "destination")) &&
+		matches(rule.Filter, stringField(a.Data, "filter")) &&
+		matches(rule.IncludeChildren, strconv.FormatBool(includeChildren))
+}
+
+// evaluateResourceRules evaluates resource rules, which require the resources in each project tree to exist
+// and no other resources of the rule's types to exist in the project.
+func (inv *inventory) evaluateResourceRules(rules []ResourceRule) []*Violation {
+	var vs []*Violation
+	for _, rule := range rules {
+		types := make(map[string]bool)
+		for _, t := range rule.ResourceTypes {
+			types[t] = true
+		}
+		for _, tree := range rule.ResourceTrees {
+			if tree.Type != "project" || tree.ResourceID == "*" {
+				continue
+			}
+			var project *cft.Asset
+			for _, p := range inv.byType[projectAssetType] {
+				if inv.projectID(p) == tree.ResourceID {
+					project = p
+				}
+			}
+			if project == nil {
+				vs = append(vs, &Violation{
+					Scanner:  "resource",
+					Rule:     rule.Name,
+					Resource: "project " + tree.ResourceID,
+					Message:  "project is missing",
+				})
+				continue
+			}
+
+			want := make(map[string]bool)
+			for _, c := range tree.Children {
+				want[c.Type+" "+c.ResourceID] = true
+			}
+			got := make(map[string]bool)
+			for _, a := range inv.dataAssets() {
+				typ, id, _, _ := inv.forsetiResource(a)
+				if !types[typ] || (inv.projectID(a) != tree.ResourceID && !want[typ+" "+id]) {
+					continue
+				}
+				got[typ+" "+id] = true
+				if !want[typ+" "+id] {
+					vs = append(vs, &Violation{
+						Scanner:  "resource",
+						Rule:     rule.Name,
+						Resource: a.Name,
+						Message:  fmt.Sprintf("%s %s is not in the resource tree of project %s", typ, id, tree.ResourceID),
+					})
+				}
+			}
+			for _, c := range tree.Children {
+				if !got[c.Type+" "+c.ResourceID] {
+					vs = append(vs, &Violation{
+						Scanner:  "resource",
+						Rule:     rule.Name,
+						Resource: project.Name,
+						Message:  fmt.Sprintf("%s %s is missing", c.Type, c.ResourceID),
+					})
+				}
+			}
+		}
+	}
+	return vs
+}
+
+// matches determines whether the value matches the rule pattern, in which * matches any sequence of characters.
+func matches(pattern, value string) bool {
+	re := "^" + strings.Replace(regexp.QuoteMeta(pattern), `\*`, ".*", -1) + "$"
+	ok, err := regexp.MatchString(re, value)
+	return err == nil && ok
+}
+
+func matchesAny(patterns []string, value string) bool {
+	for _, p := range patterns {
+		if matches(p, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func stringField(m map[string]interface{}, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
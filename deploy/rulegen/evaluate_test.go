@@ -0,0 +1,153 @@
+package rulegen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/healthcare/deploy/cft"
+	"github.com/google/go-cmp/cmp"
+)
+
+// evalAssetExport is a Cloud Asset Inventory export of the project in locConfigData.
+// foo-dataset grants access to all authenticated users and an undeclared authorized view, my-project-foo-bucket is in the wrong location, baz-api is
+// enabled, rogue-bucket was not deployed and has an ACL, and foo-instance is missing.
+const evalAssetExport = `
+{"name":"//cloudresourcemanager.googleapis.com/projects/1111","asset_type":"cloudresourcemanager.googleapis.com/Project","ancestors":["projects/1111","folders/98765321","organizations/12345678"],"resource":{"data":{"projectId":"my-project","projectNumber":"1111"}}}
+{"name":"//storage.googleapis.com/my-project-logs","asset_type":"storage.googleapis.com/Bucket","ancestors":["projects/1111","folders/98765321","organizations/12345678"],"resource":{"data":{"name":"my-project-logs","location":"US"}}}
+{"name":"//storage.googleapis.com/my-project-foo-bucket","asset_type":"storage.googleapis.com/Bucket","ancestors":["projects/1111","folders/98765321","organizations/12345678"],"resource":{"data":{"name":"my-project-foo-bucket","location":"EUROPE-WEST1"}}}
+{"name":"//storage.googleapis.com/rogue-bucket","asset_type":"storage.googleapis.com/Bucket","ancestors":["projects/1111","folders/98765321","organizations/12345678"],"resource":{"data":{"name":"rogue-bucket","location":"US","acl":[{"entity":"allUsers","role":"READER"}]}}}
+{"name":"//bigquery.googleapis.com/projects/my-project/datasets/audit_logs","asset_type":"bigquery.googleapis.com/Dataset","ancestors":["projects/1111","folders/98765321","organizations/12345678"],"resource":{"data":{"datasetReference":{"datasetId":"audit_logs"},"location":"US","access":[{"role":"OWNER","groupByEmail":"my-project-owners@my-domain.com"},{"role":"WRITER","userByEmail":"audit-logs-bq@logging-1111.iam.gserviceaccount.com"},{"role":"READER","groupByEmail":"my-project-auditors@my-domain.com"}]}}}
+{"name":"//bigquery.googleapis.com/projects/my-project/datasets/foo-dataset","asset_type":"bigquery.googleapis.com/Dataset","ancestors":["projects/1111","folders/98765321","organizations/12345678"],"resource":{"data":{"datasetReference":{"datasetId":"foo-dataset"},"location":"US","access":[{"role":"OWNER","groupByEmail":"my-project-owners@my-domain.com"},{"role":"READER","groupByEmail":"my-project-readonly@my-domain.com"},{"role":"READER","specialGroup":"allAuthenticatedUsers"},{"view":{"projectId":"my-project","datasetId":"views","tableId":"foo_view"}}]}}}
+{"name":"//logging.googleapis.com/projects/my-project/sinks/audit-logs-to-bigquery","asset_type":"logging.googleapis.com/LogSink","ancestors":["projects/1111","folders/98765321","organizations/12345678"],"resource":{"data":{"name":"audit-logs-to-bigquery","destination":"bigquery.googleapis.com/projects/my-project/datasets/audit_logs","filter":"logName:\"logs/cloudaudit.googleapis.com\""}}}
+{"name":"//serviceusage.googleapis.com/projects/1111/services/foo-api.googleapis.com","asset_type":"serviceusage.googleapis.com/Service","ancestors":["projects/1111","folders/98765321","organizations/12345678"],"resource":{"data":{"state":"ENABLED"}}}
+{"name":"//serviceusage.googleapis.com/projects/1111/services/bar-api.googleapis.com","asset_type":"serviceusage.googleapis.com/Service","ancestors":["projects/1111","folders/98765321","organizations/12345678"],"resource":{"data":{"state":"DISABLED"}}}
+{"name":"//serviceusage.googleapis.com/projects/1111/services/baz-api.googleapis.com","asset_type":"serviceusage.googleapis.com/Service","ancestors":["projects/1111","folders/98765321","organizations/12345678"],"resource":{"data":{"state":"ENABLED"}}}
+`
+
+func TestEvaluate(t *testing.T) {
+	config, _ := getTestConfigAndProject(t, locConfigData)
+	assets, err := cft.ReadAssets(strings.NewReader(evalAssetExport))
+	if err != nil {
+		t.Fatalf("cft.ReadAssets: %v", err)
+	}
+
+	got, err := Evaluate(config, assets)
+	if err != nil {
+		t.Fatalf("Evaluate: %v", err)
+	}
+
+	want := []*Violation{
+		{
+			Scanner:  "bigquery",
+			Rule:     "No public, domain or special group dataset access.",
+			Resource: "//bigquery.googleapis.com/projects/my-project/datasets/foo-dataset",
+			Message:  "access READER specialGroup:allAuthenticatedUsers violates blacklist",
+		},
+		{
+			Scanner:  "bigquery",
+			Rule:     "Whitelist for dataset(s): my-project:foo-dataset",
+			Resource: "//bigquery.googleapis.com/projects/my-project/datasets/foo-dataset",
+			Message:  "access READER specialGroup:allAuthenticatedUsers violates whitelist",
+		},
+		{
+			Scanner:  "bigquery",
+			Rule:     "Whitelist for dataset(s): my-project:foo-dataset",
+			Resource: "//bigquery.googleapis.com/projects/my-project/datasets/foo-dataset",
+			Message:  "access view:my-project.views.foo_view violates whitelist",
+		},
+		{
+			Scanner:  "bucket",
+			Rule:     "Disallow all acl rules, only allow IAM.",
+			Resource: "//storage.googleapis.com/rogue-bucket",
+			Message:  "ACL entry READER allUsers is not allowed",
+		},
+		{
+			Scanner:  "enabled_apis",
+			Rule:     "Global API whitelist.",
+			Resource: "//serviceusage.googleapis.com/projects/1111/services/baz-api.googleapis.com",
+			Message:  "API baz-api.googleapis.com is enabled in project my-project",
+		},
+		{
+			Scanner:  "enabled_apis",
+			Rule:     "API whitelist for my-project.",
+			Resource: "//serviceusage.googleapis.com/projects/1111/services/baz-api.googleapis.com",
+			Message:  "API baz-api.googleapis.com is enabled in project my-project",
+		},
+		{
+			Scanner:  "location",
+			Rule:     "Global location whitelist.",
+			Resource: "//storage.googleapis.com/my-project-foo-bucket",
+			Message:  "bucket my-project-foo-bucket is in location EUROPE-WEST1",
+		},
+		{
+			Scanner:  "location",
+			Rule:     "Project my-project resource whitelist for location US-CENTRAL1.",
+			Resource: "//storage.googleapis.com/my-project-foo-bucket",
+			Message:  "bucket my-project-foo-bucket is in location EUROPE-WEST1",
+		},
+		{
+			Scanner:  "resource",
+			Rule:     "Project resource trees.",
+			Resource: "//storage.googleapis.com/rogue-bucket",
+			Message:  "bucket rogue-bucket is not in the resource tree of project my-project",
+		},
+		{
+			Scanner:  "resource",
+			Rule:     "Project resource trees.",
+			Resource: "//cloudresourcemanager.googleapis.com/projects/1111",
+			Message:  "instance 123 is missing",
+		},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("violations differ (-got +want):\n%v", diff)
+	}
+
+	var b strings.Builder
+	if err := WriteViolations(&b, got[:4]); err != nil {
+		t.Fatalf("WriteViolations: %v", err)
+	}
+	wantText := `[bigquery] No public, domain or special group dataset access.
+  //bigquery.googleapis.com/projects/my-project/datasets/foo-dataset: access READER specialGroup:allAuthenticatedUsers violates blacklist
+[bigquery] Whitelist for dataset(s): my-project:foo-dataset
+  //bigquery.googleapis.com/projects/my-project/datasets/foo-dataset: access READER specialGroup:allAuthenticatedUsers violates whitelist
+  //bigquery.googleapis.com/projects/my-project/datasets/foo-dataset: access view:my-project.views.foo_view violates whitelist
+[bucket] Disallow all acl rules, only allow IAM.
+  //storage.googleapis.com/rogue-bucket: ACL entry READER allUsers is not allowed
+4 violations
+`
+	if diff := cmp.Diff(b.String(), wantText); diff != "" {
+		t.Errorf("text differs (-got +want):\n%v", diff)
+	}
+}
+
+func TestEvaluateNoViolations(t *testing.T) {
+	config, _ := getTestConfigAndProject(t, &ConfigData{`
+resources:
+- bigquery_dataset:
+    properties:
+      name: foo-dataset
+      location: US
+      access:
+      - view:
+          projectId: my-project
+          datasetId: views
+          tableId: foo_view`})
+	assets, err := cft.ReadAssets(strings.NewReader(`
+{"name":"//cloudresourcemanager.googleapis.com/projects/1111","asset_type":"cloudresourcemanager.googleapis.com/Project","ancestors":["projects/1111","organizations/12345678"],"resource":{"data":{"projectId":"my-project","projectNumber":"1111"}}}
+{"name":"//storage.googleapis.com/my-project-logs","asset_type":"storage.googleapis.com/Bucket","ancestors":["projects/1111","organizations/12345678"],"resource":{"data":{"name":"my-project-logs","location":"US"}}}
+{"name":"//bigquery.googleapis.com/projects/my-project/datasets/audit_logs","asset_type":"bigquery.googleapis.com/Dataset","ancestors":["projects/1111","organizations/12345678"],"resource":{"data":{"datasetReference":{"datasetId":"audit_logs"},"location":"US"}}}
+{"name":"//bigquery.googleapis.com/projects/my-project/datasets/foo-dataset","asset_type":"bigquery.googleapis.com/Dataset","ancestors":["projects/1111","organizations/12345678"],"resource":{"data":{"datasetReference":{"datasetId":"foo-dataset"},"location":"US","access":[{"role":"OWNER","groupByEmail":"my-project-owners@my-domain.com"},{"view":{"projectId":"my-project","datasetId":"views","tableId":"foo_view"}}]}}}
+{"name":"//logging.googleapis.com/projects/my-project/sinks/audit-logs-to-bigquery","asset_type":"logging.googleapis.com/LogSink","ancestors":["projects/1111","organizations/12345678"],"resource":{"data":{"destination":"bigquery.googleapis.com/projects/my-project/datasets/audit_logs","filter":"logName:\"logs/cloudaudit.googleapis.com\""}}}
+`))
+	if err != nil {
+		t.Fatalf("cft.ReadAssets: %v", err)
+	}
+
+	got, err := Evaluate(config, assets)
+	if err != nil {
+		t.Fatalf("Evaluate: %v", err)
+	}
+	for _, v := range got {
+		t.Errorf("Evaluate: got violation %+v, want none", v)
+	}
+}
@@ -0,0 +1,237 @@
+package rulegen
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/GoogleCloudPlatform/healthcare/deploy/cft"
+	"gopkg.in/yaml.v2"
+)
+
+// RuleRef identifies a generated rule and the parts of the config it was generated from.
+// The index of all rules is written next to the rules so violations can be traced back to the config.
+type RuleRef struct {
+	ID      string       `yaml:"id"`
+	Scanner string       `yaml:"scanner"`
+	Name    string       `yaml:"name"`
+	Sources []RuleSource `yaml:"sources"`
+}
+
+// RuleSource references a part of the config.
+type RuleSource struct {
+	ProjectID string `yaml:"project_id,omitempty"`
+
+	// ResourceIndex is the index of the resource in the resources of the project, if the source is a resource.
+	ResourceIndex *int `yaml:"resource_index,omitempty"`
+
+	// Path is the YAML path of the source in the config, e.g. projects[0].resources[1].gcs_bucket.
+	Path string `yaml:"path"`
+}
+
+// RuleIndex builds the references of all generated rules.
+func RuleIndex(config *cft.Config) ([]*RuleRef, error) {
+	srcs, err := newSourceIndex(config)
+	if err != nil {
+		return nil, err
+	}
+
+	gens, err := allGenerators(config)
+	if err != nil {
+		return nil, err
+	}
+	var refs []*RuleRef
+	for _, gen := range gens {
+		// Rules with the same key, such as the dataset whitelists of a project, are told apart by their ordinal.
+		counts := make(map[string]int)
+		for _, r := range gen.rules {
+			counts[r.key()]++
+			refs = append(refs, &RuleRef{
+				ID:      ruleID(gen.name, r.key(), counts[r.key()]),
+				Scanner: gen.name,
+				Name:    r.ruleName(),
+				Sources: r.sources(srcs),
+			})
+		}
+	}
+	return refs, nil
+}
+
+func (r *AuditLoggingRule) sources(idx *sourceIndex) []RuleSource { return idx.scope(r.Resources) }
+func (r *BucketRule) sources(idx *sourceIndex) []RuleSource       { return idx.scope(r.Resources) }
+func (r *CloudSQLRule) sources(idx *sourceIndex) []RuleSource     { return idx.scope(r.Resources) }
+func (r *LienRule) sources(idx *sourceIndex) []RuleSource         { return idx.scope(r.Resources) }
+
+func (r *BigqueryRule) sources(idx *sourceIndex) []RuleSource {
+	return idx.resources("dataset", r.DatasetIDs, r.Resources)
+}
+
+func (r *EnabledAPIsRule) sources(idx *sourceIndex) []RuleSource {
+	return idx.projectField(r.Resources, "enabled_apis", "overall.allowed_apis")
+}
+
+func (r *LocationRule) sources(idx *sourceIndex) []RuleSource {
+	var sources []RuleSource
+	for _, at := range r.AppliesTo {
+		sources = append(sources, idx.resources(at.Type, at.ResourceIDs, r.Resources)...)
+	}
+	return sources
+}
+
+func (r *LogSinkRule) sources(idx *sourceIndex) []RuleSource {
+	return idx.projectField(r.Resources, "audit_logs", "")
+}
+
+func (r *ResourceRule) sources(idx *sourceIndex) []RuleSource {
+	var sources []RuleSource
+	for _, t := range r.ResourceTrees {
+		if t.ResourceID == "*" {
+			continue
+		}
+		sources = append(sources, idx.byKey[sourceKey("project", t.ResourceID)]...)
+		for _, c := range t.Children {
+			sources = append(sources, idx.byKey[sourceKey(c.Type, c.ResourceID)]...)
+		}
+	}
+	return sources
+}
+
+// WriteRuleIndex writes the rule references to the file at path.
+func WriteRuleIndex(path string, refs []*RuleRef) error {
+	b, err := yaml.Marshal(refs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal rule index: %v", err)
+	}
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		return fmt.Errorf("failed to write rule index: %v", err)
+	}
+	return nil
+}
+
+// sourceIndex maps the Forseti resources generated from the config to their sources.
+type sourceIndex struct {
+	config *cft.Config
+
+	// byKey maps Forseti resource types and IDs to their sources.
+	byKey map[string][]RuleSource
+
+	// projectPaths maps project IDs to their paths in the config.
+	projectPaths map[string]string
+}
+
+func sourceKey(typ, id string) string {
+	return typ + "/" + id
+}
+
+func newSourceIndex(config *cft.Config) (*sourceIndex, error) {
+	idx := &sourceIndex{
+		config:       config,
+		byKey:        make(map[string][]RuleSource),
+		projectPaths: make(map[string]string),
+	}
+	if config.AuditLogsProject != nil {
+		if err := idx.addProject(config.AuditLogsProject, "audit_logs_project"); err != nil {
+			return nil, err
+		}
+	}
+	for i, p := range config.Projects {
+		if err := idx.addProject(p, fmt.Sprintf("projects[%d]", i)); err != nil {
+			return nil, err
+		}
+	}
+	return idx, nil
+}
+
+func (idx *sourceIndex) addProject(p *cft.Project, path string) error {
+	idx.projectPaths[p.ID] = path
+	idx.add("project", p.ID, RuleSource{ProjectID: p.ID, Path: path})
+
+	for i, r := range p.Resources {
+		if r.Kind == nil || !r.Kind.HoldsData {
+			continue
+		}
+		id, err := r.Kind.ForsetiID(p, r.Parsed)
+		if err != nil {
+			return fmt.Errorf("failed to get ID of resource %d of project %q: %v", i, p.ID, err)
+		}
+		i := i
+		idx.add(r.Kind.ForsetiType, id, RuleSource{
+			ProjectID:     p.ID,
+			ResourceIndex: &i,
+			Path:          fmt.Sprintf("%s.resources[%d].%s", path, i, r.Kind.Key),
+		})
+	}
+
+	idx.add("bucket", p.AuditLogs.LogsGCSBucket.Name, RuleSource{
+		ProjectID: p.ID,
+		Path:      path + ".audit_logs.logs_gcs_bucket",
+	})
+	datasetID := fmt.Sprintf("%s:%s", idx.config.AuditLogsProjectID(p), p.AuditLogs.LogsBigqueryDataset.Name)
+	idx.add("dataset", datasetID, RuleSource{
+		ProjectID: p.ID,
+		Path:      path + ".audit_logs.logs_bigquery_dataset",
+	})
+	return nil
+}
+
+func (idx *sourceIndex) add(typ, id string, s RuleSource) {
+	key := sourceKey(typ, id)
+	idx.byKey[key] = append(idx.byKey[key], s)
+}
+
+// resources returns the sources of the Forseti resources of the given type and IDs, or of the rule scope if the
+// rule applies to all resources.
+func (idx *sourceIndex) resources(typ string, ids []string, scope []resource) []RuleSource {
+	var sources []RuleSource
+	for _, id := range ids {
+		if typ == "*" || id == "*" {
+			return idx.scope(scope)
+		}
+		sources = append(sources, idx.byKey[sourceKey(typ, id)]...)
+	}
+	return sources
+}
+
+// projectField returns the sources of the field of each project in the scope, or the global path for rules
+// applying to all projects. If global is empty, the scope itself is the source of global rules.
+func (idx *sourceIndex) projectField(scope []resource, field, global string) []RuleSource {
+	var sources []RuleSource
+	for _, r := range scope {
+		if r.Type != "" && r.Type != "project" {
+			return idx.scope(scope)
+		}
+		for _, id := range r.IDs {
+			path, ok := idx.projectPaths[id]
+			switch {
+			case id == "*" && global != "":
+				sources = append(sources, RuleSource{Path: global})
+			case id == "*":
+				return idx.scope(scope)
+			case ok:
+				sources = append(sources, RuleSource{ProjectID: id, Path: path + "." + field})
+			}
+		}
+	}
+	return sources
+}
+
+// scope returns the sources of the organization, folder or projects a rule applies to.
+func (idx *sourceIndex) scope(scope []resource) []RuleSource {
+	var sources []RuleSource
+	for _, r := range scope {
+		switch r.Type {
+		case "organization":
+			sources = append(sources, RuleSource{Path: "overall.organization_id"})
+		case "folder":
+			sources = append(sources, RuleSource{Path: "overall.folder_id"})
+		default:
+			for _, id := range r.IDs {
+				if id == "*" {
+					sources = append(sources, RuleSource{Path: "overall"})
+					continue
+				}
+				sources = append(sources, idx.byKey[sourceKey("project", id)]...)
+			}
+		}
+	}
+	return sources
+}
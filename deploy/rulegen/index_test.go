@@ -0,0 +1,161 @@
+package rulegen
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gopkg.in/yaml.v2"
+)
+
+const wantRuleIndexYAML = `
+- id: audit_logging-4abfb60963ab
+  scanner: audit_logging
+  name: Require all Cloud Audit logs.
+  sources:
+  - path: overall
+- id: bigquery-c39c56ed462e
+  scanner: bigquery
+  name: No public, domain or special group dataset access.
+  sources:
+  - path: overall.organization_id
+- id: bigquery-9727ffcd4c75
+  scanner: bigquery
+  name: 'Whitelist for dataset(s): my-project:foo-dataset'
+  sources:
+  - project_id: my-project
+    resource_index: 0
+    path: projects[0].resources[0].bigquery_dataset
+- id: bigquery-5b6199235b15
+  scanner: bigquery
+  name: Whitelist for project my-project audit logs
+  sources:
+  - project_id: my-project
+    path: projects[0].audit_logs.logs_bigquery_dataset
+- id: bucket-3d7b1ee2de49
+  scanner: bucket
+  name: Disallow all acl rules, only allow IAM.
+  sources:
+  - path: overall
+- id: cloudsql-311f35e3178b
+  scanner: cloudsql
+  name: Disallow publicly exposed cloudsql instances (SSL disabled).
+  sources:
+  - path: overall.organization_id
+- id: cloudsql-96d12a9c2ff3
+  scanner: cloudsql
+  name: Disallow publicly exposed cloudsql instances (SSL enabled).
+  sources:
+  - path: overall.organization_id
+- id: enabled_apis-d474539d2a2f
+  scanner: enabled_apis
+  name: Global API whitelist.
+  sources:
+  - path: overall.allowed_apis
+- id: enabled_apis-e24ea2f11a12
+  scanner: enabled_apis
+  name: API whitelist for my-project.
+  sources:
+  - project_id: my-project
+    path: projects[0].enabled_apis
+- id: lien-e797b8dbe00c
+  scanner: lien
+  name: Require project deletion liens for all projects.
+  sources:
+  - path: overall.organization_id
+- id: location-6da8e9986f1e
+  scanner: location
+  name: Global location whitelist.
+  sources:
+  - path: overall.organization_id
+- id: location-2e905696886d
+  scanner: location
+  name: Project my-project resource whitelist for location US.
+  sources:
+  - project_id: my-project
+    resource_index: 0
+    path: projects[0].resources[0].bigquery_dataset
+- id: location-383e448dc5ec
+  scanner: location
+  name: Project my-project resource whitelist for location US-CENTRAL1.
+  sources:
+  - project_id: my-project
+    resource_index: 2
+    path: projects[0].resources[2].gcs_bucket
+- id: location-9cc4bc4b40a6
+  scanner: location
+  name: Project my-project resource whitelist for location US-CENTRAL1-F.
+  sources:
+  - project_id: my-project
+    resource_index: 1
+    path: projects[0].resources[1].gce_instance
+- id: location-04014fdf2837
+  scanner: location
+  name: Project my-project audit logs bucket location whitelist.
+  sources:
+  - project_id: my-project
+    path: projects[0].audit_logs.logs_gcs_bucket
+- id: location-3e0f6efb1a08
+  scanner: location
+  name: Project my-project audit logs dataset location whitelist.
+  sources:
+  - project_id: my-project
+    path: projects[0].audit_logs.logs_bigquery_dataset
+- id: log_sink-19c0f27337d5
+  scanner: log_sink
+  name: Require a BigQuery Log sink in all projects.
+  sources:
+  - path: overall.organization_id
+- id: log_sink-8dc16d7eacf9
+  scanner: log_sink
+  name: Only allow BigQuery Log sinks in all projects.
+  sources:
+  - path: overall.organization_id
+- id: log_sink-af07f03a6b85
+  scanner: log_sink
+  name: Require Log sink for project my-project.
+  sources:
+  - project_id: my-project
+    path: projects[0].audit_logs
+- id: log_sink-082b76e0c8da
+  scanner: log_sink
+  name: Whitelist Log sink for project my-project.
+  sources:
+  - project_id: my-project
+    path: projects[0].audit_logs
+- id: resource-97c1faf39ce9
+  scanner: resource
+  name: Project resource trees.
+  sources:
+  - project_id: my-project
+    path: projects[0]
+  - project_id: my-project
+    path: projects[0].audit_logs.logs_gcs_bucket
+  - project_id: my-project
+    path: projects[0].audit_logs.logs_bigquery_dataset
+  - project_id: my-project
+    resource_index: 2
+    path: projects[0].resources[2].gcs_bucket
+  - project_id: my-project
+    resource_index: 0
+    path: projects[0].resources[0].bigquery_dataset
+  - project_id: my-project
+    resource_index: 1
+    path: projects[0].resources[1].gce_instance
+`
+
+func TestRuleIndex(t *testing.T) {
+	config, _ := getTestConfigAndProject(t, locConfigData)
+	got, err := RuleIndex(config)
+	if err != nil {
+		t.Fatalf("RuleIndex = %v", err)
+	}
+
+	var want []*RuleRef
+	if err := yaml.Unmarshal([]byte(wantRuleIndexYAML), &want); err != nil {
+		t.Fatalf("yaml.Unmarshal = %v", err)
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("rule index differs (-got, +want):\n%v", diff)
+	}
+}
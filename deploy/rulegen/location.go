@@ -22,6 +22,10 @@ type appliesTo struct {
 	ResourceIDs []string `yaml:"resource_ids"`
 }
 
+// resourceLocationsRuleNameInfix is in the names of the rules whitelisting the location of the resources of a
+// project, between the project ID and the location.
+const resourceLocationsRuleNameInfix = " resource whitelist for location "
+
 // LocationRules builds location scanner rules for the given config.
 func LocationRules(config *cft.Config) ([]LocationRule, error) {
 	allLocs := make(map[string]bool)
@@ -43,7 +47,7 @@ func LocationRules(config *cft.Config) ([]LocationRule, error) {
 			}
 
 			projectRules = append(projectRules, LocationRule{
-				Name:      fmt.Sprintf("Project %s resource whitelist for location %s.", project.ID, loc),
+				Name:      fmt.Sprintf("Project %s%s%s.", project.ID, resourceLocationsRuleNameInfix, loc),
 				Mode:      "whitelist",
 				Resources: []resource{{Type: "project", IDs: []string{project.ID}}},
 				AppliesTo: applies,
@@ -93,7 +97,8 @@ func LocationRules(config *cft.Config) ([]LocationRule, error) {
 		Locations: locs,
 	}
 
-	return append([]LocationRule{globalRule}, projectRules...), nil
+	rules := append([]LocationRule{globalRule}, projectRules...)
+	return rules, nil
 }
 
 // locationToResourceInfo is used to group locations of multiple resources by their location and type.
@@ -114,20 +119,12 @@ func (m locationToResources) locations() []string {
 }
 
 func (m locationToResources) addResources(project *cft.Project) error {
-	rs := project.DataResources()
-	for _, bucket := range rs.GCSBuckets {
-		m.add(bucket.Location, "bucket", bucket.Name())
-	}
-	for _, dataset := range rs.BigqueryDatasets {
-		id := fmt.Sprintf("%s:%s", project.ID, dataset.Name())
-		m.add(dataset.Location, "dataset", id)
-	}
-	for _, instance := range rs.GCEInstances {
-		id, err := project.InstanceID(instance.Name())
+	for _, r := range project.DataHoldingResources() {
+		id, err := r.Kind.ForsetiID(project, r.Parsed)
 		if err != nil {
 			return err
 		}
-		m.add(instance.Zone, "instance", id)
+		m.add(r.Kind.Location(r.Parsed), r.Kind.ForsetiType, id)
 	}
 	return nil
 }
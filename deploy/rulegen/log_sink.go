@@ -69,9 +69,8 @@ func LogSinkRules(config *cft.Config) ([]LogSinkRule, error) {
 
 func getSink(destination string) sink {
 	return sink{
-		Destination: destination,
-		// TODO: Change the filter to be specifically an audit logs sink once deployed log sinks use that filter.
-		Filter:          "*",
+		Destination:     destination,
+		Filter:          cft.AuditLogsSinkFilter,
 		IncludeChildren: "*",
 	}
 }
@@ -24,7 +24,7 @@ func TestLogSinkRules(t *testing.T) {
     - '12345678'
   sink:
     destination: 'bigquery.googleapis.com/*'
-    filter: '*'
+    filter: 'logName:"logs/cloudaudit.googleapis.com"'
     include_children: '*'
 - name: 'Only allow BigQuery Log sinks in all projects.'
   mode: whitelist
@@ -35,7 +35,7 @@ func TestLogSinkRules(t *testing.T) {
     - '12345678'
   sink:
     destination: 'bigquery.googleapis.com/*'
-    filter: '*'
+    filter: 'logName:"logs/cloudaudit.googleapis.com"'
     include_children: '*'
 - name: 'Require Log sink for project my-project.'
   mode: required
@@ -47,7 +47,7 @@ func TestLogSinkRules(t *testing.T) {
   sink:
     destination: >-
       bigquery.googleapis.com/projects/my-project/datasets/audit_logs
-    filter: '*'
+    filter: 'logName:"logs/cloudaudit.googleapis.com"'
     include_children: '*'
 - name: 'Whitelist Log sink for project my-project.'
   mode: whitelist
@@ -59,7 +59,7 @@ func TestLogSinkRules(t *testing.T) {
   sink:
     destination: >-
       bigquery.googleapis.com/projects/my-project/datasets/audit_logs
-    filter: '*'
+    filter: 'logName:"logs/cloudaudit.googleapis.com"'
     include_children: '*'
 `
 	want := make([]LogSinkRule, 0)
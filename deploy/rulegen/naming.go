@@ -0,0 +1,31 @@
+package rulegen
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// ruleNames makes the names of the rules of a scanner unique, as Forseti identifies violated rules by name.
+type ruleNames map[string]bool
+
+// unique returns the name, suffixed with a counter if a previous rule already has it.
+func (n ruleNames) unique(name string) string {
+	u := name
+	for i := 2; n[u]; i++ {
+		u = fmt.Sprintf("%s (%d)", name, i)
+	}
+	n[u] = true
+	return u
+}
+
+// ruleID returns a stable ID for the n-th rule of the scanner with the given key (see rule.key), counting from 1.
+// IDs only change when the project or kind of rule a rule was generated for changes, not with the resources it lists.
+func ruleID(scanner, key string, n int) string {
+	s := scanner + "\n" + key
+	if n > 1 {
+		s += fmt.Sprintf("\n%d", n)
+	}
+	h := sha256.Sum256([]byte(s))
+	return fmt.Sprintf("%s-%s", scanner, hex.EncodeToString(h[:])[:12])
+}
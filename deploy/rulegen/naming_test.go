@@ -0,0 +1,100 @@
+package rulegen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRuleNamesUnique(t *testing.T) {
+	names := make(ruleNames)
+	var got []string
+	for _, n := range []string{"foo", "bar", "foo", "foo"} {
+		got = append(got, names.unique(n))
+	}
+	want := []string{"foo", "bar", "foo (2)", "foo (3)"}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("names differ (-got, +want):\n%v", diff)
+	}
+}
+
+func TestRuleID(t *testing.T) {
+	id := ruleID("bigquery", "foo", 1)
+	if !strings.HasPrefix(id, "bigquery-") {
+		t.Errorf("ruleID = %q, want prefix %q", id, "bigquery-")
+	}
+	if got := ruleID("bigquery", "foo", 1); got != id {
+		t.Errorf("ruleID = %q, want stable ID %q", got, id)
+	}
+	if got := ruleID("location", "foo", 1); got == id {
+		t.Errorf("ruleID = %q for rules of different scanners, want different IDs", got)
+	}
+	if got := ruleID("bigquery", "foo", 2); got == id {
+		t.Errorf("ruleID = %q for rules with different ordinals, want different IDs", got)
+	}
+}
+
+func TestRuleIDsIgnoreListedResources(t *testing.T) {
+	ids := func(datasets ...string) map[string]string {
+		var resources strings.Builder
+		resources.WriteString("resources:\n")
+		for _, name := range datasets {
+			resources.WriteString("- bigquery_dataset:\n    properties:\n      name: " + name + "\n      location: US\n")
+		}
+		config, _ := getTestConfigAndProject(t, &ConfigData{resources.String()})
+		refs, err := RuleIndex(config)
+		if err != nil {
+			t.Fatalf("RuleIndex = %v", err)
+		}
+		m := make(map[string]string)
+		for _, r := range refs {
+			if r.Scanner == "bigquery" {
+				m[r.Name] = r.ID
+			}
+		}
+		return m
+	}
+
+	before := ids("foo-dataset")
+	after := ids("foo-dataset", "bar-dataset")
+	if got, want := after["Whitelist for dataset(s): my-project:foo-dataset, my-project:bar-dataset"], before["Whitelist for dataset(s): my-project:foo-dataset"]; got != want {
+		t.Errorf("ID of dataset rule after adding a dataset = %q, want unchanged ID %q", got, want)
+	}
+}
+
+func TestGeneratedRuleNamesUnique(t *testing.T) {
+	config, _ := getTestConfigAndProject(t, nil)
+	gens, err := allGenerators(config)
+	if err != nil {
+		t.Fatalf("allGenerators = %v", err)
+	}
+	for _, gen := range gens {
+		names := make(map[string]bool)
+		for _, r := range gen.rules {
+			if names[r.ruleName()] {
+				t.Errorf("%s rule name %q is not unique", gen.name, r.ruleName())
+			}
+			names[r.ruleName()] = true
+		}
+	}
+}
+
+func TestBigqueryRuleNamesNotTruncated(t *testing.T) {
+	var resources strings.Builder
+	resources.WriteString("resources:\n")
+	for _, name := range []string{"a-long-dataset-name-1", "a-long-dataset-name-2", "a-long-dataset-name-3", "a-long-dataset-name-4", "a-long-dataset-name-5"} {
+		resources.WriteString("- bigquery_dataset:\n    properties:\n      name: " + name + "\n      location: US\n")
+	}
+	config, _ := getTestConfigAndProject(t, &ConfigData{resources.String()})
+
+	rules, err := BigqueryRules(config)
+	if err != nil {
+		t.Fatalf("BigqueryRules = %v", err)
+	}
+	want := "Whitelist for dataset(s): my-project:a-long-dataset-name-1, my-project:a-long-dataset-name-2, " +
+		"my-project:a-long-dataset-name-3, my-project:a-long-dataset-name-4, my-project:a-long-dataset-name-5"
+	if rules[1].Name != want {
+		t.Errorf("rule name = %q, want %q", rules[1].Name, want)
+	}
+}
@@ -0,0 +1,106 @@
+package rulegen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/healthcare/deploy/cft"
+)
+
+// OrgPolicies builds the organization policies that prevent the violations the location scanner detects, along
+// with baseline constraints for data projects. The policies are set on the broadest scope of the config
+// (see globalResource), or on each project if the config has no organization or folder. The audit logs project is
+// included like the other projects.
+func OrgPolicies(config *cft.Config) ([]*cft.OrgPolicy, error) {
+	projects := config.Projects
+	if config.AuditLogsProject != nil {
+		projects = append([]*cft.Project{config.AuditLogsProject}, projects...)
+	}
+
+	gr := globalResource(config)
+	if gr.Type != "project" {
+		return orgPoliciesFor(fmt.Sprintf("%ss/%s", gr.Type, gr.IDs[0]), projects), nil
+	}
+
+	var policies []*cft.OrgPolicy
+	for _, p := range projects {
+		policies = append(policies, orgPoliciesFor("projects/"+p.ID, []*cft.Project{p})...)
+	}
+	return policies, nil
+}
+
+// orgPoliciesFor builds the policies of a resource holding the given projects.
+func orgPoliciesFor(res string, projects []*cft.Project) []*cft.OrgPolicy {
+	locSet := make(map[string]bool)
+	addLocation := func(loc string) {
+		if loc != "" {
+			locSet[locationValue(loc)] = true
+		}
+	}
+	var externalIPInstances []string
+	for _, p := range projects {
+		// All deployed resources must be allowed to be created in their locations, not only data holding ones.
+		for _, r := range p.Resources {
+			if r.Kind != nil && r.Kind.Location != nil {
+				addLocation(r.Kind.Location(r.Parsed))
+			}
+		}
+		// Audit logs must be allowed to be created in their locations too.
+		addLocation(p.AuditLogs.LogsGCSBucket.Location)
+		addLocation(p.AuditLogs.LogsBigqueryDataset.Location)
+
+		for _, i := range p.DataResources().GCEInstances {
+			if i.AllowExternalIP {
+				externalIPInstances = append(externalIPInstances, fmt.Sprintf("projects/%s/zones/%s/instances/%s", p.ID, i.Zone, i.Name()))
+			}
+		}
+	}
+
+	locs := make([]string, 0, len(locSet))
+	for l := range locSet {
+		locs = append(locs, l)
+	}
+	sort.Strings(locs)
+
+	externalIP := &cft.OrgListPolicy{AllValues: "DENY"}
+	if len(externalIPInstances) > 0 {
+		externalIP = &cft.OrgListPolicy{AllowedValues: externalIPInstances}
+	}
+
+	return []*cft.OrgPolicy{
+		{
+			Resource:   res,
+			Constraint: "constraints/gcp.resourceLocations",
+			ListPolicy: &cft.OrgListPolicy{AllowedValues: locs},
+		},
+		{
+			Resource:      res,
+			Constraint:    "constraints/storage.uniformBucketLevelAccess",
+			BooleanPolicy: &cft.OrgBooleanPolicy{Enforced: true},
+		},
+		{
+			Resource:   res,
+			Constraint: "constraints/compute.vmExternalIpAccess",
+			ListPolicy: externalIP,
+		},
+		{
+			Resource:      res,
+			Constraint:    "constraints/iam.disableServiceAccountKeyCreation",
+			BooleanPolicy: &cft.OrgBooleanPolicy{Enforced: true},
+		},
+	}
+}
+
+// locationValue returns the value allowing the given location in a resource locations policy.
+// Regions and zones are allowed through the value group of their region, e.g. in:us-central1-locations, which holds
+// the region and all its zones, so that regional resources such as GKE clusters can create resources in its zones.
+// Multi-regions such as us are allowed as is.
+func locationValue(loc string) string {
+	loc = strings.ToLower(loc)
+	parts := strings.Split(loc, "-")
+	if len(parts) < 2 {
+		return loc
+	}
+	return fmt.Sprintf("in:%s-%s-locations", parts[0], parts[1])
+}
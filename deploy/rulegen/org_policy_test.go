@@ -0,0 +1,160 @@
+package rulegen
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/healthcare/deploy/cft"
+	"github.com/ghodss/yaml"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestOrgPolicies(t *testing.T) {
+	config, _ := getTestConfigAndProject(t, locConfigData)
+	got, err := OrgPolicies(config)
+	if err != nil {
+		t.Fatalf("OrgPolicies = %v", err)
+	}
+
+	want := []*cft.OrgPolicy{
+		{
+			Resource:   "organizations/12345678",
+			Constraint: "constraints/gcp.resourceLocations",
+			ListPolicy: &cft.OrgListPolicy{AllowedValues: []string{"in:us-central1-locations", "us"}},
+		},
+		{
+			Resource:      "organizations/12345678",
+			Constraint:    "constraints/storage.uniformBucketLevelAccess",
+			BooleanPolicy: &cft.OrgBooleanPolicy{Enforced: true},
+		},
+		{
+			Resource:   "organizations/12345678",
+			Constraint: "constraints/compute.vmExternalIpAccess",
+			ListPolicy: &cft.OrgListPolicy{AllValues: "DENY"},
+		},
+		{
+			Resource:      "organizations/12345678",
+			Constraint:    "constraints/iam.disableServiceAccountKeyCreation",
+			BooleanPolicy: &cft.OrgBooleanPolicy{Enforced: true},
+		},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("policies differ (-got, +want):\n%v", diff)
+	}
+}
+
+func TestOrgPoliciesPerProject(t *testing.T) {
+	config, _ := getTestConfigAndProject(t, &ConfigData{`
+resources:
+- gce_instance:
+    properties:
+      name: foo-instance
+      zone: us-east1-b
+      diskImage: projects/ubuntu-os-cloud/global/images/family/ubuntu-1804-lts
+      machineType: f1-micro
+      hasExternalIp: true
+    allow_external_ip: true
+- gke_cluster:
+    properties:
+      name: foo-cluster
+      clusterLocationType: Regional
+      region: us-west1
+      cluster:
+        privateClusterConfig:
+          masterIpv4CidrBlock: 172.16.0.0/28
+- network:
+    properties:
+      name: foo-network
+- subnetwork:
+    properties:
+      name: foo-subnetwork
+      network: foo-network
+      region: europe-west2
+      ipCidrRange: 10.0.0.0/20`})
+	config.Overall.OrganizationID = ""
+	config.Overall.FolderID = ""
+
+	got, err := OrgPolicies(config)
+	if err != nil {
+		t.Fatalf("OrgPolicies = %v", err)
+	}
+
+	want := []*cft.OrgPolicy{
+		{
+			Resource:   "projects/my-project",
+			Constraint: "constraints/gcp.resourceLocations",
+			ListPolicy: &cft.OrgListPolicy{AllowedValues: []string{
+				"in:europe-west2-locations",
+				"in:us-east1-locations",
+				"in:us-west1-locations",
+				"us",
+			}},
+		},
+		{
+			Resource:      "projects/my-project",
+			Constraint:    "constraints/storage.uniformBucketLevelAccess",
+			BooleanPolicy: &cft.OrgBooleanPolicy{Enforced: true},
+		},
+		{
+			Resource:   "projects/my-project",
+			Constraint: "constraints/compute.vmExternalIpAccess",
+			ListPolicy: &cft.OrgListPolicy{AllowedValues: []string{"projects/my-project/zones/us-east1-b/instances/foo-instance"}},
+		},
+		{
+			Resource:      "projects/my-project",
+			Constraint:    "constraints/iam.disableServiceAccountKeyCreation",
+			BooleanPolicy: &cft.OrgBooleanPolicy{Enforced: true},
+		},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("policies differ (-got, +want):\n%v", diff)
+	}
+}
+
+func TestOrgPoliciesAuditLogsProject(t *testing.T) {
+	auditProject := new(cft.Project)
+	if err := yaml.Unmarshal([]byte(`
+project_id: my-audit-project
+audit_logs:
+  logs_gcs_bucket:
+    location: europe-west2
+  logs_bigquery_dataset:
+    location: EU`), auditProject); err != nil {
+		t.Fatalf("yaml.Unmarshal: %v", err)
+	}
+	if err := auditProject.Init(); err != nil {
+		t.Fatalf("auditProject.Init: %v", err)
+	}
+
+	locationPolicies := func(config *cft.Config) map[string][]string {
+		policies, err := OrgPolicies(config)
+		if err != nil {
+			t.Fatalf("OrgPolicies = %v", err)
+		}
+		m := make(map[string][]string)
+		for _, p := range policies {
+			if p.Constraint == "constraints/gcp.resourceLocations" {
+				m[p.Resource] = p.ListPolicy.AllowedValues
+			}
+		}
+		return m
+	}
+
+	config, _ := getTestConfigAndProject(t, nil)
+	config.AuditLogsProject = auditProject
+	want := map[string][]string{
+		"organizations/12345678": {"eu", "in:europe-west2-locations", "us"},
+	}
+	if diff := cmp.Diff(locationPolicies(config), want); diff != "" {
+		t.Errorf("organization location policies differ (-got, +want):\n%v", diff)
+	}
+
+	config.Overall.OrganizationID = ""
+	config.Overall.FolderID = ""
+	want = map[string][]string{
+		"projects/my-audit-project": {"eu", "in:europe-west2-locations"},
+		"projects/my-project":       {"us"},
+	}
+	if diff := cmp.Diff(locationPolicies(config), want); diff != "" {
+		t.Errorf("project location policies differ (-got, +want):\n%v", diff)
+	}
+}
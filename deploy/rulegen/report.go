@@ -0,0 +1,330 @@
+package rulegen
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/GoogleCloudPlatform/healthcare/deploy/cft"
+)
+
+// ProjectReport describes how the data of a project is protected, for auditors.
+type ProjectReport struct {
+	ID            string
+	OwnersGroup   string
+	AuditorsGroup string
+	Resources     []*ReportResource
+	AuditLogs     ReportAuditLogs
+	Safeguards    []*ReportSafeguard
+}
+
+// ReportResource is a data holding resource of a project.
+type ReportResource struct {
+	Kind     string
+	Name     string
+	Location string
+	Access   []ReportAccess
+}
+
+// ReportAccess lists the members granted a role on a resource.
+type ReportAccess struct {
+	Role    string
+	Members []string
+}
+
+// ReportAuditLogs holds the destinations of a project's audit logs.
+type ReportAuditLogs struct {
+	BucketName      string
+	BucketLocation  string
+	DatasetID       string
+	DatasetLocation string
+	SinkDestination string
+}
+
+// ReportSafeguard lists the Forseti rules and deploy-time checks enforcing a HIPAA technical safeguard.
+type ReportSafeguard struct {
+	Section string
+	Name    string
+	Rules   []ReportRule
+	Checks  []string
+}
+
+// ReportRule is a Forseti rule covering a project.
+type ReportRule struct {
+	Scanner string
+	Name    string
+}
+
+// safeguard is a HIPAA technical safeguard (45 CFR 164.312) and the scanners and deploy-time checks enforcing it.
+type safeguard struct {
+	section  string
+	name     string
+	scanners []string
+	checks   []check
+}
+
+// check is a deploy-time check, which is only reported for projects with resources of one of its kinds if set.
+type check struct {
+	desc  string
+	kinds []string
+}
+
+var safeguards = []safeguard{
+	{
+		section:  "164.312(a)(1)",
+		name:     "Access control",
+		scanners: []string{"bigquery", "bucket", "enabled_apis", "location"},
+		checks: []check{
+			{desc: "Owners, read-write and read-only groups are granted access to data resources by default."},
+		},
+	},
+	{
+		section:  "164.312(b)",
+		name:     "Audit controls",
+		scanners: []string{"audit_logging", "log_sink"},
+		checks: []check{
+			{desc: "Audit logs are exported to a BigQuery dataset readable by the auditors group."},
+			{desc: "Bucket access logs are written to the audit logs bucket.", kinds: []string{"gcs_bucket"}},
+		},
+	},
+	{
+		section:  "164.312(c)(1)",
+		name:     "Integrity",
+		scanners: []string{"lien", "resource"},
+		checks: []check{
+			{desc: "Bucket versioning is always enabled.", kinds: []string{"gcs_bucket"}},
+			{desc: "Resources removed from the config are abandoned rather than deleted."},
+		},
+	},
+	{
+		section: "164.312(d)",
+		name:    "Person or entity authentication",
+		checks: []check{
+			{desc: "Instances require OS Login.", kinds: []string{"gce_instance"}},
+		},
+	},
+	{
+		section:  "164.312(e)(1)",
+		name:     "Transmission security",
+		scanners: []string{"cloudsql"},
+		checks: []check{
+			{desc: "Instances must not have external IPs unless allow_external_ip is set.", kinds: []string{"gce_instance"}},
+		},
+	},
+}
+
+// BuildReports builds the compliance report of each project in the config.
+// The config must be initialized so the reported accesses include the defaults added by Init.
+func BuildReports(config *cft.Config) ([]*ProjectReport, error) {
+	rules, err := allRules(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var reports []*ProjectReport
+	for _, p := range config.Projects {
+		r, err := buildReport(config, p, rules)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build report of project %q: %v", p.ID, err)
+		}
+		reports = append(reports, r)
+	}
+	return reports, nil
+}
+
+// scopedRule is a rule with the resources it applies to.
+type scopedRule struct {
+	ReportRule
+	resources []resource
+}
+
+// allRules runs all rule generators.
+func allRules(config *cft.Config) ([]scopedRule, error) {
+	gens, err := allGenerators(config)
+	if err != nil {
+		return nil, err
+	}
+	var rules []scopedRule
+	for _, gen := range gens {
+		for _, r := range gen.rules {
+			rules = append(rules, scopedRule{ReportRule{gen.name, r.ruleName()}, r.scope()})
+		}
+	}
+	return rules, nil
+}
+
+// coversProject determines whether a rule applies to the project.
+// Organization and folder rules apply to all projects as projects of the config are expected to be in them.
+func coversProject(rs []resource, projectID string) bool {
+	for _, r := range rs {
+		if r.Type == "organization" || r.Type == "folder" {
+			return true
+		}
+		for _, id := range r.IDs {
+			if id == "*" || id == projectID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func buildReport(config *cft.Config, project *cft.Project, rules []scopedRule) (*ProjectReport, error) {
+	auditLogsProjectID := config.AuditLogsProjectID(project)
+	report := &ProjectReport{
+		ID:            project.ID,
+		OwnersGroup:   project.OwnersGroup,
+		AuditorsGroup: project.AuditorsGroup,
+		AuditLogs: ReportAuditLogs{
+			BucketName:      project.AuditLogs.LogsGCSBucket.Name,
+			BucketLocation:  project.AuditLogs.LogsGCSBucket.Location,
+			DatasetID:       fmt.Sprintf("%s:%s", auditLogsProjectID, project.AuditLogs.LogsBigqueryDataset.Name),
+			DatasetLocation: project.AuditLogs.LogsBigqueryDataset.Location,
+			SinkDestination: auditLogSinkDestination(config, project),
+		},
+	}
+
+	kinds := make(map[string]bool)
+	for _, r := range project.DataHoldingResources() {
+		kinds[r.Kind.Key] = true
+		access, err := cft.ResourceAccess(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get access of %q: %v", r.Parsed.Name(), err)
+		}
+		report.Resources = append(report.Resources, &ReportResource{
+			Kind:     r.Kind.Key,
+			Name:     r.Parsed.Name(),
+			Location: r.Kind.Location(r.Parsed),
+			Access:   reportAccess(access),
+		})
+	}
+
+	for _, sg := range safeguards {
+		rs := &ReportSafeguard{Section: sg.section, Name: sg.name}
+		for _, scanner := range sg.scanners {
+			for _, r := range rules {
+				if r.Scanner == scanner && coversProject(r.resources, project.ID) {
+					rs.Rules = append(rs.Rules, r.ReportRule)
+				}
+			}
+		}
+		for _, c := range sg.checks {
+			if len(c.kinds) == 0 || anyKind(kinds, c.kinds) {
+				rs.Checks = append(rs.Checks, c.desc)
+			}
+		}
+		report.Safeguards = append(report.Safeguards, rs)
+	}
+	return report, nil
+}
+
+func anyKind(have map[string]bool, kinds []string) bool {
+	for _, k := range kinds {
+		if have[k] {
+			return true
+		}
+	}
+	return false
+}
+
+func reportAccess(access map[string][]string) []ReportAccess {
+	roles := make([]string, 0, len(access))
+	for role := range access {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+
+	var ra []ReportAccess
+	for _, role := range roles {
+		ra = append(ra, ReportAccess{Role: role, Members: access[role]})
+	}
+	return ra
+}
+
+const markdownReportTmpl = `# Compliance report for project {{.ID}}
+
+Owners group: {{.OwnersGroup}}
+
+Auditors group: {{.AuditorsGroup}}
+
+## Data resources
+{{if .Resources}}
+| Kind | Name | Location | Access |
+| --- | --- | --- | --- |
+{{- range .Resources}}
+| {{.Kind}} | {{.Name}} | {{.Location}} | {{range $i, $a := .Access}}{{if $i}}<br>{{end}}{{$a.Role}}: {{join $a.Members ", "}}{{end}} |
+{{- end}}
+{{else}}
+The project has no data resources.
+{{end}}
+## Audit logs
+
+- Audit logs bucket: {{.AuditLogs.BucketName}} ({{.AuditLogs.BucketLocation}})
+- Audit logs dataset: {{.AuditLogs.DatasetID}} ({{.AuditLogs.DatasetLocation}})
+- Log sink destination: {{.AuditLogs.SinkDestination}}
+
+## HIPAA technical safeguards
+{{range .Safeguards}}
+### {{.Section}} {{.Name}}
+{{if .Rules}}
+Forseti rules:
+{{range .Rules}}
+- [{{.Scanner}}] {{.Name}}
+{{- end}}
+{{end}}{{if .Checks}}
+Deploy-time checks:
+{{range .Checks}}
+- {{.}}
+{{- end}}
+{{end}}{{end}}`
+
+const htmlReportTmpl = `<!DOCTYPE html>
+<html>
+<head><title>Compliance report for project {{.ID}}</title></head>
+<body>
+<h1>Compliance report for project {{.ID}}</h1>
+<p>Owners group: {{.OwnersGroup}}</p>
+<p>Auditors group: {{.AuditorsGroup}}</p>
+<h2>Data resources</h2>
+{{if .Resources}}<table>
+<tr><th>Kind</th><th>Name</th><th>Location</th><th>Access</th></tr>
+{{range .Resources}}<tr><td>{{.Kind}}</td><td>{{.Name}}</td><td>{{.Location}}</td><td>{{range $i, $a := .Access}}{{if $i}}<br>{{end}}{{$a.Role}}: {{join $a.Members ", "}}{{end}}</td></tr>
+{{end}}</table>
+{{else}}<p>The project has no data resources.</p>
+{{end}}<h2>Audit logs</h2>
+<ul>
+<li>Audit logs bucket: {{.AuditLogs.BucketName}} ({{.AuditLogs.BucketLocation}})</li>
+<li>Audit logs dataset: {{.AuditLogs.DatasetID}} ({{.AuditLogs.DatasetLocation}})</li>
+<li>Log sink destination: {{.AuditLogs.SinkDestination}}</li>
+</ul>
+<h2>HIPAA technical safeguards</h2>
+{{range .Safeguards}}<h3>{{.Section}} {{.Name}}</h3>
+{{if .Rules}}<p>Forseti rules:</p>
+<ul>
+{{range .Rules}}<li>[{{.Scanner}}] {{.Name}}</li>
+{{end}}</ul>
+{{end}}{{if .Checks}}<p>Deploy-time checks:</p>
+<ul>
+{{range .Checks}}<li>{{.}}</li>
+{{end}}</ul>
+{{end}}{{end}}</body>
+</html>
+`
+
+var (
+	markdownReport = template.Must(template.New("markdown").Funcs(template.FuncMap{"join": strings.Join}).Parse(markdownReportTmpl))
+	htmlReport     = htmltemplate.Must(htmltemplate.New("html").Funcs(htmltemplate.FuncMap{"join": strings.Join}).Parse(htmlReportTmpl))
+)
+
+// WriteMarkdown writes the report as Markdown.
+func (r *ProjectReport) WriteMarkdown(w io.Writer) error {
+	return markdownReport.Execute(w, r)
+}
+
+// WriteHTML writes the report as HTML.
+func (r *ProjectReport) WriteHTML(w io.Writer) error {
+	return htmlReport.Execute(w, r)
+}
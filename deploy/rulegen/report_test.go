@@ -0,0 +1,149 @@
+package rulegen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const wantMarkdownReport = `# Compliance report for project my-project
+
+Owners group: my-project-owners@my-domain.com
+
+Auditors group: my-project-auditors@my-domain.com
+
+## Data resources
+
+| Kind | Name | Location | Access |
+| --- | --- | --- | --- |
+| bigquery_dataset | foo-dataset | US | OWNER: group:my-project-owners@my-domain.com<br>READER: group:my-project-readonly@my-domain.com, group:another-readonly-group@googlegroups.com<br>WRITER: group:my-project-readwrite@my-domain.com |
+| gce_instance | foo-instance | us-central1-f | roles/compute.osAdminLogin: group:my-project-owners@my-domain.com<br>roles/compute.osLogin: group:my-project-readwrite@my-domain.com |
+| gcs_bucket | my-project-foo-bucket | us-central1 | roles/storage.admin: group:my-project-owners@my-domain.com<br>roles/storage.objectAdmin: group:my-project-readwrite@my-domain.com<br>roles/storage.objectViewer: group:my-project-readonly@my-domain.com, group:another-readonly-group@googlegroups.com |
+
+## Audit logs
+
+- Audit logs bucket: my-project-logs (US)
+- Audit logs dataset: my-project:audit_logs (US)
+- Log sink destination: bigquery.googleapis.com/projects/my-project/datasets/audit_logs
+
+## HIPAA technical safeguards
+
+### 164.312(a)(1) Access control
+
+Forseti rules:
+
+- [bigquery] No public, domain or special group dataset access.
+- [bigquery] Whitelist for dataset(s): my-project:foo-dataset
+- [bigquery] Whitelist for project my-project audit logs
+- [bucket] Disallow all acl rules, only allow IAM.
+- [enabled_apis] Global API whitelist.
+- [enabled_apis] API whitelist for my-project.
+- [location] Global location whitelist.
+- [location] Project my-project resource whitelist for location US.
+- [location] Project my-project resource whitelist for location US-CENTRAL1.
+- [location] Project my-project resource whitelist for location US-CENTRAL1-F.
+- [location] Project my-project audit logs bucket location whitelist.
+- [location] Project my-project audit logs dataset location whitelist.
+
+Deploy-time checks:
+
+- Owners, read-write and read-only groups are granted access to data resources by default.
+
+### 164.312(b) Audit controls
+
+Forseti rules:
+
+- [audit_logging] Require all Cloud Audit logs.
+- [log_sink] Require a BigQuery Log sink in all projects.
+- [log_sink] Only allow BigQuery Log sinks in all projects.
+- [log_sink] Require Log sink for project my-project.
+- [log_sink] Whitelist Log sink for project my-project.
+
+Deploy-time checks:
+
+- Audit logs are exported to a BigQuery dataset readable by the auditors group.
+- Bucket access logs are written to the audit logs bucket.
+
+### 164.312(c)(1) Integrity
+
+Forseti rules:
+
+- [lien] Require project deletion liens for all projects.
+- [resource] Project resource trees.
+
+Deploy-time checks:
+
+- Bucket versioning is always enabled.
+- Resources removed from the config are abandoned rather than deleted.
+
+### 164.312(d) Person or entity authentication
+
+Deploy-time checks:
+
+- Instances require OS Login.
+
+### 164.312(e)(1) Transmission security
+
+Forseti rules:
+
+- [cloudsql] Disallow publicly exposed cloudsql instances (SSL disabled).
+- [cloudsql] Disallow publicly exposed cloudsql instances (SSL enabled).
+
+Deploy-time checks:
+
+- Instances must not have external IPs unless allow_external_ip is set.
+`
+
+func TestReport(t *testing.T) {
+	config, _ := getTestConfigAndProject(t, locConfigData)
+	reports, err := BuildReports(config)
+	if err != nil {
+		t.Fatalf("BuildReports = %v", err)
+	}
+	if len(reports) != 1 {
+		t.Fatalf("len(reports) = %v, want 1", len(reports))
+	}
+
+	var md strings.Builder
+	if err := reports[0].WriteMarkdown(&md); err != nil {
+		t.Fatalf("WriteMarkdown = %v", err)
+	}
+	if diff := cmp.Diff(md.String(), wantMarkdownReport); diff != "" {
+		t.Errorf("markdown report differs (-got, +want):\n%v", diff)
+	}
+
+	var html strings.Builder
+	if err := reports[0].WriteHTML(&html); err != nil {
+		t.Fatalf("WriteHTML = %v", err)
+	}
+	for _, want := range []string{
+		"<h1>Compliance report for project my-project</h1>",
+		"<tr><td>gcs_bucket</td><td>my-project-foo-bucket</td><td>us-central1</td>",
+		"<li>[lien] Require project deletion liens for all projects.</li>",
+	} {
+		if !strings.Contains(html.String(), want) {
+			t.Errorf("HTML report does not contain %q:\n%v", want, html.String())
+		}
+	}
+}
+
+func TestReportNoDataResources(t *testing.T) {
+	config, _ := getTestConfigAndProject(t, nil)
+	reports, err := BuildReports(config)
+	if err != nil {
+		t.Fatalf("BuildReports = %v", err)
+	}
+
+	var md strings.Builder
+	if err := reports[0].WriteMarkdown(&md); err != nil {
+		t.Fatalf("WriteMarkdown = %v", err)
+	}
+	if !strings.Contains(md.String(), "The project has no data resources.") {
+		t.Errorf("report does not state that there are no data resources:\n%v", md.String())
+	}
+	// Checks of resource kinds the project does not have are not reported.
+	if strings.Contains(md.String(), "Bucket versioning is always enabled.") {
+		t.Errorf("report contains bucket checks for project without buckets:\n%v", md.String())
+	}
+}
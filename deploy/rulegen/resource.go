@@ -2,15 +2,25 @@ package rulegen
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/GoogleCloudPlatform/healthcare/deploy/cft"
 )
 
-var resourceTypes = []string{
-	"project",
-	"bucket",
-	"dataset",
-	"instance",
+// resourceTypes returns the types of resources covered by the resource trees: projects and the types of all
+// registered data holding resource kinds.
+func resourceTypes() []string {
+	types := []string{"project"}
+	seen := make(map[string]bool)
+	var dataTypes []string
+	for _, k := range cft.ResourceKinds() {
+		if k.HoldsData && !seen[k.ForsetiType] {
+			seen[k.ForsetiType] = true
+			dataTypes = append(dataTypes, k.ForsetiType)
+		}
+	}
+	sort.Strings(dataTypes)
+	return append(types, dataTypes...)
 }
 
 // ResourceRule represents a forseti resource scanner rule.
@@ -49,32 +59,20 @@ func ResourceRules(config *cft.Config) ([]ResourceRule, error) {
 			},
 		}
 
-		rs := project.DataResources()
-
-		for _, b := range rs.GCSBuckets {
-			pt.Children = append(pt.Children, resourceTree{
-				Type:       "bucket",
-				ResourceID: b.Name(),
-			})
-		}
-
-		for _, d := range rs.BigqueryDatasets {
-			pt.Children = append(pt.Children, resourceTree{
-				Type:       "dataset",
-				ResourceID: fmt.Sprintf("%s:%s", project.ID, d.Name()),
-			})
-		}
-
-		for _, i := range rs.GCEInstances {
-			id, err := project.InstanceID(i.Name())
+		// Group children by type to keep the trees stable regardless of the order of resources in the config.
+		var children []resourceTree
+		for _, r := range project.DataHoldingResources() {
+			id, err := r.Kind.ForsetiID(project, r.Parsed)
 			if err != nil {
 				return nil, err
 			}
-			pt.Children = append(pt.Children, resourceTree{
-				Type:       "instance",
+			children = append(children, resourceTree{
+				Type:       r.Kind.ForsetiType,
 				ResourceID: id,
 			})
 		}
+		sort.SliceStable(children, func(i, j int) bool { return children[i].Type < children[j].Type })
+		pt.Children = append(pt.Children, children...)
 
 		trees = append(trees, pt)
 	}
@@ -82,7 +80,7 @@ func ResourceRules(config *cft.Config) ([]ResourceRule, error) {
 	return []ResourceRule{{
 		Name:          "Project resource trees.",
 		Mode:          "required",
-		ResourceTypes: resourceTypes,
+		ResourceTypes: resourceTypes(),
 		ResourceTrees: trees,
 	}}, nil
 }
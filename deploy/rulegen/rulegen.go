@@ -5,31 +5,149 @@ package rulegen
 
 import (
 	"fmt"
+	"reflect"
+	"strings"
 
 	"github.com/GoogleCloudPlatform/healthcare/deploy/cft"
 	"gopkg.in/yaml.v2" // don't use ghodss/yaml as it does not preserve key ordering
 )
 
+// rule is a rule of any scanner.
+type rule interface {
+	// ruleName returns the name of the rule.
+	ruleName() string
+
+	// scope returns the organization, folder or projects the rule applies to.
+	scope() []resource
+
+	// sources returns the parts of the config the rule was generated from.
+	sources(idx *sourceIndex) []RuleSource
+
+	// key identifies the rule by the project and kind of rule it was generated for, so that it does not change when
+	// the resources the rule lists change. For most scanners, rule names are made of those and used as keys.
+	key() string
+}
+
+func (r *AuditLoggingRule) ruleName() string { return r.Name }
+func (r *BigqueryRule) ruleName() string     { return r.Name }
+func (r *BucketRule) ruleName() string       { return r.Name }
+func (r *CloudSQLRule) ruleName() string     { return r.Name }
+func (r *EnabledAPIsRule) ruleName() string  { return r.Name }
+func (r *LienRule) ruleName() string         { return r.Name }
+func (r *LocationRule) ruleName() string     { return r.Name }
+func (r *LogSinkRule) ruleName() string      { return r.Name }
+func (r *ResourceRule) ruleName() string     { return r.Name }
+
+func (r *AuditLoggingRule) scope() []resource { return r.Resources }
+func (r *BigqueryRule) scope() []resource     { return r.Resources }
+func (r *BucketRule) scope() []resource       { return r.Resources }
+func (r *CloudSQLRule) scope() []resource     { return r.Resources }
+func (r *EnabledAPIsRule) scope() []resource  { return r.Resources }
+func (r *LienRule) scope() []resource         { return r.Resources }
+func (r *LocationRule) scope() []resource     { return r.Resources }
+func (r *LogSinkRule) scope() []resource      { return r.Resources }
+
+func (r *AuditLoggingRule) key() string { return r.Name }
+func (r *BucketRule) key() string       { return r.Name }
+func (r *CloudSQLRule) key() string     { return r.Name }
+func (r *EnabledAPIsRule) key() string  { return r.Name }
+func (r *LienRule) key() string         { return r.Name }
+func (r *LogSinkRule) key() string      { return r.Name }
+func (r *ResourceRule) key() string     { return r.Name }
+
+// key identifies dataset whitelists by their project as their names list their datasets.
+func (r *BigqueryRule) key() string {
+	if strings.HasPrefix(r.Name, datasetsRuleNamePrefix) {
+		return fmt.Sprintf("Dataset whitelist of project %s.", projectOf(r.Resources))
+	}
+	return r.Name
+}
+
+// key identifies resource location whitelists by their project as their names hold their location.
+func (r *LocationRule) key() string {
+	if strings.Contains(r.Name, resourceLocationsRuleNameInfix) {
+		return fmt.Sprintf("Resource location whitelist of project %s.", projectOf(r.Resources))
+	}
+	return r.Name
+}
+
+// projectOf returns the ID of the project of a rule that applies to a single project.
+func projectOf(rs []resource) string {
+	if len(rs) == 0 || len(rs[0].IDs) == 0 {
+		return ""
+	}
+	return rs[0].IDs[0]
+}
+
+// scope returns the projects of the resource trees of the rule.
+func (r *ResourceRule) scope() []resource {
+	var ids []string
+	for _, t := range r.ResourceTrees {
+		ids = append(ids, t.ResourceID)
+	}
+	return []resource{{Type: "project", IDs: ids}}
+}
+
+// generators generate the rules of each scanner, which are written to <scanner>_rules.yaml.
+// The rules returned by each generator must be a slice of structs with a Name field whose pointers implement rule.
+var generators = []struct {
+	scanner  string
+	generate func(*cft.Config) (interface{}, error)
+}{
+	{"audit_logging", func(c *cft.Config) (interface{}, error) { return AuditLoggingRules(c) }},
+	{"bigquery", func(c *cft.Config) (interface{}, error) { return BigqueryRules(c) }},
+	{"bucket", func(c *cft.Config) (interface{}, error) { return BucketRules(c) }},
+	{"cloudsql", func(c *cft.Config) (interface{}, error) { return CloudSQLRules(c) }},
+	{"enabled_apis", func(c *cft.Config) (interface{}, error) { return EnabledAPIsRules(c) }},
+	{"lien", func(c *cft.Config) (interface{}, error) { return LienRules(c) }},
+	{"location", func(c *cft.Config) (interface{}, error) { return LocationRules(c) }},
+	{"log_sink", func(c *cft.Config) (interface{}, error) { return LogSinkRules(c) }},
+	{"resource", func(c *cft.Config) (interface{}, error) { return ResourceRules(c) }},
+}
+
+// generator holds the rules generated for a scanner.
 type generator struct {
 	name  string
-	rules interface{}
+	rules []rule
+
+	// ruleType is the type of the rules of the scanner, e.g. BigqueryRule.
+	ruleType reflect.Type
 }
 
 // Run runs the rule generator. Currently it does not write the rules anywhere except stdout.
 func Run(config *cft.Config) error {
-	var gens []generator
-	bqRules, err := BigqueryRules(config)
+	gens, err := allGenerators(config)
 	if err != nil {
-		return fmt.Errorf("failed to generate bigquery rules: %v", err)
+		return err
 	}
-	gens = append(gens, generator{"bigquery", bqRules})
-
 	for _, gen := range gens {
 		b, err := yaml.Marshal(map[string]interface{}{"rules": gen.rules})
 		if err != nil {
-			return fmt.Errorf("failed to marshal rules: %v", err)
+			return fmt.Errorf("failed to marshal %s rules: %v", gen.name, err)
 		}
-		fmt.Println(string(b))
+		fmt.Printf("# %s_rules.yaml\n%s\n", gen.name, string(b))
 	}
 	return nil
 }
+
+// allGenerators runs the rule generators of all scanners.
+// The names of the rules of each scanner are made unique, as Forseti identifies violated rules by name.
+func allGenerators(config *cft.Config) ([]generator, error) {
+	var gens []generator
+	for _, g := range generators {
+		rs, err := g.generate(config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate %s rules: %v", g.scanner, err)
+		}
+		v := reflect.ValueOf(rs)
+		rules := make([]rule, v.Len())
+		names := make(ruleNames)
+		for i := range rules {
+			name := v.Index(i).FieldByName("Name")
+			name.SetString(names.unique(name.String()))
+			rules[i] = v.Index(i).Addr().Interface().(rule)
+		}
+		gens = append(gens, generator{g.scanner, rules, v.Type().Elem()})
+	}
+	return gens, nil
+}
@@ -162,6 +162,11 @@ def generate_config(context):
           'properties': {
               'location': logs_gcs_bucket['location'],
               'storageClass': logs_gcs_bucket['storage_class'],
+              'iamConfiguration': {
+                  'uniformBucketLevelAccess': {
+                      'enabled': True,
+                  },
+              },
               'lifecycle': {
                   'rule': [{
                       'action': {
@@ -221,6 +226,7 @@ def generate_config(context):
 
   # Create a logs metric sink of audit logs to a BigQuery dataset. This also
   # creates a service account that must be given WRITER access to the dataset.
+  # Only audit logs are exported. The filter must match cft.AuditLogsSinkFilter.
   log_sink_name = 'audit-logs-to-bigquery'
   resources.append({
       'name': log_sink_name,
@@ -335,6 +341,11 @@ def generate_config(context):
         'properties': {
             'location': data_bucket['location'],
             'storageClass': data_bucket['storage_class'],
+            'iamConfiguration': {
+                'uniformBucketLevelAccess': {
+                    'enabled': True,
+                },
+            },
             'logging': {
                 'logBucket': logs_bucket_id,
             },
@@ -263,6 +263,11 @@ class TestDataProject(absltest.TestCase):
                 'properties': {
                     'location': 'US',
                     'storageClass': 'MULTI_REGIONAL',
+                    'iamConfiguration': {
+                        'uniformBucketLevelAccess': {
+                            'enabled': True,
+                        },
+                    },
                     'lifecycle': {
                         'rule': [{
                             'action': {
@@ -456,6 +461,11 @@ class TestDataProject(absltest.TestCase):
                         'enabled': True
                     },
                     'storageClass': 'REGIONAL',
+                    'iamConfiguration': {
+                        'uniformBucketLevelAccess': {
+                            'enabled': True,
+                        },
+                    },
                     'logging': {
                         'logBucket': 'my-project-logs'
                     }
@@ -536,6 +546,11 @@ class TestDataProject(absltest.TestCase):
                         'enabled': True
                     },
                     'storageClass': 'REGIONAL',
+                    'iamConfiguration': {
+                        'uniformBucketLevelAccess': {
+                            'enabled': True,
+                        },
+                    },
                     'logging': {
                         'logBucket': 'my-project-logs'
                     }
@@ -582,6 +597,11 @@ class TestDataProject(absltest.TestCase):
                         'enabled': True
                     },
                     'storageClass': 'REGIONAL',
+                    'iamConfiguration': {
+                        'uniformBucketLevelAccess': {
+                            'enabled': True,
+                        },
+                    },
                     'logging': {
                         'logBucket': 'my-project-logs'
                     }
@@ -1004,6 +1024,11 @@ class TestDataProject(absltest.TestCase):
                         'enabled': True
                     },
                     'storageClass': 'MULTI_REGIONAL',
+                    'iamConfiguration': {
+                        'uniformBucketLevelAccess': {
+                            'enabled': True,
+                        },
+                    },
                     'logging': {
                         'logBucket': 'some_remote_bucket'
                     }
@@ -31,6 +31,11 @@ def generate_config(context):
         'properties': {
             'location': logs_bucket['location'],
             'storageClass': logs_bucket['storage_class'],
+            'iamConfiguration': {
+                'uniformBucketLevelAccess': {
+                    'enabled': True,
+                },
+            },
             'lifecycle': {
                 'rule': [{
                     'action': {
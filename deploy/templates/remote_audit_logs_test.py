@@ -90,6 +90,11 @@ class TestRemoteAuditLogsTemplate(absltest.TestCase):
                 'properties': {
                     'location': 'US',
                     'storageClass': 'MULTI_REGIONAL',
+                    'iamConfiguration': {
+                        'uniformBucketLevelAccess': {
+                            'enabled': True,
+                        },
+                    },
                     'lifecycle': {
                         'rule': [{
                             'action': {
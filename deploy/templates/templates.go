@@ -0,0 +1,5 @@
+// Package templates bundles the Deployment Manager templates so that the deployer does not depend on the working directory.
+// The contents of the templates are generated into templates_data.go, which must be regenerated when they change.
+package templates
+
+//go:generate go run ../cmd/embed_templates/embed_templates.go data_project.py gce_vms.py metric.py remote_audit_logs.py *.py.schema
@@ -0,0 +1,14 @@
+// Code generated by embed_templates. DO NOT EDIT.
+
+package templates
+
+// Files maps the base names of the templates and their schemas to their contents.
+var Files = map[string][]byte{
+	"data_project.py":             []byte("# Copyright 2018 Google LLC\n#\n# Licensed under the Apache License, Version 2.0 (the \"License\");\n# you may not use this file except in compliance with the License.\n# You may obtain a copy of the License at\n#\n#     https://www.apache.org/licenses/LICENSE-2.0\n#\n# Unless required by applicable law or agreed to in writing, software\n# distributed under the License is distributed on an \"AS IS\" BASIS,\n# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.\n# See the License for the specific language governing permissions and\n# limitations under the License.\n\"\"\"Configures a data project with storage and logging.\n\nFor details and usage, see deploy/README.md.\n\"\"\"\n\n# Map from prefixes used by IAM to those used in BigQuery.\n_IAM_TO_BIGQUERY_MEMBER = {\n    'user': 'userByEmail',\n    'group': 'groupByEmail',\n    'domain': 'domain',\n    # BigQuery treats serviceAccounts as users.\n    'serviceAccount': 'userByEmail',\n}\n\nDEFAULT_CUSTOM_ROLE_STAGE = 'GA'\n\n\ndef _get_bigquery_access_for_role(role_name, members):\n  \"\"\"Converts role and IAM style members to BigQuery style ACL.\"\"\"\n\n  access_for_role = []\n  for member in members:\n    if member == 'allAuthenticatedUsers':\n      access_for_role.append({'role': role_name, 'specialGroup': member})\n    else:\n      member_type, member_name = member.split(':')\n      access_for_role.append({\n          'role': role_name,\n          _IAM_TO_BIGQUERY_MEMBER[member_type]: member_name\n      })\n  return access_for_role\n\n\ndef generate_config(context):\n  \"\"\"Generate Deployment Manager configuration.\"\"\"\n\n  project_id = context.env['project']\n\n  if ('local_audit_logs' in context.properties) == (\n      'remote_audit_logs' in context.properties):\n    raise ValueError('Must specify local_audit_logs or remote_audit_logs but '\n                     'not both.')\n  use_local_logs = 'local_audit_logs' in context.properties\n  has_organization = context.properties['has_organization']\n\n  resources = []\n\n  # Custom roles\n  custom_roles = context.properties.get('custom_roles', [])\n  for role in custom_roles:\n    name = role['name']\n    permissions = role['permissions']\n    description = role.get('description', name)\n    title = role.get('title', name)\n    resources.append({\n        'name': name,\n        'type': 'gcp-types/iam-v1:projects.roles',\n        'properties': {\n            'parent': 'projects/' + project_id,\n            'roleId': name,\n            'role': {\n                'title': title,\n                'description': description,\n                'stage': DEFAULT_CUSTOM_ROLE_STAGE,\n                'includedPermissions': permissions,\n            }\n        }\n    })\n\n  # Set project-level IAM roles. Adding owners and auditors roles, and removing\n  # the single-owner. Non-organization projects cannot have a owner group, so\n  # use projectIamAdmin instead.\n  if has_organization:\n    owners_group_role = 'roles/owner'\n  else:\n    owners_group_role = 'roles/resourcemanager.projectIamAdmin'\n\n  project_bindings = {\n      owners_group_role: ['group:' + context.properties['owners_group']],\n      'roles/iam.securityReviewer': [\n          'group:' + context.properties['auditors_group']\n      ],\n  }\n  if 'editors_group' in context.properties:\n    project_bindings['roles/editor'] = [\n        'group:' + context.properties['editors_group']\n    ]\n\n  # Merge in additional permissions, which may include the above roles.\n  for additional in context.properties.get('additional_project_permissions',\n                                           []):\n    for role in additional['roles']:\n      project_bindings[role] = (\n          project_bindings.get(role, []) + additional['members'])\n\n  policy_patch = {\n      'add': [{\n          'role': role,\n          'members': members\n      } for role, members in sorted(project_bindings.items())]\n  }\n  if has_organization and 'remove_owner_user' in context.properties:\n    policy_patch['remove'] = [{\n        'role': 'roles/owner',\n        'members': ['user:' + context.properties['remove_owner_user']],\n    }]\n  get_iam_policy_name = 'set-project-bindings-get-iam-policy'\n  resources.extend([\n      {\n          'name': get_iam_policy_name,\n          'action': ('gcp-types/cloudresourcemanager-v1:'\n                     'cloudresourcemanager.projects.getIamPolicy'),\n          'properties': {\n              'resource': project_id,\n          },\n          'metadata': {\n              'runtimePolicy': ['UPDATE_ALWAYS'],\n          },\n      },\n      {\n          'name': 'set-project-bindings-patch-iam-policy',\n          'action': ('gcp-types/cloudresourcemanager-v1:'\n                     'cloudresourcemanager.projects.setIamPolicy'),\n          'properties': {\n              'resource': project_id,\n              'policy': '$(ref.' + get_iam_policy_name + ')',\n              'gcpIamPolicyPatch': policy_patch,\n          },\n          'metadata': {\n              'runtimePolicy': ['UPDATE_ON_CHANGE'],\n          },\n      },\n  ])\n\n  # Create a logs GCS bucket and BigQuery dataset, or get the names of the\n  # remote bucket and dataset.\n  previous_gcs_bucket = None\n  logs_bucket_id = None\n  if use_local_logs:\n    logs_gcs_bucket = context.properties['local_audit_logs'].get(\n        'logs_gcs_bucket')\n    # Logs GCS bucket is only needed if there are data GCS buckets.\n    if logs_gcs_bucket:\n      logs_bucket_id = project_id + '-logs'\n      # Create the local GCS bucket to hold logs.\n      resources.append({\n          'name': logs_bucket_id,\n          'type': 'storage.v1.bucket',\n          'properties': {\n              'location': logs_gcs_bucket['location'],\n              'storageClass': logs_gcs_bucket['storage_class'],\n              'iamConfiguration': {\n                  'uniformBucketLevelAccess': {\n                      'enabled': True,\n                  },\n              },\n              'lifecycle': {\n                  'rule': [{\n                      'action': {\n                          'type': 'Delete'\n                      },\n                      'condition': {\n                          'age': logs_gcs_bucket['ttl_days'],\n                          'isLive': True,\n                      },\n                  }],\n              },\n          },\n          'accessControl': {\n              'gcpIamPolicy': {\n                  'bindings': [\n                      {\n                          'role':\n                              'roles/storage.admin',\n                          'members': [\n                              'group:' + context.properties['owners_group']\n                          ],\n                      },\n                      {\n                          'role':\n                              'roles/storage.objectViewer',\n                          'members': [\n                              'group:' + context.properties['auditors_group']\n                          ],\n                      },\n                      {\n                          'role':\n                              'roles/storage.objectCreator',\n                          'members': [\n                              'group:cloud-storage-analytics@google.com'\n                          ],\n                      },\n                  ],\n              },\n          },\n      })\n      previous_gcs_bucket = logs_bucket_id\n\n    # Get name of local BigQuery dataset to hold audit logs.\n    # This dataset will need to be created after running this deployment\n    dataset_id = 'audit_logs'\n    log_sink_destination = ('bigquery.googleapis.com/projects/' + project_id +\n                            '/datasets/' + dataset_id)\n  else:\n    logs_bucket_id = context.properties['remote_audit_logs'].get(\n        'logs_gcs_bucket_name')\n\n    log_sink_destination = (\n        'bigquery.googleapis.com/projects/' +\n        context.properties['remote_audit_logs']['audit_logs_project_id'] +\n        '/datasets/' +\n        context.properties['remote_audit_logs']['logs_bigquery_dataset_id'])\n\n  # Create a logs metric sink of audit logs to a BigQuery dataset. This also\n  # creates a service account that must be given WRITER access to the dataset.\n  # Only audit logs are exported. The filter must match cft.AuditLogsSinkFilter.\n  log_sink_name = 'audit-logs-to-bigquery'\n  resources.append({\n      'name': log_sink_name,\n      'type': 'logging.v2.sink',\n      'properties': {\n          'sink': log_sink_name,\n          'destination': log_sink_destination,\n          'filter': 'logName:\"logs/cloudaudit.googleapis.com\"',\n          'uniqueWriterIdentity': True,\n      },\n  })\n\n  # BigQuery dataset(s) to hold actual data. Create serially to avoid exceeding\n  # API quota.\n  previous_bq_update = None\n  for bq_dataset in context.properties.get('bigquery_datasets', []):\n    ds_name = bq_dataset['name']\n    bq_create_resource = {\n        'name': 'create-big-query-dataset-' + ds_name,\n        'type': 'bigquery.v2.dataset',\n        'properties': {\n            'datasetReference': {\n                'datasetId': ds_name,\n            },\n            'location': bq_dataset['location'],\n        },\n    }\n    if previous_bq_update:\n      bq_create_resource['metadata'] = {'dependsOn': [previous_bq_update]}\n    resources.append(bq_create_resource)\n\n    add_permissions = bq_dataset.get('additional_dataset_permissions', {})\n    access_list = [{\n        'role': 'OWNER',\n        'groupByEmail': context.properties['owners_group']\n    }]\n\n    for reader in context.properties.get('data_readonly_groups', []):\n      access_list.append({'role': 'READER', 'groupByEmail': reader})\n\n    for writer in context.properties.get('data_readwrite_groups', []):\n      access_list.append({'role': 'WRITER', 'groupByEmail': writer})\n\n    access_list += (\n        _get_bigquery_access_for_role('OWNER', add_permissions.get(\n            'owners', [])) + _get_bigquery_access_for_role(\n                'WRITER', add_permissions.get('readwrite', [])) +\n        _get_bigquery_access_for_role('READER',\n                                      add_permissions.get('readonly', [])))\n\n    # Update permissions for the dataset. This also removes the deployment\n    # manager service account's access.\n    previous_bq_update = 'update-big-query-dataset-' + ds_name\n    resources.append({\n        'name': previous_bq_update,\n        'action': 'gcp-types/bigquery-v2:bigquery.datasets.patch',\n        'properties': {\n            'projectId': project_id,\n            'datasetId': ds_name,\n            'access': access_list,\n        },\n        'metadata': {\n            'dependsOn': ['create-big-query-dataset-' + ds_name],\n            'runtimePolicy': ['UPDATE_ON_CHANGE'],\n        },\n    })\n\n  # GCS bucket(s) to hold actual data. Create serially to avoid exceeding API\n  # quota.\n\n  default_bucket_owners = ['group:' + context.properties['owners_group']]\n  default_bucket_readwrite = [\n      'group:' + readwrite\n      for readwrite in context.properties.get('data_readwrite_groups', [])\n  ]\n  default_bucket_readonly = [\n      'group:' + readonly\n      for readonly in context.properties.get('data_readonly_groups', [])\n  ]\n\n  for data_bucket in context.properties.get('data_buckets', []):\n    if not logs_bucket_id:\n      raise ValueError('Logs GCS bucket must be provided for data buckets.')\n\n    bucket_roles = []\n    add_permissions = data_bucket.get('additional_bucket_permissions', {})\n    bucket_roles.append({\n        'role': 'roles/storage.admin',\n        'members': default_bucket_owners + add_permissions.get('owners', [])\n    })\n    bucket_roles.append({\n        'role':\n            'roles/storage.objectAdmin',\n        'members':\n            default_bucket_readwrite + add_permissions.get('readwrite', [])\n    })\n    bucket_roles.append({\n        'role': 'roles/storage.objectViewer',\n        'members': default_bucket_readonly + add_permissions.get(\n            'readonly', [])\n    })\n    bucket_roles.append({\n        'role': 'roles/storage.objectCreator',\n        'members': add_permissions.get('writeonly', [])\n    })\n\n    bindings = [role for role in bucket_roles if role['members']]\n    data_bucket_id = data_bucket['name']\n    data_bucket_resource = {\n        'name': data_bucket_id,\n        'type': 'storage.v1.bucket',\n        'properties': {\n            'location': data_bucket['location'],\n            'storageClass': data_bucket['storage_class'],\n            'iamConfiguration': {\n                'uniformBucketLevelAccess': {\n                    'enabled': True,\n                },\n            },\n            'logging': {\n                'logBucket': logs_bucket_id,\n            },\n            'versioning': {\n                'enabled': True,\n            },\n        },\n        'accessControl': {\n            'gcpIamPolicy': {\n                'bindings': bindings,\n            },\n        },\n    }\n    if previous_gcs_bucket:\n      data_bucket_resource['metadata'] = {\n          'dependsOn': [previous_gcs_bucket],\n      }\n    resources.append(data_bucket_resource)\n    previous_gcs_bucket = data_bucket_id\n\n    # Create a logs-based metric for unexpected users, if a list of expected\n    # users is provided.\n    if 'expected_users' in data_bucket:\n      unexpected_access_filter = (\n          'resource.type=gcs_bucket AND '\n          'logName=projects/%(project_id)s/logs/'\n          'cloudaudit.googleapis.com%%2Fdata_access AND '\n          'protoPayload.resourceName=projects/_/buckets/%(bucket_id)s AND '\n          # Permission Denied (status.code 7) has no principalEmail saved.\n          'protoPayload.status.code!=7 AND '\n          'protoPayload.authenticationInfo.principalEmail!=(%(exp_users)s)') % {\n              'project_id': project_id,\n              'bucket_id': data_bucket_id,\n              'exp_users': (' AND '.join(data_bucket['expected_users']))\n          }\n      resources.append({\n          'name': 'unexpected-access-' + data_bucket_id,\n          'type': 'logging.v2.metric',\n          'properties': {\n              'metric':\n                  'unexpected-access-' + data_bucket_id,\n              'description':\n                  'Count of unexpected data access to ' + data_bucket_id + '.',\n              'filter':\n                  unexpected_access_filter,\n              'metricDescriptor': {\n                  'metricKind':\n                      'DELTA',\n                  'valueType':\n                      'INT64',\n                  'unit':\n                      '1',\n                  'labels': [{\n                      'key': 'user',\n                      'valueType': 'STRING',\n                      'description': 'Unexpected user',\n                  }],\n              },\n              'labelExtractors': {\n                  'user':\n                      'EXTRACT(protoPayload.authenticationInfo.principalEmail)'\n              },\n          },\n      })\n\n  # Create a Pub/Sub topic for the Cloud Healthcare service account to publish\n  # to, with a subscription for the readwrite group.\n  if 'pubsub' in context.properties:\n    pubsub_config = context.properties['pubsub']\n    topic_name = pubsub_config['topic']\n    publisher_account = pubsub_config['publisher_account']\n    resources.append({\n        'name': topic_name,\n        'type': 'pubsub.v1.topic',\n        'properties': {\n            'topic': topic_name,\n        },\n        'accessControl': {\n            'gcpIamPolicy': {\n                'bindings': [{\n                    'role': 'roles/pubsub.publisher',\n                    'members': ['serviceAccount:' + publisher_account],\n                }],\n            },\n        },\n    })\n    resources.append({\n        'name': pubsub_config['subscription'],\n        'type': 'pubsub.v1.subscription',\n        'properties': {\n            'subscription': pubsub_config['subscription'],\n            'topic': 'projects/{}/topics/{}'.format(project_id, topic_name),\n            'ackDeadlineSeconds': pubsub_config['ack_deadline_sec']\n        },\n        'accessControl': {\n            'gcpIamPolicy': {\n                'bindings': [{\n                    'role':\n                        'roles/pubsub.editor',\n                    'members': [\n                        'group:' + writer for writer in\n                        context.properties['data_readwrite_groups']\n                    ],\n                },],\n            },\n        },\n        'metadata': {\n            'dependsOn': [topic_name],\n        },\n    })\n\n  # Create Logs-based metrics for IAM policy changes.\n  policy_change_filter = ('protoPayload.methodName=\"SetIamPolicy\" OR\\n'\n                          'protoPayload.methodName:\".setIamPolicy\"')\n  resources.append({\n      'name': 'iam-policy-change-count',\n      'type': 'logging.v2.metric',\n      'properties': {\n          'metric': 'iam-policy-change-count',\n          'description': 'Count of IAM policy changes.',\n          'filter': policy_change_filter,\n          'metricDescriptor': {\n              'metricKind':\n                  'DELTA',\n              'valueType':\n                  'INT64',\n              'unit':\n                  '1',\n              'labels': [{\n                  'key': 'user',\n                  'valueType': 'STRING',\n                  'description': 'Unexpected user',\n              }],\n          },\n          'labelExtractors': {\n              'user': 'EXTRACT(protoPayload.authenticationInfo.principalEmail)'\n          },\n      },\n  })\n\n  # Create Logs-based metrics for GCS bucket permission changes.\n  bucket_change_filter = \"\"\"\n      resource.type=gcs_bucket AND\n      protoPayload.serviceName=storage.googleapis.com AND\n      (protoPayload.methodName=storage.setIamPermissions OR\n       protoPayload.methodName=storage.objects.update)\"\"\"\n  resources.append({\n      'name': 'bucket-permission-change-count',\n      'type': 'logging.v2.metric',\n      'properties': {\n          'metric': 'bucket-permission-change-count',\n          'description': 'Count of GCS permissions changes.',\n          'filter': bucket_change_filter,\n          'metricDescriptor': {\n              'metricKind':\n                  'DELTA',\n              'valueType':\n                  'INT64',\n              'unit':\n                  '1',\n              'labels': [{\n                  'key': 'user',\n                  'valueType': 'STRING',\n                  'description': 'Unexpected user',\n              }],\n          },\n          'labelExtractors': {\n              'user': 'EXTRACT(protoPayload.authenticationInfo.principalEmail)'\n          },\n      },\n  })\n\n  # Create Logs-based metrics for Bigquery permission changes.\n  bigquery_change_filter = ('resource.type=\"bigquery_resource\" AND\\n'\n                            'protoPayload.methodName=\"datasetservice.update\"')\n  resources.append({\n      'name': 'bigquery-settings-change-count',\n      'type': 'logging.v2.metric',\n      'properties': {\n          'metric': 'bigquery-settings-change-count',\n          'description': 'Count of bigquery permission changes.',\n          'filter': bigquery_change_filter,\n          'metricDescriptor': {\n              'metricKind':\n                  'DELTA',\n              'valueType':\n                  'INT64',\n              'unit':\n                  '1',\n              'labels': [{\n                  'key': 'user',\n                  'valueType': 'STRING',\n                  'description': 'Unexpected user',\n              }],\n          },\n          'labelExtractors': {\n              'user': 'EXTRACT(protoPayload.authenticationInfo.principalEmail)'\n          },\n      },\n  })\n\n  # Enable data-access logging. UPDATE_ALWAYS is added to metadata to get a new\n  # etag each time.\n  resources.extend([\n      {\n          'name': 'audit-configs-get-iam-etag',\n          'action': ('gcp-types/cloudresourcemanager-v1:'\n                     'cloudresourcemanager.projects.getIamPolicy'),\n          'properties': {\n              'resource': project_id,\n          },\n          'metadata': {\n              'dependsOn': ['set-project-bindings-patch-iam-policy'],\n              'runtimePolicy': ['UPDATE_ALWAYS'],\n          },\n      },\n      {\n          'name': 'audit-configs-patch-iam-policy',\n          'action': ('gcp-types/cloudresourcemanager-v1:'\n                     'cloudresourcemanager.projects.setIamPolicy'),\n          'properties': {\n              'resource': project_id,\n              'policy': {\n                  'etag':\n                      '$(ref.audit-configs-get-iam-etag.etag)',\n                  'auditConfigs': [{\n                      'auditLogConfigs': [\n                          {\n                              'logType': 'ADMIN_READ'\n                          },\n                          {\n                              'logType': 'DATA_WRITE'\n                          },\n                          {\n                              'logType': 'DATA_READ'\n                          },\n                      ],\n                      'service': 'allServices',\n                  }],\n              },\n              'updateMask': 'auditConfigs,etag',\n          },\n          'metadata': {\n              'dependsOn': ['audit-configs-get-iam-etag'],\n              'runtimePolicy': ['UPDATE_ON_CHANGE'],\n          },\n      },\n  ])\n\n  return {'resources': resources}\n"),
+	"data_project.py.schema":      []byte("# Copyright 2018 Google LLC\n#\n# Licensed under the Apache License, Version 2.0 (the \"License\");\n# you may not use this file except in compliance with the License.\n# You may obtain a copy of the License at\n#\n#     https://www.apache.org/licenses/LICENSE-2.0\n#\n# Unless required by applicable law or agreed to in writing, software\n# distributed under the License is distributed on an \"AS IS\" BASIS,\n# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.\n# See the License for the specific language governing permissions and\n# limitations under the License.\n\ninfo:\n  title: Data Project\n  description: |\n    Set up a data project, configuring IAM policies, enabling audit logging,\n    creating GCS buckets, BigQuery datasets and Logs-based metrics.\n\nimports:\n- path: data_project.py\n\nrequired:\n- owners_group\n- auditors_group\n- has_organization\n\nproperties:\n  has_organization:\n    type: boolean\n    description: |\n      If true, this project is under an organization, so the Owners role can be\n      assigned to a group. Otherwise, the owners_group is granted\n      resourcemanager.projectIamAdmin instead, which has permission to grant\n      the owner role to users.\n  remove_owner_user:\n    type: string\n    description: |\n      If provided, and has_organization if true, then remove this user as an\n      owner of the project when adding owners_group as an owner.\n  owners_group:\n    type: string\n    description: Owners group for this project.\n  editors_group:\n    type: string\n    description: Optional editors group for this project. Not recommended.\n  auditors_group:\n    type: string\n    description: Group to be granted access to audit logs in this project.\n  data_readwrite_groups:\n    type: array\n    description: |\n      Groups to be granted Read/Write access to non-logging GCS buckets,\n      BigQuery datasets and Pubsub subscriptions in this project.\n    items:\n      type: string\n  data_readonly_groups:\n    type: array\n    description: |\n      Groups to be granted Read-only access to non-logging GCS buckets and\n      BigQuery datasets in this project.\n    items:\n      type: string\n  additional_project_permissions:\n    type: array\n    description: |\n      Additional project-level roles to grant to members, not covered by the\n      groups above. These are required in special cases but generally not\n      recommended.\n    items:\n      type: object\n      properties:\n        roles:\n          type: array\n          description: A list of roles to grant to each of the listed members.\n          items:\n            type: string\n        members:\n          type: array\n          description: A list of members to be granted each of the listed roles.\n          items:\n            type: string\n  local_audit_logs:\n    type: object\n    description: |\n      Configuration of log storage if saved in the new project. Config must\n      contain either local_audit_logs or remote_audit_logs, but not both.\n    required:\n      - logs_bigquery_dataset\n    properties:\n      logs_gcs_bucket:\n        type: object\n        description: |\n          GCS Bucket in the new project for holding GCS audit logs. Required if\n          adding GCS data buckets.\n        required:\n        - location\n        - storage_class\n        - ttl_days\n        properties:\n          location:\n            type: string\n            description: Regional or multi-regional location of the bucket.\n          storage_class:\n            type: string\n            description: Storage class of the bucket.\n          ttl_days:\n            type: integer\n            description: TTL on objects in this bucket.\n      logs_bigquery_dataset:\n        type: object\n        description: |\n          BigQuery dataset sink in the new project for holding audit logs.\n        required:\n        - location\n        properties:\n          location:\n            type: string\n            description: Location of the dataset.\n  remote_audit_logs:\n    type: object\n    description: |\n      Configuration of log storage if saved in an separate project. Config must\n      contain either local_audit_logs or remote_audit_logs, but not both.\n    required:\n    - audit_logs_project_id\n    - logs_bigquery_dataset_id\n    properties:\n      audit_logs_project_id:\n        type: string\n        description: |\n          ID of the GCP project that stores GCS audit logs for this project.\n      logs_gcs_bucket_name:\n        type: string\n        description: |\n          Name of the GCS bucket to hold logs for this project. Required if\n          adding data buckets.\n      logs_bigquery_dataset_id:\n        type: string\n        description: |\n          ID of the BigQuery dataset sink to hold audit logs for this project.\n  bigquery_datasets:\n    type: array\n    description: List of BigQuery (non-logs) datasets to create.\n    items:\n      type: object\n      required:\n      - name\n      - location\n      properties:\n        name:\n          type: string\n          description: Name of the BiqQuery dataset.\n        location:\n          type: string\n          description: Location of the dataset.\n  data_buckets:\n    type: array\n    description: List of GCS (non-logs) buckets to create.\n    items:\n      type: object\n      required:\n      - name\n      - location\n      - storage_class\n      properties:\n        name_suffix:\n          type: string\n          description: |\n            Suffix appended to project_id as the name of the GCS bucket.\n        location:\n          type: string\n          description: Regional or multi-regional location of the bucket.\n        storage_class:\n          type: string\n          description: Storage class of the bucket.\n        expected_users:\n          type: array\n          description: |\n            Optional list of expected users to access this bucket. Unexpected\n            users will increment a logs-based metric.\n          items:\n            type: string\n  pubsub:\n    type: object\n    description: |\n      The topic that the given service account can publish updates to.\n    required:\n    - topic\n    - subscription\n    - publisher_account\n    - ack_deadline_sec\n    properties:\n      topic:\n        type: string\n        description: Name of the pubsub topic.\n      subscription:\n        type: string\n        description: Name of the pubsub subscription.\n      publisher_account:\n        type: string\n        description: Service account that publishes updates to topic.\n      ack_deadline_sec:\n        type: integer\n        description: Ack deadline for the pubsub subscription.\n"),
+	"gce_vms.py":                  []byte("# Copyright 2018 Google LLC\n#\n# Licensed under the Apache License, Version 2.0 (the \"License\");\n# you may not use this file except in compliance with the License.\n# You may obtain a copy of the License at\n#\n#     https://www.apache.org/licenses/LICENSE-2.0\n#\n# Unless required by applicable law or agreed to in writing, software\n# distributed under the License is distributed on an \"AS IS\" BASIS,\n# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.\n# See the License for the specific language governing permissions and\n# limitations under the License.\n\"\"\"Creates new GCE VMs with specified zone, machine type and boot image.\"\"\"\n\n\ndef generate_config(context):\n  \"\"\"Generate Deployment Manager configuration.\"\"\"\n  resources = []\n\n  vm_names_to_shutdown = set(context.properties.get('vm_names_to_shutdown', []))\n\n  for vm in context.properties['gce_instances']:\n    vm_name = vm['name']\n    zone = vm['zone']\n    machine_type = 'zones/{}/machineTypes/{}'.format(zone, vm['machine_type'])\n    boot_image = vm['boot_image_name']\n\n    # Create a new VM.\n    vm_resource = {\n        'name': vm_name,\n        'type': 'compute.v1.instance',\n        'properties': {\n            'zone':\n                zone,\n            'machineType':\n                machine_type,\n            'disks': [{\n                'deviceName': 'boot',\n                'type': 'PERSISTENT',\n                'boot': True,\n                'autoDelete': True,\n                'initializeParams': {\n                    'sourceImage': boot_image,\n                },\n            }],\n            'networkInterfaces': [{\n                'network':\n                    'global/networks/default',\n                'accessConfigs': [{\n                    'name': 'External NAT',\n                    'type': 'ONE_TO_ONE_NAT',\n                }],\n            }]\n        },\n    }\n\n    metadata = vm.get('metadata', {})\n    if metadata:\n      vm_resource['properties']['metadata'] = metadata\n\n    if vm_name in vm_names_to_shutdown:\n      resource_name = 'initial-stop-' + vm_name\n      resources.append({\n          'name': resource_name,\n          'action': 'gcp-types/compute-v1:compute.instances.stop',\n          'properties': {\n              'instance': vm_name,\n              'zone': zone,\n          },\n          'metadata': {\n              'runtimePolicy': ['UPDATE_ALWAYS'],\n          },\n      })\n      metadata['dependsOn'] = [resource_name]\n\n    resources.append(vm_resource)\n\n    method = 'start' if vm['start_vm'] else 'stop'\n    resources.append({\n        'name': 'final-{}-{}'.format(method, vm_name),\n        'action': 'gcp-types/compute-v1:compute.instances.' + method,\n        'properties': {\n            'instance': vm_name,\n            'zone': zone,\n        },\n        'metadata': {\n            'dependsOn': [vm_name],\n            'runtimePolicy': ['UPDATE_ALWAYS'],\n        },\n    })\n\n  # Create firewall rules (if any).\n  for rule in context.properties.get('firewall_rules', []):\n    name = rule.pop('name')\n    resources.append({\n        'name': name,\n        'type': 'compute.v1.firewall',\n        'properties': rule\n    })\n\n  return {'resources': resources}\n"),
+	"gce_vms.py.schema":           []byte("# Copyright 2018 Google LLC\n#\n# Licensed under the Apache License, Version 2.0 (the \"License\");\n# you may not use this file except in compliance with the License.\n# You may obtain a copy of the License at\n#\n#     https://www.apache.org/licenses/LICENSE-2.0\n#\n# Unless required by applicable law or agreed to in writing, software\n# distributed under the License is distributed on an \"AS IS\" BASIS,\n# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.\n# See the License for the specific language governing permissions and\n# limitations under the License.\n\ninfo:\n  title: GCE VMs\n  description: |\n    Creates Google Compute Engine VMs and firewall rules.\n\nimports:\n- path: gce_vms.py\n\nrequired:\n- gce_instances\n\nproperties:\n  gce_instances:\n    type: array\n    description: List of VM instances to create.\n    items:\n      type: object\n      required:\n      - name\n      - zone\n      - machine_type\n      - boot_image_name\n      - start_vm\n      properties:\n        name:\n          type: string\n          description: Name of the VM to create.\n        zone:\n          type: string\n          description: Zone of the VM, for example, us-central1-f.\n        machine_type:\n          type: string\n          description: The type of the new instance, for example n1-standard-1.\n        boot_image_name:\n          type: string\n          description: |\n            Name of an existing image to use as the source to create a boot\n            disk. e.g. global/images/my_boot_image\n        start_vm:\n          type: boolean\n          description: If True, leave the new VM in a started state.\n        startup_script:\n          type: string\n          description: Script to run when start the VM.\n  vm_names_to_shutdown:\n    type: array\n    description: |\n      Names of VMs to shut down prior to deployment. VMs must be shut down in\n      order to perform an update.\n    items:\n      type: string\n  firewall_rules:\n    type: array\n    description: |\n      Optional list of firewall rules. See\n      https://cloud.google.com/compute/docs/reference/rest/v1/firewalls for\n      details of allowed fields in a firewall rule.\n    items:\n      type: object\n      required:\n      - name\n"),
+	"metric.py":                   []byte("# Copyright 2018 Google LLC\n#\n# Licensed under the Apache License, Version 2.0 (the \"License\");\n# you may not use this file except in compliance with the License.\n# You may obtain a copy of the License at\n#\n#     https://www.apache.org/licenses/LICENSE-2.0\n#\n# Unless required by applicable law or agreed to in writing, software\n# distributed under the License is distributed on an \"AS IS\" BASIS,\n# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.\n# See the License for the specific language governing permissions and\n# limitations under the License.\n# TODO: add a schema?\n\"\"\"Configures a logging metric.\"\"\"\n\n\ndef generate_config(context):\n  \"\"\"Generate Deployment Manager config.\"\"\"\n\n  return {\n      'resources': [{\n          'name': context.properties['metric'],\n          'type': 'logging.v2.metric',\n          'properties': context.properties,\n      }]\n  }\n"),
+	"remote_audit_logs.py":        []byte("# Copyright 2018 Google LLC\n#\n# Licensed under the Apache License, Version 2.0 (the \"License\");\n# you may not use this file except in compliance with the License.\n# You may obtain a copy of the License at\n#\n#     https://www.apache.org/licenses/LICENSE-2.0\n#\n# Unless required by applicable law or agreed to in writing, software\n# distributed under the License is distributed on an \"AS IS\" BASIS,\n# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.\n# See the License for the specific language governing permissions and\n# limitations under the License.\n\"\"\"Creates new GCS buckets and BigQuery datasets in an Audit Logs project.\"\"\"\n\n\ndef generate_config(context):\n  \"\"\"Generate Deployment Manager configuration.\"\"\"\n\n  project_id = context.env['project']\n  owners_group = context.properties['owners_group']\n  auditors_group = context.properties['auditors_group']\n  resources = []\n\n  # The GCS bucket to hold logs.\n  logs_bucket = context.properties.get('logs_gcs_bucket')\n  if logs_bucket:\n    resources.append({\n        'name': logs_bucket['name'],\n        'type': 'storage.v1.bucket',\n        'properties': {\n            'location': logs_bucket['location'],\n            'storageClass': logs_bucket['storage_class'],\n            'iamConfiguration': {\n                'uniformBucketLevelAccess': {\n                    'enabled': True,\n                },\n            },\n            'lifecycle': {\n                'rule': [{\n                    'action': {\n                        'type': 'Delete'\n                    },\n                    'condition': {\n                        'age': logs_bucket['ttl_days'],\n                        'isLive': True,\n                    },\n                }],\n            },\n        },\n        'accessControl': {\n            'gcpIamPolicy': {\n                'bindings': [\n                    {\n                        'role': 'roles/storage.admin',\n                        'members': ['group:' + owners_group,],\n                    },\n                    {\n                        'role': 'roles/storage.objectCreator',\n                        'members': ['group:cloud-storage-analytics@google.com'],\n                    },\n                    {\n                        'role': 'roles/storage.objectViewer',\n                        'members': ['group:' + auditors_group,],\n                    },\n                ],\n            },\n        },\n    })\n\n  # BigQuery dataset to hold audit logs.\n  logs_dataset = context.properties.get('logs_bigquery_dataset')\n  if logs_dataset:\n    dataset_id = logs_dataset['name']\n    resources.append({\n        'name': dataset_id,\n        'type': 'bigquery.v2.dataset',\n        'properties': {\n            'datasetReference': {\n                'datasetId': dataset_id,\n            },\n            'location': logs_dataset['location'],\n        },\n    })\n\n    # Update permissions for the dataset. This also removes the deployment\n    # manager service account's access.\n    resources.append({\n        'name': 'update-' + dataset_id,\n        'action': 'gcp-types/bigquery-v2:bigquery.datasets.patch',\n        'properties': {\n            'projectId':\n                project_id,\n            'datasetId':\n                dataset_id,\n            'access': [\n                {\n                    'role': 'OWNER',\n                    'groupByEmail': owners_group,\n                },\n                {\n                    'role': 'READER',\n                    'groupByEmail': auditors_group,\n                },\n                {\n                    'role': 'WRITER',\n                    'userByEmail': logs_dataset['log_sink_service_account'],\n                },\n            ],\n        },\n        'metadata': {\n            'dependsOn': [dataset_id],\n        },\n    })\n\n  return {'resources': resources}\n"),
+	"remote_audit_logs.py.schema": []byte("# Copyright 2018 Google LLC\n#\n# Licensed under the Apache License, Version 2.0 (the \"License\");\n# you may not use this file except in compliance with the License.\n# You may obtain a copy of the License at\n#\n#     https://www.apache.org/licenses/LICENSE-2.0\n#\n# Unless required by applicable law or agreed to in writing, software\n# distributed under the License is distributed on an \"AS IS\" BASIS,\n# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.\n# See the License for the specific language governing permissions and\n# limitations under the License.\n\ninfo:\n  title: Audit Logs\n  description: Create GCS buckets and/or BigQuery datasets to hold audit logs.\n\nimports:\n- path: remote_audit_logs.py\n\nrequired:\n- owners_group\n- auditors_group\n\nproperties:\n  owners_group:\n    type: string\n    description: Owners group for audit logs.\n  auditors_group:\n    type: string\n    description: Group to be granted read access to audit logs.\n  logs_gcs_bucket:\n    type: object\n    description: GCS logs bucket to create.\n    required:\n    - name\n    - location\n    - storage_class\n    - ttl_days\n    properties:\n      name:\n        type: string\n        description: Name of the GCS bucket.\n      location:\n        type: string\n        description: Regional or multi-regional location of the bucket.\n      storage_class:\n        type: string\n        description: Storage class of the bucket.\n      ttl_days:\n        type: integer\n        description: TTL on objects in this bucket.\n  logs_bigquery_dataset:\n    type: object\n    description: BigQuery audit log dataset to create.\n    required:\n    - name\n    - location\n    - log_sink_service_account\n    properties:\n      name:\n        type: string\n        description: Name of the BiqQuery dataset.\n      location:\n        type: string\n        description: Location of the dataset.\n      log_sink_service_account:\n        type: string\n        description: Service account for the Logging Sink that exports logs."),
+}